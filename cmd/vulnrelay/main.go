@@ -8,17 +8,29 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/acceptance"
+	"github.com/jfeddern/VulnRelay/internal/audit"
+	"github.com/jfeddern/VulnRelay/internal/auth"
 	"github.com/jfeddern/VulnRelay/internal/engine"
 	"github.com/jfeddern/VulnRelay/internal/metrics"
+	"github.com/jfeddern/VulnRelay/internal/notify"
 	"github.com/jfeddern/VulnRelay/internal/providers"
+	awsprovider "github.com/jfeddern/VulnRelay/internal/providers/aws"
+	"github.com/jfeddern/VulnRelay/internal/providers/local"
+	"github.com/jfeddern/VulnRelay/internal/rpc"
 	"github.com/jfeddern/VulnRelay/internal/server"
+	"github.com/jfeddern/VulnRelay/internal/severity"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 )
 
@@ -61,77 +73,756 @@ func main() {
 
 func parseConfig() *engine.Config {
 	config := &engine.Config{}
+	var severityCacheTTLs string
+	var corsAllowedOrigins string
+	var riskScoreWeights string
+	var registryHosts string
+	var repositoryAllowlist string
+	var ignoreContainers string
+	var namespaces string
+	var allowedSeverities string
+	var retainedSeverities string
+	var severityOrder string
+	var webhookThresholds string
+	var metricsDropLabels string
+	var healthyScanStatuses string
 
-	flag.StringVar(&config.Mode, "mode", "cluster", "Operation mode: cluster or local")
+	flag.StringVar(&config.Mode, "mode", "cluster", "Operation mode: cluster, local or another registered provider")
 	flag.IntVar(&config.Port, "port", 9090, "Port to expose metrics on")
+	flag.IntVar(&config.GRPCPort, "grpc-port", 0, "Port to expose the gRPC vulnerability API on (0 = disabled)")
+	flag.IntVar(&config.MaxHeaderBytes, "max-header-bytes", 1<<20, "Maximum size of HTTP request headers in bytes")
+	flag.Int64Var(&config.MaxRequestBodyBytes, "max-request-body-bytes", 1<<20, "Maximum size of HTTP request bodies in bytes; larger requests are rejected with 413 (0 = unlimited)")
 	flag.StringVar(&config.ECRAccountID, "ecr-account-id", "", "AWS account ID for ECR registry")
 	flag.StringVar(&config.ECRRegion, "ecr-region", "", "AWS region for ECR registry")
-	flag.StringVar(&config.ImageListFile, "image-list-file", "", "Path to JSON file with image list (required for local mode)")
+	flag.StringVar(&config.AssumeRoleARN, "assume-role-arn", "", "IAM role ARN to assume for ECR access")
+	flag.StringVar(&config.CrossAccountRole, "cross-account-role", "ECRVulnerabilityExporterRole", "Role name or ARN template ({account_id}) assumed for cross-account ECR access")
+	flag.StringVar(&config.ImageListFile, "image-list-file", "", "Path, http(s):// or s3:// URL of the JSON image list (required for local mode)")
+	flag.StringVar(&config.DefaultImageTag, "default-image-tag", "", "Tag applied to image list entries without a tag or digest, e.g. latest (default none)")
 	flag.DurationVar(&config.ScrapeInterval, "scrape-interval", 5*time.Minute, "Interval to refresh data from ECR")
 	flag.BoolVar(&config.MockMode, "mock", false, "Enable mock mode for local testing (no external API calls)")
+	flag.StringVar(&config.ImagePlatform, "image-platform", "linux/amd64", "Platform (os/arch[/variant]) scanned for multi-arch images")
+	flag.BoolVar(&config.ResolveDigests, "resolve-digests", false, "Include the digest of the scanned image in /vulnerabilities results")
+	flag.BoolVar(&config.TriggerScans, "trigger-scans", false, "Start an ECR scan of images without one, at most once per 24h per image")
+	flag.Float64Var(&config.SourceRequestsPerSecond, "ecr-rate-limit", 0, "Maximum ECR requests per second (0 = unlimited)")
+	flag.BoolVar(&config.AdaptiveConcurrency, "adaptive-concurrency", false, "Halve a registry's concurrent ECR calls when ECR throttles and ramp them back up as calls succeed")
+	flag.StringVar(&severityCacheTTLs, "cache-ttl-by-severity", "", "Per-severity cache TTLs, e.g. CRITICAL=5m,HIGH=15m")
+	flag.IntVar(&config.MaxImagesPerCycle, "max-images-per-cycle", 0, "Maximum images processed per collection, in image URI order (0 = unlimited)")
+	flag.BoolVar(&config.PipelineDiscovery, "pipeline-discovery", false, "Start fetching vulnerabilities as each namespace is discovered instead of after discovery completes (cluster mode)")
+	flag.IntVar(&config.MaxRetainedFindings, "max-retained-findings", 0, "Maximum findings kept in memory; beyond it, the images with the most findings keep only counts (0 = unlimited)")
+	flag.IntVar(&config.SnapshotHistory, "snapshot-history", 1, "Number of previous collections kept for /vulnerabilities?snapshot=previous (0 = none)")
+	flag.DurationVar(&config.CacheRefreshAhead, "cache-refresh-ahead", 0, "Refresh cached entries read within this window of expiry in the background (0 disables)")
+	flag.StringVar(&riskScoreWeights, "risk-score-weights", "", "Per-severity risk score weights, e.g. CRITICAL=10,HIGH=5,MEDIUM=2,LOW=1")
+	flag.BoolVar(&config.DisableVulnerabilitiesEndpoint, "disable-vulnerabilities-endpoint", false, "Disable the /vulnerabilities and /cves JSON endpoints (only /metrics and /health are served)")
+	flag.BoolVar(&config.DebugIdentityEndpoint, "debug-identity-endpoint", false, "Serve the AWS identity used for ECR requests on /debug/identity (requires -oidc-jwks-url)")
+	flag.IntVar(&config.APIMaxFindings, "api-max-findings", 0, "Maximum total findings returned by /vulnerabilities (0 = unlimited)")
+	flag.BoolVar(&config.APIPrettyPrint, "api-pretty-print", false, "Pretty-print JSON API responses by default; ?pretty=false or ?pretty=true overrides it per request")
+	flag.IntVar(&config.MetricsMaxSeries, "metrics-max-series", 0, "Maximum series emitted per metric on /metrics (0 = unlimited)")
+	flag.IntVar(&config.MetricsMaxImageURILength, "metrics-max-image-uri-length", 200, "Maximum length of the image_uri metric label; longer URIs are truncated")
+	flag.BoolVar(&config.MetricsReleaseLabel, "metrics-release-label", false, "Add the workload's Helm release as a release label on per-image metrics")
+	flag.StringVar(&config.MetricsClusterName, "cluster-name", "", "Cluster name added as a cluster label to every metric, to tell clusters apart in a shared Prometheus")
+	flag.BoolVar(&config.KeepStaleOnFailure, "keep-stale-on-failure", false, "Keep an image's previous data, flagged as stale, when fetching it fails")
+	flag.DurationVar(&config.MaxDataAge, "max-data-age", 0, "Drop an image's data once it hasn't been refreshed for this long, even while collections fail (0 = never)")
+	flag.BoolVar(&config.MetricsFreshOnly, "metrics-fresh-only", false, "Omit images with stale data from /metrics; /vulnerabilities still lists them flagged as stale")
+	flag.StringVar(&metricsDropLabels, "metrics-drop-labels", "", "Comma-separated labels removed from per-image metrics: "+strings.Join(metrics.DroppableLabels, ", "))
+	flag.BoolVar(&config.MetricsDescriptionInfo, "metrics-description-info", false, "Expose CVE descriptions on ecr_cve_description_info, one series per CVE, instead of a description label on ecr_vulnerability_info")
+	flag.StringVar(&healthyScanStatuses, "metrics-healthy-scan-statuses", "", "Comma-separated scan statuses ecr_image_scan_status reports as 1, e.g. COMPLETE,ACTIVE for continuous scanning (default COMPLETE)")
+	flag.BoolVar(&config.MetricsInfluxEndpoint, "metrics-influx-endpoint", false, "Serve per-image vulnerability counts in the InfluxDB line protocol on /metrics/influx")
+	flag.BoolVar(&config.MetricsPlatformLabel, "metrics-platform-label", false, "Add the platform scanned for multi-arch images as a platform label on per-image metrics")
+	flag.BoolVar(&config.FailOnScanErrors, "fail-on-scan-errors", false, "Report not-ready on /ready when the fraction of failed scans exceeds -scan-error-threshold")
+	flag.Float64Var(&config.ScanErrorThreshold, "scan-error-threshold", 0, "Highest tolerated fraction (0-1) of failed scans before /ready reports not-ready")
+	flag.StringVar(&config.ReadinessSeverityThreshold, "readiness-severity-threshold", "", "Report not-ready on /ready while any image has findings of this severity or a more severe one, e.g. HIGH (default findings are ignored)")
+	flag.StringVar(&severityOrder, "severity-order", "", "Comma-separated severities, most severe first, used to rank and filter JSON API results (default CRITICAL,HIGH,MEDIUM,LOW)")
+	flag.StringVar(&allowedSeverities, "allowed-severities", "", "Comma-separated severities to keep, e.g. CRITICAL,HIGH,MEDIUM,LOW; other findings are dropped (default all)")
+	flag.BoolVar(&config.SkipUntaggedImages, "skip-untagged-images", false, "Don't scan ECR images referenced by digest only (repository@sha256:...)")
+	flag.StringVar(&retainedSeverities, "retained-finding-severities", "", "Comma-separated severities whose ECR findings are kept in memory, e.g. CRITICAL,HIGH; counts still include every finding (default all)")
+	flag.StringVar(&config.AcceptedCVEsFile, "accepted-cves-file", "", "Path to JSON file with accepted CVEs excluded from active counts")
+	flag.BoolVar(&config.OnlyRunning, "only-running", false, "Only scan images used by running pods (skips workloads scaled to zero)")
+	flag.BoolVar(&config.SkipSuspendedCronJobs, "skip-suspended-cronjobs", false, "Don't scan images of CronJobs whose schedule is suspended")
+	flag.BoolVar(&config.RequireScanAnnotation, "require-scan-annotation", false, "Only scan workloads annotated with vulnrelay.io/scan: \"true\"")
+	flag.StringVar(&repositoryAllowlist, "repository-allowlist", "", "Comma-separated ECR repository globs to scan, e.g. team-a/*,payments (default all)")
+	flag.IntVar(&config.InitialCollectionRetries, "initial-collection-retries", 3, "How often a failed first collection is retried before waiting for the next scrape interval")
+	flag.DurationVar(&config.InitialCollectionBackoff, "initial-collection-backoff", 5*time.Second, "Wait before retrying a failed first collection, doubling per attempt up to 1m")
+	flag.BoolVar(&config.CheckRegistryScanning, "check-registry-scanning", false, "Read the ECR registry scanning configuration at startup and warn when enhanced scanning is off")
+	flag.StringVar(&config.Kubeconfig, "kubeconfig", "", "Path to a kubeconfig file; skips the in-cluster config (default $KUBECONFIG or ~/.kube/config outside a pod)")
+	flag.StringVar(&config.KubeContext, "kube-context", "", "Kubeconfig context to use; skips the in-cluster config (default current context)")
+	flag.DurationVar(&config.KubeStartupTimeout, "kube-startup-timeout", time.Minute, "How long to retry connecting to the Kubernetes API at startup (0 = single attempt)")
+	flag.StringVar(&namespaces, "namespaces", "", "Comma-separated namespaces to discover workloads in (default: all namespaces)")
+	flag.IntVar(&config.DiscoveryConcurrency, "discovery-concurrency", 4, "Maximum namespaces listed concurrently when -namespaces is set")
+	flag.StringVar(&ignoreContainers, "ignore-containers", "", "Comma-separated container name globs whose images are skipped, e.g. istio-proxy,*-sidecar")
+	flag.StringVar(&config.FieldSelector, "field-selector", "", "Kubernetes field selector restricting discovered workloads (e.g. metadata.name=api)")
+	flag.StringVar(&config.VulnerabilitySource, "vulnerability-source", "ecr", "Vulnerability source: ecr, harbor or another registered source")
+	flag.StringVar(&config.HarborURL, "harbor-url", "", "Harbor base URL (required for the harbor source)")
+	flag.StringVar(&config.HarborUsername, "harbor-username", "", "Harbor user or robot account name")
+	flag.StringVar(&config.HarborPassword, "harbor-password", "", "Harbor password or robot account secret")
+	flag.StringVar(&config.WebhookURL, "webhook-url", "", "Webhook URL notified of new vulnerabilities after each collection")
+	flag.StringVar(&config.WebhookMinSeverity, "webhook-min-severity", "CRITICAL", "Lowest severity notified for namespaces without a matching threshold")
+	flag.IntVar(&config.WebhookMaxRetries, "webhook-max-retries", notify.DefaultWebhookMaxRetries, "Retries of a failed webhook delivery, with jittered exponential backoff (0 = no retries)")
+	flag.DurationVar(&config.WebhookDeliveryTimeout, "webhook-delivery-timeout", notify.DefaultWebhookDeliveryTimeout, "Time limit for delivering a collection's webhook notifications, retries included")
+	flag.BoolVar(&config.WebhookNotifyAll, "webhook-notify-all", false, "Notify every finding meeting its threshold after each collection instead of only new ones")
+	flag.IntVar(&config.WebhookBatchSize, "webhook-batch-size", 0, "Findings per webhook notification, larger sets are split (0 = one notification)")
+	flag.IntVar(&config.WebhookDiffConcurrency, "webhook-diff-concurrency", 1, "Workers diffing each collection against the previous one for webhook notifications")
+	flag.StringVar(&webhookThresholds, "webhook-severity-thresholds", "", "Per-namespace notification thresholds as glob patterns, e.g. prod-*=HIGH,sandbox-*=CRITICAL")
+	flag.StringVar(&config.S3ExportBucket, "s3-export-bucket", "", "S3 bucket receiving the full vulnerability dataset as JSON after each collection")
+	flag.StringVar(&config.S3ExportPrefix, "s3-export-prefix", "", "Key prefix of the objects written to -s3-export-bucket")
+	flag.StringVar(&config.ElasticsearchURL, "elasticsearch-url", "", "Elasticsearch/OpenSearch _bulk URL receiving every finding after each collection")
+	flag.StringVar(&config.ElasticsearchIndex, "elasticsearch-index", notify.DefaultElasticsearchIndex, "Index the findings are written to")
+	flag.StringVar(&registryHosts, "registry-hosts", "", "Comma-separated extra registry host suffixes treated as ECR (e.g. DNS aliases)")
+	flag.StringVar(&config.OIDCIssuer, "oidc-issuer", "", "Expected OIDC token issuer (iss claim)")
+	flag.StringVar(&config.OIDCJWKSURL, "oidc-jwks-url", "", "OIDC JWKS URL; enables bearer token authentication for the JSON endpoints")
+	flag.StringVar(&config.OIDCAudience, "oidc-audience", "", "Required OIDC token audience (aud claim)")
+	flag.StringVar(&config.AuditLog, "audit-log", "", "Write JSON audit entries for JSON endpoint requests to stdout, stderr or a file path")
+	flag.StringVar(&corsAllowedOrigins, "cors-allowed-origins", "", "Comma-separated origins allowed for CORS requests (* for any)")
 	flag.Parse()
 
 	// Override with environment variables if set
-	if envMode := os.Getenv("MODE"); envMode != "" {
+	env := func(name string) string {
+		value, err := getEnv(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return value
+	}
+
+	if envMode := env("MODE"); envMode != "" {
 		config.Mode = envMode
 	}
-	if envPort := os.Getenv("PORT"); envPort != "" {
+	if envPort := env("PORT"); envPort != "" {
 		if port, err := fmt.Sscanf(envPort, "%d", &config.Port); err != nil || port != 1 {
 			log.Printf("Invalid PORT environment variable: %s", envPort)
 		}
 	}
-	if envAccountID := os.Getenv("AWS_ECR_ACCOUNT_ID"); envAccountID != "" {
+	if envGRPCPort := env("GRPC_PORT"); envGRPCPort != "" {
+		if port, err := strconv.Atoi(envGRPCPort); err == nil && port >= 0 {
+			config.GRPCPort = port
+		} else {
+			log.Printf("Invalid GRPC_PORT environment variable: %s", envGRPCPort)
+		}
+	}
+	if envMaxHeaderBytes := env("MAX_HEADER_BYTES"); envMaxHeaderBytes != "" {
+		if maxBytes, err := strconv.Atoi(envMaxHeaderBytes); err == nil && maxBytes >= 0 {
+			config.MaxHeaderBytes = maxBytes
+		} else {
+			log.Printf("Invalid MAX_HEADER_BYTES environment variable: %s", envMaxHeaderBytes)
+		}
+	}
+	if envMaxBodyBytes := env("MAX_REQUEST_BODY_BYTES"); envMaxBodyBytes != "" {
+		if maxBytes, err := strconv.ParseInt(envMaxBodyBytes, 10, 64); err == nil && maxBytes >= 0 {
+			config.MaxRequestBodyBytes = maxBytes
+		} else {
+			log.Printf("Invalid MAX_REQUEST_BODY_BYTES environment variable: %s", envMaxBodyBytes)
+		}
+	}
+	if envAccountID := env("AWS_ECR_ACCOUNT_ID"); envAccountID != "" {
 		config.ECRAccountID = envAccountID
 	}
-	if envRegion := os.Getenv("AWS_ECR_REGION"); envRegion != "" {
+	if envRegion := env("AWS_ECR_REGION"); envRegion != "" {
 		config.ECRRegion = envRegion
 	}
-	if envImageFile := os.Getenv("IMAGE_LIST_FILE"); envImageFile != "" {
+	if envRoleARN := env("AWS_IAM_ASSUME_ROLE_ARN"); envRoleARN != "" {
+		config.AssumeRoleARN = envRoleARN
+	}
+	if envCrossAccountRole := env("AWS_IAM_CROSS_ACCOUNT_ROLE"); envCrossAccountRole != "" {
+		config.CrossAccountRole = envCrossAccountRole
+	}
+	if envImageFile := env("IMAGE_LIST_FILE"); envImageFile != "" {
 		config.ImageListFile = envImageFile
 	}
-	if envInterval := os.Getenv("SCRAPE_INTERVAL"); envInterval != "" {
+	if envDefaultTag := env("DEFAULT_IMAGE_TAG"); envDefaultTag != "" {
+		config.DefaultImageTag = envDefaultTag
+	}
+	if envAcceptedFile := env("ACCEPTED_CVES_FILE"); envAcceptedFile != "" {
+		config.AcceptedCVEsFile = envAcceptedFile
+	}
+	if envInterval := env("SCRAPE_INTERVAL"); envInterval != "" {
 		if interval, err := time.ParseDuration(envInterval); err == nil {
 			config.ScrapeInterval = interval
 		}
 	}
-	if envMock := os.Getenv("MOCK_MODE"); envMock == "true" || envMock == "1" {
+	if envMock := env("MOCK_MODE"); envMock == "true" || envMock == "1" {
 		config.MockMode = true
 	}
+	if envPlatform := env("IMAGE_PLATFORM"); envPlatform != "" {
+		config.ImagePlatform = envPlatform
+	}
+	if envResolveDigests := env("RESOLVE_DIGESTS"); envResolveDigests == "true" || envResolveDigests == "1" {
+		config.ResolveDigests = true
+	}
+	if envTriggerScans := env("TRIGGER_SCANS"); envTriggerScans == "true" || envTriggerScans == "1" {
+		config.TriggerScans = true
+	}
+	if envRateLimit := env("AWS_ECR_RATE_LIMIT"); envRateLimit != "" {
+		if rps, err := strconv.ParseFloat(envRateLimit, 64); err == nil && rps >= 0 {
+			config.SourceRequestsPerSecond = rps
+		} else {
+			log.Printf("Invalid AWS_ECR_RATE_LIMIT environment variable: %s", envRateLimit)
+		}
+	}
+	if envAdaptive := env("ADAPTIVE_CONCURRENCY"); envAdaptive == "true" || envAdaptive == "1" {
+		config.AdaptiveConcurrency = true
+	}
+	if envTTLs := env("CACHE_TTL_BY_SEVERITY"); envTTLs != "" {
+		severityCacheTTLs = envTTLs
+	}
+	if envRefreshAhead := env("CACHE_REFRESH_AHEAD"); envRefreshAhead != "" {
+		if window, err := time.ParseDuration(envRefreshAhead); err == nil && window >= 0 {
+			config.CacheRefreshAhead = window
+		} else {
+			log.Printf("Invalid CACHE_REFRESH_AHEAD environment variable: %s", envRefreshAhead)
+		}
+	}
 
-	// Validate configuration
-	if !config.MockMode {
-		if config.ECRAccountID == "" || config.ECRRegion == "" {
-			log.Fatal("ECR account ID and region are required (unless using mock mode)")
+	if envDisable := env("DISABLE_VULNERABILITIES_ENDPOINT"); envDisable == "true" || envDisable == "1" {
+		config.DisableVulnerabilitiesEndpoint = true
+	}
+	if envDebugIdentity := env("DEBUG_IDENTITY_ENDPOINT"); envDebugIdentity == "true" || envDebugIdentity == "1" {
+		config.DebugIdentityEndpoint = true
+	}
+	if envMaxFindings := env("API_MAX_FINDINGS"); envMaxFindings != "" {
+		if maxFindings, err := strconv.Atoi(envMaxFindings); err == nil && maxFindings >= 0 {
+			config.APIMaxFindings = maxFindings
+		} else {
+			log.Printf("Invalid API_MAX_FINDINGS environment variable: %s", envMaxFindings)
+		}
+	}
+	if envPrettyPrint := env("API_PRETTY_PRINT"); envPrettyPrint == "true" || envPrettyPrint == "1" {
+		config.APIPrettyPrint = true
+	}
+	if envMaxImages := env("MAX_IMAGES_PER_CYCLE"); envMaxImages != "" {
+		if maxImages, err := strconv.Atoi(envMaxImages); err == nil && maxImages >= 0 {
+			config.MaxImagesPerCycle = maxImages
+		} else {
+			log.Printf("Invalid MAX_IMAGES_PER_CYCLE environment variable: %s", envMaxImages)
+		}
+	}
+	if envPipeline := env("PIPELINE_DISCOVERY"); envPipeline == "true" || envPipeline == "1" {
+		config.PipelineDiscovery = true
+	}
+	if envMaxFindings := env("MAX_RETAINED_FINDINGS"); envMaxFindings != "" {
+		if maxFindings, err := strconv.Atoi(envMaxFindings); err == nil && maxFindings >= 0 {
+			config.MaxRetainedFindings = maxFindings
+		} else {
+			log.Printf("Invalid MAX_RETAINED_FINDINGS environment variable: %s", envMaxFindings)
 		}
 	}
-	if config.Mode == "local" && !config.MockMode && config.ImageListFile == "" {
-		log.Fatal("Image list file is required for local mode (unless using mock mode)")
+	if envSnapshotHistory := env("SNAPSHOT_HISTORY"); envSnapshotHistory != "" {
+		if history, err := strconv.Atoi(envSnapshotHistory); err == nil && history >= 0 {
+			config.SnapshotHistory = history
+		} else {
+			log.Printf("Invalid SNAPSHOT_HISTORY environment variable: %s", envSnapshotHistory)
+		}
+	}
+	if envMaxSeries := env("METRICS_MAX_SERIES"); envMaxSeries != "" {
+		if maxSeries, err := strconv.Atoi(envMaxSeries); err == nil && maxSeries >= 0 {
+			config.MetricsMaxSeries = maxSeries
+		} else {
+			log.Printf("Invalid METRICS_MAX_SERIES environment variable: %s", envMaxSeries)
+		}
+	}
+	if envMaxImageURILength := env("METRICS_MAX_IMAGE_URI_LENGTH"); envMaxImageURILength != "" {
+		if maxLength, err := strconv.Atoi(envMaxImageURILength); err == nil && maxLength > 0 {
+			config.MetricsMaxImageURILength = maxLength
+		} else {
+			log.Printf("Invalid METRICS_MAX_IMAGE_URI_LENGTH environment variable: %s", envMaxImageURILength)
+		}
+	}
+	if envReleaseLabel := env("METRICS_RELEASE_LABEL"); envReleaseLabel == "true" || envReleaseLabel == "1" {
+		config.MetricsReleaseLabel = true
+	}
+	if envClusterName := env("CLUSTER_NAME"); envClusterName != "" {
+		config.MetricsClusterName = envClusterName
+	}
+	if envKeepStale := env("KEEP_STALE_ON_FAILURE"); envKeepStale == "true" || envKeepStale == "1" {
+		config.KeepStaleOnFailure = true
+	}
+	if envMaxDataAge := env("MAX_DATA_AGE"); envMaxDataAge != "" {
+		if age, err := time.ParseDuration(envMaxDataAge); err == nil && age >= 0 {
+			config.MaxDataAge = age
+		} else {
+			log.Printf("Invalid MAX_DATA_AGE environment variable: %s", envMaxDataAge)
+		}
+	}
+	if envFreshOnly := env("METRICS_FRESH_ONLY"); envFreshOnly == "true" || envFreshOnly == "1" {
+		config.MetricsFreshOnly = true
+	}
+	if envInflux := env("METRICS_INFLUX_ENDPOINT"); envInflux == "true" || envInflux == "1" {
+		config.MetricsInfluxEndpoint = true
+	}
+	if envDescriptionInfo := env("METRICS_DESCRIPTION_INFO"); envDescriptionInfo == "true" || envDescriptionInfo == "1" {
+		config.MetricsDescriptionInfo = true
+	}
+	if envPlatformLabel := env("METRICS_PLATFORM_LABEL"); envPlatformLabel == "true" || envPlatformLabel == "1" {
+		config.MetricsPlatformLabel = true
+	}
+	if envDropLabels := env("METRICS_DROP_LABELS"); envDropLabels != "" {
+		metricsDropLabels = envDropLabels
+	}
+	if envHealthyStatuses := env("METRICS_HEALTHY_SCAN_STATUSES"); envHealthyStatuses != "" {
+		healthyScanStatuses = envHealthyStatuses
+	}
+	if envFailOnScanErrors := env("FAIL_ON_SCAN_ERRORS"); envFailOnScanErrors == "true" || envFailOnScanErrors == "1" {
+		config.FailOnScanErrors = true
+	}
+	if envThreshold := env("SCAN_ERROR_THRESHOLD"); envThreshold != "" {
+		if threshold, err := strconv.ParseFloat(envThreshold, 64); err == nil {
+			config.ScanErrorThreshold = threshold
+		} else {
+			log.Printf("Invalid SCAN_ERROR_THRESHOLD environment variable: %s", envThreshold)
+		}
+	}
+	if envSeverityThreshold := env("READINESS_SEVERITY_THRESHOLD"); envSeverityThreshold != "" {
+		config.ReadinessSeverityThreshold = envSeverityThreshold
+	}
+	if envOnlyRunning := env("ONLY_RUNNING"); envOnlyRunning == "true" || envOnlyRunning == "1" {
+		config.OnlyRunning = true
+	}
+	if envSkipSuspended := env("SKIP_SUSPENDED_CRONJOBS"); envSkipSuspended == "true" || envSkipSuspended == "1" {
+		config.SkipSuspendedCronJobs = true
+	}
+	if envRequireScan := env("REQUIRE_SCAN_ANNOTATION"); envRequireScan == "true" || envRequireScan == "1" {
+		config.RequireScanAnnotation = true
+	}
+	if envKubeContext := env("KUBE_CONTEXT"); envKubeContext != "" {
+		config.KubeContext = envKubeContext
+	}
+	if envFieldSelector := env("FIELD_SELECTOR"); envFieldSelector != "" {
+		config.FieldSelector = envFieldSelector
+	}
+	if envOrigins := env("CORS_ALLOWED_ORIGINS"); envOrigins != "" {
+		corsAllowedOrigins = envOrigins
+	}
+	if envSource := env("VULNERABILITY_SOURCE"); envSource != "" {
+		config.VulnerabilitySource = envSource
+	}
+	if envHarborURL := env("HARBOR_URL"); envHarborURL != "" {
+		config.HarborURL = envHarborURL
+	}
+	if envHarborUsername := env("HARBOR_USERNAME"); envHarborUsername != "" {
+		config.HarborUsername = envHarborUsername
+	}
+	if envHarborPassword := env("HARBOR_PASSWORD"); envHarborPassword != "" {
+		config.HarborPassword = envHarborPassword
+	}
+	if envWebhookURL := env("WEBHOOK_URL"); envWebhookURL != "" {
+		config.WebhookURL = envWebhookURL
+	}
+	if envWebhookMinSeverity := env("WEBHOOK_MIN_SEVERITY"); envWebhookMinSeverity != "" {
+		config.WebhookMinSeverity = envWebhookMinSeverity
+	}
+	if envWebhookMaxRetries := env("WEBHOOK_MAX_RETRIES"); envWebhookMaxRetries != "" {
+		if retries, err := strconv.Atoi(envWebhookMaxRetries); err == nil && retries >= 0 {
+			config.WebhookMaxRetries = retries
+		} else {
+			log.Printf("Invalid WEBHOOK_MAX_RETRIES environment variable: %s", envWebhookMaxRetries)
+		}
+	}
+	if envWebhookDeliveryTimeout := env("WEBHOOK_DELIVERY_TIMEOUT"); envWebhookDeliveryTimeout != "" {
+		if timeout, err := time.ParseDuration(envWebhookDeliveryTimeout); err == nil && timeout > 0 {
+			config.WebhookDeliveryTimeout = timeout
+		} else {
+			log.Printf("Invalid WEBHOOK_DELIVERY_TIMEOUT environment variable: %s", envWebhookDeliveryTimeout)
+		}
+	}
+	if envWebhookNotifyAll := env("WEBHOOK_NOTIFY_ALL"); envWebhookNotifyAll != "" {
+		config.WebhookNotifyAll = envWebhookNotifyAll == "true" || envWebhookNotifyAll == "1"
+	}
+	if envWebhookBatchSize := env("WEBHOOK_BATCH_SIZE"); envWebhookBatchSize != "" {
+		if size, err := strconv.Atoi(envWebhookBatchSize); err == nil && size >= 0 {
+			config.WebhookBatchSize = size
+		} else {
+			log.Printf("Invalid WEBHOOK_BATCH_SIZE environment variable: %s", envWebhookBatchSize)
+		}
+	}
+	if envWebhookDiffConcurrency := env("WEBHOOK_DIFF_CONCURRENCY"); envWebhookDiffConcurrency != "" {
+		if workers, err := strconv.Atoi(envWebhookDiffConcurrency); err == nil && workers >= 0 {
+			config.WebhookDiffConcurrency = workers
+		} else {
+			log.Printf("Invalid WEBHOOK_DIFF_CONCURRENCY environment variable: %s", envWebhookDiffConcurrency)
+		}
+	}
+	if envWebhookThresholds := env("WEBHOOK_SEVERITY_THRESHOLDS"); envWebhookThresholds != "" {
+		webhookThresholds = envWebhookThresholds
+	}
+	if envS3ExportBucket := env("S3_EXPORT_BUCKET"); envS3ExportBucket != "" {
+		config.S3ExportBucket = envS3ExportBucket
+	}
+	if envS3ExportPrefix := env("S3_EXPORT_PREFIX"); envS3ExportPrefix != "" {
+		config.S3ExportPrefix = envS3ExportPrefix
+	}
+	if envElasticsearchURL := env("ELASTICSEARCH_URL"); envElasticsearchURL != "" {
+		config.ElasticsearchURL = envElasticsearchURL
+	}
+	if envElasticsearchIndex := env("ELASTICSEARCH_INDEX"); envElasticsearchIndex != "" {
+		config.ElasticsearchIndex = envElasticsearchIndex
+	}
+	if envOIDCIssuer := env("OIDC_ISSUER"); envOIDCIssuer != "" {
+		config.OIDCIssuer = envOIDCIssuer
+	}
+	if envOIDCJWKSURL := env("OIDC_JWKS_URL"); envOIDCJWKSURL != "" {
+		config.OIDCJWKSURL = envOIDCJWKSURL
+	}
+	if envOIDCAudience := env("OIDC_AUDIENCE"); envOIDCAudience != "" {
+		config.OIDCAudience = envOIDCAudience
+	}
+	if envAuditLog := env("AUDIT_LOG"); envAuditLog != "" {
+		config.AuditLog = envAuditLog
+	}
+	if envWeights := env("RISK_SCORE_WEIGHTS"); envWeights != "" {
+		riskScoreWeights = envWeights
+	}
+	if envRegistryHosts := env("REGISTRY_HOSTS"); envRegistryHosts != "" {
+		registryHosts = envRegistryHosts
+	}
+	if envInitialRetries := env("INITIAL_COLLECTION_RETRIES"); envInitialRetries != "" {
+		if retries, err := strconv.Atoi(envInitialRetries); err == nil && retries >= 0 {
+			config.InitialCollectionRetries = retries
+		} else {
+			log.Printf("Invalid INITIAL_COLLECTION_RETRIES environment variable: %s", envInitialRetries)
+		}
+	}
+	if envInitialBackoff := env("INITIAL_COLLECTION_BACKOFF"); envInitialBackoff != "" {
+		if backoff, err := time.ParseDuration(envInitialBackoff); err == nil && backoff > 0 {
+			config.InitialCollectionBackoff = backoff
+		} else {
+			log.Printf("Invalid INITIAL_COLLECTION_BACKOFF environment variable: %s", envInitialBackoff)
+		}
+	}
+	if envCheckScanning := env("CHECK_REGISTRY_SCANNING"); envCheckScanning == "true" || envCheckScanning == "1" {
+		config.CheckRegistryScanning = true
+	}
+	if envStartupTimeout := env("KUBE_STARTUP_TIMEOUT"); envStartupTimeout != "" {
+		if timeout, err := time.ParseDuration(envStartupTimeout); err == nil && timeout >= 0 {
+			config.KubeStartupTimeout = timeout
+		} else {
+			log.Printf("Invalid KUBE_STARTUP_TIMEOUT environment variable: %s", envStartupTimeout)
+		}
+	}
+	if envAllowedSeverities := env("ALLOWED_SEVERITIES"); envAllowedSeverities != "" {
+		allowedSeverities = envAllowedSeverities
+	}
+	if envSkipUntagged := env("SKIP_UNTAGGED_IMAGES"); envSkipUntagged == "true" || envSkipUntagged == "1" {
+		config.SkipUntaggedImages = true
+	}
+	if envRetainedSeverities := env("RETAINED_FINDING_SEVERITIES"); envRetainedSeverities != "" {
+		retainedSeverities = envRetainedSeverities
+	}
+	if envSeverityOrder := env("SEVERITY_ORDER"); envSeverityOrder != "" {
+		severityOrder = envSeverityOrder
+	}
+	if envNamespaces := env("NAMESPACES"); envNamespaces != "" {
+		namespaces = envNamespaces
+	}
+	if envDiscoveryConcurrency := env("DISCOVERY_CONCURRENCY"); envDiscoveryConcurrency != "" {
+		if concurrency, err := strconv.Atoi(envDiscoveryConcurrency); err == nil && concurrency > 0 {
+			config.DiscoveryConcurrency = concurrency
+		} else {
+			log.Printf("Invalid DISCOVERY_CONCURRENCY environment variable: %s", envDiscoveryConcurrency)
+		}
+	}
+	if envIgnoreContainers := env("IGNORE_CONTAINERS"); envIgnoreContainers != "" {
+		ignoreContainers = envIgnoreContainers
+	}
+	if envAllowlist := env("REPOSITORY_ALLOWLIST"); envAllowlist != "" {
+		repositoryAllowlist = envAllowlist
+	}
+
+	config.CORSAllowedOrigins = splitList(corsAllowedOrigins)
+	config.RegistryHosts = splitList(registryHosts)
+	config.RepositoryAllowlist = splitList(repositoryAllowlist)
+	config.IgnoreContainers = splitList(ignoreContainers)
+	config.Namespaces = splitList(namespaces)
+	config.AllowedSeverities = splitList(strings.ToUpper(allowedSeverities))
+	config.RetainedFindingSeverities = splitList(strings.ToUpper(retainedSeverities))
+	config.SeverityOrder = splitList(strings.ToUpper(severityOrder))
+	config.ReadinessSeverityThreshold = strings.ToUpper(strings.TrimSpace(config.ReadinessSeverityThreshold))
+	config.MetricsDropLabels = splitList(strings.ToLower(metricsDropLabels))
+	config.MetricsHealthyScanStatuses = splitList(strings.ToUpper(healthyScanStatuses))
+
+	if severityCacheTTLs != "" {
+		ttls, err := parseSeverityTTLs(severityCacheTTLs)
+		if err != nil {
+			log.Fatalf("Invalid cache TTL by severity: %v", err)
+		}
+		config.SeverityCacheTTLs = ttls
+	}
+	if riskScoreWeights != "" {
+		weights, err := parseRiskScoreWeights(riskScoreWeights)
+		if err != nil {
+			log.Fatalf("Invalid risk score weights: %v", err)
+		}
+		config.RiskScoreWeights = weights
+	}
+	if webhookThresholds != "" {
+		thresholds, err := notify.ParseSeverityThresholds(webhookThresholds, severity.NewOrder(config.SeverityOrder))
+		if err != nil {
+			log.Fatalf("Invalid webhook severity thresholds: %v", err)
+		}
+		config.WebhookSeverityThresholds = thresholds
+	}
+
+	// Validate configuration
+	if err := config.Validate(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if !config.MockMode && !providers.HasVulnerabilitySource(config.VulnerabilitySource) {
+		log.Fatalf("Invalid vulnerability source '%s': must be one of %s",
+			config.VulnerabilitySource, strings.Join(providers.VulnerabilitySources(), ", "))
+	}
+	if !config.MockMode && !providers.HasCloudProvider(config.Mode) {
+		log.Fatalf("Invalid mode '%s': must be one of %s", config.Mode, strings.Join(providers.CloudProviders(), ", "))
 	}
 
 	return config
 }
 
+// getEnv returns the value of the named environment variable. If NAME_FILE is
+// set, the value is read from that file instead, following the Docker/Kubernetes
+// secrets convention. The file variant takes precedence over the direct variable.
+func getEnv(name string) (string, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s_FILE '%s': %w", name, path, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+	return os.Getenv(name), nil
+}
+
+// splitList splits a comma-separated value into trimmed, non-empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseSeverityTTLs parses a comma-separated list of SEVERITY=duration pairs
+func parseSeverityTTLs(value string) (map[string]time.Duration, error) {
+	ttls := make(map[string]time.Duration)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		severity, durationStr, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("expected SEVERITY=duration, got '%s'", pair)
+		}
+
+		ttl, err := time.ParseDuration(strings.TrimSpace(durationStr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration for %s: %w", severity, err)
+		}
+		if ttl <= 0 {
+			return nil, fmt.Errorf("duration for %s must be positive", severity)
+		}
+
+		ttls[strings.ToUpper(strings.TrimSpace(severity))] = ttl
+	}
+	return ttls, nil
+}
+
+// parseRiskScoreWeights parses a comma-separated list of SEVERITY=weight pairs
+func parseRiskScoreWeights(value string) (map[string]float64, error) {
+	weights := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		severity, weightStr, found := strings.Cut(pair, "=")
+		if !found {
+			return nil, fmt.Errorf("expected SEVERITY=weight, got '%s'", pair)
+		}
+
+		weight, err := strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid weight for %s: %w", severity, err)
+		}
+		if weight < 0 {
+			return nil, fmt.Errorf("weight for %s must not be negative", severity)
+		}
+
+		weights[strings.ToUpper(strings.TrimSpace(severity))] = weight
+	}
+	return weights, nil
+}
+
+// redacted replaces secret values in logged configuration
+const redacted = "[REDACTED]"
+
+// startupConfigFields returns the resolved configuration as log fields, with
+// secrets redacted, so the full startup config is captured by log aggregation
+func startupConfigFields(config *engine.Config) logrus.Fields {
+	severityTTLs := make(map[string]string, len(config.SeverityCacheTTLs))
+	for severity, ttl := range config.SeverityCacheTTLs {
+		severityTTLs[severity] = ttl.String()
+	}
+
+	assumeRoleARN := ""
+	if config.AssumeRoleARN != "" {
+		assumeRoleARN = redacted
+	}
+	harborPassword := ""
+	if config.HarborPassword != "" {
+		harborPassword = redacted
+	}
+	// Webhook URLs commonly embed a token (e.g. Slack incoming webhooks)
+	webhookURL := ""
+	if config.WebhookURL != "" {
+		webhookURL = redacted
+	}
+	// Elasticsearch URLs may carry basic auth credentials
+	elasticsearchURL := ""
+	if config.ElasticsearchURL != "" {
+		elasticsearchURL = redacted
+	}
+	webhookThresholds := make([]string, 0, len(config.WebhookSeverityThresholds))
+	for _, threshold := range config.WebhookSeverityThresholds {
+		webhookThresholds = append(webhookThresholds, threshold.Pattern+"="+threshold.Severity)
+	}
+
+	return logrus.Fields{
+		"mode":                             config.Mode,
+		"port":                             config.Port,
+		"grpc_port":                        config.GRPCPort,
+		"max_header_bytes":                 config.MaxHeaderBytes,
+		"max_request_body_bytes":           config.MaxRequestBodyBytes,
+		"mock_mode":                        config.MockMode,
+		"ecr_account_id":                   config.ECRAccountID,
+		"ecr_region":                       config.ECRRegion,
+		"assume_role_arn":                  assumeRoleARN,
+		"cross_account_role":               config.CrossAccountRole,
+		"image_platform":                   config.ImagePlatform,
+		"resolve_digests":                  config.ResolveDigests,
+		"trigger_scans":                    config.TriggerScans,
+		"image_list_file":                  local.RedactSource(config.ImageListFile),
+		"default_image_tag":                config.DefaultImageTag,
+		"accepted_cves_file":               config.AcceptedCVEsFile,
+		"vulnerability_source":             config.VulnerabilitySource,
+		"harbor_url":                       config.HarborURL,
+		"harbor_username":                  config.HarborUsername,
+		"harbor_password":                  harborPassword,
+		"field_selector":                   config.FieldSelector,
+		"ignore_containers":                config.IgnoreContainers,
+		"namespaces":                       config.Namespaces,
+		"discovery_concurrency":            config.DiscoveryConcurrency,
+		"allowed_severities":               config.AllowedSeverities,
+		"retained_finding_severities":      config.RetainedFindingSeverities,
+		"skip_untagged_images":             config.SkipUntaggedImages,
+		"severity_order":                   config.SeverityOrder,
+		"kubeconfig":                       config.Kubeconfig,
+		"kube_context":                     config.KubeContext,
+		"kube_startup_timeout":             config.KubeStartupTimeout.String(),
+		"initial_collection_retries":       config.InitialCollectionRetries,
+		"initial_collection_backoff":       config.InitialCollectionBackoff.String(),
+		"check_registry_scanning":          config.CheckRegistryScanning,
+		"only_running":                     config.OnlyRunning,
+		"skip_suspended_cronjobs":          config.SkipSuspendedCronJobs,
+		"require_scan_annotation":          config.RequireScanAnnotation,
+		"registry_hosts":                   config.RegistryHosts,
+		"repository_allowlist":             config.RepositoryAllowlist,
+		"fail_on_scan_errors":              config.FailOnScanErrors,
+		"scan_error_threshold":             config.ScanErrorThreshold,
+		"readiness_severity_threshold":     config.ReadinessSeverityThreshold,
+		"scrape_interval":                  config.ScrapeInterval.String(),
+		"cache_ttl_by_severity":            severityTTLs,
+		"cache_refresh_ahead":              config.CacheRefreshAhead.String(),
+		"risk_score_weights":               config.RiskScoreWeights,
+		"ecr_rate_limit":                   config.SourceRequestsPerSecond,
+		"adaptive_concurrency":             config.AdaptiveConcurrency,
+		"disable_vulnerabilities_endpoint": config.DisableVulnerabilitiesEndpoint,
+		"debug_identity_endpoint":          config.DebugIdentityEndpoint,
+		"api_max_findings":                 config.APIMaxFindings,
+		"api_pretty_print":                 config.APIPrettyPrint,
+		"max_images_per_cycle":             config.MaxImagesPerCycle,
+		"pipeline_discovery":               config.PipelineDiscovery,
+		"max_retained_findings":            config.MaxRetainedFindings,
+		"snapshot_history":                 config.SnapshotHistory,
+		"metrics_max_series":               config.MetricsMaxSeries,
+		"metrics_release_label":            config.MetricsReleaseLabel,
+		"cluster_name":                     config.MetricsClusterName,
+		"metrics_platform_label":           config.MetricsPlatformLabel,
+		"metrics_description_info":         config.MetricsDescriptionInfo,
+		"metrics_drop_labels":              config.MetricsDropLabels,
+		"metrics_healthy_scan_statuses":    config.MetricsHealthyScanStatuses,
+		"keep_stale_on_failure":            config.KeepStaleOnFailure,
+		"max_data_age":                     config.MaxDataAge,
+		"metrics_fresh_only":               config.MetricsFreshOnly,
+		"metrics_max_image_uri_length":     config.MetricsMaxImageURILength,
+		"metrics_influx_endpoint":          config.MetricsInfluxEndpoint,
+		"webhook_url":                      webhookURL,
+		"webhook_min_severity":             config.WebhookMinSeverity,
+		"webhook_max_retries":              config.WebhookMaxRetries,
+		"webhook_delivery_timeout":         config.WebhookDeliveryTimeout.String(),
+		"webhook_severity_thresholds":      webhookThresholds,
+		"webhook_notify_all":               config.WebhookNotifyAll,
+		"webhook_batch_size":               config.WebhookBatchSize,
+		"webhook_diff_concurrency":         config.WebhookDiffConcurrency,
+		"s3_export_bucket":                 config.S3ExportBucket,
+		"s3_export_prefix":                 config.S3ExportPrefix,
+		"elasticsearch_url":                elasticsearchURL,
+		"elasticsearch_index":              config.ElasticsearchIndex,
+		"cors_allowed_origins":             config.CORSAllowedOrigins,
+		"oidc_issuer":                      config.OIDCIssuer,
+		"oidc_jwks_url":                    config.OIDCJWKSURL,
+		"oidc_audience":                    config.OIDCAudience,
+		"audit_log":                        config.AuditLog,
+	}
+}
+
 type Exporter struct {
-	config *engine.Config
-	logger *logrus.Logger
-	engine *engine.Engine
+	config   *engine.Config
+	logger   *logrus.Logger
+	engine   *engine.Engine
+	verifier *auth.OIDCVerifier // Optional bearer token verifier for the JSON endpoints
+	audit    *audit.Logger      // Optional access log for the JSON endpoints
 }
 
 func NewExporter(config *engine.Config, logger *logrus.Logger) (*Exporter, error) {
-	logger.WithFields(logrus.Fields{
-		"mode":            config.Mode,
-		"port":            config.Port,
-		"ecr_region":      config.ECRRegion,
-		"scrape_interval": config.ScrapeInterval,
-	}).Info("Initializing VulnRelay")
+	logger.WithFields(startupConfigFields(config)).Info("Initializing VulnRelay")
 
 	// Create providers using factory
 	providerConfig := &providers.ProviderConfig{
-		Mode:          config.Mode,
-		ECRAccountID:  config.ECRAccountID,
-		ECRRegion:     config.ECRRegion,
-		ImageListFile: config.ImageListFile,
-		MockMode:      config.MockMode,
+		Mode:             config.Mode,
+		ECRAccountID:     config.ECRAccountID,
+		ECRRegion:        config.ECRRegion,
+		AssumeRoleARN:    config.AssumeRoleARN,
+		CrossAccountRole: config.CrossAccountRole,
+		ImagePlatform:    config.ImagePlatform,
+		ResolveDigests:   config.ResolveDigests,
+		TriggerScans:     config.TriggerScans,
+		ImageListFile:    config.ImageListFile,
+		DefaultImageTag:  config.DefaultImageTag,
+		FieldSelector:    config.FieldSelector,
+		RegistryHosts:    config.RegistryHosts,
+		OnlyRunning:      config.OnlyRunning,
+		SkipSuspended:    config.SkipSuspendedCronJobs,
+		MockMode:         config.MockMode,
+
+		RepositoryAllowlist: config.RepositoryAllowlist,
+		RetainedSeverities:  config.RetainedFindingSeverities,
+		SkipUntaggedImages:  config.SkipUntaggedImages,
+		KubeStartupTimeout:  config.KubeStartupTimeout,
+		IgnoreContainers:    config.IgnoreContainers,
+
+		Namespaces:           config.Namespaces,
+		DiscoveryConcurrency: config.DiscoveryConcurrency,
+
+		RequireScanAnnotation: config.RequireScanAnnotation,
+
+		Kubeconfig:  config.Kubeconfig,
+		KubeContext: config.KubeContext,
+
+		VulnerabilitySource: config.VulnerabilitySource,
+		HarborURL:           config.HarborURL,
+		HarborUsername:      config.HarborUsername,
+		HarborPassword:      config.HarborPassword,
 	}
 
 	cloudProvider, err := providers.CreateCloudProvider(providerConfig, logger)
@@ -147,10 +838,82 @@ func NewExporter(config *engine.Config, logger *logrus.Logger) (*Exporter, error
 	// Create vulnerability engine
 	vulnEngine := engine.NewEngine(cloudProvider, vulnSource, config, logger)
 
+	if config.AcceptedCVEsFile != "" {
+		acceptedCVEs, err := acceptance.LoadFile(config.AcceptedCVEsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load accepted CVEs: %w", err)
+		}
+		vulnEngine.SetAcceptedCVEs(acceptedCVEs)
+		logger.WithField("accepted_cves", acceptedCVEs.Len()).Info("Loaded accepted CVEs")
+	}
+
+	if config.WebhookURL != "" {
+		notifier, err := notify.NewWebhookNotifier(notify.WebhookOptions{
+			URL:                 config.WebhookURL,
+			MinSeverity:         config.WebhookMinSeverity,
+			NamespaceThresholds: config.WebhookSeverityThresholds,
+			SeverityOrder:       config.SeverityOrder,
+			MaxRetries:          config.WebhookMaxRetries,
+			DeliveryTimeout:     config.WebhookDeliveryTimeout,
+			NotifyAll:           config.WebhookNotifyAll,
+			BatchSize:           config.WebhookBatchSize,
+			DiffConcurrency:     config.WebhookDiffConcurrency,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure webhook notifications: %w", err)
+		}
+		vulnEngine.AddCollectionHook(notifier.Notify)
+	}
+
+	if config.ElasticsearchURL != "" {
+		sink, err := notify.NewElasticsearchSink(notify.ElasticsearchOptions{
+			URL:   config.ElasticsearchURL,
+			Index: config.ElasticsearchIndex,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure Elasticsearch export: %w", err)
+		}
+		vulnEngine.AddCollectionHook(sink.Export)
+	}
+
+	if config.S3ExportBucket != "" {
+		sink, err := awsprovider.NewS3Sink(context.Background(), awsprovider.S3SinkOptions{
+			Bucket: config.S3ExportBucket,
+			Prefix: config.S3ExportPrefix,
+			Region: config.ECRRegion,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure S3 export: %w", err)
+		}
+		vulnEngine.AddCollectionHook(sink.Export)
+	}
+
+	var verifier *auth.OIDCVerifier
+	if config.OIDCJWKSURL != "" {
+		verifier, err = auth.NewOIDCVerifier(auth.OIDCOptions{
+			Issuer:   config.OIDCIssuer,
+			JWKSURL:  config.OIDCJWKSURL,
+			Audience: config.OIDCAudience,
+		}, logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure OIDC authentication: %w", err)
+		}
+	}
+
+	var auditLogger *audit.Logger
+	if config.AuditLog != "" {
+		auditLogger, err = audit.Open(config.AuditLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Exporter{
-		config: config,
-		logger: logger,
-		engine: vulnEngine,
+		config:   config,
+		logger:   logger,
+		engine:   vulnEngine,
+		verifier: verifier,
+		audit:    auditLogger,
 	}, nil
 }
 
@@ -158,22 +921,22 @@ func (e *Exporter) Start(ctx context.Context) error {
 	// Start the vulnerability engine
 	go e.engine.Start(ctx)
 
-	// Create HTTP server
-	mux := http.NewServeMux()
-	mux.HandleFunc("/metrics", e.securityMiddleware(metrics.CreateMetricsHandler(e.engine, e.logger)))
-	mux.HandleFunc("/vulnerabilities", e.securityMiddleware(server.CreateVulnerabilitiesHandler(e.engine, e.logger)))
-	mux.HandleFunc("/health", e.securityMiddleware(e.healthHandler))
-
-	server := &http.Server{
-		Addr:              fmt.Sprintf(":%d", e.config.Port),
-		Handler:           mux,
-		ReadTimeout:       10 * time.Second,
-		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      10 * time.Second,
-		IdleTimeout:       60 * time.Second,
-		MaxHeaderBytes:    1 << 20, // 1 MB
+	// The gRPC API is served alongside the HTTP server when enabled
+	if e.config.GRPCPort > 0 {
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", e.config.GRPCPort))
+		if err != nil {
+			return fmt.Errorf("failed to listen on gRPC port %d: %w", e.config.GRPCPort, err)
+		}
+		e.logger.WithField("port", e.config.GRPCPort).Info("Starting gRPC server")
+		go func() {
+			if err := rpc.Serve(ctx, listener, e.engine, e.logger); err != nil {
+				e.logger.WithError(err).Error("gRPC server failed")
+			}
+		}()
 	}
 
+	server := e.newServer()
+
 	go func() {
 		<-ctx.Done()
 		e.logger.Info("Shutting down HTTP server")
@@ -194,7 +957,97 @@ func (e *Exporter) Start(ctx context.Context) error {
 	return nil
 }
 
+// newServer creates the HTTP server for the registered routes
+func (e *Exporter) newServer() *http.Server {
+	return &http.Server{
+		Addr:              fmt.Sprintf(":%d", e.config.Port),
+		Handler:           e.bodyLimitMiddleware(e.newMux().ServeHTTP),
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		MaxHeaderBytes:    e.config.MaxHeaderBytes,
+	}
+}
+
+// newMux registers the HTTP routes
+func (e *Exporter) newMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	metricsOptions := metrics.Options{
+		RiskScoreWeights:    e.config.RiskScoreWeights,
+		MaxSeriesPerMetric:  e.config.MetricsMaxSeries,
+		ReleaseLabel:        e.config.MetricsReleaseLabel,
+		PlatformLabel:       e.config.MetricsPlatformLabel,
+		FreshOnly:           e.config.MetricsFreshOnly,
+		MaxImageURILength:   e.config.MetricsMaxImageURILength,
+		DropLabels:          e.config.MetricsDropLabels,
+		Collectors:          []prometheus.Collector{awsprovider.DefaultAPIMetrics},
+		ClusterName:         e.config.MetricsClusterName,
+		DescriptionInfo:     e.config.MetricsDescriptionInfo,
+		HealthyScanStatuses: e.config.MetricsHealthyScanStatuses,
+	}
+	mux.HandleFunc("/metrics", e.securityMiddleware(metrics.CreateMetricsHandler(e.engine, metricsOptions, e.logger)))
+	if e.config.MetricsInfluxEndpoint {
+		mux.HandleFunc("/metrics/influx", e.securityMiddleware(metrics.CreateInfluxHandler(e.engine, metricsOptions, e.logger)))
+	}
+	readinessOptions := server.ReadinessOptions{
+		FailOnScanErrors:   e.config.FailOnScanErrors,
+		ScanErrorThreshold: e.config.ScanErrorThreshold,
+		SeverityThreshold:  e.config.ReadinessSeverityThreshold,
+		SeverityOrder:      e.config.SeverityOrder,
+	}
+
+	// Probes are also served at the /healthz and /readyz paths some tooling expects
+	healthHandler := e.corsMiddleware(e.securityMiddleware(e.healthHandler))
+	readinessHandler := e.securityMiddleware(server.CreateReadinessHandler(e.engine, readinessOptions, e.logger))
+	mux.HandleFunc("/health", healthHandler)
+	mux.HandleFunc("/healthz", healthHandler)
+	mux.HandleFunc("/ready", readinessHandler)
+	mux.HandleFunc("/readyz", readinessHandler)
+	mux.HandleFunc("/summary", e.corsMiddleware(e.securityMiddleware(server.CreateSummaryHandler(e.engine, readinessOptions, e.logger))))
+
+	// Invalidation changes state, so it is only served to authenticated callers
+	if e.verifier != nil {
+		mux.HandleFunc("/cache/invalidate", e.methodSecurityMiddleware(e.auditMiddleware(e.authMiddleware(server.CreateInvalidateHandler(e.engine, e.logger))), http.MethodPost))
+	}
+
+	// The detailed JSON endpoints can be disabled in locked-down deployments
+	if !e.config.DisableVulnerabilitiesEndpoint {
+		apiOptions := server.Options{
+			MaxFindings:   e.config.APIMaxFindings,
+			SeverityOrder: e.config.SeverityOrder,
+			PrettyPrint:   e.config.APIPrettyPrint,
+		}
+		vulnerabilitiesHandler := server.CreateVulnerabilitiesHandler(e.engine, apiOptions, e.logger)
+
+		mux.HandleFunc("/vulnerabilities", e.corsMiddleware(e.securityMiddleware(e.auditMiddleware(e.authMiddleware(vulnerabilitiesHandler)))))
+		mux.HandleFunc("/vulnerabilities/namespaces", e.corsMiddleware(e.securityMiddleware(e.auditMiddleware(e.authMiddleware(server.CreateNamespacesHandler(e.engine, apiOptions, e.logger))))))
+		mux.HandleFunc("/vulnerabilities/workloads", e.corsMiddleware(e.securityMiddleware(e.auditMiddleware(e.authMiddleware(server.CreateWorkloadsHandler(e.engine, apiOptions, e.logger))))))
+		mux.HandleFunc("/vulnerabilities/diff", e.corsMiddleware(e.securityMiddleware(e.auditMiddleware(e.authMiddleware(server.CreateDiffHandler(e.engine, apiOptions, e.logger))))))
+		mux.HandleFunc("/cves", e.corsMiddleware(e.securityMiddleware(e.auditMiddleware(e.authMiddleware(server.CreateCVEsHandler(e.engine, apiOptions, e.logger))))))
+
+		// The identity reveals the AWS account and role, so it is opt-in and
+		// never served without authentication
+		if e.config.DebugIdentityEndpoint && e.verifier != nil {
+			mux.HandleFunc("/debug/identity", e.securityMiddleware(e.auditMiddleware(e.authMiddleware(server.CreateIdentityHandler(e.engine, e.logger)))))
+		}
+	}
+
+	return mux
+}
+
 func (e *Exporter) securityMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return e.methodSecurityMiddleware(next, http.MethodGet, http.MethodHead)
+}
+
+// methodSecurityMiddleware adds the security headers and only lets the given
+// methods through
+func (e *Exporter) methodSecurityMiddleware(next http.HandlerFunc, methods ...string) http.HandlerFunc {
+	allowed := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		allowed[method] = true
+	}
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Security headers
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -204,7 +1057,8 @@ func (e *Exporter) securityMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		w.Header().Set("Content-Security-Policy", "default-src 'none'; script-src 'none'; object-src 'none'; frame-ancestors 'none'")
 
 		// Only allow specific HTTP methods
-		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if !allowed[r.Method] {
+			w.Header().Set("Allow", strings.Join(methods, ", "))
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
@@ -221,6 +1075,82 @@ func (e *Exporter) securityMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// bodyLimitMiddleware rejects requests whose body exceeds MaxRequestBodyBytes
+// with 413. Bodies of unknown length are capped as they are read, so handlers
+// reading them get an *http.MaxBytesError instead.
+func (e *Exporter) bodyLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	limit := e.config.MaxRequestBodyBytes
+	if limit <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > limit {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limit)
+		next(w, r)
+	}
+}
+
+// authMiddleware requires a valid OIDC bearer token when authentication is enabled
+func (e *Exporter) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	if e.verifier == nil {
+		return next
+	}
+	return e.verifier.Middleware(next)
+}
+
+// auditMiddleware records each request in the audit log when enabled; it runs
+// outside authMiddleware so requests rejected for their token are recorded too
+func (e *Exporter) auditMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	if e.audit == nil {
+		return next
+	}
+	return e.audit.Middleware(next)
+}
+
+// corsMiddleware adds CORS headers for allowed origins and answers preflight
+// requests, which would otherwise be rejected by the method restriction
+func (e *Exporter) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || len(e.config.CORSAllowedOrigins) == 0 {
+			next(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Origin")
+
+		allowedOrigin := ""
+		for _, allowed := range e.config.CORSAllowedOrigins {
+			if allowed == "*" || allowed == origin {
+				allowedOrigin = allowed
+				break
+			}
+		}
+
+		if allowedOrigin != "" {
+			w.Header().Set("Access-Control-Allow-Origin", allowedOrigin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Authorization, Content-Type")
+		}
+
+		// Answer preflight requests directly
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowedOrigin == "" {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
 func (e *Exporter) healthHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	fmt.Fprintf(w, `{"status":"ok"}`)
@@ -0,0 +1,91 @@
+// ABOUTME: Risk acceptance list of CVEs that security has formally accepted.
+// ABOUTME: Loads accepted CVEs from a JSON file, optionally scoped to specific images.
+
+package acceptance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Entry is a single accepted CVE in the acceptance file
+type Entry struct {
+	CVE    string   `json:"cve"`
+	Images []string `json:"images,omitempty"` // Image URIs or repositories; empty accepts the CVE everywhere
+	Reason string   `json:"reason,omitempty"`
+}
+
+// List answers whether a CVE is accepted for an image. A nil List accepts nothing.
+type List struct {
+	global   map[string]bool            // CVEs accepted for every image
+	perImage map[string]map[string]bool // CVE -> image URIs or repositories
+}
+
+// LoadFile reads and parses an acceptance file
+func LoadFile(path string) (*List, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read accepted CVEs file '%s': %w", path, err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse accepted CVEs JSON: %w", err)
+	}
+
+	return NewList(entries)
+}
+
+// NewList builds a List from entries
+func NewList(entries []Entry) (*List, error) {
+	list := &List{
+		global:   make(map[string]bool),
+		perImage: make(map[string]map[string]bool),
+	}
+
+	for i, entry := range entries {
+		cve := strings.TrimSpace(entry.CVE)
+		if cve == "" {
+			return nil, fmt.Errorf("accepted CVE entry %d is missing a cve", i)
+		}
+
+		if len(entry.Images) == 0 {
+			list.global[cve] = true
+			continue
+		}
+		if list.perImage[cve] == nil {
+			list.perImage[cve] = make(map[string]bool)
+		}
+		for _, image := range entry.Images {
+			if image = strings.TrimSpace(image); image != "" {
+				list.perImage[cve][image] = true
+			}
+		}
+	}
+
+	return list, nil
+}
+
+// IsAccepted reports whether the CVE is accepted for the image, matched by
+// full image URI or by repository
+func (l *List) IsAccepted(cve, imageURI, repository string) bool {
+	if l == nil || cve == "" {
+		return false
+	}
+	if l.global[cve] {
+		return true
+	}
+
+	images := l.perImage[cve]
+	return images[imageURI] || (repository != "" && images[repository])
+}
+
+// Len returns the number of accepted CVE entries
+func (l *List) Len() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.global) + len(l.perImage)
+}
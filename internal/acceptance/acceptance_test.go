@@ -0,0 +1,119 @@
+// ABOUTME: Unit tests for the accepted CVE risk acceptance list.
+// ABOUTME: Tests file loading, global and per-image matching, and validation.
+
+package acceptance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListIsAccepted(t *testing.T) {
+	list, err := NewList([]Entry{
+		{CVE: "CVE-2024-0001", Reason: "Not reachable"},
+		{CVE: "CVE-2024-0002", Images: []string{"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"}},
+		{CVE: "CVE-2024-0003", Images: []string{"worker"}},
+	})
+	if err != nil {
+		t.Fatalf("NewList() failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		cve        string
+		imageURI   string
+		repository string
+		expected   bool
+	}{
+		{"global acceptance", "CVE-2024-0001", "123456789012.dkr.ecr.us-east-1.amazonaws.com/any:v1", "any", true},
+		{"accepted for image URI", "CVE-2024-0002", "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1", "api", true},
+		{"not accepted for other tag", "CVE-2024-0002", "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v2", "api", false},
+		{"accepted for repository", "CVE-2024-0003", "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v7", "worker", true},
+		{"not accepted for other repository", "CVE-2024-0003", "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1", "api", false},
+		{"unlisted CVE", "CVE-2024-9999", "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1", "api", false},
+		{"empty CVE", "", "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1", "api", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := list.IsAccepted(tt.cve, tt.imageURI, tt.repository); got != tt.expected {
+				t.Errorf("IsAccepted(%q, %q, %q) = %v, want %v", tt.cve, tt.imageURI, tt.repository, got, tt.expected)
+			}
+		})
+	}
+
+	if list.Len() != 3 {
+		t.Errorf("Expected 3 entries, got %d", list.Len())
+	}
+}
+
+func TestNilListAcceptsNothing(t *testing.T) {
+	var list *List
+	if list.IsAccepted("CVE-2024-0001", "registry/app:v1", "app") {
+		t.Error("Expected nil list to accept nothing")
+	}
+	if list.Len() != 0 {
+		t.Errorf("Expected nil list length 0, got %d", list.Len())
+	}
+}
+
+func TestNewListMissingCVE(t *testing.T) {
+	if _, err := NewList([]Entry{{Images: []string{"api"}}}); err == nil {
+		t.Error("Expected error for entry without a CVE")
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name        string
+		content     string
+		expectError bool
+		expectedLen int
+	}{
+		{
+			name:        "valid file",
+			content:     `[{"cve": "CVE-2024-0001", "reason": "Mitigated by WAF"}, {"cve": "CVE-2024-0002", "images": ["api"]}]`,
+			expectedLen: 2,
+		},
+		{
+			name:        "empty list",
+			content:     `[]`,
+			expectedLen: 0,
+		},
+		{
+			name:        "invalid JSON",
+			content:     `{"cve": `,
+			expectError: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "accepted-"+string(rune('a'+i))+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatalf("Failed to write test file: %v", err)
+			}
+
+			list, err := LoadFile(path)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadFile() failed: %v", err)
+			}
+			if list.Len() != tt.expectedLen {
+				t.Errorf("Expected %d entries, got %d", tt.expectedLen, list.Len())
+			}
+		})
+	}
+
+	if _, err := LoadFile(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected error for missing file")
+	}
+}
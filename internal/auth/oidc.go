@@ -0,0 +1,484 @@
+// ABOUTME: OIDC bearer token authentication for the HTTP API.
+// ABOUTME: Verifies JWT signatures against a cached JWKS and checks issuer, audience and expiry.
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// defaultJWKSCacheTTL is how long fetched signing keys are reused
+const defaultJWKSCacheTTL = time.Hour
+
+// jwksRefreshCooldown limits refetches triggered by unknown key IDs or failed
+// fetches, so made-up key IDs or an unreachable identity provider can't cause
+// a fetch per request
+const jwksRefreshCooldown = time.Minute
+
+// clockSkew is the leeway allowed when checking exp and nbf
+const clockSkew = time.Minute
+
+// OIDCOptions configures OIDC token verification
+type OIDCOptions struct {
+	Issuer   string        // Expected iss claim (optional)
+	JWKSURL  string        // URL of the identity provider's JSON Web Key Set
+	Audience string        // Required aud claim
+	CacheTTL time.Duration // How long to cache the JWKS (default 1h)
+}
+
+// Claims holds the registered JWT claims that are verified
+type Claims struct {
+	Issuer    string   `json:"iss"`
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *int64   `json:"exp"`
+	NotBefore *int64   `json:"nbf"`
+}
+
+// claimsKey is the context key of the verified claims of a request
+type claimsKey struct{}
+
+// claimsRecorderKey is the context key of the claims recorder of a request
+type claimsRecorderKey struct{}
+
+// claimsRecorder holds the claims verified further down the handler chain
+type claimsRecorder struct {
+	claims *Claims
+}
+
+// ContextWithClaims returns a copy of ctx carrying the verified claims
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	if recorder, ok := ctx.Value(claimsRecorderKey{}).(*claimsRecorder); ok {
+		recorder.claims = claims
+	}
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims the middleware verified for a request
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(*Claims)
+	return claims, ok
+}
+
+// ContextWithClaimsRecorder returns a copy of ctx that records the claims the
+// middleware verifies further down the chain, so middleware running before
+// authentication, like audit logging, can see the caller once the handler
+// returns. The returned function reports the recorded claims.
+func ContextWithClaimsRecorder(ctx context.Context) (context.Context, func() (*Claims, bool)) {
+	recorder := &claimsRecorder{}
+	return context.WithValue(ctx, claimsRecorderKey{}, recorder), func() (*Claims, bool) {
+		return recorder.claims, recorder.claims != nil
+	}
+}
+
+// audience accepts the aud claim as a single string or an array of strings
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return fmt.Errorf("invalid aud claim: %w", err)
+	}
+	*a = multiple
+	return nil
+}
+
+// jsonWebKey is a single key from a JWKS document
+type jsonWebKey struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// OIDCVerifier validates bearer tokens issued by an OIDC identity provider
+type OIDCVerifier struct {
+	options OIDCOptions
+	client  *http.Client
+	logger  *logrus.Logger
+	now     func() time.Time
+
+	refreshes singleflight.Group // Shares a JWKS fetch between concurrent requests
+
+	mutex      sync.Mutex
+	keys       map[string]crypto.PublicKey
+	fetchedAt  time.Time // Time the last fetch completed, successfully or not
+	refreshErr error     // Error of the last fetch attempt
+}
+
+// NewOIDCVerifier creates a verifier for the given identity provider settings
+func NewOIDCVerifier(options OIDCOptions, logger *logrus.Logger) (*OIDCVerifier, error) {
+	parsed, err := url.Parse(options.JWKSURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid JWKS URL %q: must be an http(s) URL", options.JWKSURL)
+	}
+	if options.Audience == "" {
+		return nil, errors.New("an audience is required for OIDC authentication")
+	}
+	if options.CacheTTL <= 0 {
+		options.CacheTTL = defaultJWKSCacheTTL
+	}
+
+	return &OIDCVerifier{
+		options: options,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		logger:  logger,
+		now:     time.Now,
+	}, nil
+}
+
+// Verify checks the token's signature and claims and returns the claims if valid
+func (v *OIDCVerifier) Verify(ctx context.Context, rawToken string) (*Claims, error) {
+	parts := strings.Split(rawToken, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("invalid token header: %w", err)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", err)
+	}
+
+	key, err := v.signingKey(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", err)
+	}
+	if err := v.validateClaims(&claims); err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+// validateClaims checks expiry, not-before, issuer and audience
+func (v *OIDCVerifier) validateClaims(claims *Claims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(clockSkew)) {
+		return errors.New("token is expired")
+	}
+	if claims.NotBefore != nil && now.Add(clockSkew).Before(time.Unix(*claims.NotBefore, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	if v.options.Issuer != "" && claims.Issuer != v.options.Issuer {
+		return fmt.Errorf("unexpected issuer %q", claims.Issuer)
+	}
+	if !slices.Contains(claims.Audience, v.options.Audience) {
+		return errors.New("token audience does not match")
+	}
+
+	return nil
+}
+
+// signingKey returns the key for a key ID, refreshing the cached JWKS when it
+// has expired or doesn't contain the key
+func (v *OIDCVerifier) signingKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mutex.Lock()
+	age := v.now().Sub(v.fetchedAt)
+	_, found := v.lookupKey(kid)
+	v.mutex.Unlock()
+
+	// The cooldown also applies without any cached keys, so an unreachable
+	// identity provider is retried at most once a minute
+	if age > v.options.CacheTTL || (!found && age > jwksRefreshCooldown) {
+		// The fetch runs outside the mutex so requests with cached keys aren't
+		// held up, and detached from the request since its result is shared
+		_, err, _ := v.refreshes.Do("jwks", func() (interface{}, error) {
+			return nil, v.refreshKeys(context.WithoutCancel(ctx))
+		})
+		if err != nil {
+			// Keep serving from stale keys if the identity provider is unreachable
+			v.logger.WithError(err).Warn("Failed to refresh JWKS")
+		}
+	}
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	key, found := v.lookupKey(kid)
+	if !found {
+		if v.keys == nil && v.refreshErr != nil {
+			return nil, v.refreshErr
+		}
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+// lookupKey finds a key by ID; tokens without a key ID match a single-key set.
+// Must be called with v.mutex held.
+func (v *OIDCVerifier) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, found := v.keys[kid]
+	return key, found
+}
+
+// refreshKeys fetches the JWKS and replaces the cached keys on success
+func (v *OIDCVerifier) refreshKeys(ctx context.Context) error {
+	v.mutex.Lock()
+	// A fetch that completed since the caller checked makes this one redundant
+	if !v.fetchedAt.IsZero() && v.now().Sub(v.fetchedAt) <= jwksRefreshCooldown {
+		v.mutex.Unlock()
+		return nil
+	}
+	v.mutex.Unlock()
+
+	keys, err := v.fetchKeys(ctx)
+
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+
+	// Failures are recorded too so they respect the cooldown. Until then,
+	// requests that need a refresh join the fetch in flight.
+	v.fetchedAt = v.now()
+	v.refreshErr = err
+	if err != nil {
+		return err
+	}
+	v.keys = keys
+	v.logger.WithField("key_count", len(keys)).Debug("Refreshed JWKS")
+	return nil
+}
+
+// fetchKeys fetches and parses the JWKS
+func (v *OIDCVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.options.JWKSURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build JWKS request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("JWKS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %s", resp.Status)
+	}
+
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&jwks); err != nil {
+		return nil, fmt.Errorf("failed to decode JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			v.logger.WithError(err).WithField("kid", jwk.Kid).Warn("Skipping unsupported JWKS key")
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return keys, nil
+}
+
+// publicKey converts a JWK into an RSA or ECDSA public key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil || !e.IsInt64() {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC x coordinate: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC y coordinate: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// verifySignature checks a JWS signature for the supported RS* and ES* algorithms
+func verifySignature(alg string, key crypto.PublicKey, signingInput string, signature []byte) error {
+	var hasher hash.Hash
+	var hashType crypto.Hash
+	switch alg {
+	case "RS256", "ES256":
+		hasher, hashType = sha256.New(), crypto.SHA256
+	case "RS384", "ES384":
+		hasher, hashType = sha512.New384(), crypto.SHA384
+	case "RS512", "ES512":
+		hasher, hashType = sha512.New(), crypto.SHA512
+	default:
+		return fmt.Errorf("unsupported signing algorithm %q", alg)
+	}
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %q does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hashType, digest, signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algorithm %q does not match EC key", alg)
+		}
+		// JWS encodes ECDSA signatures as fixed-size r || s
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+	default:
+		return errors.New("unsupported key type")
+	}
+
+	return nil
+}
+
+// Middleware rejects requests without a valid bearer token and passes the
+// verified claims on in the request context
+func (v *OIDCVerifier) Middleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rawToken, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="vulnrelay"`)
+			http.Error(w, "Missing bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := v.Verify(r.Context(), rawToken)
+		if err != nil {
+			v.logger.WithError(err).WithFields(logrus.Fields{
+				"path":      r.URL.Path,
+				"remote_ip": r.RemoteAddr,
+			}).Warn("Rejected invalid bearer token")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="vulnrelay", error="invalid_token"`)
+			http.Error(w, "Invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+
+		v.logger.WithFields(logrus.Fields{
+			"path":    r.URL.Path,
+			"subject": claims.Subject,
+		}).Debug("Authenticated request")
+
+		next(w, r.WithContext(ContextWithClaims(r.Context(), claims)))
+	}
+}
+
+// bearerToken extracts the token from an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// decodeSegment decodes a base64url JSON segment of a JWT
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// decodeBigInt decodes a base64url unsigned big-endian integer
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,434 @@
+// ABOUTME: Unit tests for OIDC bearer token verification.
+// ABOUTME: Signs tokens with local keys served from a test JWKS endpoint.
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	testIssuer   = "https://idp.example.com"
+	testAudience = "vulnrelay"
+	testSubject  = "alice"
+)
+
+// testJWKS serves a key set and counts how often it was fetched
+type testJWKS struct {
+	keys        []jsonWebKey
+	fetches     atomic.Int32
+	unavailable atomic.Bool // Fails requests like an unreachable identity provider
+}
+
+func (j *testJWKS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	j.fetches.Add(1)
+	if j.unavailable.Load() {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": j.keys})
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kid: kid,
+		Kty: "RSA",
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jsonWebKey {
+	return jsonWebKey{
+		Kid: kid,
+		Kty: "EC",
+		Crv: "P-256",
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+}
+
+// signToken builds a JWT signed with an RSA (RS256) or ECDSA P-256 (ES256) key
+func signToken(t *testing.T, signer crypto.Signer, kid string, claims map[string]interface{}) string {
+	t.Helper()
+
+	alg := "RS256"
+	if _, ok := signer.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = sig
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"iss": testIssuer,
+		"sub": testSubject,
+		"aud": testAudience,
+		"exp": now.Add(time.Hour).Unix(),
+		"iat": now.Unix(),
+	}
+}
+
+func newTestVerifier(t *testing.T, jwks http.Handler) *OIDCVerifier {
+	t.Helper()
+
+	server := httptest.NewServer(jwks)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	verifier, err := NewOIDCVerifier(OIDCOptions{
+		Issuer:   testIssuer,
+		JWKSURL:  server.URL,
+		Audience: testAudience,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewOIDCVerifier() failed: %v", err)
+	}
+	return verifier
+}
+
+func TestOIDCVerifierVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	jwks := &testJWKS{keys: []jsonWebKey{rsaJWK("rsa-key", &rsaKey.PublicKey), ecJWK("ec-key", &ecKey.PublicKey)}}
+	verifier := newTestVerifier(t, jwks)
+	now := time.Now()
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims(now)
+		claims[key] = value
+		return claims
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		expectError bool
+	}{
+		{
+			name:  "valid RS256 token",
+			token: signToken(t, rsaKey, "rsa-key", validClaims(now)),
+		},
+		{
+			name:  "valid ES256 token",
+			token: signToken(t, ecKey, "ec-key", validClaims(now)),
+		},
+		{
+			name:  "audience list containing the audience",
+			token: signToken(t, rsaKey, "rsa-key", with("aud", []string{"other", testAudience})),
+		},
+		{
+			name:        "expired token",
+			token:       signToken(t, rsaKey, "rsa-key", with("exp", now.Add(-time.Hour).Unix())),
+			expectError: true,
+		},
+		{
+			name:        "wrong audience",
+			token:       signToken(t, rsaKey, "rsa-key", with("aud", "another-app")),
+			expectError: true,
+		},
+		{
+			name:        "wrong issuer",
+			token:       signToken(t, rsaKey, "rsa-key", with("iss", "https://evil.example.com")),
+			expectError: true,
+		},
+		{
+			name:        "not valid yet",
+			token:       signToken(t, rsaKey, "rsa-key", with("nbf", now.Add(time.Hour).Unix())),
+			expectError: true,
+		},
+		{
+			name:        "missing expiry",
+			token:       signToken(t, rsaKey, "rsa-key", with("exp", nil)),
+			expectError: true,
+		},
+		{
+			name:        "signed by an untrusted key",
+			token:       signToken(t, otherKey, "rsa-key", validClaims(now)),
+			expectError: true,
+		},
+		{
+			name:        "unknown key ID",
+			token:       signToken(t, otherKey, "other-key", validClaims(now)),
+			expectError: true,
+		},
+		{
+			name:        "malformed token",
+			token:       "not-a-jwt",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tt.token)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify() failed: %v", err)
+			}
+			if claims.Subject != "alice" {
+				t.Errorf("Expected subject alice, got %q", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestOIDCVerifierCachesJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	jwks := &testJWKS{keys: []jsonWebKey{rsaJWK("rsa-key", &rsaKey.PublicKey)}}
+	verifier := newTestVerifier(t, jwks)
+
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+	token := signToken(t, rsaKey, "rsa-key", validClaims(now))
+
+	for i := 0; i < 5; i++ {
+		if _, err := verifier.Verify(context.Background(), token); err != nil {
+			t.Fatalf("Verify() failed: %v", err)
+		}
+	}
+	if fetches := jwks.fetches.Load(); fetches != 1 {
+		t.Errorf("Expected JWKS to be fetched once, got %d", fetches)
+	}
+
+	// A rotated key is picked up once the refresh cooldown has passed
+	jwks.keys = append(jwks.keys, rsaJWK("new-key", &newKey.PublicKey))
+	rotated := signToken(t, newKey, "new-key", validClaims(now))
+
+	if _, err := verifier.Verify(context.Background(), rotated); err == nil {
+		t.Error("Expected unknown key to be rejected within the refresh cooldown")
+	}
+
+	now = now.Add(2 * jwksRefreshCooldown)
+	if _, err := verifier.Verify(context.Background(), rotated); err != nil {
+		t.Errorf("Expected rotated key to be accepted after refresh, got %v", err)
+	}
+	if fetches := jwks.fetches.Load(); fetches != 2 {
+		t.Errorf("Expected JWKS to be fetched twice, got %d", fetches)
+	}
+}
+
+func TestOIDCVerifierJWKSFailureCooldown(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	jwks := &testJWKS{keys: []jsonWebKey{rsaJWK("rsa-key", &rsaKey.PublicKey)}}
+	jwks.unavailable.Store(true)
+	verifier := newTestVerifier(t, jwks)
+
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+	token := signToken(t, rsaKey, "rsa-key", validClaims(now))
+
+	// Without cached keys, failed fetches are retried only after the cooldown
+	for i := 0; i < 5; i++ {
+		if _, err := verifier.Verify(context.Background(), token); err == nil {
+			t.Fatal("Expected verification to fail while the JWKS is unavailable")
+		}
+	}
+	if fetches := jwks.fetches.Load(); fetches != 1 {
+		t.Errorf("Expected one JWKS fetch within the cooldown, got %d", fetches)
+	}
+
+	jwks.unavailable.Store(false)
+	now = now.Add(2 * jwksRefreshCooldown)
+	if _, err := verifier.Verify(context.Background(), token); err != nil {
+		t.Errorf("Expected token to be accepted once the JWKS is reachable, got %v", err)
+	}
+	if fetches := jwks.fetches.Load(); fetches != 2 {
+		t.Errorf("Expected JWKS to be fetched twice, got %d", fetches)
+	}
+}
+
+func TestOIDCVerifierConcurrentRefresh(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	// The identity provider holds each request until it's released
+	jwks := &testJWKS{keys: []jsonWebKey{rsaJWK("rsa-key", &rsaKey.PublicKey)}}
+	requested := make(chan struct{}, 10)
+	release := make(chan struct{}, 10)
+	verifier := newTestVerifier(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested <- struct{}{}
+		<-release
+		jwks.ServeHTTP(w, r)
+	}))
+
+	now := time.Now()
+	verifier.now = func() time.Time { return now }
+	token := signToken(t, rsaKey, "rsa-key", validClaims(now))
+
+	// Concurrent requests without cached keys share one fetch
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.Verify(context.Background(), token)
+			errs <- err
+		}()
+	}
+	<-requested
+	release <- struct{}{}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Verify() failed: %v", err)
+		}
+	}
+	if fetches := jwks.fetches.Load(); fetches != 1 {
+		t.Errorf("Expected concurrent requests to share one JWKS fetch, got %d", fetches)
+	}
+
+	// A refresh for an unknown key doesn't block tokens signed with cached keys
+	now = now.Add(2 * jwksRefreshCooldown)
+	rotated := signToken(t, newKey, "new-key", validClaims(now))
+	refreshed := make(chan struct{})
+	go func() {
+		defer close(refreshed)
+		verifier.Verify(context.Background(), rotated)
+	}()
+	<-requested
+
+	verified := make(chan error, 1)
+	go func() {
+		_, err := verifier.Verify(context.Background(), token)
+		verified <- err
+	}()
+	select {
+	case err := <-verified:
+		if err != nil {
+			t.Errorf("Expected cached key to verify during a refresh, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("Verification with a cached key waited for the JWKS refresh")
+	}
+
+	release <- struct{}{}
+	<-refreshed
+}
+
+func TestOIDCVerifierMiddleware(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	jwks := &testJWKS{keys: []jsonWebKey{rsaJWK("rsa-key", &rsaKey.PublicKey)}}
+	verifier := newTestVerifier(t, jwks)
+	now := time.Now()
+
+	expired := validClaims(now)
+	expired["exp"] = now.Add(-time.Hour).Unix()
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"valid token", "Bearer " + signToken(t, rsaKey, "rsa-key", validClaims(now)), http.StatusOK},
+		{"expired token", "Bearer " + signToken(t, rsaKey, "rsa-key", expired), http.StatusUnauthorized},
+		{"missing header", "", http.StatusUnauthorized},
+		{"basic auth", "Basic dXNlcjpwYXNz", http.StatusUnauthorized},
+	}
+
+	var subject string
+	handler := verifier.Middleware(func(w http.ResponseWriter, r *http.Request) {
+		// Verified claims are passed on to the handler
+		if claims, ok := ClaimsFromContext(r.Context()); ok {
+			subject = claims.Subject
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/vulnerabilities", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && subject != testSubject {
+				t.Errorf("Expected subject %q in the request context, got %q", testSubject, subject)
+			}
+			if tt.expectedStatus == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("Expected WWW-Authenticate header on 401")
+			}
+		})
+	}
+}
+
+func TestNewOIDCVerifierValidation(t *testing.T) {
+	logger := logrus.New()
+
+	tests := []struct {
+		name    string
+		options OIDCOptions
+	}{
+		{"missing JWKS URL", OIDCOptions{Audience: testAudience}},
+		{"non-http JWKS URL", OIDCOptions{JWKSURL: "file:///etc/jwks.json", Audience: testAudience}},
+		{"missing audience", OIDCOptions{JWKSURL: "https://idp.example.com/jwks"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewOIDCVerifier(tt.options, logger); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
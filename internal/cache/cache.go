@@ -7,21 +7,49 @@ import (
 	"sync"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/severity"
 	"github.com/jfeddern/VulnRelay/internal/types"
 
 	"github.com/sirupsen/logrus"
 )
 
+// PendingScanTTL caches results of scans that haven't completed yet, such as
+// a just-triggered scan, so their findings are picked up soon after it finishes
+const PendingScanTTL = 2 * time.Minute
+
+// pendingScanStatuses are the scan statuses of results without findings yet
+var pendingScanStatuses = map[string]bool{
+	"IN_PROGRESS": true,
+	"PENDING":     true,
+}
+
 type CacheEntry struct {
 	Data      *types.ImageVulnerability
 	ExpiresAt time.Time
+
+	// generation identifies the Set that stored the entry, so a refresh
+	// started from it can tell whether it was replaced or deleted meanwhile
+	generation uint64
 }
 
+// RefreshFunc fetches fresh vulnerability data for an image
+type RefreshFunc func(imageURI string) (*types.ImageVulnerability, error)
+
 type VulnerabilityCache struct {
-	cache  map[string]*CacheEntry
-	mutex  sync.RWMutex
-	ttl    time.Duration
-	logger *logrus.Logger
+	cache        map[string]*CacheEntry
+	mutex        sync.RWMutex
+	ttl          time.Duration
+	severityTTLs map[string]time.Duration // Optional TTL override keyed by highest severity
+	severities   severity.Order           // Ranks severities for severityTTLs
+	generation   uint64                   // Incremented by every Set
+	logger       *logrus.Logger
+
+	// Refresh-ahead: entries read within refreshWindow of expiry are
+	// refreshed in the background while the current data is served
+	refreshWindow time.Duration
+	refresh       RefreshFunc
+	refreshMutex  sync.Mutex
+	refreshing    map[string]bool
 }
 
 func NewVulnerabilityCache(logger *logrus.Logger) *VulnerabilityCache {
@@ -53,20 +81,192 @@ func (c *VulnerabilityCache) Get(imageURI string) *types.ImageVulnerability {
 		return nil
 	}
 
+	if c.refresh != nil && time.Until(entry.ExpiresAt) <= c.refreshWindow {
+		c.scheduleRefresh(imageURI, entry.generation)
+	}
+
 	c.logger.WithField("image", imageURI).Debug("Cache hit")
 	return entry.Data
 }
 
+// SetRefreshAhead enables refresh-ahead: a Get within window of an entry's
+// expiry still returns the cached data but refreshes the entry in the
+// background, so entries cached together don't all miss at once. A zero
+// window or nil refresh function disables it.
+func (c *VulnerabilityCache) SetRefreshAhead(window time.Duration, refresh RefreshFunc) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if window <= 0 || refresh == nil {
+		c.refreshWindow = 0
+		c.refresh = nil
+		return
+	}
+	c.refreshWindow = window
+	c.refresh = refresh
+}
+
+// scheduleRefresh starts a background refresh of an entry unless one is
+// already running for it
+func (c *VulnerabilityCache) scheduleRefresh(imageURI string, generation uint64) {
+	c.refreshMutex.Lock()
+	defer c.refreshMutex.Unlock()
+
+	if c.refreshing == nil {
+		c.refreshing = make(map[string]bool)
+	}
+	if c.refreshing[imageURI] {
+		return
+	}
+	c.refreshing[imageURI] = true
+
+	refresh := c.refresh
+	go func() {
+		defer func() {
+			c.refreshMutex.Lock()
+			delete(c.refreshing, imageURI)
+			c.refreshMutex.Unlock()
+		}()
+
+		vulnerability, err := refresh(imageURI)
+		if err != nil {
+			// The entry is left to expire; the next cycle fetches it again
+			c.logger.WithError(err).WithField("image", imageURI).Warn("Cache refresh-ahead failed")
+			return
+		}
+		if !c.storeRefresh(imageURI, generation, vulnerability) {
+			c.logger.WithField("image", imageURI).Debug("Discarded refresh of a cache entry replaced or deleted meanwhile")
+			return
+		}
+		c.logger.WithField("image", imageURI).Debug("Refreshed cache entry ahead of expiry")
+	}()
+}
+
+// storeRefresh caches refreshed data unless the entry it refreshes was
+// replaced or deleted while the refresh ran, e.g. by an invalidation. An entry
+// whose findings were dropped meanwhile stays without findings. It reports
+// whether the data was stored.
+func (c *VulnerabilityCache) storeRefresh(imageURI string, generation uint64, vulnerability *types.ImageVulnerability) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, exists := c.cache[imageURI]
+	if !exists || entry.generation != generation {
+		return false
+	}
+	if entry.Data != nil && entry.Data.FindingsDropped && vulnerability != nil {
+		shed := *vulnerability
+		shed.Findings = nil
+		shed.FindingsDropped = true
+		vulnerability = &shed
+	}
+	c.store(imageURI, vulnerability)
+	return true
+}
+
 func (c *VulnerabilityCache) Set(imageURI string, vulnerability *types.ImageVulnerability) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
+	c.store(imageURI, vulnerability)
+}
+
+// store caches an entry as a new generation. Must be called with c.mutex held.
+func (c *VulnerabilityCache) store(imageURI string, vulnerability *types.ImageVulnerability) {
+	c.generation++
+	ttl := c.ttlFor(vulnerability)
 	c.cache[imageURI] = &CacheEntry{
-		Data:      vulnerability,
-		ExpiresAt: time.Now().Add(c.ttl),
+		Data:       vulnerability,
+		ExpiresAt:  time.Now().Add(ttl),
+		generation: c.generation,
+	}
+
+	c.logger.WithFields(logrus.Fields{
+		"image": imageURI,
+		"ttl":   ttl,
+	}).Debug("Cached vulnerability data")
+}
+
+// Delete removes an image's entry so the next lookup fetches fresh data. It
+// reports whether the image was cached.
+func (c *VulnerabilityCache) Delete(imageURI string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	_, exists := c.cache[imageURI]
+	delete(c.cache, imageURI)
+
+	c.logger.WithFields(logrus.Fields{
+		"image":  imageURI,
+		"cached": exists,
+	}).Debug("Deleted cache entry")
+	return exists
+}
+
+// DropFindings replaces an entry by a copy without findings, flagged as
+// FindingsDropped, keeping its counts and expiry. It reports whether the image
+// was cached.
+func (c *VulnerabilityCache) DropFindings(imageURI string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry, exists := c.cache[imageURI]
+	if !exists || entry.Data == nil {
+		return false
+	}
+
+	shed := *entry.Data
+	shed.Findings = nil
+	shed.FindingsDropped = true
+	c.cache[imageURI] = &CacheEntry{Data: &shed, ExpiresAt: entry.ExpiresAt, generation: entry.generation}
+	return true
+}
+
+// Clear removes every entry, so all images are fetched fresh
+func (c *VulnerabilityCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	cleared := len(c.cache)
+	c.cache = make(map[string]*CacheEntry)
+
+	c.logger.WithField("cleared_entries", cleared).Debug("Cleared cache")
+}
+
+// SetSeverityTTLs configures per-severity TTLs. An entry's TTL is taken from its
+// highest severity with findings, ranked by the given order; severities without
+// an override use the default TTL.
+func (c *VulnerabilityCache) SetSeverityTTLs(ttls map[string]time.Duration, severities severity.Order) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.severities = severities
+	c.severityTTLs = make(map[string]time.Duration, len(ttls))
+	for severity, ttl := range ttls {
+		c.severityTTLs[severity] = ttl
+	}
+}
+
+// ttlFor returns the effective TTL for an entry based on its highest severity.
+// Pending scans are cached for at most PendingScanTTL.
+func (c *VulnerabilityCache) ttlFor(vulnerability *types.ImageVulnerability) time.Duration {
+	if vulnerability != nil && pendingScanStatuses[vulnerability.ScanStatus] {
+		return min(c.ttl, PendingScanTTL)
+	}
+	if len(c.severityTTLs) == 0 || vulnerability == nil {
+		return c.ttl
+	}
+
+	for _, level := range c.severities.Severities() {
+		if vulnerability.Vulnerabilities[level] > 0 {
+			if ttl, ok := c.severityTTLs[level]; ok {
+				return ttl
+			}
+			return c.ttl
+		}
 	}
 
-	c.logger.WithField("image", imageURI).Debug("Cached vulnerability data")
+	return c.ttl
 }
 
 func (c *VulnerabilityCache) startCleanup() {
@@ -6,9 +6,11 @@ package cache
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/severity"
 	"github.com/jfeddern/VulnRelay/internal/types"
 
 	"github.com/sirupsen/logrus"
@@ -317,6 +319,126 @@ func TestCacheOverwrite(t *testing.T) {
 	}
 }
 
+func TestCacheDelete(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cache := NewVulnerabilityCache(logger)
+
+	patched := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	other := "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1"
+	for _, image := range []string{patched, other} {
+		cache.Set(image, &types.ImageVulnerability{ImageURI: image, ScanStatus: "COMPLETE"})
+	}
+
+	if !cache.Delete(patched) {
+		t.Error("Expected Delete to report the cached image")
+	}
+	if cache.Get(patched) != nil {
+		t.Error("Expected a cache miss after Delete")
+	}
+	if cache.Get(other) == nil {
+		t.Error("Expected other entries to stay cached")
+	}
+	if total, _ := cache.Stats(); total != 1 {
+		t.Errorf("Expected 1 remaining entry, got %d", total)
+	}
+
+	if cache.Delete("123456789012.dkr.ecr.us-east-1.amazonaws.com/unknown:v1") {
+		t.Error("Expected Delete to report an unknown image as not cached")
+	}
+}
+
+func TestCacheDropFindings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cache := NewVulnerabilityCache(logger)
+
+	image := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	original := &types.ImageVulnerability{
+		ImageURI:        image,
+		Vulnerabilities: map[string]int{"HIGH": 2},
+		TotalCount:      2,
+		Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001", Severity: "HIGH"}, {Name: "CVE-2024-0002", Severity: "HIGH"}},
+	}
+	cache.Set(image, original)
+	expiresAt := cache.cache[image].ExpiresAt
+
+	if !cache.DropFindings(image) {
+		t.Fatal("Expected DropFindings to report the cached image")
+	}
+	cached := cache.Get(image)
+	if cached == nil || cached.Findings != nil || !cached.FindingsDropped {
+		t.Fatalf("Expected a cached entry without findings, got %+v", cached)
+	}
+	if cached.TotalCount != 2 || cached.Vulnerabilities["HIGH"] != 2 {
+		t.Errorf("Expected counts to be kept, got %+v", cached)
+	}
+	if !cache.cache[image].ExpiresAt.Equal(expiresAt) {
+		t.Error("Expected the expiry to be kept")
+	}
+	if len(original.Findings) != 2 || original.FindingsDropped {
+		t.Error("Expected the original result to be left unchanged")
+	}
+
+	if cache.DropFindings("123456789012.dkr.ecr.us-east-1.amazonaws.com/unknown:v1") {
+		t.Error("Expected DropFindings to report an unknown image as not cached")
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cache := NewVulnerabilityCache(logger)
+
+	images := make([]string, 5)
+	for i := range images {
+		images[i] = fmt.Sprintf("123456789012.dkr.ecr.us-east-1.amazonaws.com/app-%d:v1", i)
+		cache.Set(images[i], &types.ImageVulnerability{ImageURI: images[i], ScanStatus: "COMPLETE"})
+	}
+
+	cache.Clear()
+
+	for _, image := range images {
+		if cache.Get(image) != nil {
+			t.Errorf("Expected a cache miss for %s after Clear", image)
+		}
+	}
+	if total, _ := cache.Stats(); total != 0 {
+		t.Errorf("Expected an empty cache, got %d entries", total)
+	}
+
+	// The cache stays usable after clearing
+	cache.Set(images[0], &types.ImageVulnerability{ImageURI: images[0]})
+	if cache.Get(images[0]) == nil {
+		t.Error("Expected a cache hit after setting a cleared image again")
+	}
+}
+
+func TestCacheDeleteAndClearConcurrency(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cache := NewVulnerabilityCache(logger)
+
+	// Run with -race to detect unsynchronized access
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			image := fmt.Sprintf("app-%d:v1", i%3)
+			for j := 0; j < 100; j++ {
+				cache.Set(image, &types.ImageVulnerability{ImageURI: image})
+				cache.Get(image)
+				cache.Delete(image)
+				if j%25 == 0 {
+					cache.Clear()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
 func TestCacheStatsAccuracy(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -413,3 +535,355 @@ func (h *testLogHook) Fire(entry *logrus.Entry) error {
 	*h.entries = append(*h.entries, *entry)
 	return nil
 }
+
+func TestCacheSeverityTTLs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cache := &VulnerabilityCache{
+		cache:  make(map[string]*CacheEntry),
+		ttl:    30 * time.Minute,
+		logger: logger,
+	}
+	cache.SetSeverityTTLs(map[string]time.Duration{
+		"CRITICAL": 5 * time.Minute,
+		"LOW":      2 * time.Hour,
+	}, severity.NewOrder(nil))
+
+	criticalImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/critical-app:v1.0.0"
+	lowImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/low-app:v1.0.0"
+	mediumImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/medium-app:v1.0.0"
+
+	before := time.Now()
+	cache.Set(criticalImage, &types.ImageVulnerability{
+		ImageURI:        criticalImage,
+		Vulnerabilities: map[string]int{"CRITICAL": 1, "LOW": 4},
+	})
+	cache.Set(lowImage, &types.ImageVulnerability{
+		ImageURI:        lowImage,
+		Vulnerabilities: map[string]int{"LOW": 3},
+	})
+	cache.Set(mediumImage, &types.ImageVulnerability{
+		ImageURI:        mediumImage,
+		Vulnerabilities: map[string]int{"MEDIUM": 2},
+	})
+
+	criticalExpiry := cache.cache[criticalImage].ExpiresAt
+	lowExpiry := cache.cache[lowImage].ExpiresAt
+	mediumExpiry := cache.cache[mediumImage].ExpiresAt
+
+	if !criticalExpiry.Before(lowExpiry) {
+		t.Errorf("Expected CRITICAL entry to expire before LOW-only entry: critical=%v low=%v", criticalExpiry, lowExpiry)
+	}
+
+	if got := criticalExpiry.Sub(before); got < 5*time.Minute || got > 6*time.Minute {
+		t.Errorf("Expected CRITICAL TTL of ~5m, got %v", got)
+	}
+
+	if got := lowExpiry.Sub(before); got < 2*time.Hour || got > 2*time.Hour+time.Minute {
+		t.Errorf("Expected LOW TTL of ~2h, got %v", got)
+	}
+
+	// Severities without an override fall back to the default TTL
+	if got := mediumExpiry.Sub(before); got < 30*time.Minute || got > 31*time.Minute {
+		t.Errorf("Expected default TTL of ~30m for MEDIUM, got %v", got)
+	}
+}
+
+func TestCacheSeverityTTLsOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cache := &VulnerabilityCache{
+		cache:  make(map[string]*CacheEntry),
+		ttl:    30 * time.Minute,
+		logger: logger,
+	}
+	cache.SetSeverityTTLs(map[string]time.Duration{
+		"LOW":        2 * time.Hour,
+		"NEGLIGIBLE": 6 * time.Hour,
+	}, severity.NewOrder([]string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"}))
+
+	negligibleImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/negligible-app:v1.0.0"
+	lowImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/low-app:v1.0.0"
+
+	before := time.Now()
+	cache.Set(negligibleImage, &types.ImageVulnerability{
+		ImageURI:        negligibleImage,
+		Vulnerabilities: map[string]int{"NEGLIGIBLE": 3},
+	})
+	cache.Set(lowImage, &types.ImageVulnerability{
+		ImageURI:        lowImage,
+		Vulnerabilities: map[string]int{"LOW": 1, "NEGLIGIBLE": 3},
+	})
+
+	// Severities added to the order get their override, ranked below LOW
+	if got := cache.cache[negligibleImage].ExpiresAt.Sub(before); got < 6*time.Hour || got > 6*time.Hour+time.Minute {
+		t.Errorf("Expected NEGLIGIBLE TTL of ~6h, got %v", got)
+	}
+	if got := cache.cache[lowImage].ExpiresAt.Sub(before); got < 2*time.Hour || got > 2*time.Hour+time.Minute {
+		t.Errorf("Expected LOW TTL of ~2h, got %v", got)
+	}
+}
+
+func TestCachePendingScanTTL(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+	cache := NewVulnerabilityCache(logger)
+	cache.SetSeverityTTLs(map[string]time.Duration{"LOW": 6 * time.Hour}, severity.NewOrder(nil))
+
+	tests := []struct {
+		status   string
+		expected time.Duration
+	}{
+		{status: "IN_PROGRESS", expected: PendingScanTTL},
+		{status: "PENDING", expected: PendingScanTTL},
+		{status: "COMPLETE", expected: 30 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.status, func(t *testing.T) {
+			image := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:" + strings.ToLower(tt.status)
+			before := time.Now()
+			cache.Set(image, &types.ImageVulnerability{ImageURI: image, Vulnerabilities: map[string]int{}, ScanStatus: tt.status})
+
+			if got := cache.cache[image].ExpiresAt.Sub(before); got < tt.expected || got > tt.expected+time.Minute {
+				t.Errorf("Expected a TTL of %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestCacheRefreshAhead(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cache := &VulnerabilityCache{
+		cache:  make(map[string]*CacheEntry),
+		ttl:    time.Minute,
+		logger: logger,
+	}
+
+	testImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0"
+	refreshed := make(chan string, 10)
+	cache.SetRefreshAhead(2*time.Minute, func(imageURI string) (*types.ImageVulnerability, error) {
+		refreshed <- imageURI
+		return &types.ImageVulnerability{ImageURI: imageURI, TotalCount: 7}, nil
+	})
+
+	// With a 1m TTL every entry is inside the 2m refresh window
+	cache.Set(testImage, &types.ImageVulnerability{ImageURI: testImage, TotalCount: 3})
+	oldExpiry := cache.cache[testImage].ExpiresAt
+
+	result := cache.Get(testImage)
+	if result == nil || result.TotalCount != 3 {
+		t.Fatalf("Expected near-expiry Get to serve cached data, got %+v", result)
+	}
+
+	select {
+	case uri := <-refreshed:
+		if uri != testImage {
+			t.Errorf("Expected refresh of %s, got %s", testImage, uri)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a background refresh to be scheduled")
+	}
+
+	// The refreshed data replaces the entry with a new expiry
+	deadline := time.Now().Add(time.Second)
+	for {
+		cache.mutex.RLock()
+		entry := cache.cache[testImage]
+		cache.mutex.RUnlock()
+		if entry.Data.TotalCount == 7 {
+			if !entry.ExpiresAt.After(oldExpiry) {
+				t.Error("Expected refreshed entry to have a later expiry")
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected refreshed data to be cached")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestCacheRefreshAheadOutsideWindow(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cache := &VulnerabilityCache{
+		cache:  make(map[string]*CacheEntry),
+		ttl:    30 * time.Minute,
+		logger: logger,
+	}
+
+	var calls int
+	var mu sync.Mutex
+	cache.SetRefreshAhead(time.Minute, func(imageURI string) (*types.ImageVulnerability, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		return &types.ImageVulnerability{ImageURI: imageURI}, nil
+	})
+
+	testImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0"
+	cache.Set(testImage, &types.ImageVulnerability{ImageURI: testImage})
+	for i := 0; i < 5; i++ {
+		cache.Get(testImage)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 0 {
+		t.Errorf("Expected no refresh for an entry far from expiry, got %d", calls)
+	}
+}
+
+func TestCacheRefreshAheadSingleFlight(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cache := &VulnerabilityCache{
+		cache:  make(map[string]*CacheEntry),
+		ttl:    time.Minute,
+		logger: logger,
+	}
+
+	release := make(chan struct{})
+	var calls int
+	var mu sync.Mutex
+	cache.SetRefreshAhead(2*time.Minute, func(imageURI string) (*types.ImageVulnerability, error) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		<-release
+		return nil, fmt.Errorf("source unavailable")
+	})
+
+	testImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0"
+	cache.Set(testImage, &types.ImageVulnerability{ImageURI: testImage, TotalCount: 3})
+	for i := 0; i < 5; i++ {
+		if result := cache.Get(testImage); result == nil {
+			t.Fatal("Expected cached data while a refresh is running")
+		}
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 1 {
+		t.Errorf("Expected a single refresh while one is in flight, got %d", calls)
+	}
+}
+
+func TestCacheRefreshAheadRaces(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	testImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:v1.0.0"
+	refreshedData := &types.ImageVulnerability{
+		ImageURI:        testImage,
+		Vulnerabilities: map[string]int{"HIGH": 2},
+		TotalCount:      2,
+		Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001"}, {Name: "CVE-2024-0002"}},
+	}
+
+	tests := []struct {
+		name   string
+		during func(cache *VulnerabilityCache) // Runs while the refresh is in flight
+		check  func(t *testing.T, entry *CacheEntry)
+	}{
+		{
+			name:   "findings dropped",
+			during: func(cache *VulnerabilityCache) { cache.DropFindings(testImage) },
+			check: func(t *testing.T, entry *CacheEntry) {
+				if entry == nil || entry.Data.TotalCount != 2 {
+					t.Fatalf("Expected the refreshed counts to be cached, got %+v", entry)
+				}
+				if entry.Data.Findings != nil || !entry.Data.FindingsDropped {
+					t.Errorf("Expected the refreshed entry to stay without findings, got %d findings", len(entry.Data.Findings))
+				}
+			},
+		},
+		{
+			name:   "deleted",
+			during: func(cache *VulnerabilityCache) { cache.Delete(testImage) },
+			check: func(t *testing.T, entry *CacheEntry) {
+				if entry != nil {
+					t.Errorf("Expected the deleted entry not to be recreated, got %+v", entry.Data)
+				}
+			},
+		},
+		{
+			name:   "cleared",
+			during: func(cache *VulnerabilityCache) { cache.Clear() },
+			check: func(t *testing.T, entry *CacheEntry) {
+				if entry != nil {
+					t.Errorf("Expected the cleared entry not to be recreated, got %+v", entry.Data)
+				}
+			},
+		},
+		{
+			name: "replaced",
+			during: func(cache *VulnerabilityCache) {
+				cache.Set(testImage, &types.ImageVulnerability{ImageURI: testImage, TotalCount: 5})
+			},
+			check: func(t *testing.T, entry *CacheEntry) {
+				if entry == nil || entry.Data.TotalCount != 5 {
+					t.Errorf("Expected the newer entry to be kept, got %+v", entry)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := &VulnerabilityCache{
+				cache:  make(map[string]*CacheEntry),
+				ttl:    time.Minute,
+				logger: logger,
+			}
+
+			started := make(chan struct{})
+			release := make(chan struct{})
+			cache.SetRefreshAhead(2*time.Minute, func(imageURI string) (*types.ImageVulnerability, error) {
+				close(started)
+				<-release
+				return refreshedData, nil
+			})
+
+			cache.Set(testImage, &types.ImageVulnerability{
+				ImageURI:   testImage,
+				TotalCount: 1,
+				Findings:   []types.VulnerabilityFinding{{Name: "CVE-2024-0001"}},
+			})
+			cache.Get(testImage)
+			<-started
+			tt.during(cache)
+			close(release)
+
+			// Wait for the refresh to finish
+			deadline := time.Now().Add(time.Second)
+			for {
+				cache.refreshMutex.Lock()
+				running := cache.refreshing[testImage]
+				cache.refreshMutex.Unlock()
+				if !running {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Expected the refresh to finish")
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			cache.mutex.RLock()
+			entry := cache.cache[testImage]
+			cache.mutex.RUnlock()
+			tt.check(t, entry)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+// ABOUTME: Adaptive limit on concurrent vulnerability source calls per registry.
+// ABOUTME: Halves the limit when the source throttles and ramps it back up additively (AIMD).
+
+package engine
+
+import "sync"
+
+// fetchConcurrency limits concurrent fetches with additive increase and
+// multiplicative decrease: throttling halves the limit, and every window of
+// limit successful fetches raises it by one, up to max
+type fetchConcurrency struct {
+	mutex     sync.Mutex
+	cond      *sync.Cond
+	limit     int
+	max       int
+	inFlight  int
+	successes int // Successful fetches since the limit last changed
+
+	// epoch changes with every decrease, so fetches started before it don't
+	// halve the limit again when they report the same throttling
+	epoch int
+}
+
+// newFetchConcurrency creates a limit starting at maxLimit
+func newFetchConcurrency(maxLimit int) *fetchConcurrency {
+	c := &fetchConcurrency{limit: maxLimit, max: maxLimit}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+// acquire waits until a fetch may start and returns the epoch to pass to
+// release
+func (c *fetchConcurrency) acquire() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for c.inFlight >= c.limit {
+		c.cond.Wait()
+	}
+	c.inFlight++
+	return c.epoch
+}
+
+// release ends a fetch started in epoch and adjusts the limit by its outcome
+func (c *fetchConcurrency) release(epoch int, throttled bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.inFlight--
+	switch {
+	case throttled && epoch == c.epoch:
+		c.limit = max(1, c.limit/2)
+		c.successes = 0
+		c.epoch++
+	case !throttled:
+		c.successes++
+		if c.successes >= c.limit && c.limit < c.max {
+			c.limit++
+			c.successes = 0
+		}
+	}
+	c.cond.Broadcast()
+}
+
+// current returns the effective limit
+func (c *fetchConcurrency) current() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.limit
+}
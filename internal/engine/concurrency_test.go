@@ -0,0 +1,208 @@
+// ABOUTME: Unit tests for the adaptive fetch concurrency limit.
+// ABOUTME: Covers halving on throttling, additive recovery, blocking at the limit and engine integration.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFetchConcurrencyAIMD(t *testing.T) {
+	c := newFetchConcurrency(8)
+
+	fetch := func(throttled bool) {
+		c.release(c.acquire(), throttled)
+	}
+
+	// Each throttled fetch halves the limit, down to 1
+	for _, expected := range []int{4, 2, 1, 1} {
+		fetch(true)
+		if got := c.current(); got != expected {
+			t.Fatalf("Expected limit %d after throttling, got %d", expected, got)
+		}
+	}
+
+	// A window of limit successes raises the limit by one
+	for _, expected := range []int{2, 3, 4} {
+		for i := 0; i < expected-1; i++ {
+			fetch(false)
+		}
+		if got := c.current(); got != expected {
+			t.Fatalf("Expected limit %d after recovery, got %d", expected, got)
+		}
+	}
+
+	// The limit never exceeds the maximum
+	for i := 0; i < 100; i++ {
+		fetch(false)
+	}
+	if got := c.current(); got != 8 {
+		t.Errorf("Expected limit to recover to 8, got %d", got)
+	}
+}
+
+func TestFetchConcurrencyConcurrentThrottling(t *testing.T) {
+	c := newFetchConcurrency(8)
+
+	// Fetches in flight together are throttled together; that's one signal
+	epochs := make([]int, 4)
+	for i := range epochs {
+		epochs[i] = c.acquire()
+	}
+	for _, epoch := range epochs {
+		c.release(epoch, true)
+	}
+
+	if got := c.current(); got != 4 {
+		t.Errorf("Expected one halving to 4, got %d", got)
+	}
+}
+
+func TestFetchConcurrencyBlocksAtLimit(t *testing.T) {
+	c := newFetchConcurrency(2)
+	first := c.acquire()
+	c.acquire()
+
+	acquired := make(chan struct{})
+	go func() {
+		c.acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected acquire to block at the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.release(first, false)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected acquire to proceed after a release")
+	}
+}
+
+// errThrottled is returned by throttlingVulnerabilitySource while it throttles
+var errThrottled = errors.New("rate exceeded")
+
+// throttlingVulnerabilitySource throttles while throttling is set
+type throttlingVulnerabilitySource struct {
+	MockVulnerabilitySource
+	throttling atomic.Bool
+}
+
+func (s *throttlingVulnerabilitySource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	if s.throttling.Load() {
+		return nil, fmt.Errorf("describe image scan findings: %w", errThrottled)
+	}
+	return s.MockVulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
+}
+
+func (s *throttlingVulnerabilitySource) IsThrottled(err error) bool {
+	return errors.Is(err, errThrottled)
+}
+
+func TestEngineAdaptiveConcurrency(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const registry = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	var images []types.ImageInfo
+	for i := 0; i < 20; i++ {
+		images = append(images, types.ImageInfo{URI: fmt.Sprintf("%s/app-%d:v1", registry, i)})
+	}
+
+	source := &throttlingVulnerabilitySource{}
+	engine := NewEngine(&MockCloudProvider{images: images}, source, &Config{AdaptiveConcurrency: true}, logger)
+	collect := func() int {
+		for _, img := range images {
+			engine.InvalidateImage(img.URI)
+		}
+		if err := engine.collectVulnerabilities(context.Background()); err != nil {
+			t.Fatalf("collectVulnerabilities() failed: %v", err)
+		}
+		return engine.GetFetchConcurrency()[registry]
+	}
+
+	if got := collect(); got != maxConcurrentFetches {
+		t.Fatalf("Expected full concurrency %d without throttling, got %d", maxConcurrentFetches, got)
+	}
+
+	// Throttling lowers the registry's concurrency
+	source.throttling.Store(true)
+	throttled := collect()
+	if throttled >= maxConcurrentFetches {
+		t.Fatalf("Expected concurrency below %d while throttled, got %d", maxConcurrentFetches, throttled)
+	}
+
+	// Once throttling subsides, successful fetches ramp it back up
+	source.throttling.Store(false)
+	recovered := throttled
+	for cycle := 0; cycle < 10 && recovered < maxConcurrentFetches; cycle++ {
+		recovered = collect()
+	}
+	if recovered != maxConcurrentFetches {
+		t.Errorf("Expected concurrency to recover to %d, got %d", maxConcurrentFetches, recovered)
+	}
+}
+
+func TestEngineAdaptiveConcurrencyIgnoresCacheHits(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const registry = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	var images []types.ImageInfo
+	for i := 0; i < 20; i++ {
+		images = append(images, types.ImageInfo{URI: fmt.Sprintf("%s/app-%d:v1", registry, i)})
+	}
+
+	source := &throttlingVulnerabilitySource{}
+	engine := NewEngine(&MockCloudProvider{images: images}, source, &Config{AdaptiveConcurrency: true}, logger)
+	collect := func() int {
+		if err := engine.collectVulnerabilities(context.Background()); err != nil {
+			t.Fatalf("collectVulnerabilities() failed: %v", err)
+		}
+		return engine.GetFetchConcurrency()[registry]
+	}
+	collect()
+
+	// A single throttled source call halves the limit
+	source.throttling.Store(true)
+	engine.InvalidateImage(images[0].URI)
+	if got := collect(); got != maxConcurrentFetches/2 {
+		t.Fatalf("Expected concurrency %d after one throttled call, got %d", maxConcurrentFetches/2, got)
+	}
+
+	// Cached images don't reach the source, so they don't ramp it back up
+	source.throttling.Store(false)
+	engine.InvalidateImage(images[0].URI)
+	collect()
+	for range 3 {
+		if got := collect(); got != maxConcurrentFetches/2 {
+			t.Fatalf("Expected concurrency to stay at %d with cache hits only, got %d", maxConcurrentFetches/2, got)
+		}
+	}
+}
+
+func TestEngineFetchConcurrencyDisabled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	source := &throttlingVulnerabilitySource{}
+	source.throttling.Store(true)
+	engine := NewEngine(&MockCloudProvider{images: []types.ImageInfo{{URI: "app:v1"}}}, source, &Config{}, logger)
+	engine.collectVulnerabilities(context.Background())
+
+	if limits := engine.GetFetchConcurrency(); limits != nil {
+		t.Errorf("Expected no adaptive limits when disabled, got %v", limits)
+	}
+}
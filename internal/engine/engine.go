@@ -5,12 +5,22 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/acceptance"
 	"github.com/jfeddern/VulnRelay/internal/cache"
+	"github.com/jfeddern/VulnRelay/internal/notify"
+	"github.com/jfeddern/VulnRelay/internal/severity"
 	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 )
 
 // CloudProvider interface abstracts different cloud providers (AWS EKS, Google GKE, Azure AKS)
@@ -20,6 +30,14 @@ type CloudProvider interface {
 	IsRegistryImage(imageURI string) bool
 }
 
+// ImageStreamer is optionally implemented by cloud providers that can send
+// discovered images in batches, e.g. one namespace at a time, before
+// discovery completes. StreamImages returns once every batch has been sent;
+// it doesn't close the channel.
+type ImageStreamer interface {
+	StreamImages(ctx context.Context, batches chan<- []types.ImageInfo) error
+}
+
 // VulnerabilitySource interface abstracts different vulnerability scanning sources
 type VulnerabilitySource interface {
 	Name() string
@@ -27,15 +45,330 @@ type VulnerabilitySource interface {
 	ParseImageURI(imageURI string) (repository, tag string, err error)
 }
 
+// ImageFilter is optionally implemented by vulnerability sources that only
+// cover some of the discovered images, e.g. through a repository allowlist.
+// The filter is resolved once per registry and collection cycle, e.g. by
+// listing the registry's repositories, and then applied to every batch of the
+// registry's discovered images.
+type ImageFilter interface {
+	ResolveImageFilter(ctx context.Context, registry string) (func([]types.ImageInfo) []types.ImageInfo, error)
+}
+
+// IdentitySource is optionally implemented by vulnerability sources that can
+// report the cloud identity they authenticate as
+type IdentitySource interface {
+	CallerIdentity(ctx context.Context) (*types.CallerIdentity, error)
+}
+
+// ScanConfigurationSource is optionally implemented by vulnerability sources
+// that can report how their registry scans images
+type ScanConfigurationSource interface {
+	// RegistryScanType returns the scan type, e.g. "BASIC" or "ENHANCED"
+	RegistryScanType(ctx context.Context) (string, error)
+}
+
+// ThrottleDetector is optionally implemented by vulnerability sources that
+// can tell throttling apart from other errors, enabling adaptive concurrency
+type ThrottleDetector interface {
+	IsThrottled(err error) bool
+}
+
 // Config holds configuration for the vulnerability collection engine
 type Config struct {
-	Mode           string
-	Port           int
-	ECRAccountID   string
-	ECRRegion      string
-	ImageListFile  string
-	ScrapeInterval time.Duration
-	MockMode       bool // Enable mock providers for local testing
+	Mode             string
+	Port             int
+	GRPCPort         int // Port of the optional gRPC API (0 = disabled)
+	ECRAccountID     string
+	ECRRegion        string
+	AssumeRoleARN    string // Explicit IAM role to assume for ECR access
+	CrossAccountRole string // Role name or ARN template auto-assumed for cross-account ECR
+	ImagePlatform    string // Platform scanned for multi-arch images, e.g. linux/amd64
+	ResolveDigests   bool   // Report the scanned manifest digest of each image
+	TriggerScans     bool   // Start a scan of images without one, at most once per 24h each
+	ImageListFile    string
+	DefaultImageTag  string // Tag applied to image list entries without one, e.g. latest
+	ScrapeInterval   time.Duration
+	MockMode         bool // Enable mock providers for local testing
+
+	// InitialCollectionRetries is how often a failed first collection is
+	// retried before waiting for the next scrape interval
+	InitialCollectionRetries int
+
+	// InitialCollectionBackoff is the wait before the first retry; it doubles
+	// per attempt up to maxInitialCollectionBackoff (0 = default)
+	InitialCollectionBackoff time.Duration
+
+	// CheckRegistryScanning reads the registry's scan type at startup, logging
+	// a warning when enhanced scanning is not enabled
+	CheckRegistryScanning bool
+
+	// KeepStaleOnFailure keeps an image's previous data, flagged as stale,
+	// when fetching it fails instead of dropping the image until the next
+	// successful fetch
+	KeepStaleOnFailure bool
+
+	// MaxDataAge drops an image's data once it hasn't been refreshed by a
+	// successful fetch for this long, even while collections keep failing or
+	// stale data is kept (0 = never)
+	MaxDataAge time.Duration
+
+	// SeverityCacheTTLs overrides the cache TTL based on an image's highest severity
+	SeverityCacheTTLs map[string]time.Duration
+
+	// MaxRetainedFindings bounds memory by shedding the findings, but not the
+	// counts, of the images with the most findings once a collection has more
+	// than this many (0 = unlimited)
+	MaxRetainedFindings int
+
+	// MaxImagesPerCycle processes at most this many discovered images per
+	// collection, in image URI order, e.g. for smoke tests (0 = unlimited)
+	MaxImagesPerCycle int
+
+	// PipelineDiscovery starts fetching vulnerabilities as the cloud provider
+	// streams discovered images instead of after discovery completes; only
+	// providers implementing ImageStreamer stream
+	PipelineDiscovery bool
+
+	// SnapshotHistory is how many previous collections are kept for
+	// point-in-time queries (0 = none)
+	SnapshotHistory int
+
+	// AllowedSeverities drops findings whose severity is not listed, e.g. to
+	// ignore INFORMATIONAL or UNTRIAGED findings. Empty keeps every severity.
+	AllowedSeverities []string
+
+	// RetainedFindingSeverities makes the vulnerability source keep only the
+	// findings of these severities, e.g. to save memory on LOW findings in
+	// large clusters, while severity counts still include every finding.
+	// Empty keeps every finding.
+	RetainedFindingSeverities []string
+
+	// SkipUntaggedImages makes the vulnerability source skip images referenced
+	// by digest only, e.g. repository@sha256:...
+	SkipUntaggedImages bool
+
+	// SeverityOrder lists severities most severe first. It ranks and filters
+	// JSON API results and the readiness, webhook and cache TTL severities;
+	// empty uses CRITICAL, HIGH, MEDIUM, LOW
+	SeverityOrder []string
+
+	// CacheRefreshAhead refreshes cached entries read within this window of
+	// their expiry in the background (0 = disabled)
+	CacheRefreshAhead time.Duration
+
+	// RiskScoreWeights overrides per-severity weights of the image risk score metric
+	RiskScoreWeights map[string]float64
+
+	// MetricsMaxSeries caps the series emitted per metric on /metrics (0 = unlimited)
+	MetricsMaxSeries int
+
+	// MetricsReleaseLabel adds the workload's Helm release as a label on
+	// per-image metrics
+	MetricsReleaseLabel bool
+
+	// MetricsPlatformLabel adds the platform scanned for multi-arch images as
+	// a label on per-image metrics
+	MetricsPlatformLabel bool
+
+	// MetricsDescriptionInfo exposes CVE descriptions on a separate info
+	// metric keyed by CVE instead of a label on every finding's series
+	MetricsDescriptionInfo bool
+
+	// MetricsHealthyScanStatuses lists the scan statuses the scan status
+	// metric reports as 1; empty reports only COMPLETE
+	MetricsHealthyScanStatuses []string
+
+	// MetricsDropLabels lists context labels, such as tag, removed from
+	// per-image metrics to reduce cardinality
+	MetricsDropLabels []string
+
+	// MetricsClusterName adds a cluster label with this value to every metric,
+	// telling clusters apart in a shared Prometheus (empty = no label)
+	MetricsClusterName string
+
+	// MetricsFreshOnly omits images with stale data from /metrics
+	MetricsFreshOnly bool
+
+	// MetricsMaxImageURILength caps the image_uri metric label; longer URIs
+	// are truncated with a hash suffix (0 = 200 characters)
+	MetricsMaxImageURILength int
+
+	// MetricsInfluxEndpoint serves the per-image data in the InfluxDB line
+	// protocol on /metrics/influx
+	MetricsInfluxEndpoint bool
+
+	// SourceRequestsPerSecond caps calls to the vulnerability source (0 = unlimited)
+	SourceRequestsPerSecond float64
+
+	// AdaptiveConcurrency halves a registry's concurrent source calls when the
+	// source reports throttling and ramps them back up as calls succeed
+	AdaptiveConcurrency bool
+
+	// MaxHeaderBytes caps the size of HTTP request headers (0 = the net/http
+	// default of 1 MB)
+	MaxHeaderBytes int
+
+	// MaxRequestBodyBytes caps the size of HTTP request bodies; larger
+	// requests are rejected with 413 (0 = unlimited)
+	MaxRequestBodyBytes int64
+
+	// DisableVulnerabilitiesEndpoint removes the /vulnerabilities and /cves JSON routes
+	DisableVulnerabilitiesEndpoint bool
+
+	// DebugIdentityEndpoint serves the AWS identity of the vulnerability
+	// source on /debug/identity; it requires OIDC authentication
+	DebugIdentityEndpoint bool
+
+	// APIMaxFindings caps the total findings returned by /vulnerabilities (0 = unlimited)
+	APIMaxFindings int
+
+	// APIPrettyPrint indents JSON API responses by default; requests can
+	// still override it with the pretty query parameter
+	APIPrettyPrint bool
+
+	// FailOnScanErrors makes /ready report not-ready when the fraction of
+	// FAILED or unknown scans exceeds ScanErrorThreshold (0-1)
+	FailOnScanErrors   bool
+	ScanErrorThreshold float64
+
+	// ReadinessSeverityThreshold makes /ready report not-ready while any
+	// image has active findings of this severity or a more severe one, e.g.
+	// HIGH to ignore MEDIUM and LOW (empty = findings don't affect readiness)
+	ReadinessSeverityThreshold string
+
+	// FieldSelector restricts discovered cluster workloads, e.g. metadata.name=api
+	FieldSelector string
+
+	// KubeStartupTimeout bounds retries of the initial Kubernetes API connection
+	KubeStartupTimeout time.Duration
+
+	// IgnoreContainers lists container name globs, e.g. istio-proxy, whose
+	// images are skipped during cluster discovery
+	IgnoreContainers []string
+
+	// Namespaces restricts cluster discovery to these namespaces (empty = all)
+	Namespaces []string
+
+	// DiscoveryConcurrency caps the namespaces listed at once during discovery
+	DiscoveryConcurrency int
+
+	// RequireScanAnnotation restricts cluster discovery to workloads annotated
+	// with vulnrelay.io/scan: "true"
+	RequireScanAnnotation bool
+
+	// Kubeconfig and KubeContext select the cluster to discover from instead
+	// of the in-cluster config
+	Kubeconfig  string
+	KubeContext string
+
+	// RepositoryAllowlist restricts ECR scanning to repositories matching these globs
+	RepositoryAllowlist []string
+
+	// VulnerabilitySource selects the scanner results are read from: "ecr" or "harbor"
+	VulnerabilitySource string
+
+	// Harbor connection settings, used when VulnerabilitySource is "harbor"
+	HarborURL      string
+	HarborUsername string
+	HarborPassword string
+
+	// AcceptedCVEsFile is a JSON risk acceptance list; accepted findings are
+	// flagged and excluded from the active counts
+	AcceptedCVEsFile string
+
+	// OnlyRunning restricts cluster discovery to images used by running pods
+	OnlyRunning bool
+
+	// SkipSuspendedCronJobs excludes CronJobs with a suspended schedule from discovery
+	SkipSuspendedCronJobs bool
+
+	// RegistryHosts lists extra registry host suffixes (e.g. ECR DNS aliases) treated as ECR
+	RegistryHosts []string
+
+	// CORSAllowedOrigins lists browser origins allowed to call the JSON endpoints ("*" for any)
+	CORSAllowedOrigins []string
+
+	// OIDC bearer token authentication for the JSON endpoints, enabled when
+	// OIDCJWKSURL is set
+	OIDCIssuer   string
+	OIDCJWKSURL  string
+	OIDCAudience string
+
+	// AuditLog enables JSON audit entries for requests to the JSON endpoints,
+	// written to "stdout", "stderr" or a file path
+	AuditLog string
+
+	// Webhook notifications for new findings, enabled when WebhookURL is set.
+	// WebhookMinSeverity applies to namespaces without a matching
+	// WebhookSeverityThresholds pattern.
+	WebhookURL                string
+	WebhookMinSeverity        string
+	WebhookSeverityThresholds []notify.SeverityThreshold
+
+	// WebhookMaxRetries is the number of retries of a failed delivery before
+	// the notification is logged as a dead letter
+	WebhookMaxRetries int
+
+	// WebhookDeliveryTimeout bounds the deliveries of a collection, retries
+	// included, so backoff doesn't hold up the next hooks (0 = default)
+	WebhookDeliveryTimeout time.Duration
+
+	// WebhookNotifyAll skips the diff against the previous collection and
+	// notifies every qualifying finding; WebhookBatchSize splits larger sets
+	// across several notifications (0 = one notification)
+	WebhookNotifyAll bool
+	WebhookBatchSize int
+
+	// WebhookDiffConcurrency is the number of workers diffing a collection
+	// against the previous one, for very large collections (default 1)
+	WebhookDiffConcurrency int
+
+	// S3ExportBucket enables uploading each collection's full dataset as a
+	// timestamped JSON object under S3ExportPrefix
+	S3ExportBucket string
+	S3ExportPrefix string
+
+	// ElasticsearchURL enables posting every finding to this _bulk endpoint
+	// after each collection, indexed into ElasticsearchIndex
+	ElasticsearchURL   string
+	ElasticsearchIndex string
+}
+
+// collectionDurationEMAAlpha weights the latest cycle in the collection duration
+// EMA; 0.3 smooths single outliers while still tracking sustained slowdowns
+const collectionDurationEMAAlpha = 0.3
+
+// maxConcurrentFetches caps concurrent vulnerability source calls across all
+// registries
+const maxConcurrentFetches = 10
+
+// emptyDiscoveryThreshold is the number of consecutive cycles discovering no
+// images after which discovery is reported as empty
+const emptyDiscoveryThreshold = 3
+
+// Backoff between retries of a failed initial collection
+const (
+	defaultInitialCollectionBackoff = 5 * time.Second
+	maxInitialCollectionBackoff     = time.Minute
+)
+
+// refreshAheadTimeout bounds a single background cache refresh
+const refreshAheadTimeout = time.Minute
+
+// CollectionHook is called after each successful collection with the new data.
+// The data is shared with the engine and must not be modified.
+type CollectionHook func(ctx context.Context, data map[string]*types.ImageVulnerabilityData)
+
+// snapshot is the data of a completed collection
+type snapshot struct {
+	data        map[string]*types.ImageVulnerabilityData
+	collectedAt time.Time
+}
+
+// findingKey identifies a CVE on a specific image for first-seen tracking
+type findingKey struct {
+	imageURI string
+	cve      string
 }
 
 // Engine orchestrates vulnerability data collection using pluggable providers
@@ -43,36 +376,88 @@ type Engine struct {
 	cloudProvider       CloudProvider
 	vulnerabilitySource VulnerabilitySource
 	cache               *cache.VulnerabilityCache
+	limiter             *rate.Limiter      // Optional token bucket for vulnerability source calls
+	allowedSeverities   map[string]bool    // Severities kept from source results (nil = all)
+	inflight            singleflight.Group // Shares concurrent source calls for the same image URI
+	fetchSlots          chan struct{}      // Caps concurrent source calls at maxConcurrentFetches
 	config              *Config
 	logger              *logrus.Logger
+	now                 func() time.Time // Clock used for first-seen timestamps
 
 	// Current vulnerability data with metadata
-	mutex              sync.RWMutex
-	vulnerabilityData  map[string]*types.ImageVulnerabilityData
-	lastCollectionTime time.Time
+	mutex                 sync.RWMutex
+	vulnerabilityData     map[string]*types.ImageVulnerabilityData
+	lastCollectionTime    time.Time
+	snapshots             []snapshot // Previous collections, most recent first
+	lastTickTime          time.Time
+	collectionDurationEMA time.Duration
+	emptyDiscoveries      int    // Consecutive cycles that discovered no images
+	registryScanType      string // Scan type read at startup, empty when unchecked
+	registryCollections   []types.RegistryCollection
+	firstSeen             map[findingKey]time.Time
+	refreshedAt           map[string]time.Time         // Last successful fetch per image URI
+	fetchConcurrency      map[string]*fetchConcurrency // Adaptive limit per registry
+	acceptedCVEs          *acceptance.List
+	collectionHooks       []CollectionHook
 }
 
 // NewEngine creates a new vulnerability collection engine
 func NewEngine(cloudProvider CloudProvider, vulnerabilitySource VulnerabilitySource, config *Config, logger *logrus.Logger) *Engine {
-	return &Engine{
+	vulnCache := cache.NewVulnerabilityCache(logger)
+	if len(config.SeverityCacheTTLs) > 0 {
+		vulnCache.SetSeverityTTLs(config.SeverityCacheTTLs, severity.NewOrder(config.SeverityOrder))
+	}
+
+	// Token bucket with a burst of 1 so concurrent workers never exceed the quota
+	var limiter *rate.Limiter
+	if config.SourceRequestsPerSecond > 0 {
+		limiter = rate.NewLimiter(rate.Limit(config.SourceRequestsPerSecond), 1)
+	}
+
+	var allowedSeverities map[string]bool
+	if len(config.AllowedSeverities) > 0 {
+		allowedSeverities = make(map[string]bool, len(config.AllowedSeverities))
+		for _, severity := range config.AllowedSeverities {
+			allowedSeverities[strings.ToUpper(severity)] = true
+		}
+	}
+
+	engine := &Engine{
 		cloudProvider:       cloudProvider,
 		vulnerabilitySource: vulnerabilitySource,
-		cache:               cache.NewVulnerabilityCache(logger),
+		cache:               vulnCache,
+		limiter:             limiter,
+		allowedSeverities:   allowedSeverities,
+		fetchSlots:          make(chan struct{}, maxConcurrentFetches),
 		config:              config,
 		logger:              logger,
+		now:                 time.Now,
 		vulnerabilityData:   make(map[string]*types.ImageVulnerabilityData),
+		firstSeen:           make(map[findingKey]time.Time),
+		refreshedAt:         make(map[string]time.Time),
+		fetchConcurrency:    make(map[string]*fetchConcurrency),
+	}
+
+	if config.CacheRefreshAhead > 0 {
+		vulnCache.SetRefreshAhead(config.CacheRefreshAhead, engine.refreshImageVulnerability)
 	}
+
+	return engine
 }
 
 // Start begins the vulnerability collection process
 func (e *Engine) Start(ctx context.Context) {
 	logger := e.logger.WithField("component", "vulnerability_engine")
 
-	// Perform initial collection
-	if err := e.collectVulnerabilities(ctx); err != nil {
-		logger.WithError(err).Error("Initial vulnerability collection failed")
+	e.checkRegistryScanning(ctx, logger)
+	if _, ok := e.cloudProvider.(ImageStreamer); e.config.PipelineDiscovery && !ok {
+		logger.WithField("provider", e.cloudProvider.Name()).Warn("Cloud provider does not stream discovered images; fetching starts after discovery completes")
 	}
 
+	// Perform initial collection
+	e.recordTick(time.Now())
+	e.initialCollection(ctx, logger)
+
 	// Start periodic collection
 	ticker := time.NewTicker(e.config.ScrapeInterval)
 	defer ticker.Stop()
@@ -84,7 +469,8 @@ func (e *Engine) Start(ctx context.Context) {
 		case <-ctx.Done():
 			logger.Info("Vulnerability engine stopping")
 			return
-		case <-ticker.C:
+		case tick := <-ticker.C:
+			e.recordTick(tick)
 			if err := e.collectVulnerabilities(ctx); err != nil {
 				logger.WithError(err).Error("Vulnerability collection failed")
 			}
@@ -92,85 +478,609 @@ func (e *Engine) Start(ctx context.Context) {
 	}
 }
 
-func (e *Engine) collectVulnerabilities(ctx context.Context) error {
-	logger := e.logger.WithField("operation", "collect_vulnerabilities")
-	startTime := time.Now()
+// checkRegistryScanning records the registry's scan type when enabled, so a
+// registry without enhanced scanning is noticed before its findings are
+// missing Inspector details
+func (e *Engine) checkRegistryScanning(ctx context.Context, logger *logrus.Entry) {
+	if !e.config.CheckRegistryScanning {
+		return
+	}
 
-	logger.Info("Starting vulnerability data collection")
+	source, ok := e.vulnerabilitySource.(ScanConfigurationSource)
+	if !ok {
+		logger.WithField("source", e.vulnerabilitySource.Name()).Warn("Vulnerability source does not report a registry scanning configuration")
+		return
+	}
 
-	// Discover images using cloud provider
-	images, err := e.cloudProvider.DiscoverImages(ctx)
+	scanType, err := source.RegistryScanType(ctx)
 	if err != nil {
-		return err
+		logger.WithError(err).Warn("Failed to check registry scanning configuration")
+		return
 	}
 
-	logger.WithField("image_count", len(images)).Info("Discovered images")
+	e.mutex.Lock()
+	e.registryScanType = scanType
+	e.mutex.Unlock()
 
-	// Collect vulnerabilities for each image
-	newVulnerabilityData := make(map[string]*types.ImageVulnerabilityData)
+	entry := logger.WithField("scan_type", scanType)
+	if scanType == "ENHANCED" {
+		entry.Info("Registry uses enhanced scanning")
+		return
+	}
+	entry.Warn("Registry does not use enhanced scanning; findings lack Inspector details such as type, package, fix and exploit availability")
+}
 
-	// Use semaphore to limit concurrent API calls
-	semaphore := make(chan struct{}, 10) // Max 10 concurrent calls
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+// initialCollection runs the first collection, retrying failures with
+// exponential backoff so a dependency that is briefly unavailable at startup
+// doesn't leave the data empty for a whole scrape interval
+func (e *Engine) initialCollection(ctx context.Context, logger *logrus.Entry) {
+	backoff := e.config.InitialCollectionBackoff
+	if backoff <= 0 {
+		backoff = defaultInitialCollectionBackoff
+	}
 
-	for _, imageInfo := range images {
-		wg.Add(1)
-		go func(imgInfo types.ImageInfo) {
-			defer wg.Done()
+	for attempt := 1; ; attempt++ {
+		err := e.collectVulnerabilities(ctx)
+		if err == nil {
+			return
+		}
+		if attempt > e.config.InitialCollectionRetries {
+			logger.WithError(err).Error("Initial vulnerability collection failed")
+			return
+		}
 
-			semaphore <- struct{}{}        // Acquire semaphore
-			defer func() { <-semaphore }() // Release semaphore
+		logger.WithError(err).WithFields(logrus.Fields{
+			"attempt":  attempt,
+			"retry_in": backoff,
+		}).Warn("Initial vulnerability collection failed, retrying")
 
-			vuln, err := e.getImageVulnerability(ctx, imgInfo.URI)
-			if err != nil {
-				logger.WithError(err).WithField("image", imgInfo.URI).Error("Failed to get vulnerability data")
-				return
-			}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxInitialCollectionBackoff)
+	}
+}
 
-			mu.Lock()
-			newVulnerabilityData[imgInfo.URI] = &types.ImageVulnerabilityData{
-				ImageVulnerability: vuln,
-				ImageInfo:          imgInfo,
-			}
-			mu.Unlock()
-		}(imageInfo)
+func (e *Engine) collectVulnerabilities(ctx context.Context) error {
+	logger := e.logger.WithField("operation", "collect_vulnerabilities")
+	startTime := time.Now()
+
+	logger.Info("Starting vulnerability data collection")
+
+	// Data past MaxDataAge is dropped even if this collection fails
+	e.mutex.Lock()
+	if pruned := e.pruneExpired(); pruned > 0 {
+		logger.WithField("pruned_images", pruned).Info("Pruned images not refreshed within the max data age")
 	}
+	e.mutex.Unlock()
 
-	wg.Wait()
+	var images []types.ImageInfo
+	var results []registryResult
+	var err error
+	if streamer, ok := e.cloudProvider.(ImageStreamer); ok && e.config.PipelineDiscovery {
+		images, results, err = e.streamAndCollect(ctx, streamer, logger)
+	} else {
+		images, results, err = e.discoverAndCollect(ctx, logger)
+	}
+	if err != nil {
+		return err
+	}
+
+	// Aggregate the registries' results
+	newVulnerabilityData := make(map[string]*types.ImageVulnerabilityData)
+	var processed []types.ImageInfo
+	statuses := make([]types.RegistryCollection, len(results))
+	var filterErrs []error
+	for i, result := range results {
+		maps.Copy(newVulnerabilityData, result.data)
+		processed = append(processed, result.images...)
+		statuses[i] = result.status
+		if result.err != nil {
+			filterErrs = append(filterErrs, result.err)
+		}
+	}
+	e.recordRegistryCollections(statuses)
+
+	// Without any registry to collect from, keep the previous data
+	if len(filterErrs) > 0 && len(filterErrs) == len(results) {
+		return errors.Join(filterErrs...)
+	}
 
 	// Update the vulnerability data
 	e.mutex.Lock()
+	e.annotateFindings(processed, newVulnerabilityData)
+	staleImages := 0
+	if e.config.KeepStaleOnFailure {
+		staleImages = e.mergeStale(processed, newVulnerabilityData)
+	}
+	if shed := e.shedFindings(newVulnerabilityData); shed > 0 {
+		logger.WithFields(logrus.Fields{
+			"max_retained_findings": e.config.MaxRetainedFindings,
+			"shed_images":           shed,
+		}).Warn("Too many findings to retain; keeping only counts for the images with the most findings")
+	}
+	e.recordRefresh(newVulnerabilityData)
+	e.recordSnapshot()
 	e.vulnerabilityData = newVulnerabilityData
 	e.lastCollectionTime = time.Now()
+	hooks := e.collectionHooks
 	e.mutex.Unlock()
 
+	for _, hook := range hooks {
+		hook(ctx, newVulnerabilityData)
+	}
+
 	duration := time.Since(startTime)
+	e.recordCollectionDuration(duration)
 	logger.WithFields(logrus.Fields{
 		"duration":                duration,
 		"images_processed":        len(newVulnerabilityData),
+		"registries":              len(results),
+		"stale_images":            staleImages,
 		"total_images_discovered": len(images),
 	}).Info("Vulnerability data collection completed")
 
 	return nil
 }
 
+// discoverAndCollect discovers every image before collecting the registries,
+// returning the discovered images and each registry's result
+func (e *Engine) discoverAndCollect(ctx context.Context, logger *logrus.Entry) ([]types.ImageInfo, []registryResult, error) {
+	// Discover images using cloud provider
+	images, err := e.cloudProvider.DiscoverImages(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Equivalent spellings of an image share one entry in the data and cache
+	images = normalizeImages(images)
+
+	logger.WithField("image_count", len(images)).Info("Discovered images")
+	e.recordDiscovery(len(images), logger)
+
+	// Registries are collected concurrently, so a failing or slow registry
+	// doesn't hold up the others
+	groups := groupByRegistry(images)
+	collectors := make([]*registryCollector, len(groups))
+	filtered := make([][]types.ImageInfo, len(groups))
+	var wg sync.WaitGroup
+	for i, group := range groups {
+		collectors[i] = e.newRegistryCollector(ctx, group.registry, logger.WithField("registry", group.registry))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			filtered[i] = collectors[i].filter(group.images)
+		}()
+	}
+	wg.Wait()
+
+	// The limit picks from the images the source covers, so filtered images
+	// don't take the place of ones that would be fetched
+	if limit := e.config.MaxImagesPerCycle; limit > 0 {
+		var selected []types.ImageInfo
+		for _, images := range filtered {
+			selected = append(selected, images...)
+		}
+		if len(selected) > limit {
+			logger.WithFields(logrus.Fields{
+				"max_images_per_cycle": limit,
+				"skipped_image_count":  len(selected) - limit,
+			}).Warn("Limiting images processed this cycle")
+
+			kept := make(map[string]bool, limit)
+			for _, img := range limitImages(selected, limit) {
+				kept[img.URI] = true
+			}
+			for i, images := range filtered {
+				filtered[i] = slices.DeleteFunc(images, func(img types.ImageInfo) bool { return !kept[img.URI] })
+			}
+		}
+	}
+
+	for i, collector := range collectors {
+		collector.enqueue(filtered[i])
+	}
+	results := make([]registryResult, len(groups))
+	for i, collector := range collectors {
+		results[i] = collector.finish()
+	}
+
+	return images, results, nil
+}
+
+// AddCollectionHook registers a hook run after every successful collection,
+// in registration order
+func (e *Engine) AddCollectionHook(hook CollectionHook) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.collectionHooks = append(e.collectionHooks, hook)
+}
+
+// limitImages returns the first limit images by URI, so the same subset is
+// processed every cycle regardless of discovery order
+func limitImages(images []types.ImageInfo, limit int) []types.ImageInfo {
+	sorted := slices.Clone(images)
+	slices.SortStableFunc(sorted, func(a, b types.ImageInfo) int {
+		return strings.Compare(a.URI, b.URI)
+	})
+	return sorted[:limit]
+}
+
+// prioritizeNewImages orders images that were not in the previous cycle's data
+// before known ones, keeping discovery order within each group, and returns how
+// many are new
+func (e *Engine) prioritizeNewImages(images []types.ImageInfo) ([]types.ImageInfo, int) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	queue := make([]types.ImageInfo, 0, len(images))
+	var known []types.ImageInfo
+	for _, img := range images {
+		if _, seen := e.vulnerabilityData[img.URI]; seen {
+			known = append(known, img)
+		} else {
+			queue = append(queue, img)
+		}
+	}
+	newImages := len(queue)
+
+	return append(queue, known...), newImages
+}
+
+// annotateFindings stamps each finding with when its CVE was first observed on
+// the image and marks accepted CVEs, removing them from the severity counts.
+// Results are copied since cached vulnerabilities are shared across cycles.
+// First-seen history is kept for images whose fetch failed this cycle and
+// dropped for images no longer discovered. Must be called with e.mutex held.
+func (e *Engine) annotateFindings(images []types.ImageInfo, data map[string]*types.ImageVulnerabilityData) {
+	now := e.now()
+	seen := make(map[findingKey]time.Time)
+
+	for uri, vulnData := range data {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+
+		vulnCopy := *vulnData.ImageVulnerability
+		vulnCopy.Findings = make([]types.VulnerabilityFinding, len(vulnData.Findings))
+		for i, finding := range vulnData.Findings {
+			key := findingKey{imageURI: uri, cve: finding.Name}
+			firstSeen, exists := seen[key]
+			if !exists {
+				if firstSeen, exists = e.firstSeen[key]; !exists {
+					firstSeen = now
+				}
+				seen[key] = firstSeen
+			}
+			finding.FirstSeen = &firstSeen
+			finding.Accepted = e.acceptedCVEs.IsAccepted(finding.Name, uri, vulnCopy.Repository)
+			vulnCopy.Findings[i] = finding
+		}
+		excludeAccepted(&vulnCopy)
+		vulnData.ImageVulnerability = &vulnCopy
+	}
+
+	// Keep history for discovered images that failed to fetch this cycle
+	failed := make(map[string]bool)
+	for _, img := range images {
+		if _, ok := data[img.URI]; !ok {
+			failed[img.URI] = true
+		}
+	}
+	if len(failed) > 0 {
+		for key, firstSeen := range e.firstSeen {
+			if failed[key.imageURI] {
+				seen[key] = firstSeen
+			}
+		}
+	}
+
+	e.firstSeen = seen
+}
+
+// mergeStale adds the previous data of discovered images whose fetch failed
+// this cycle, flagged as stale, and returns how many were added. The data was
+// annotated when it was collected. Must be called with e.mutex held.
+func (e *Engine) mergeStale(images []types.ImageInfo, data map[string]*types.ImageVulnerabilityData) int {
+	stale := 0
+	for _, img := range images {
+		if _, ok := data[img.URI]; ok {
+			continue
+		}
+		previous, ok := e.vulnerabilityData[img.URI]
+		if !ok || previous.ImageVulnerability == nil || e.expired(img.URI, e.now()) {
+			continue
+		}
+		data[img.URI] = &types.ImageVulnerabilityData{
+			ImageVulnerability: previous.ImageVulnerability,
+			ImageInfo:          img,
+			Stale:              true,
+		}
+		stale++
+	}
+	return stale
+}
+
+// recordRefresh records when each image's data was last fetched successfully,
+// dropping images no longer in the data. Stale data keeps the time of its
+// original fetch. Must be called with e.mutex held.
+func (e *Engine) recordRefresh(data map[string]*types.ImageVulnerabilityData) {
+	now := e.now()
+	refreshedAt := make(map[string]time.Time, len(data))
+	for uri, vulnData := range data {
+		if previous, ok := e.refreshedAt[uri]; ok && vulnData.Stale {
+			refreshedAt[uri] = previous
+			continue
+		}
+		refreshedAt[uri] = now
+	}
+	e.refreshedAt = refreshedAt
+}
+
+// expired reports whether an image's data is older than MaxDataAge. Must be
+// called with e.mutex held.
+func (e *Engine) expired(uri string, now time.Time) bool {
+	if e.config.MaxDataAge <= 0 {
+		return false
+	}
+	refreshedAt, ok := e.refreshedAt[uri]
+	return ok && now.Sub(refreshedAt) > e.config.MaxDataAge
+}
+
+// pruneExpired replaces the data with a copy without images past MaxDataAge
+// and returns how many were dropped. The data is copied since snapshots and
+// collection hooks share it. Must be called with e.mutex held.
+func (e *Engine) pruneExpired() int {
+	now := e.now()
+	var expired []string
+	for uri := range e.vulnerabilityData {
+		if e.expired(uri, now) {
+			expired = append(expired, uri)
+		}
+	}
+	if len(expired) == 0 {
+		return 0
+	}
+
+	data := maps.Clone(e.vulnerabilityData)
+	for _, uri := range expired {
+		delete(data, uri)
+		delete(e.refreshedAt, uri)
+	}
+	e.vulnerabilityData = data
+	return len(expired)
+}
+
+// shedFindings drops the findings of the images with the most findings until
+// at most MaxRetainedFindings remain, keeping their counts and flagging them
+// as FindingsDropped. Cached results are shed as well, so the findings don't
+// stay in memory. Returns how many images were shed. Must be called with
+// e.mutex held.
+func (e *Engine) shedFindings(data map[string]*types.ImageVulnerabilityData) int {
+	limit := e.config.MaxRetainedFindings
+	if limit <= 0 {
+		return 0
+	}
+
+	total := 0
+	uris := make([]string, 0, len(data))
+	for uri, vulnData := range data {
+		if vulnData.ImageVulnerability != nil && len(vulnData.Findings) > 0 {
+			total += len(vulnData.Findings)
+			uris = append(uris, uri)
+		}
+	}
+	if total <= limit {
+		return 0
+	}
+
+	sort.Slice(uris, func(i, j int) bool {
+		a, b := len(data[uris[i]].Findings), len(data[uris[j]].Findings)
+		if a != b {
+			return a > b
+		}
+		return uris[i] < uris[j]
+	})
+
+	shed := 0
+	for _, uri := range uris {
+		if total <= limit {
+			break
+		}
+		vulnData := *data[uri]
+		total -= len(vulnData.Findings)
+
+		vulnCopy := *vulnData.ImageVulnerability
+		vulnCopy.Findings = nil
+		vulnCopy.FindingsDropped = true
+		vulnData.ImageVulnerability = &vulnCopy
+		data[uri] = &vulnData
+
+		e.cache.DropFindings(uri)
+		shed++
+	}
+	return shed
+}
+
+// excludeAccepted removes accepted findings from the active severity counts
+func excludeAccepted(vuln *types.ImageVulnerability) {
+	var counts map[string]int
+	for _, finding := range vuln.Findings {
+		if !finding.Accepted {
+			continue
+		}
+		if counts == nil {
+			counts = maps.Clone(vuln.Vulnerabilities)
+		}
+		if counts[finding.Severity] > 0 {
+			counts[finding.Severity]--
+			vuln.TotalCount--
+		}
+	}
+	if counts != nil {
+		vuln.Vulnerabilities = counts
+	}
+}
+
+// SetAcceptedCVEs sets the risk acceptance list applied from the next collection cycle
+func (e *Engine) SetAcceptedCVEs(list *acceptance.List) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.acceptedCVEs = list
+}
+
+// recordTick records when a collection cycle was triggered
+func (e *Engine) recordTick(tick time.Time) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.lastTickTime = tick
+}
+
+// recordDiscovery tracks consecutive cycles without discovered images and
+// warns once they reach emptyDiscoveryThreshold, since an empty result is
+// indistinguishable from broken discovery (e.g. missing RBAC permissions)
+func (e *Engine) recordDiscovery(imageCount int, logger *logrus.Entry) {
+	e.mutex.Lock()
+	if imageCount > 0 {
+		e.emptyDiscoveries = 0
+	} else {
+		e.emptyDiscoveries++
+	}
+	emptyDiscoveries := e.emptyDiscoveries
+	e.mutex.Unlock()
+
+	if emptyDiscoveries >= emptyDiscoveryThreshold {
+		logger.WithField("consecutive_empty_discoveries", emptyDiscoveries).
+			Warn("No images discovered for several consecutive cycles; check the cloud provider configuration and permissions")
+	}
+}
+
+// recordCollectionDuration folds a completed cycle's duration into the EMA,
+// seeding it with the first observation
+func (e *Engine) recordCollectionDuration(duration time.Duration) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.collectionDurationEMA == 0 {
+		e.collectionDurationEMA = duration
+		return
+	}
+	e.collectionDurationEMA = time.Duration(collectionDurationEMAAlpha*float64(duration) +
+		(1-collectionDurationEMAAlpha)*float64(e.collectionDurationEMA))
+}
+
 func (e *Engine) getImageVulnerability(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
 	// Try cache first
 	if cachedVuln := e.cache.Get(imageURI); cachedVuln != nil {
 		return cachedVuln, nil
 	}
 
-	// Fetch from vulnerability source
+	// Concurrent requests for the same image share a single source call
+	result, err, _ := e.inflight.Do(imageURI, func() (interface{}, error) {
+		// A flight that just finished may have populated the cache
+		if cachedVuln := e.cache.Get(imageURI); cachedVuln != nil {
+			return cachedVuln, nil
+		}
+
+		vuln, err := e.fetchImageVulnerability(ctx, imageURI)
+		if err != nil {
+			return nil, err
+		}
+
+		// Cache the result
+		e.cache.Set(imageURI, vuln)
+
+		return vuln, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*types.ImageVulnerability), nil
+}
+
+// fetchImageVulnerability calls the vulnerability source, honouring the
+// registry's adaptive concurrency, the concurrency cap and the rate limit
+func (e *Engine) fetchImageVulnerability(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	// Only calls reaching the source adjust the registry's adaptive limit;
+	// cache hits and joined fetches say nothing about its throttling
+	var err error
+	if concurrency := e.registryConcurrency(imageRegistry(imageURI)); concurrency != nil {
+		epoch := concurrency.acquire()
+		defer func() { concurrency.release(epoch, e.isThrottled(err)) }()
+	}
+
+	// Registries are collected concurrently, so the cap is shared by all of them
+	select {
+	case e.fetchSlots <- struct{}{}:
+		defer func() { <-e.fetchSlots }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// Wait for a rate limit token before calling the source
+	if e.limiter != nil {
+		if err := e.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
 	vuln, err := e.vulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
 	if err != nil {
 		return nil, err
 	}
 
-	// Cache the result
-	e.cache.Set(imageURI, vuln)
+	return e.filterSeverities(vuln), nil
+}
+
+// filterSeverities drops findings and severity counts outside the allowed
+// severities. The source result is copied rather than modified.
+func (e *Engine) filterSeverities(vuln *types.ImageVulnerability) *types.ImageVulnerability {
+	if e.allowedSeverities == nil || vuln == nil {
+		return vuln
+	}
+
+	filtered := *vuln
+	filtered.Vulnerabilities = make(map[string]int, len(vuln.Vulnerabilities))
+	for severity, count := range vuln.Vulnerabilities {
+		if e.allowedSeverities[strings.ToUpper(severity)] {
+			filtered.Vulnerabilities[severity] = count
+		} else {
+			filtered.TotalCount -= count
+		}
+	}
 
-	return vuln, nil
+	filtered.Findings = nil
+	for _, finding := range vuln.Findings {
+		if e.allowedSeverities[strings.ToUpper(finding.Severity)] {
+			filtered.Findings = append(filtered.Findings, finding)
+		}
+	}
+
+	return &filtered
+}
+
+// refreshImageVulnerability is the cache's refresh-ahead callback; it runs
+// outside any collection cycle, so it gets its own timeout. It shares the
+// source call with a collection fetching the same image; the cache stores the
+// result only if the entry wasn't replaced or deleted meanwhile.
+func (e *Engine) refreshImageVulnerability(imageURI string) (*types.ImageVulnerability, error) {
+	result, err, _ := e.inflight.Do(imageURI, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), refreshAheadTimeout)
+		defer cancel()
+
+		return e.fetchImageVulnerability(ctx, imageURI)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result.(*types.ImageVulnerability), nil
 }
 
 // GetVulnerabilityData returns current vulnerability data and collection time
@@ -178,11 +1088,119 @@ func (e *Engine) GetVulnerabilityData() (map[string]*types.ImageVulnerabilityDat
 	e.mutex.RLock()
 	defer e.mutex.RUnlock()
 
-	// Return a copy to prevent race conditions
+	return e.currentData(), e.lastCollectionTime
+}
+
+// currentData returns a copy of the current data, to prevent race conditions,
+// without data that passed MaxDataAge since the last collection pruned it.
+// Must be called with e.mutex held.
+func (e *Engine) currentData() map[string]*types.ImageVulnerabilityData {
+	now := e.now()
 	data := make(map[string]*types.ImageVulnerabilityData)
 	for k, v := range e.vulnerabilityData {
+		if e.expired(k, now) {
+			continue
+		}
 		data[k] = v
 	}
+	return data
+}
+
+// recordSnapshot keeps the current data as the most recent previous snapshot
+// before it is replaced. Must be called with e.mutex held.
+func (e *Engine) recordSnapshot() {
+	if e.config.SnapshotHistory <= 0 || e.lastCollectionTime.IsZero() {
+		return
+	}
+
+	e.snapshots = append([]snapshot{{data: e.vulnerabilityData, collectedAt: e.lastCollectionTime}}, e.snapshots...)
+	if len(e.snapshots) > e.config.SnapshotHistory {
+		e.snapshots = e.snapshots[:e.config.SnapshotHistory]
+	}
+}
+
+// GetSnapshot returns the data of an earlier collection, where age 1 is the
+// previous collection. ok is false if that snapshot is not retained.
+func (e *Engine) GetSnapshot(age int) (data map[string]*types.ImageVulnerabilityData, collectedAt time.Time, ok bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if age < 1 || age > len(e.snapshots) {
+		return nil, time.Time{}, false
+	}
+
+	snap := e.snapshots[age-1]
+	return maps.Clone(snap.data), snap.collectedAt, true
+}
+
+// GetSnapshotAndCurrent returns the data of an earlier collection, like
+// GetSnapshot, together with the current data, read at once so that a
+// collection finishing in between can't mix the two
+func (e *Engine) GetSnapshotAndCurrent(age int) (snapshot map[string]*types.ImageVulnerabilityData, snapshotAt time.Time, current map[string]*types.ImageVulnerabilityData, currentAt time.Time, ok bool) {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if age < 1 || age > len(e.snapshots) {
+		return nil, time.Time{}, nil, time.Time{}, false
+	}
+
+	snap := e.snapshots[age-1]
+	return maps.Clone(snap.data), snap.collectedAt, e.currentData(), e.lastCollectionTime, true
+}
+
+// GetCollectionDurationEMA returns the exponential moving average of collection
+// cycle durations, or zero before the first cycle completes
+func (e *Engine) GetCollectionDurationEMA() time.Duration {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.collectionDurationEMA
+}
+
+// IsDiscoveryEmpty reports whether the last emptyDiscoveryThreshold or more
+// consecutive cycles discovered no images
+func (e *Engine) IsDiscoveryEmpty() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.emptyDiscoveries >= emptyDiscoveryThreshold
+}
+
+// GetRegistryScanType returns the registry scan type read at startup, or an
+// empty string when it was not checked or the check failed
+func (e *Engine) GetRegistryScanType() string {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return e.registryScanType
+}
+
+// CallerIdentity returns the identity the vulnerability source authenticates
+// as, or nil when the source does not expose one
+func (e *Engine) CallerIdentity(ctx context.Context) (*types.CallerIdentity, error) {
+	source, ok := e.vulnerabilitySource.(IdentitySource)
+	if !ok {
+		return nil, nil
+	}
+	return source.CallerIdentity(ctx)
+}
+
+// InvalidateImage drops an image's cached vulnerability data so the next
+// collection fetches it again. It reports whether the image was cached.
+func (e *Engine) InvalidateImage(imageURI string) bool {
+	return e.cache.Delete(normalizeImageURI(imageURI))
+}
+
+// GetScrapeInterval returns the configured interval between collection cycles
+func (e *Engine) GetScrapeInterval() time.Duration {
+	return e.config.ScrapeInterval
+}
+
+// GetLastTickTime returns when the last collection cycle was triggered, or the
+// zero time before the first cycle starts
+func (e *Engine) GetLastTickTime() time.Time {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
 
-	return data, e.lastCollectionTime
+	return e.lastTickTime
 }
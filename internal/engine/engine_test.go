@@ -6,11 +6,19 @@ package engine
 import (
 	"context"
 	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/acceptance"
+	"github.com/jfeddern/VulnRelay/internal/cache"
 	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
+	"golang.org/x/time/rate"
 )
 
 // Mock implementations for testing
@@ -304,6 +312,144 @@ func TestEngineCollectVulnerabilitiesWithErrors(t *testing.T) {
 	}
 }
 
+func TestEngineKeepStaleOnFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageURI := "test-image:latest"
+
+	tests := []struct {
+		name          string
+		keepStale     bool
+		expectedStale bool
+	}{
+		{"failed image is dropped by default", false, false},
+		{"failed image keeps stale data", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudProvider := &MockCloudProvider{
+				name:   "test-cloud",
+				images: []types.ImageInfo{{URI: imageURI, Namespace: "default", Workload: "test", WorkloadType: "Deployment"}},
+			}
+			source := &MockVulnerabilitySource{
+				name:         "test-vuln",
+				vulns:        make(map[string]*types.ImageVulnerability),
+				errorMessage: "vulnerability source error",
+			}
+			engine := NewEngine(cloudProvider, source, &Config{ScrapeInterval: 5 * time.Minute, KeepStaleOnFailure: tt.keepStale}, logger)
+			ctx := context.Background()
+
+			if err := engine.collectVulnerabilities(ctx); err != nil {
+				t.Fatalf("collectVulnerabilities() failed: %v", err)
+			}
+
+			// The next fetch misses the cache and fails
+			engine.InvalidateImage(imageURI)
+			source.shouldError = true
+			if err := engine.collectVulnerabilities(ctx); err != nil {
+				t.Fatalf("collectVulnerabilities() failed: %v", err)
+			}
+
+			data, _ := engine.GetVulnerabilityData()
+			vulnData, ok := data[imageURI]
+			if ok != tt.expectedStale {
+				t.Fatalf("Expected image present = %v, got %v", tt.expectedStale, ok)
+			}
+			if !tt.expectedStale {
+				return
+			}
+			if !vulnData.Stale || vulnData.ImageVulnerability == nil || vulnData.Workload != "test" {
+				t.Errorf("Expected the previous data flagged as stale, got %+v", vulnData)
+			}
+
+			// A successful fetch replaces the stale data
+			source.shouldError = false
+			if err := engine.collectVulnerabilities(ctx); err != nil {
+				t.Fatalf("collectVulnerabilities() failed: %v", err)
+			}
+			data, _ = engine.GetVulnerabilityData()
+			if data[imageURI] == nil || data[imageURI].Stale {
+				t.Errorf("Expected fresh data after a successful fetch, got %+v", data[imageURI])
+			}
+		})
+	}
+}
+
+func TestEngineMaxDataAge(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const imageURI = "test-image:latest"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		fail func(cloudProvider *MockCloudProvider, source *MockVulnerabilitySource)
+	}{
+		{
+			name: "discovery keeps failing",
+			fail: func(cloudProvider *MockCloudProvider, source *MockVulnerabilitySource) {
+				cloudProvider.shouldError = true
+				cloudProvider.errorMessage = "discovery error"
+			},
+		},
+		{
+			name: "stale data is kept",
+			fail: func(cloudProvider *MockCloudProvider, source *MockVulnerabilitySource) {
+				source.shouldError = true
+				source.errorMessage = "vulnerability source error"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudProvider := &MockCloudProvider{images: []types.ImageInfo{{URI: imageURI}}}
+			source := &MockVulnerabilitySource{vulns: make(map[string]*types.ImageVulnerability)}
+			engine := NewEngine(cloudProvider, source, &Config{KeepStaleOnFailure: true, MaxDataAge: time.Hour}, logger)
+			ctx := context.Background()
+
+			engine.now = func() time.Time { return start }
+			if err := engine.collectVulnerabilities(ctx); err != nil {
+				t.Fatalf("collectVulnerabilities() failed: %v", err)
+			}
+
+			// Later cycles fail, so the image's data is no longer refreshed
+			tt.fail(cloudProvider, source)
+			collectAt := func(now time.Time) map[string]*types.ImageVulnerabilityData {
+				engine.now = func() time.Time { return now }
+				engine.InvalidateImage(imageURI)
+				engine.collectVulnerabilities(ctx)
+				data, _ := engine.GetVulnerabilityData()
+				return data
+			}
+
+			if data := collectAt(start.Add(30 * time.Minute)); data[imageURI] == nil {
+				t.Fatal("Expected the image to be kept within the max data age")
+			}
+			if data := collectAt(start.Add(90 * time.Minute)); data[imageURI] != nil {
+				t.Errorf("Expected the image to be pruned past the max data age, got %+v", data[imageURI])
+			}
+			engine.mutex.RLock()
+			_, retained := engine.vulnerabilityData[imageURI]
+			engine.mutex.RUnlock()
+			if retained {
+				t.Error("Expected the pruned image to be removed from the stored data")
+			}
+
+			// A successful fetch brings the image back
+			cloudProvider.shouldError = false
+			source.shouldError = false
+			data := collectAt(start.Add(2 * time.Hour))
+			if data[imageURI] == nil || data[imageURI].Stale {
+				t.Errorf("Expected fresh data after a successful fetch, got %+v", data[imageURI])
+			}
+		})
+	}
+}
+
 func TestEngineGetImageVulnerabilityWithCache(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -347,6 +493,1019 @@ func TestEngineGetImageVulnerabilityWithCache(t *testing.T) {
 	}
 }
 
+func TestEngineInvalidateImage(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &recordingVulnerabilitySource{
+		MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln", vulns: make(map[string]*types.ImageVulnerability)},
+	}
+	engine := NewEngine(&MockCloudProvider{name: "test-cloud"}, source, &Config{ScrapeInterval: 5 * time.Minute}, logger)
+
+	ctx := context.Background()
+	imageURI := "test-image:latest"
+
+	if engine.InvalidateImage(imageURI) {
+		t.Error("Expected an uncached image to be reported as not cached")
+	}
+
+	// Cached after the first fetch, fetched again once invalidated
+	for i := 0; i < 2; i++ {
+		if _, err := engine.getImageVulnerability(ctx, imageURI); err != nil {
+			t.Fatalf("getImageVulnerability() failed: %v", err)
+		}
+	}
+	if !engine.InvalidateImage(imageURI) {
+		t.Error("Expected the cached image to be invalidated")
+	}
+	if _, err := engine.getImageVulnerability(ctx, imageURI); err != nil {
+		t.Fatalf("getImageVulnerability() failed: %v", err)
+	}
+
+	if len(source.calls) != 2 {
+		t.Errorf("Expected 2 source calls, got %d", len(source.calls))
+	}
+}
+
+// recordingVulnerabilitySource records the time of every call it receives
+type recordingVulnerabilitySource struct {
+	MockVulnerabilitySource
+	mu    sync.Mutex
+	calls []time.Time
+}
+
+func (r *recordingVulnerabilitySource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	r.mu.Lock()
+	r.calls = append(r.calls, time.Now())
+	r.mu.Unlock()
+	return r.MockVulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
+}
+
+func TestEngineCollectionHooks(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	images := []types.ImageInfo{
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1", Namespace: "production"},
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v2", Namespace: "staging"},
+	}
+	provider := &MockCloudProvider{name: "test-cloud", images: images}
+	source := &MockVulnerabilitySource{name: "test-vuln", vulns: make(map[string]*types.ImageVulnerability)}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+	var order []string
+	var received map[string]*types.ImageVulnerabilityData
+	engine.AddCollectionHook(func(ctx context.Context, data map[string]*types.ImageVulnerabilityData) {
+		order = append(order, "first")
+		received = data
+	})
+	engine.AddCollectionHook(func(ctx context.Context, data map[string]*types.ImageVulnerabilityData) {
+		order = append(order, "second")
+	})
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Expected hooks to run once in registration order, got %v", order)
+	}
+	if len(received) != len(images) {
+		t.Fatalf("Expected hook to receive %d images, got %d", len(images), len(received))
+	}
+	// Hooks see annotated data, e.g. first-seen timestamps
+	for uri, vulnData := range received {
+		for _, finding := range vulnData.Findings {
+			if finding.FirstSeen == nil {
+				t.Errorf("Expected annotated findings for %s", uri)
+			}
+		}
+	}
+
+	// A failed collection doesn't run hooks
+	provider.shouldError = true
+	provider.errorMessage = "discovery failed"
+	if err := engine.collectVulnerabilities(context.Background()); err == nil {
+		t.Fatal("Expected collection to fail")
+	}
+	if len(order) != 2 {
+		t.Errorf("Expected no hook calls for a failed collection, got %v", order)
+	}
+}
+
+// filteringVulnerabilitySource implements ImageFilter by keeping listed images
+type filteringVulnerabilitySource struct {
+	MockVulnerabilitySource
+	allowed map[string]bool
+	err     error
+}
+
+func (f *filteringVulnerabilitySource) ResolveImageFilter(ctx context.Context, registry string) (func([]types.ImageInfo) []types.ImageInfo, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return allowedImages(f.allowed), nil
+}
+
+// allowedImages returns a filter keeping the images with allowed URIs
+func allowedImages(allowed map[string]bool) func([]types.ImageInfo) []types.ImageInfo {
+	return func(images []types.ImageInfo) []types.ImageInfo {
+		var filtered []types.ImageInfo
+		for _, image := range images {
+			if allowed[image.URI] {
+				filtered = append(filtered, image)
+			}
+		}
+		return filtered
+	}
+}
+
+func TestEngineImageFilter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	allowedURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/team-a/api:v1"
+	provider := &MockCloudProvider{name: "test-cloud", images: []types.ImageInfo{
+		{URI: allowedURI},
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/team-b/api:v1"},
+	}}
+	source := &filteringVulnerabilitySource{
+		MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln", vulns: make(map[string]*types.ImageVulnerability)},
+		allowed:                 map[string]bool{allowedURI: true},
+	}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	data, _ := engine.GetVulnerabilityData()
+	if len(data) != 1 || data[allowedURI] == nil {
+		t.Errorf("Expected only the allowed image, got %d images", len(data))
+	}
+
+	// A filter error fails the cycle and keeps the previous data
+	source.err = errors.New("describe repositories failed")
+	if err := engine.collectVulnerabilities(context.Background()); err == nil {
+		t.Fatal("Expected collection to fail when filtering fails")
+	}
+	if data, _ := engine.GetVulnerabilityData(); len(data) != 1 {
+		t.Errorf("Expected previous data to be kept, got %d images", len(data))
+	}
+}
+
+func TestEngineCacheRefreshAhead(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// A window longer than the default TTL puts every cached entry inside it
+	config := &Config{
+		Mode:              "cluster",
+		ScrapeInterval:    5 * time.Minute,
+		CacheRefreshAhead: time.Hour,
+	}
+
+	source := &recordingVulnerabilitySource{
+		MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln", vulns: make(map[string]*types.ImageVulnerability)},
+	}
+	engine := NewEngine(&MockCloudProvider{name: "test-cloud"}, source, config, logger)
+
+	ctx := context.Background()
+	imageURI := "test-image:latest"
+
+	if _, err := engine.getImageVulnerability(ctx, imageURI); err != nil {
+		t.Fatalf("First call failed: %v", err)
+	}
+	// The cache hit is served immediately and triggers a background refresh
+	if vuln, err := engine.getImageVulnerability(ctx, imageURI); err != nil || vuln == nil {
+		t.Fatalf("Cached call failed: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		source.mu.Lock()
+		calls := len(source.calls)
+		source.mu.Unlock()
+		if calls == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected refresh-ahead to call the source again, got %d calls", calls)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestEngineSourceRateLimit(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// One token per hour: the burst of 1 allows the first call, and waiting
+	// for the next token would pass the cycle's deadline, which the limiter
+	// refuses right away instead of sleeping, so the test doesn't depend on
+	// timing
+	config := &Config{
+		Mode:                    "cluster",
+		ScrapeInterval:          5 * time.Minute,
+		SourceRequestsPerSecond: 1.0 / 3600,
+	}
+
+	var images []types.ImageInfo
+	for i := 0; i < 10; i++ {
+		images = append(images, types.ImageInfo{
+			URI:       fmt.Sprintf("123456789012.dkr.ecr.us-east-1.amazonaws.com/app-%d:v1", i),
+			Namespace: "default",
+		})
+	}
+
+	source := &recordingVulnerabilitySource{MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln"}}
+	engine := NewEngine(&MockCloudProvider{name: "test-cloud", images: images}, source, config, logger)
+	if limit, burst := engine.limiter.Limit(), engine.limiter.Burst(); limit != rate.Limit(config.SourceRequestsPerSecond) || burst != 1 {
+		t.Fatalf("Expected a limit of %v with a burst of 1, got %v with %d", config.SourceRequestsPerSecond, limit, burst)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	// The fetches refused by the limiter fail; the cycle's outcome isn't checked
+	_ = engine.collectVulnerabilities(ctx)
+
+	if len(source.calls) != 1 {
+		t.Errorf("Expected 1 source call within the rate limit, got %d", len(source.calls))
+	}
+}
+
+// uriRecordingVulnerabilitySource records the image URI of every call in order
+type uriRecordingVulnerabilitySource struct {
+	MockVulnerabilitySource
+	mu   sync.Mutex
+	uris []string
+}
+
+func (u *uriRecordingVulnerabilitySource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	u.mu.Lock()
+	u.uris = append(u.uris, imageURI)
+	u.mu.Unlock()
+	return u.MockVulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
+}
+
+func TestEngineNewImageFetchedInSameCycle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var images []types.ImageInfo
+	for i := 0; i < 25; i++ {
+		images = append(images, types.ImageInfo{URI: fmt.Sprintf("123456789012.dkr.ecr.us-east-1.amazonaws.com/app-%d:v1", i)})
+	}
+
+	provider := &MockCloudProvider{name: "test-cloud", images: images}
+	source := &uriRecordingVulnerabilitySource{MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln"}}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: 5 * time.Minute}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	// A new deployment appears; known images are served from the cache
+	newImage := types.ImageInfo{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/new-app:v1"}
+	provider.images = append(append([]types.ImageInfo{}, images...), newImage)
+	source.uris = nil
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	if len(source.uris) != 1 || source.uris[0] != newImage.URI {
+		t.Errorf("Expected only the new image to be fetched, got %v", source.uris)
+	}
+	data, _ := engine.GetVulnerabilityData()
+	if _, exists := data[newImage.URI]; !exists {
+		t.Error("Expected the new image in the data of the cycle it was first seen")
+	}
+}
+
+func TestEngineEquivalentImageURIs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	canonical := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest"
+	provider := &MockCloudProvider{name: "test-cloud", images: []types.ImageInfo{
+		{URI: canonical},
+		{URI: canonical + "/"},
+		{URI: "123456789012.DKR.ECR.US-EAST-1.AMAZONAWS.COM/app:latest"},
+	}}
+	source := &uriRecordingVulnerabilitySource{MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln"}}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: 5 * time.Minute}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	if len(source.uris) != 1 || source.uris[0] != canonical {
+		t.Errorf("Expected a single fetch of %s, got %v", canonical, source.uris)
+	}
+	data, _ := engine.GetVulnerabilityData()
+	if len(data) != 1 || data[canonical] == nil {
+		t.Errorf("Expected a single entry keyed by %s, got %d entries", canonical, len(data))
+	}
+
+	// Invalidation accepts any equivalent spelling
+	if !engine.InvalidateImage(canonical + "/") {
+		t.Error("Expected an equivalent URI to invalidate the cached entry")
+	}
+}
+
+func TestEngineMaxRetainedFindings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// withFindings returns a result with count HIGH findings
+	withFindings := func(uri string, count int) *types.ImageVulnerability {
+		vuln := &types.ImageVulnerability{ImageURI: uri, Vulnerabilities: map[string]int{"HIGH": count}, TotalCount: count}
+		for i := 0; i < count; i++ {
+			vuln.Findings = append(vuln.Findings, types.VulnerabilityFinding{Name: fmt.Sprintf("CVE-2024-%04d", i), Severity: "HIGH"})
+		}
+		return vuln
+	}
+
+	large := "123456789012.dkr.ecr.us-east-1.amazonaws.com/large:v1"
+	medium := "123456789012.dkr.ecr.us-east-1.amazonaws.com/medium:v1"
+	small := "123456789012.dkr.ecr.us-east-1.amazonaws.com/small:v1"
+	provider := &MockCloudProvider{name: "test-cloud", images: []types.ImageInfo{{URI: large}, {URI: medium}, {URI: small}}}
+	source := &MockVulnerabilitySource{name: "test-vuln", vulns: map[string]*types.ImageVulnerability{
+		large:  withFindings(large, 5),
+		medium: withFindings(medium, 3),
+		small:  withFindings(small, 1),
+	}}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: 5 * time.Minute, MaxRetainedFindings: 4}, logger)
+
+	for cycle := 1; cycle <= 2; cycle++ {
+		if err := engine.collectVulnerabilities(context.Background()); err != nil {
+			t.Fatalf("collectVulnerabilities() failed: %v", err)
+		}
+
+		// Shedding the largest image brings the 9 findings down to 4
+		data, _ := engine.GetVulnerabilityData()
+		if shed := data[large]; len(shed.Findings) != 0 || !shed.FindingsDropped {
+			t.Errorf("Cycle %d: expected the largest image's findings to be dropped, got %d findings", cycle, len(shed.Findings))
+		}
+		if data[large].TotalCount != 5 || data[large].Vulnerabilities["HIGH"] != 5 {
+			t.Errorf("Cycle %d: expected counts to stay accurate, got %+v", cycle, data[large].Vulnerabilities)
+		}
+		for uri, expected := range map[string]int{medium: 3, small: 1} {
+			if len(data[uri].Findings) != expected || data[uri].FindingsDropped {
+				t.Errorf("Cycle %d: expected %s to keep its %d findings, got %d", cycle, uri, expected, len(data[uri].Findings))
+			}
+		}
+	}
+
+	if cached := engine.cache.Get(large); cached == nil || cached.Findings != nil {
+		t.Error("Expected the cached result to be shed as well")
+	}
+	if len(source.vulns[large].Findings) != 5 {
+		t.Error("Expected the source result to be left unchanged")
+	}
+}
+
+func TestEngineMaxImagesPerCycle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Discovery order differs from URI order
+	var images []types.ImageInfo
+	for _, name := range []string{"web", "api", "worker", "cron", "batch"} {
+		images = append(images, types.ImageInfo{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + ":v1"})
+	}
+
+	provider := &MockCloudProvider{name: "test-cloud", images: images}
+	source := &uriRecordingVulnerabilitySource{MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln"}}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: 5 * time.Minute, MaxImagesPerCycle: 2}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	expected := []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/batch:v1",
+	}
+	fetched := append([]string{}, source.uris...)
+	sort.Strings(fetched)
+	if !reflect.DeepEqual(fetched, expected) {
+		t.Errorf("Expected only %v to be fetched, got %v", expected, fetched)
+	}
+	data, _ := engine.GetVulnerabilityData()
+	if len(data) != 2 {
+		t.Errorf("Expected 2 images in the data, got %d", len(data))
+	}
+	for _, uri := range expected {
+		if _, exists := data[uri]; !exists {
+			t.Errorf("Expected %s in the data", uri)
+		}
+	}
+}
+
+// filteringURIRecordingSource records fetched URIs and only covers allowed ones
+type filteringURIRecordingSource struct {
+	uriRecordingVulnerabilitySource
+	allowed map[string]bool
+}
+
+func (f *filteringURIRecordingSource) ResolveImageFilter(ctx context.Context, registry string) (func([]types.ImageInfo) []types.ImageInfo, error) {
+	return allowedImages(f.allowed), nil
+}
+
+func TestEngineMaxImagesPerCycleAfterFilter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const registry = "123456789012.dkr.ecr.us-east-1.amazonaws.com/"
+	var images []types.ImageInfo
+	for _, name := range []string{"web", "api", "worker", "cron", "batch"} {
+		images = append(images, types.ImageInfo{URI: registry + name + ":v1"})
+	}
+
+	// The first images by URI are outside the allowlist, so the limit picks
+	// from the remaining ones
+	provider := &MockCloudProvider{name: "test-cloud", images: images}
+	source := &filteringURIRecordingSource{
+		uriRecordingVulnerabilitySource: uriRecordingVulnerabilitySource{MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln"}},
+		allowed: map[string]bool{
+			registry + "cron:v1":   true,
+			registry + "web:v1":    true,
+			registry + "worker:v1": true,
+		},
+	}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: 5 * time.Minute, MaxImagesPerCycle: 2}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	expected := []string{registry + "cron:v1", registry + "web:v1"}
+	fetched := append([]string{}, source.uris...)
+	sort.Strings(fetched)
+	if !reflect.DeepEqual(fetched, expected) {
+		t.Errorf("Expected only %v to be fetched, got %v", expected, fetched)
+	}
+	if registries := engine.GetRegistryCollections(); len(registries) != 1 || registries[0].Collected != 2 || registries[0].Failed != 0 {
+		t.Errorf("Expected 2 collected images and no failures, got %+v", registries)
+	}
+}
+
+func TestEnginePrioritizeNewImages(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	engine := NewEngine(&MockCloudProvider{}, &MockVulnerabilitySource{}, &Config{}, logger)
+	engine.vulnerabilityData = map[string]*types.ImageVulnerabilityData{
+		"registry/known-a:v1": {},
+		"registry/known-b:v1": {},
+	}
+
+	queue, newImages := engine.prioritizeNewImages([]types.ImageInfo{
+		{URI: "registry/known-a:v1"},
+		{URI: "registry/new-a:v1"},
+		{URI: "registry/known-b:v1"},
+		{URI: "registry/new-b:v1"},
+	})
+
+	if newImages != 2 {
+		t.Errorf("Expected 2 new images, got %d", newImages)
+	}
+
+	var order []string
+	for _, img := range queue {
+		order = append(order, img.URI)
+	}
+	expected := []string{"registry/new-a:v1", "registry/new-b:v1", "registry/known-a:v1", "registry/known-b:v1"}
+	if fmt.Sprint(order) != fmt.Sprint(expected) {
+		t.Errorf("Expected queue %v, got %v", expected, order)
+	}
+}
+
+// blockingVulnerabilitySource counts calls and blocks each one until released
+type blockingVulnerabilitySource struct {
+	MockVulnerabilitySource
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (b *blockingVulnerabilitySource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	b.calls.Add(1)
+	<-b.release
+	return &types.ImageVulnerability{
+		ImageURI:        imageURI,
+		Vulnerabilities: map[string]int{"HIGH": 1},
+		TotalCount:      1,
+		ScanStatus:      "COMPLETE",
+	}, nil
+}
+
+func TestEngineGetImageVulnerabilitySingleflight(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &blockingVulnerabilitySource{release: make(chan struct{})}
+	engine := NewEngine(&MockCloudProvider{}, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+	const imageURI = "123456789012.dkr.ecr.us-east-1.amazonaws.com/shared:v1"
+	const callers = 50
+
+	var wg sync.WaitGroup
+	results := make([]*types.ImageVulnerability, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = engine.getImageVulnerability(context.Background(), imageURI)
+		}(i)
+	}
+
+	// Hold the first call open long enough for every caller to miss the cache
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	if calls := source.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 source call for concurrent identical requests, got %d", calls)
+	}
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("Caller %d failed: %v", i, errs[i])
+		}
+		if results[i] == nil || results[i].ImageURI != imageURI {
+			t.Errorf("Caller %d got unexpected result %+v", i, results[i])
+		}
+	}
+}
+
+func TestEngineRefreshAheadSharesCollectionFetch(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &blockingVulnerabilitySource{release: make(chan struct{})}
+	engine := NewEngine(&MockCloudProvider{}, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+	const imageURI = "123456789012.dkr.ecr.us-east-1.amazonaws.com/shared:v1"
+
+	// A refresh-ahead of an image a collection is fetching joins that fetch
+	var wg sync.WaitGroup
+	var refreshErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		engine.getImageVulnerability(context.Background(), imageURI)
+	}()
+	go func() {
+		defer wg.Done()
+		_, refreshErr = engine.refreshImageVulnerability(imageURI)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	close(source.release)
+	wg.Wait()
+
+	if refreshErr != nil {
+		t.Fatalf("refreshImageVulnerability() failed: %v", refreshErr)
+	}
+	if calls := source.calls.Load(); calls != 1 {
+		t.Errorf("Expected 1 source call for an overlapping refresh and collection, got %d", calls)
+	}
+}
+
+func TestEngineCollectionDurationEMA(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	engine := NewEngine(&MockCloudProvider{}, &MockVulnerabilitySource{}, &Config{ScrapeInterval: time.Minute}, logger)
+
+	if ema := engine.GetCollectionDurationEMA(); ema != 0 {
+		t.Errorf("Expected zero EMA before the first cycle, got %v", ema)
+	}
+
+	// The first observation seeds the EMA
+	engine.recordCollectionDuration(10 * time.Second)
+	if ema := engine.GetCollectionDurationEMA(); ema != 10*time.Second {
+		t.Errorf("Expected EMA seeded with 10s, got %v", ema)
+	}
+
+	// A single outlier moves the EMA only partially
+	engine.recordCollectionDuration(40 * time.Second)
+	if ema := engine.GetCollectionDurationEMA(); ema != 19*time.Second {
+		t.Errorf("Expected EMA of 19s after one 40s cycle, got %v", ema)
+	}
+
+	// A sustained slowdown converges toward the recent average
+	for i := 0; i < 15; i++ {
+		engine.recordCollectionDuration(30 * time.Second)
+	}
+	ema := engine.GetCollectionDurationEMA()
+	if diff := ema - 30*time.Second; diff < -100*time.Millisecond || diff > 100*time.Millisecond {
+		t.Errorf("Expected EMA to converge to 30s, got %v", ema)
+	}
+
+	// A real collection cycle also updates the EMA
+	cycleEngine := NewEngine(&MockCloudProvider{}, &MockVulnerabilitySource{}, &Config{ScrapeInterval: time.Minute}, logger)
+	if err := cycleEngine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	if cycleEngine.GetCollectionDurationEMA() <= 0 {
+		t.Error("Expected positive EMA after a collection cycle")
+	}
+}
+
+func TestEngineAllowedSeverities(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	sourceVuln := &types.ImageVulnerability{
+		ImageURI:        imageURI,
+		Vulnerabilities: map[string]int{"CRITICAL": 1, "HIGH": 2, "INFORMATIONAL": 3, "UNTRIAGED": 1},
+		TotalCount:      7,
+		ScanStatus:      "COMPLETE",
+		Findings: []types.VulnerabilityFinding{
+			{Name: "CVE-2024-0001", Severity: "CRITICAL"},
+			{Name: "CVE-2024-0002", Severity: "HIGH"},
+			{Name: "CVE-2024-0003", Severity: "INFORMATIONAL"},
+			{Name: "CVE-2024-0004", Severity: "UNTRIAGED"},
+		},
+	}
+
+	cloudProvider := &MockCloudProvider{name: "mock", images: []types.ImageInfo{{URI: imageURI, Namespace: "default"}}}
+	vulnSource := &MockVulnerabilitySource{name: "mock", vulns: map[string]*types.ImageVulnerability{imageURI: sourceVuln}}
+	config := &Config{
+		ScrapeInterval:    time.Minute,
+		AllowedSeverities: []string{"critical", "HIGH", "MEDIUM", "LOW"},
+	}
+	engine := NewEngine(cloudProvider, vulnSource, config, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	data, _ := engine.GetVulnerabilityData()
+	vuln := data[imageURI]
+	if vuln == nil {
+		t.Fatalf("Expected vulnerability data for %s", imageURI)
+	}
+
+	expectedCounts := map[string]int{"CRITICAL": 1, "HIGH": 2}
+	if len(vuln.Vulnerabilities) != len(expectedCounts) {
+		t.Errorf("Expected counts %v, got %v", expectedCounts, vuln.Vulnerabilities)
+	}
+	for severity, count := range expectedCounts {
+		if vuln.Vulnerabilities[severity] != count {
+			t.Errorf("Expected %d %s, got %d", count, severity, vuln.Vulnerabilities[severity])
+		}
+	}
+	if vuln.TotalCount != 3 {
+		t.Errorf("Expected total count 3, got %d", vuln.TotalCount)
+	}
+
+	var cves []string
+	for _, finding := range vuln.Findings {
+		cves = append(cves, finding.Name)
+	}
+	if len(cves) != 2 || cves[0] != "CVE-2024-0001" || cves[1] != "CVE-2024-0002" {
+		t.Errorf("Expected only CRITICAL and HIGH findings, got %v", cves)
+	}
+
+	// The cached entry is filtered as well, and the source result is untouched
+	if cached := engine.cache.Get(imageURI); cached == nil || cached.TotalCount != 3 {
+		t.Errorf("Expected filtered result in cache, got %+v", cached)
+	}
+	if sourceVuln.TotalCount != 7 || len(sourceVuln.Findings) != 4 {
+		t.Error("Expected the source result not to be modified")
+	}
+}
+
+func TestEngineSnapshots(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cycleImages := [][]types.ImageInfo{
+		{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1", Namespace: "default"}},
+		{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v2", Namespace: "default"}},
+		{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v3", Namespace: "default"}},
+	}
+
+	cloudProvider := &MockCloudProvider{name: "mock"}
+	vulnSource := &MockVulnerabilitySource{name: "mock"}
+	engine := NewEngine(cloudProvider, vulnSource, &Config{ScrapeInterval: time.Minute, SnapshotHistory: 1}, logger)
+	ctx := context.Background()
+
+	collect := func(images []types.ImageInfo) {
+		t.Helper()
+		cloudProvider.images = images
+		if err := engine.collectVulnerabilities(ctx); err != nil {
+			t.Fatalf("collectVulnerabilities() failed: %v", err)
+		}
+	}
+
+	collect(cycleImages[0])
+	if _, _, ok := engine.GetSnapshot(1); ok {
+		t.Error("Expected no previous snapshot after the first collection")
+	}
+	_, firstCollection := engine.GetVulnerabilityData()
+
+	collect(cycleImages[1])
+	previous, collectedAt, ok := engine.GetSnapshot(1)
+	if !ok {
+		t.Fatal("Expected a previous snapshot after the second collection")
+	}
+	if _, exists := previous[cycleImages[0][0].URI]; !exists || len(previous) != 1 {
+		t.Errorf("Expected the previous snapshot to hold the first collection, got %d images", len(previous))
+	}
+	if !collectedAt.Equal(firstCollection) {
+		t.Errorf("Expected snapshot time %v, got %v", firstCollection, collectedAt)
+	}
+
+	// Only one snapshot is retained
+	collect(cycleImages[2])
+	if previous, _, _ := engine.GetSnapshot(1); previous[cycleImages[1][0].URI] == nil {
+		t.Error("Expected the previous snapshot to hold the second collection")
+	}
+	if _, _, ok := engine.GetSnapshot(2); ok {
+		t.Error("Expected snapshots beyond the history size to be dropped")
+	}
+
+	// The snapshot and the current data are returned together
+	previous, _, current, currentAt, ok := engine.GetSnapshotAndCurrent(1)
+	if !ok || previous[cycleImages[1][0].URI] == nil || current[cycleImages[2][0].URI] == nil || len(current) != 1 {
+		t.Errorf("Expected the second and third collections, got %d and %d images", len(previous), len(current))
+	}
+	if _, lastCollection := engine.GetVulnerabilityData(); !currentAt.Equal(lastCollection) {
+		t.Errorf("Expected current collection time %v, got %v", lastCollection, currentAt)
+	}
+	if _, _, _, _, ok := engine.GetSnapshotAndCurrent(2); ok {
+		t.Error("Expected no data for a snapshot beyond the history size")
+	}
+}
+
+func TestEngineDiscoveryEmpty(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cloudProvider := &MockCloudProvider{name: "mock"}
+	vulnSource := &MockVulnerabilitySource{name: "mock"}
+	engine := NewEngine(cloudProvider, vulnSource, &Config{ScrapeInterval: time.Minute}, logger)
+	ctx := context.Background()
+
+	for cycle := 1; cycle <= emptyDiscoveryThreshold; cycle++ {
+		if err := engine.collectVulnerabilities(ctx); err != nil {
+			t.Fatalf("collectVulnerabilities() failed: %v", err)
+		}
+		if got, want := engine.IsDiscoveryEmpty(), cycle >= emptyDiscoveryThreshold; got != want {
+			t.Errorf("After %d empty cycles IsDiscoveryEmpty() = %v, want %v", cycle, got, want)
+		}
+	}
+
+	// A single cycle with images clears the indicator
+	cloudProvider.images = []types.ImageInfo{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1", Namespace: "default"}}
+	if err := engine.collectVulnerabilities(ctx); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	if engine.IsDiscoveryEmpty() {
+		t.Error("Expected the indicator to clear once images are discovered")
+	}
+}
+
+type identityVulnerabilitySource struct {
+	MockVulnerabilitySource
+	identity *types.CallerIdentity
+}
+
+func (s *identityVulnerabilitySource) CallerIdentity(ctx context.Context) (*types.CallerIdentity, error) {
+	return s.identity, nil
+}
+
+type scanConfigurationVulnerabilitySource struct {
+	MockVulnerabilitySource
+	scanType string
+	err      error
+	calls    int
+}
+
+func (s *scanConfigurationVulnerabilitySource) RegistryScanType(ctx context.Context) (string, error) {
+	s.calls++
+	return s.scanType, s.err
+}
+
+func TestEngineCheckRegistryScanning(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name          string
+		enabled       bool
+		source        *scanConfigurationVulnerabilitySource
+		expected      string
+		expectedCalls int
+	}{
+		{
+			name:          "enhanced scanning",
+			enabled:       true,
+			source:        &scanConfigurationVulnerabilitySource{scanType: "ENHANCED"},
+			expected:      "ENHANCED",
+			expectedCalls: 1,
+		},
+		{
+			name:          "basic scanning",
+			enabled:       true,
+			source:        &scanConfigurationVulnerabilitySource{scanType: "BASIC"},
+			expected:      "BASIC",
+			expectedCalls: 1,
+		},
+		{
+			name:          "check failure",
+			enabled:       true,
+			source:        &scanConfigurationVulnerabilitySource{err: errors.New("AccessDeniedException")},
+			expectedCalls: 1,
+		},
+		{
+			name:   "check disabled",
+			source: &scanConfigurationVulnerabilitySource{scanType: "ENHANCED"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.source.name = "test-vuln"
+			config := &Config{CheckRegistryScanning: tt.enabled}
+			engine := NewEngine(&MockCloudProvider{name: "mock"}, tt.source, config, logger)
+
+			engine.checkRegistryScanning(context.Background(), logger.WithField("test", tt.name))
+
+			if tt.source.calls != tt.expectedCalls {
+				t.Errorf("Expected %d scanning configuration calls, got %d", tt.expectedCalls, tt.source.calls)
+			}
+			if got := engine.GetRegistryScanType(); got != tt.expected {
+				t.Errorf("Expected scan type %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	// Sources without a scanning configuration are skipped
+	engine := NewEngine(&MockCloudProvider{name: "mock"}, &MockVulnerabilitySource{name: "mock"}, &Config{CheckRegistryScanning: true}, logger)
+	engine.checkRegistryScanning(context.Background(), logger.WithField("test", "unsupported"))
+	if got := engine.GetRegistryScanType(); got != "" {
+		t.Errorf("Expected no scan type, got %q", got)
+	}
+}
+
+func TestEngineCallerIdentity(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	identity := &types.CallerIdentity{Account: "123456789012", ARN: "arn:aws:iam::123456789012:role/vulnrelay"}
+	engine := NewEngine(&MockCloudProvider{name: "mock"}, &identityVulnerabilitySource{identity: identity}, &Config{}, logger)
+	got, err := engine.CallerIdentity(context.Background())
+	if err != nil || got != identity {
+		t.Errorf("Expected the source identity, got %+v (err %v)", got, err)
+	}
+
+	// Sources without an identity report none
+	engine = NewEngine(&MockCloudProvider{name: "mock"}, &MockVulnerabilitySource{name: "mock"}, &Config{}, logger)
+	got, err = engine.CallerIdentity(context.Background())
+	if err != nil || got != nil {
+		t.Errorf("Expected no identity, got %+v (err %v)", got, err)
+	}
+}
+
+func TestEngineFirstSeen(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const imageURI = "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	imageWith := func(cves ...string) *types.ImageVulnerability {
+		vuln := &types.ImageVulnerability{
+			ImageURI:        imageURI,
+			Vulnerabilities: map[string]int{"HIGH": len(cves)},
+			TotalCount:      len(cves),
+			ScanStatus:      "COMPLETE",
+		}
+		for _, cve := range cves {
+			vuln.Findings = append(vuln.Findings, types.VulnerabilityFinding{Name: cve, Severity: "HIGH"})
+		}
+		return vuln
+	}
+
+	source := &MockVulnerabilitySource{vulns: map[string]*types.ImageVulnerability{imageURI: imageWith("CVE-2024-0001")}}
+	engine := NewEngine(&MockCloudProvider{images: []types.ImageInfo{{URI: imageURI}}}, source, &Config{}, logger)
+
+	cycle1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cycle2 := cycle1.Add(time.Hour)
+
+	firstSeen := func() map[string]time.Time {
+		data, _ := engine.GetVulnerabilityData()
+		result := make(map[string]time.Time)
+		for _, finding := range data[imageURI].Findings {
+			if finding.FirstSeen == nil {
+				t.Fatalf("Expected first-seen timestamp on %s", finding.Name)
+			}
+			result[finding.Name] = *finding.FirstSeen
+		}
+		return result
+	}
+
+	// Cycle 1: the finding is observed for the first time
+	engine.now = func() time.Time { return cycle1 }
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	if got := firstSeen()["CVE-2024-0001"]; !got.Equal(cycle1) {
+		t.Errorf("Expected CVE-2024-0001 first seen at %v, got %v", cycle1, got)
+	}
+
+	// Cycle 2: the finding persists and a new one appears
+	source.vulns[imageURI] = imageWith("CVE-2024-0001", "CVE-2024-0002")
+	engine.cache = cache.NewVulnerabilityCache(logger)
+	engine.now = func() time.Time { return cycle2 }
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	seen := firstSeen()
+	if got := seen["CVE-2024-0001"]; !got.Equal(cycle1) {
+		t.Errorf("Expected CVE-2024-0001 first seen to stay at %v, got %v", cycle1, got)
+	}
+	if got := seen["CVE-2024-0002"]; !got.Equal(cycle2) {
+		t.Errorf("Expected CVE-2024-0002 first seen at %v, got %v", cycle2, got)
+	}
+
+	// The source's data must not be modified
+	for _, finding := range source.vulns[imageURI].Findings {
+		if finding.FirstSeen != nil {
+			t.Errorf("Expected source finding %s to be left unannotated", finding.Name)
+		}
+	}
+}
+
+func TestEngineAcceptedCVEs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const imageURI = "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	source := &MockVulnerabilitySource{vulns: map[string]*types.ImageVulnerability{
+		imageURI: {
+			ImageURI:        imageURI,
+			Repository:      "app",
+			Tag:             "v1",
+			Vulnerabilities: map[string]int{"CRITICAL": 1, "HIGH": 2},
+			TotalCount:      3,
+			ScanStatus:      "COMPLETE",
+			Findings: []types.VulnerabilityFinding{
+				{Name: "CVE-2024-0001", Severity: "CRITICAL"},
+				{Name: "CVE-2024-0002", Severity: "HIGH"},
+				{Name: "CVE-2024-0003", Severity: "HIGH"},
+			},
+		},
+	}}
+
+	accepted, err := acceptance.NewList([]acceptance.Entry{
+		{CVE: "CVE-2024-0002", Images: []string{"app"}},
+		{CVE: "CVE-2024-0003", Images: []string{"other-app"}},
+	})
+	if err != nil {
+		t.Fatalf("NewList() failed: %v", err)
+	}
+
+	engine := NewEngine(&MockCloudProvider{images: []types.ImageInfo{{URI: imageURI}}}, source, &Config{}, logger)
+	engine.SetAcceptedCVEs(accepted)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	data, _ := engine.GetVulnerabilityData()
+	vuln := data[imageURI]
+
+	// The accepted CVE is excluded from the active counts
+	if vuln.Vulnerabilities["HIGH"] != 1 || vuln.Vulnerabilities["CRITICAL"] != 1 {
+		t.Errorf("Expected counts CRITICAL=1 HIGH=1, got %v", vuln.Vulnerabilities)
+	}
+	if vuln.TotalCount != 2 {
+		t.Errorf("Expected total count 2, got %d", vuln.TotalCount)
+	}
+
+	// ...but still listed with the accepted flag
+	if len(vuln.Findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %d", len(vuln.Findings))
+	}
+	for _, finding := range vuln.Findings {
+		expected := finding.Name == "CVE-2024-0002"
+		if finding.Accepted != expected {
+			t.Errorf("Expected %s accepted=%v, got %v", finding.Name, expected, finding.Accepted)
+		}
+	}
+
+	// The source's data must not be modified
+	if source.vulns[imageURI].Vulnerabilities["HIGH"] != 2 || source.vulns[imageURI].TotalCount != 3 {
+		t.Error("Expected source counts to be left unchanged")
+	}
+}
+
 func TestEngineGetVulnerabilityDataConcurrency(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -379,6 +1538,79 @@ func TestEngineGetVulnerabilityDataConcurrency(t *testing.T) {
 	// If we reach here without deadlock, the test passes
 }
 
+// flakyCloudProvider fails discovery a fixed number of times before succeeding
+type flakyCloudProvider struct {
+	MockCloudProvider
+	failures int
+	calls    atomic.Int32
+}
+
+func (f *flakyCloudProvider) DiscoverImages(ctx context.Context) ([]types.ImageInfo, error) {
+	if int(f.calls.Add(1)) <= f.failures {
+		return nil, errors.New("kubernetes API not reachable")
+	}
+	return f.images, nil
+}
+
+func TestEngineInitialCollectionRetry(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	tests := []struct {
+		name          string
+		failures      int
+		retries       int
+		expectedCalls int32
+		expectData    bool
+	}{
+		{name: "succeeds on retry", failures: 1, retries: 3, expectedCalls: 2, expectData: true},
+		{name: "retries are bounded", failures: 10, retries: 2, expectedCalls: 3, expectData: false},
+		{name: "retries disabled", failures: 1, retries: 0, expectedCalls: 1, expectData: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cloudProvider := &flakyCloudProvider{
+				MockCloudProvider: MockCloudProvider{
+					name:   "mock",
+					images: []types.ImageInfo{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1", Namespace: "default"}},
+				},
+				failures: tt.failures,
+			}
+			// The scrape interval is long enough that no tick happens during the test
+			engine := NewEngine(cloudProvider, &MockVulnerabilitySource{name: "mock"}, &Config{
+				ScrapeInterval:           time.Hour,
+				InitialCollectionRetries: tt.retries,
+				InitialCollectionBackoff: time.Millisecond,
+			}, logger)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				engine.Start(ctx)
+				close(done)
+			}()
+
+			deadline := time.Now().Add(5 * time.Second)
+			for cloudProvider.calls.Load() < tt.expectedCalls && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			// Allow a stray extra attempt to show up before checking the count
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			<-done
+
+			if calls := cloudProvider.calls.Load(); calls != tt.expectedCalls {
+				t.Errorf("Expected %d discovery calls, got %d", tt.expectedCalls, calls)
+			}
+			data, lastUpdated := engine.GetVulnerabilityData()
+			if got := len(data) > 0 && !lastUpdated.IsZero(); got != tt.expectData {
+				t.Errorf("Data present before the first tick = %v, want %v", got, tt.expectData)
+			}
+		})
+	}
+}
+
 func TestEngineStartAndStop(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -400,6 +1632,7 @@ func TestEngineStartAndStop(t *testing.T) {
 	}
 
 	engine := NewEngine(mockCloudProvider, mockVulnSource, config, logger)
+	startTime := time.Now()
 
 	// Start engine in goroutine
 	ctx, cancel := context.WithCancel(context.Background())
@@ -429,6 +1662,14 @@ func TestEngineStartAndStop(t *testing.T) {
 	if len(data) == 0 {
 		t.Error("No vulnerability data was collected")
 	}
+
+	// Verify scrape cadence is tracked
+	if engine.GetScrapeInterval() != config.ScrapeInterval {
+		t.Errorf("GetScrapeInterval() = %v, want %v", engine.GetScrapeInterval(), config.ScrapeInterval)
+	}
+	if lastTick := engine.GetLastTickTime(); !lastTick.After(startTime.Add(config.ScrapeInterval - time.Millisecond)) {
+		t.Errorf("Expected last tick after at least one interval, got %v (start %v)", lastTick, startTime)
+	}
 }
 
 func TestConfigValidation(t *testing.T) {
@@ -0,0 +1,68 @@
+// ABOUTME: Normalization of discovered image URIs before they key vulnerability data.
+// ABOUTME: Collapses spellings of the same image that differ in host case or separators.
+
+package engine
+
+import (
+	"strings"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+)
+
+// normalizeImageURI returns the canonical spelling of an image URI: surrounding
+// whitespace and slashes are trimmed, repeated slashes collapsed and the
+// registry host lowercased. Repository and tag are left as they are, since
+// tags are case-sensitive.
+func normalizeImageURI(imageURI string) string {
+	uri := strings.Trim(strings.TrimSpace(imageURI), "/")
+	for strings.Contains(uri, "//") {
+		uri = strings.ReplaceAll(uri, "//", "/")
+	}
+
+	if host, rest, ok := splitRegistryHost(uri); ok {
+		uri = strings.ToLower(host) + "/" + rest
+	}
+	return uri
+}
+
+// splitRegistryHost splits the registry host off an image URI. Like Docker,
+// the first component is a host only when it looks like one.
+func splitRegistryHost(imageURI string) (host, rest string, ok bool) {
+	host, rest, found := strings.Cut(imageURI, "/")
+	if !found || !(strings.ContainsAny(host, ".:") || host == "localhost") {
+		return "", imageURI, false
+	}
+	return host, rest, true
+}
+
+// workloadKey identifies a workload using an image
+type workloadKey struct {
+	namespace, workloadType, workload string
+}
+
+// normalizeImages normalizes the URI of each discovered image and drops later
+// duplicates, keeping the first image's workload context. Each image's
+// WorkloadCount is set to the number of distinct workloads using it.
+func normalizeImages(images []types.ImageInfo) []types.ImageInfo {
+	normalized := make([]types.ImageInfo, 0, len(images))
+	index := make(map[string]int, len(images))
+	workloads := make(map[string]map[workloadKey]bool, len(images))
+	for _, img := range images {
+		img.URI = normalizeImageURI(img.URI)
+		if _, seen := index[img.URI]; !seen {
+			index[img.URI] = len(normalized)
+			workloads[img.URI] = make(map[workloadKey]bool)
+			img.WorkloadCount = 0
+			normalized = append(normalized, img)
+		}
+
+		// Images listed without a workload, as in local mode, count none
+		if img.Workload != "" {
+			workloads[img.URI][workloadKey{img.Namespace, img.WorkloadType, img.Workload}] = true
+		}
+	}
+	for uri, i := range index {
+		normalized[i].WorkloadCount = len(workloads[uri])
+	}
+	return normalized
+}
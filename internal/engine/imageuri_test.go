@@ -0,0 +1,81 @@
+// ABOUTME: Tests for image URI normalization.
+// ABOUTME: Covers host case, redundant separators and deduplication of equivalent images.
+
+package engine
+
+import (
+	"testing"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+)
+
+func TestNormalizeImageURI(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		expected string
+	}{
+		{"canonical", "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest", "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest"},
+		{"trailing slash", "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest/", "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest"},
+		{"host case", "123456789012.DKR.ECR.US-EAST-1.amazonaws.com/app:latest", "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest"},
+		{"repeated slashes", "123456789012.dkr.ecr.us-east-1.amazonaws.com//team//app:latest", "123456789012.dkr.ecr.us-east-1.amazonaws.com/team/app:latest"},
+		{"surrounding whitespace", " registry.example.com/app:v1\n", "registry.example.com/app:v1"},
+		{"host with port", "Registry.Example.com:5000/app:v1", "registry.example.com:5000/app:v1"},
+		{"localhost", "localhost//app:v1", "localhost/app:v1"},
+		{"tag case is kept", "registry.example.com/app:RC1", "registry.example.com/app:RC1"},
+		{"no host", "Library/app:v1", "Library/app:v1"},
+		{"digest", "registry.example.com/app@sha256:abc123", "registry.example.com/app@sha256:abc123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeImageURI(tt.uri); got != tt.expected {
+				t.Errorf("normalizeImageURI(%q) = %q, expected %q", tt.uri, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeImages(t *testing.T) {
+	images := normalizeImages([]types.ImageInfo{
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest", Namespace: "production"},
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest/", Namespace: "staging"},
+		{URI: "123456789012.DKR.ECR.us-east-1.amazonaws.com/app:latest", Namespace: "dev"},
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1", Namespace: "production"},
+	})
+
+	if len(images) != 2 {
+		t.Fatalf("Expected 2 images, got %d: %+v", len(images), images)
+	}
+	if images[0].Namespace != "production" {
+		t.Errorf("Expected the first occurrence's context to be kept, got namespace %q", images[0].Namespace)
+	}
+	if images[1].URI != "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1" {
+		t.Errorf("Unexpected second image %q", images[1].URI)
+	}
+}
+
+func TestNormalizeImagesWorkloadCount(t *testing.T) {
+	const app = "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	images := normalizeImages([]types.ImageInfo{
+		{URI: app, Namespace: "production", Workload: "web", WorkloadType: "Deployment"},
+		{URI: app, Namespace: "production", Workload: "web", WorkloadType: "Deployment"}, // Second container
+		{URI: app + "/", Namespace: "production", Workload: "worker", WorkloadType: "Deployment"},
+		{URI: app, Namespace: "staging", Workload: "web", WorkloadType: "Deployment"},
+		{URI: app, Namespace: "production", Workload: "web", WorkloadType: "CronJob"},
+		{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/tool:v1"}, // Listed without a workload
+	})
+
+	expected := map[string]int{
+		app: 4,
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/tool:v1": 0,
+	}
+	if len(images) != len(expected) {
+		t.Fatalf("Expected %d images, got %d: %+v", len(expected), len(images), images)
+	}
+	for _, img := range images {
+		if img.WorkloadCount != expected[img.URI] {
+			t.Errorf("Expected %d workloads for %s, got %d", expected[img.URI], img.URI, img.WorkloadCount)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// ABOUTME: Pipelined collection that fetches vulnerabilities while discovery runs.
+// ABOUTME: Queues each batch of streamed images on its registry's collector as it arrives.
+
+package engine
+
+import (
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// streamAndCollect collects the registries while the provider streams
+// discovered images, returning the discovered images and each registry's
+// result. An image is fetched once, when first discovered; its workload
+// details are completed from the whole discovery before returning. A failed
+// discovery stops the fetches in progress and fails the cycle, like a failed
+// DiscoverImages.
+func (e *Engine) streamAndCollect(ctx context.Context, streamer ImageStreamer, logger *logrus.Entry) ([]types.ImageInfo, []registryResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan []types.ImageInfo)
+	var streamErr error
+	go func() {
+		defer close(batches)
+		streamErr = streamer.StreamImages(ctx, batches)
+	}()
+
+	var discovered []types.ImageInfo
+	queued := make(map[string]bool)
+	collectors := make(map[string]*registryCollector)
+	for batch := range batches {
+		discovered = append(discovered, batch...)
+
+		// Equivalent spellings of an image share one entry in the data and cache
+		var fresh []types.ImageInfo
+		for _, img := range normalizeImages(batch) {
+			if !queued[img.URI] {
+				queued[img.URI] = true
+				fresh = append(fresh, img)
+			}
+		}
+
+		for _, group := range groupByRegistry(fresh) {
+			collector, ok := collectors[group.registry]
+			if !ok {
+				collector = e.newRegistryCollector(ctx, group.registry, logger.WithField("registry", group.registry))
+				collectors[group.registry] = collector
+			}
+			collector.add(group.images)
+		}
+	}
+
+	registries := slices.Sorted(maps.Keys(collectors))
+	if streamErr != nil {
+		cancel()
+		for _, registry := range registries {
+			collectors[registry].finish()
+		}
+		return nil, nil, streamErr
+	}
+
+	images := normalizeImages(discovered)
+	logger.WithFields(logrus.Fields{
+		"image_count": len(images),
+		"registries":  len(registries),
+	}).Info("Discovered images")
+	e.recordDiscovery(len(images), logger)
+
+	// Later batches can add workloads using an image queued earlier
+	infos := make(map[string]types.ImageInfo, len(images))
+	for _, img := range images {
+		infos[img.URI] = img
+	}
+	results := make([]registryResult, len(registries))
+	for i, registry := range registries {
+		result := collectors[registry].finish()
+		for j, img := range result.images {
+			result.images[j] = infos[img.URI]
+		}
+		for uri, vulnData := range result.data {
+			vulnData.ImageInfo = infos[uri]
+		}
+		results[i] = result
+	}
+	return images, results, nil
+}
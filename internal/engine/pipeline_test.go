@@ -0,0 +1,196 @@
+// ABOUTME: Tests for pipelined collection while the provider streams discovered images.
+// ABOUTME: Covers fetching during discovery, merged workload details and failed discovery.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// streamingCloudProvider sends its batches one at a time, waiting for the
+// previous batch's first image to be fetched before sending the next, so
+// discovery only completes when fetching overlaps it
+type streamingCloudProvider struct {
+	MockCloudProvider
+	batches [][]types.ImageInfo
+	fetched <-chan string
+	err     error // Returned after the batches
+}
+
+func (p *streamingCloudProvider) DiscoverImages(ctx context.Context) ([]types.ImageInfo, error) {
+	var images []types.ImageInfo
+	for _, batch := range p.batches {
+		images = append(images, batch...)
+	}
+	return images, nil
+}
+
+func (p *streamingCloudProvider) StreamImages(ctx context.Context, batches chan<- []types.ImageInfo) error {
+	for i, batch := range p.batches {
+		batches <- batch
+		if i == len(p.batches)-1 || p.fetched == nil {
+			continue
+		}
+
+		// Discovery of the next batch continues only once fetching started
+		select {
+		case uri := <-p.fetched:
+			if uri != batch[0].URI {
+				return errors.New("unexpected fetch of " + uri)
+			}
+		case <-time.After(time.Second):
+			return errors.New("no fetch started while discovery was running")
+		}
+	}
+	return p.err
+}
+
+// notifyingVulnerabilitySource reports every fetched image URI
+type notifyingVulnerabilitySource struct {
+	MockVulnerabilitySource
+	fetched chan string
+}
+
+func (s *notifyingVulnerabilitySource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	select {
+	case s.fetched <- imageURI:
+	default:
+	}
+	return s.MockVulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
+}
+
+func TestEnginePipelineDiscovery(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const (
+		api    = "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+		web    = "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v1"
+		worker = "210987654321.dkr.ecr.eu-west-1.amazonaws.com/worker:v1"
+	)
+	fetched := make(chan string, 1)
+	source := &notifyingVulnerabilitySource{fetched: fetched}
+	provider := &streamingCloudProvider{
+		batches: [][]types.ImageInfo{
+			{{URI: api, Namespace: "team-a", Workload: "api", WorkloadType: "Deployment"}},
+			{{URI: web, Namespace: "team-b", Workload: "web", WorkloadType: "Deployment"}},
+			{
+				{URI: api, Namespace: "team-c", Workload: "api", WorkloadType: "Deployment"},
+				{URI: worker, Namespace: "team-c", Workload: "worker", WorkloadType: "Deployment"},
+			},
+		},
+		fetched: fetched,
+	}
+	engine := NewEngine(provider, source, &Config{PipelineDiscovery: true}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	data, _ := engine.GetVulnerabilityData()
+	if len(data) != 3 {
+		t.Fatalf("Expected 3 images, got %d", len(data))
+	}
+
+	// An image discovered again in a later batch is fetched once and counts
+	// the workloads of every batch
+	if info := data[api].ImageInfo; info.Namespace != "team-a" || info.WorkloadCount != 2 {
+		t.Errorf("Expected api from team-a used by 2 workloads, got %+v", info)
+	}
+
+	registries := engine.GetRegistryCollections()
+	if len(registries) != 2 || registries[0].Registry != "123456789012.dkr.ecr.us-east-1.amazonaws.com" || registries[0].Collected != 2 {
+		t.Errorf("Expected both registries collected in order, got %+v", registries)
+	}
+}
+
+func TestEnginePipelineDiscoveryFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	batch := []types.ImageInfo{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"}}
+	provider := &streamingCloudProvider{batches: [][]types.ImageInfo{batch}}
+	engine := NewEngine(provider, &MockVulnerabilitySource{}, &Config{PipelineDiscovery: true}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+
+	// A discovery failure after the first batch fails the cycle and keeps the
+	// previous data, as when DiscoverImages fails
+	provider.batches = append(provider.batches, []types.ImageInfo{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v1"}})
+	provider.err = errors.New("list deployments: forbidden")
+	if err := engine.collectVulnerabilities(context.Background()); err == nil {
+		t.Fatal("Expected collection to fail when discovery fails")
+	}
+	if data, _ := engine.GetVulnerabilityData(); len(data) != 1 {
+		t.Errorf("Expected previous data to be kept, got %d images", len(data))
+	}
+}
+
+func TestEnginePipelineDiscoveryDisabled(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// Without pipelining, a streaming provider is asked for all images at once
+	fetched := make(chan string, 1)
+	provider := &streamingCloudProvider{
+		batches: [][]types.ImageInfo{
+			{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"}},
+			{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v1"}},
+		},
+		fetched: fetched,
+		err:     errors.New("streamed without pipelining"),
+	}
+	engine := NewEngine(provider, &notifyingVulnerabilitySource{fetched: fetched}, &Config{}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	if data, _ := engine.GetVulnerabilityData(); len(data) != 2 {
+		t.Errorf("Expected 2 images, got %d", len(data))
+	}
+}
+
+// countingFilterSource counts how often its image filter is resolved
+type countingFilterSource struct {
+	MockVulnerabilitySource
+	resolved int
+}
+
+func (s *countingFilterSource) ResolveImageFilter(ctx context.Context, registry string) (func([]types.ImageInfo) []types.ImageInfo, error) {
+	s.resolved++
+	return func(images []types.ImageInfo) []types.ImageInfo { return images }, nil
+}
+
+func TestEnginePipelineDiscoveryResolvesFilterOnce(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// One registry's images arrive in several batches, e.g. one per namespace
+	provider := &streamingCloudProvider{
+		batches: [][]types.ImageInfo{
+			{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"}},
+			{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v1"}},
+			{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1"}},
+		},
+	}
+	source := &countingFilterSource{}
+	engine := NewEngine(provider, source, &Config{PipelineDiscovery: true}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	if data, _ := engine.GetVulnerabilityData(); len(data) != 3 {
+		t.Errorf("Expected 3 images, got %d", len(data))
+	}
+	if source.resolved != 1 {
+		t.Errorf("Expected the filter to be resolved once for the registry, got %d", source.resolved)
+	}
+}
@@ -0,0 +1,290 @@
+// ABOUTME: Per-registry collection, run concurrently with isolated error handling.
+// ABOUTME: Groups images by registry host and records each registry's outcome.
+
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultRegistry is the registry of image references without a host, as in Docker
+const defaultRegistry = "docker.io"
+
+// registryImages are the images of one registry
+type registryImages struct {
+	registry string
+	images   []types.ImageInfo
+}
+
+// registryResult is the outcome of collecting one registry
+type registryResult struct {
+	data   map[string]*types.ImageVulnerabilityData
+	images []types.ImageInfo // Images collected, or all images when filtering failed
+	status types.RegistryCollection
+	err    error // Set when the registry's images could not be filtered
+}
+
+// imageRegistry returns the registry host of a normalized image URI
+func imageRegistry(imageURI string) string {
+	if host, _, ok := splitRegistryHost(imageURI); ok {
+		return host
+	}
+	return defaultRegistry
+}
+
+// groupByRegistry groups images by registry, sorted by registry and keeping
+// the image order within each registry
+func groupByRegistry(images []types.ImageInfo) []registryImages {
+	index := make(map[string]int)
+	var groups []registryImages
+	for _, img := range images {
+		registry := imageRegistry(img.URI)
+		i, ok := index[registry]
+		if !ok {
+			i = len(groups)
+			index[registry] = i
+			groups = append(groups, registryImages{registry: registry})
+		}
+		groups[i].images = append(groups[i].images, img)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].registry < groups[j].registry })
+	return groups
+}
+
+// registryCollector fetches the images of one registry as they are added, so
+// fetching can start while discovery is still running
+type registryCollector struct {
+	engine    *Engine
+	ctx       context.Context
+	logger    *logrus.Entry
+	queue     *imageQueue
+	workers   sync.WaitGroup
+	mutex     sync.Mutex
+	result    registryResult
+	newImages int // Images queued ahead of ones known from the previous cycle
+
+	// imageFilter is the source's filter, resolved when the first images are
+	// added; nil until then or when the source doesn't filter
+	imageFilter func([]types.ImageInfo) []types.ImageInfo
+}
+
+// newRegistryCollector starts the fetch workers of a registry
+func (e *Engine) newRegistryCollector(ctx context.Context, registry string, logger *logrus.Entry) *registryCollector {
+	c := &registryCollector{
+		engine: e,
+		ctx:    ctx,
+		logger: logger,
+		queue:  newImageQueue(),
+		result: registryResult{
+			data:   make(map[string]*types.ImageVulnerabilityData),
+			status: types.RegistryCollection{Registry: registry},
+		},
+	}
+
+	// A fixed worker pool limits concurrent API calls; the queue hands out
+	// images in the order they are added
+	for i := 0; i < maxConcurrentFetches; i++ {
+		c.workers.Add(1)
+		go c.work()
+	}
+	return c
+}
+
+// add filters images and queues them for fetching
+func (c *registryCollector) add(images []types.ImageInfo) {
+	c.enqueue(c.filter(images))
+}
+
+// filter returns the images the vulnerability source covers. The source's
+// filter is resolved once, for the first images; when that fails, images are
+// no longer fetched and count as failed, so filter returns none.
+func (c *registryCollector) filter(images []types.ImageInfo) []types.ImageInfo {
+	if c.result.err != nil {
+		c.result.images = append(c.result.images, images...)
+		return nil
+	}
+
+	source, ok := c.engine.vulnerabilitySource.(ImageFilter)
+	if !ok {
+		return images
+	}
+	if c.imageFilter == nil {
+		imageFilter, err := source.ResolveImageFilter(c.ctx, c.result.status.Registry)
+		if err != nil {
+			c.logger.WithError(err).Error("Failed to filter discovered images; skipping registry this cycle")
+			c.result.images = append(c.result.images, images...)
+			c.result.err = fmt.Errorf("failed to filter discovered images of %s: %w", c.result.status.Registry, err)
+			return nil
+		}
+		c.imageFilter = imageFilter
+	}
+	return c.imageFilter(images)
+}
+
+// enqueue queues filtered images for fetching, newly discovered images first
+// so fresh deployments show up quickly
+func (c *registryCollector) enqueue(images []types.ImageInfo) {
+	c.result.images = append(c.result.images, images...)
+
+	queue, newImages := c.engine.prioritizeNewImages(images)
+	c.newImages += newImages
+	c.queue.push(queue)
+}
+
+// work fetches queued images until the queue is closed and drained
+func (c *registryCollector) work() {
+	defer c.workers.Done()
+
+	for {
+		imgInfo, ok := c.queue.pop()
+		if !ok {
+			return
+		}
+
+		vuln, err := c.engine.getImageVulnerability(c.ctx, imgInfo.URI)
+		if err != nil {
+			c.logger.WithError(err).WithField("image", imgInfo.URI).Error("Failed to get vulnerability data")
+			continue
+		}
+
+		c.mutex.Lock()
+		c.result.data[imgInfo.URI] = &types.ImageVulnerabilityData{
+			ImageVulnerability: vuln,
+			ImageInfo:          imgInfo,
+		}
+		c.mutex.Unlock()
+	}
+}
+
+// finish waits for the queued fetches and returns the registry's outcome
+func (c *registryCollector) finish() registryResult {
+	if c.newImages > 0 {
+		c.logger.WithField("new_image_count", c.newImages).Info("Prioritizing newly discovered images")
+	}
+	c.queue.close()
+	c.workers.Wait()
+
+	result := c.result
+	if result.err != nil && len(result.data) == 0 {
+		// Nothing was fetched before the filter failed: the registry failed
+		result.status.Failed = len(result.images)
+		return result
+	}
+	result.status.Collected = len(result.data)
+	result.status.Failed = len(result.images) - len(result.data)
+	result.status.Success = result.status.Collected > 0 || result.status.Failed == 0
+	return result
+}
+
+// imageQueue is an unbounded FIFO of images, so adding images never waits
+// for the workers
+type imageQueue struct {
+	mutex  sync.Mutex
+	cond   *sync.Cond
+	images []types.ImageInfo
+	closed bool
+}
+
+func newImageQueue() *imageQueue {
+	q := &imageQueue{}
+	q.cond = sync.NewCond(&q.mutex)
+	return q
+}
+
+// push appends images to the queue
+func (q *imageQueue) push(images []types.ImageInfo) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.images = append(q.images, images...)
+	q.cond.Broadcast()
+}
+
+// pop waits for the next image; ok is false once the queue is closed and empty
+func (q *imageQueue) pop() (img types.ImageInfo, ok bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for len(q.images) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.images) == 0 {
+		return types.ImageInfo{}, false
+	}
+	img = q.images[0]
+	q.images = q.images[1:]
+	return img, true
+}
+
+// close wakes the workers to finish once the queue is empty
+func (q *imageQueue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// registryConcurrency returns the adaptive fetch limit of a registry, kept
+// across cycles, or nil when adaptive concurrency is disabled
+func (e *Engine) registryConcurrency(registry string) *fetchConcurrency {
+	if !e.config.AdaptiveConcurrency {
+		return nil
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	concurrency, ok := e.fetchConcurrency[registry]
+	if !ok {
+		concurrency = newFetchConcurrency(maxConcurrentFetches)
+		e.fetchConcurrency[registry] = concurrency
+	}
+	return concurrency
+}
+
+// isThrottled reports whether the source rejected a call for exceeding its quota
+func (e *Engine) isThrottled(err error) bool {
+	detector, ok := e.vulnerabilitySource.(ThrottleDetector)
+	return err != nil && ok && detector.IsThrottled(err)
+}
+
+// GetFetchConcurrency returns the current adaptive fetch limit per registry,
+// or nil when adaptive concurrency is disabled
+func (e *Engine) GetFetchConcurrency() map[string]int {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	if len(e.fetchConcurrency) == 0 {
+		return nil
+	}
+	limits := make(map[string]int, len(e.fetchConcurrency))
+	for registry, concurrency := range e.fetchConcurrency {
+		limits[registry] = concurrency.current()
+	}
+	return limits
+}
+
+// recordRegistryCollections keeps the per-registry outcome of the latest collection
+func (e *Engine) recordRegistryCollections(statuses []types.RegistryCollection) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.registryCollections = statuses
+}
+
+// GetRegistryCollections returns the per-registry outcome of the latest
+// collection, sorted by registry
+func (e *Engine) GetRegistryCollections() []types.RegistryCollection {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+
+	return append([]types.RegistryCollection(nil), e.registryCollections...)
+}
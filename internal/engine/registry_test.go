@@ -0,0 +1,179 @@
+// ABOUTME: Tests for per-registry collection and its error isolation.
+// ABOUTME: Covers grouping by registry host and a failing registry next to a healthy one.
+
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// registryFailingSource fails every fetch, or the filtering, of one registry's images
+type registryFailingSource struct {
+	MockVulnerabilitySource
+	failingRegistry string
+	failFilter      bool
+}
+
+func (r *registryFailingSource) ResolveImageFilter(ctx context.Context, registry string) (func([]types.ImageInfo) []types.ImageInfo, error) {
+	if r.failFilter && registry == r.failingRegistry {
+		return nil, errors.New("describe repositories failed")
+	}
+	return func(images []types.ImageInfo) []types.ImageInfo { return images }, nil
+}
+
+func (r *registryFailingSource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	if imageRegistry(imageURI) == r.failingRegistry {
+		return nil, errors.New("access denied")
+	}
+	return r.MockVulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
+}
+
+func TestGroupByRegistry(t *testing.T) {
+	groups := groupByRegistry([]types.ImageInfo{
+		{URI: "222222222222.dkr.ecr.eu-west-1.amazonaws.com/api:v1"},
+		{URI: "111111111111.dkr.ecr.us-east-1.amazonaws.com/web:v1"},
+		{URI: "nginx:1.25"},
+		{URI: "222222222222.dkr.ecr.eu-west-1.amazonaws.com/worker:v1"},
+	})
+
+	var registries []string
+	for _, group := range groups {
+		registries = append(registries, group.registry)
+	}
+	expected := []string{"111111111111.dkr.ecr.us-east-1.amazonaws.com", "222222222222.dkr.ecr.eu-west-1.amazonaws.com", "docker.io"}
+	if !reflect.DeepEqual(registries, expected) {
+		t.Fatalf("Expected registries %v, got %v", expected, registries)
+	}
+	if len(groups[1].images) != 2 || groups[1].images[0].URI != "222222222222.dkr.ecr.eu-west-1.amazonaws.com/api:v1" {
+		t.Errorf("Expected both images of the second registry in discovery order, got %+v", groups[1].images)
+	}
+}
+
+func TestEngineRegistryIsolation(t *testing.T) {
+	const (
+		healthy = "111111111111.dkr.ecr.us-east-1.amazonaws.com"
+		failing = "222222222222.dkr.ecr.eu-west-1.amazonaws.com"
+	)
+
+	tests := []struct {
+		name       string
+		failFilter bool
+	}{
+		{"fetches of one registry fail", false},
+		{"filtering of one registry fails", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logger := logrus.New()
+			logger.SetLevel(logrus.FatalLevel)
+
+			provider := &MockCloudProvider{name: "test-cloud", images: []types.ImageInfo{
+				{URI: healthy + "/web:v1"},
+				{URI: healthy + "/api:v1"},
+				{URI: failing + "/web:v1"},
+			}}
+			source := &registryFailingSource{
+				MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln", vulns: make(map[string]*types.ImageVulnerability)},
+				failingRegistry:         failing,
+				failFilter:              tt.failFilter,
+			}
+			engine := NewEngine(provider, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+			if err := engine.collectVulnerabilities(context.Background()); err != nil {
+				t.Fatalf("Expected the cycle to succeed despite the failing registry, got: %v", err)
+			}
+
+			data, _ := engine.GetVulnerabilityData()
+			if len(data) != 2 || data[healthy+"/web:v1"] == nil || data[healthy+"/api:v1"] == nil {
+				t.Errorf("Expected the healthy registry's 2 images, got %d images", len(data))
+			}
+
+			expected := []types.RegistryCollection{
+				{Registry: healthy, Collected: 2, Success: true},
+				{Registry: failing, Failed: 1},
+			}
+			if got := engine.GetRegistryCollections(); !reflect.DeepEqual(got, expected) {
+				t.Errorf("Expected registry collections %+v, got %+v", expected, got)
+			}
+		})
+	}
+}
+
+func TestEngineAllRegistriesFailFiltering(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	const failing = "222222222222.dkr.ecr.eu-west-1.amazonaws.com"
+	provider := &MockCloudProvider{name: "test-cloud", images: []types.ImageInfo{{URI: failing + "/web:v1"}}}
+	source := &registryFailingSource{
+		MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln", vulns: make(map[string]*types.ImageVulnerability)},
+		failingRegistry:         failing,
+		failFilter:              true,
+	}
+	engine := NewEngine(provider, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+	err := engine.collectVulnerabilities(context.Background())
+	if err == nil || !strings.Contains(err.Error(), failing) {
+		t.Fatalf("Expected the cycle to fail naming the registry, got: %v", err)
+	}
+	if collections := engine.GetRegistryCollections(); len(collections) != 1 || collections[0].Success {
+		t.Errorf("Expected the failed registry to be recorded, got %+v", collections)
+	}
+}
+
+// concurrencyTrackingSource records the most source calls in flight at once
+type concurrencyTrackingSource struct {
+	MockVulnerabilitySource
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *concurrencyTrackingSource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	c.mu.Lock()
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	c.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	c.mu.Lock()
+	c.inFlight--
+	c.mu.Unlock()
+	return c.MockVulnerabilitySource.GetImageVulnerabilities(ctx, imageURI)
+}
+
+func TestEngineConcurrencyCapSharedByRegistries(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	var images []types.ImageInfo
+	for _, registry := range []string{"111111111111.dkr.ecr.us-east-1.amazonaws.com", "222222222222.dkr.ecr.eu-west-1.amazonaws.com", "333333333333.dkr.ecr.ap-south-1.amazonaws.com"} {
+		for i := 0; i < 2*maxConcurrentFetches; i++ {
+			images = append(images, types.ImageInfo{URI: fmt.Sprintf("%s/app-%d:v1", registry, i)})
+		}
+	}
+	source := &concurrencyTrackingSource{MockVulnerabilitySource: MockVulnerabilitySource{name: "test-vuln"}}
+	engine := NewEngine(&MockCloudProvider{name: "test-cloud", images: images}, source, &Config{ScrapeInterval: time.Minute}, logger)
+
+	if err := engine.collectVulnerabilities(context.Background()); err != nil {
+		t.Fatalf("collectVulnerabilities() failed: %v", err)
+	}
+	if data, _ := engine.GetVulnerabilityData(); len(data) != len(images) {
+		t.Errorf("Expected %d images, got %d", len(images), len(data))
+	}
+	if source.maxInFlight > maxConcurrentFetches {
+		t.Errorf("Expected at most %d concurrent source calls across registries, got %d", maxConcurrentFetches, source.maxInFlight)
+	}
+}
@@ -0,0 +1,171 @@
+// ABOUTME: Validation of the engine configuration before startup.
+// ABOUTME: Rejects invalid values and contradictory option combinations with clear messages.
+
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jfeddern/VulnRelay/internal/metrics"
+	"github.com/jfeddern/VulnRelay/internal/severity"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// Validate checks option values and cross-field constraints, returning every
+// problem found. Mode and vulnerability source names are checked against the
+// provider registry by the caller, since providers depend on this package.
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	// Sources and providers
+	if c.MockMode {
+		if c.ECRAccountID != "" || c.AssumeRoleARN != "" {
+			fail("mock mode makes no AWS calls: unset the ECR account ID and assume role ARN, or disable mock mode")
+		}
+	} else {
+		switch c.VulnerabilitySource {
+		case "ecr":
+			if c.ECRAccountID == "" || c.ECRRegion == "" {
+				fail("ECR account ID and region are required (unless using mock mode)")
+			}
+		case "harbor":
+			if c.HarborURL == "" {
+				fail("Harbor URL is required for the harbor vulnerability source")
+			}
+		}
+		if c.Mode == "local" && c.ImageListFile == "" {
+			fail("image list file is required for local mode (unless using mock mode)")
+		}
+	}
+	if c.ResolveDigests && c.VulnerabilitySource != "ecr" {
+		fail("digest resolution is only supported by the ecr vulnerability source, but the source is %q", c.VulnerabilitySource)
+	}
+	if c.TriggerScans && c.VulnerabilitySource != "ecr" {
+		fail("scan triggering is only supported by the ecr vulnerability source, but the source is %q", c.VulnerabilitySource)
+	}
+	if c.AdaptiveConcurrency && c.VulnerabilitySource != "ecr" {
+		fail("adaptive concurrency is only supported by the ecr vulnerability source, but the source is %q", c.VulnerabilitySource)
+	}
+	if len(c.RetainedFindingSeverities) > 0 && c.VulnerabilitySource != "ecr" {
+		fail("retained finding severities are only supported by the ecr vulnerability source, but the source is %q", c.VulnerabilitySource)
+	}
+	if c.SkipUntaggedImages && c.VulnerabilitySource != "ecr" {
+		fail("skipping untagged images is only supported by the ecr vulnerability source, but the source is %q", c.VulnerabilitySource)
+	}
+	if c.HarborURL != "" && c.VulnerabilitySource != "harbor" {
+		fail("Harbor URL is set but the vulnerability source is %q: use the harbor source or unset the Harbor URL", c.VulnerabilitySource)
+	}
+	if c.ImageListFile != "" && c.Mode != "local" {
+		fail("image list file is only read in local mode, but mode is %q", c.Mode)
+	}
+	if c.DefaultImageTag != "" {
+		if c.Mode != "local" {
+			fail("default image tag only applies to the local mode image list, but mode is %q", c.Mode)
+		}
+		if strings.ContainsAny(c.DefaultImageTag, ":@/") {
+			fail("invalid default image tag '%s': must be a tag without ':', '@' or '/'", c.DefaultImageTag)
+		}
+	}
+
+	// Discovery
+	for _, pattern := range c.RepositoryAllowlist {
+		if _, err := path.Match(pattern, ""); err != nil {
+			fail("invalid repository allowlist pattern '%s': %v", pattern, err)
+		}
+	}
+	for _, pattern := range c.IgnoreContainers {
+		if _, err := path.Match(pattern, ""); err != nil {
+			fail("invalid ignored container pattern '%s': %v", pattern, err)
+		}
+	}
+	if c.FieldSelector != "" {
+		if _, err := fields.ParseSelector(c.FieldSelector); err != nil {
+			fail("invalid field selector '%s': %v", c.FieldSelector, err)
+		}
+	}
+
+	if c.MaxImagesPerCycle < 0 {
+		fail("invalid max images per cycle %d: must be 0 (unlimited) or more", c.MaxImagesPerCycle)
+	}
+	if c.MaxImagesPerCycle > 0 && c.PipelineDiscovery {
+		fail("max images per cycle picks images from the complete discovery, so it can't be combined with discovery pipelining")
+	}
+	if c.MaxRetainedFindings < 0 {
+		fail("invalid max retained findings %d: must be 0 (unlimited) or more", c.MaxRetainedFindings)
+	}
+
+	if c.MaxDataAge < 0 {
+		fail("invalid max data age %v: must be 0 (never expire) or more", c.MaxDataAge)
+	}
+
+	// Severities must be part of the severity order to be ranked
+	severities := severity.NewOrder(c.SeverityOrder)
+	if threshold := c.ReadinessSeverityThreshold; threshold != "" && !severities.Known(strings.ToUpper(threshold)) {
+		fail("invalid readiness severity threshold '%s': must be one of %s", threshold, strings.Join(severities.Severities(), ", "))
+	}
+	for _, level := range slices.Sorted(maps.Keys(c.SeverityCacheTTLs)) {
+		if !severities.Known(level) {
+			fail("invalid cache TTL severity '%s': must be one of %s", level, strings.Join(severities.Severities(), ", "))
+		}
+	}
+
+	// Readiness and metrics
+	if c.ScanErrorThreshold < 0 || c.ScanErrorThreshold > 1 {
+		fail("invalid scan error threshold %v: must be between 0 and 1", c.ScanErrorThreshold)
+	}
+	for _, label := range c.MetricsDropLabels {
+		if !metrics.IsDroppableLabel(label) {
+			fail("invalid metrics label to drop '%s': must be one of %s", label, strings.Join(metrics.DroppableLabels, ", "))
+		}
+	}
+	if c.MetricsFreshOnly && !c.KeepStaleOnFailure {
+		fail("metrics fresh only has no effect without keep stale on failure, which is the only source of stale data")
+	}
+
+	// HTTP server
+	if c.MaxHeaderBytes < 0 {
+		fail("invalid max header bytes %d: must be 0 (net/http default) or more", c.MaxHeaderBytes)
+	}
+	if c.MaxRequestBodyBytes < 0 {
+		fail("invalid max request body bytes %d: must be 0 (unlimited) or more", c.MaxRequestBodyBytes)
+	}
+
+	// Authentication and exports
+	if c.OIDCJWKSURL != "" && c.OIDCAudience == "" {
+		fail("OIDC audience is required when OIDC authentication is enabled")
+	}
+	if c.OIDCJWKSURL == "" && (c.OIDCIssuer != "" || c.OIDCAudience != "") {
+		fail("OIDC issuer and audience have no effect without an OIDC JWKS URL")
+	}
+	if c.DebugIdentityEndpoint && c.OIDCJWKSURL == "" {
+		fail("the debug identity endpoint exposes the AWS account and role, so it requires OIDC authentication")
+	}
+	if c.DebugIdentityEndpoint && c.DisableVulnerabilitiesEndpoint {
+		fail("the debug identity endpoint can't be enabled while the JSON endpoints are disabled")
+	}
+	if c.WebhookURL == "" && len(c.WebhookSeverityThresholds) > 0 {
+		fail("webhook severity thresholds are set without a webhook URL")
+	}
+	if c.WebhookBatchSize < 0 {
+		fail("invalid webhook batch size %d: must be 0 (one notification) or more", c.WebhookBatchSize)
+	}
+	if c.WebhookDeliveryTimeout < 0 {
+		fail("invalid webhook delivery timeout %v: must be positive", c.WebhookDeliveryTimeout)
+	}
+	if c.WebhookDiffConcurrency < 0 {
+		fail("invalid webhook diff concurrency %d: must be 0 (one worker) or more", c.WebhookDiffConcurrency)
+	}
+	if c.S3ExportBucket == "" && c.S3ExportPrefix != "" {
+		fail("S3 export prefix is set without an S3 export bucket")
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,367 @@
+// ABOUTME: Tests for engine configuration validation.
+// ABOUTME: Covers valid configurations and each rejected value or option combination.
+
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/notify"
+)
+
+func TestConfigValidate(t *testing.T) {
+	// validConfig returns a minimal valid cluster mode configuration
+	validConfig := func() *Config {
+		return &Config{
+			Mode:                "cluster",
+			VulnerabilitySource: "ecr",
+			ECRAccountID:        "123456789012",
+			ECRRegion:           "us-east-1",
+		}
+	}
+
+	tests := []struct {
+		name          string
+		modify        func(c *Config)
+		expectedError string // Empty when the configuration is valid
+	}{
+		{
+			name:   "valid cluster mode",
+			modify: func(c *Config) {},
+		},
+		{
+			name: "valid mock mode",
+			modify: func(c *Config) {
+				c.MockMode = true
+				c.ECRAccountID = ""
+				c.ECRRegion = ""
+			},
+		},
+		{
+			name: "valid local mode",
+			modify: func(c *Config) {
+				c.Mode = "local"
+				c.ImageListFile = "images.json"
+				c.DefaultImageTag = "latest"
+			},
+		},
+		{
+			name: "valid harbor source",
+			modify: func(c *Config) {
+				c.VulnerabilitySource = "harbor"
+				c.HarborURL = "https://harbor.example.com"
+			},
+		},
+		{
+			name: "valid exports, authentication and metrics options",
+			modify: func(c *Config) {
+				c.OIDCJWKSURL = "https://issuer.example.com/jwks"
+				c.OIDCAudience = "vulnrelay"
+				c.DebugIdentityEndpoint = true
+				c.WebhookURL = "https://hooks.example.com"
+				c.WebhookSeverityThresholds = []notify.SeverityThreshold{{Pattern: "prod-*", Severity: "HIGH"}}
+				c.S3ExportBucket = "archive"
+				c.S3ExportPrefix = "vulnrelay"
+				c.MetricsDropLabels = []string{"tag"}
+				c.KeepStaleOnFailure = true
+				c.MetricsFreshOnly = true
+				c.ResolveDigests = true
+				c.TriggerScans = true
+				c.AdaptiveConcurrency = true
+				c.RetainedFindingSeverities = []string{"CRITICAL", "HIGH"}
+			},
+		},
+		{
+			name:          "mock mode with an ECR account",
+			modify:        func(c *Config) { c.MockMode = true },
+			expectedError: "mock mode makes no AWS calls",
+		},
+		{
+			name: "mock mode with an assume role ARN",
+			modify: func(c *Config) {
+				c.MockMode = true
+				c.ECRAccountID = ""
+				c.AssumeRoleARN = "arn:aws:iam::123456789012:role/Scanner"
+			},
+			expectedError: "mock mode makes no AWS calls",
+		},
+		{
+			name:          "ECR without account ID",
+			modify:        func(c *Config) { c.ECRAccountID = "" },
+			expectedError: "ECR account ID and region are required",
+		},
+		{
+			name:          "ECR without region",
+			modify:        func(c *Config) { c.ECRRegion = "" },
+			expectedError: "ECR account ID and region are required",
+		},
+		{
+			name:          "harbor source without URL",
+			modify:        func(c *Config) { c.VulnerabilitySource = "harbor" },
+			expectedError: "Harbor URL is required",
+		},
+		{
+			name:          "Harbor URL with the ECR source",
+			modify:        func(c *Config) { c.HarborURL = "https://harbor.example.com" },
+			expectedError: "Harbor URL is set but the vulnerability source is \"ecr\"",
+		},
+		{
+			name: "digest resolution with the harbor source",
+			modify: func(c *Config) {
+				c.VulnerabilitySource = "harbor"
+				c.HarborURL = "https://harbor.example.com"
+				c.ResolveDigests = true
+			},
+			expectedError: "digest resolution is only supported by the ecr vulnerability source",
+		},
+		{
+			name: "scan triggering with the harbor source",
+			modify: func(c *Config) {
+				c.VulnerabilitySource = "harbor"
+				c.HarborURL = "https://harbor.example.com"
+				c.TriggerScans = true
+			},
+			expectedError: "scan triggering is only supported by the ecr vulnerability source",
+		},
+		{
+			name: "adaptive concurrency with the harbor source",
+			modify: func(c *Config) {
+				c.VulnerabilitySource = "harbor"
+				c.HarborURL = "https://harbor.example.com"
+				c.AdaptiveConcurrency = true
+			},
+			expectedError: "adaptive concurrency is only supported by the ecr vulnerability source",
+		},
+		{
+			name: "retained finding severities with the harbor source",
+			modify: func(c *Config) {
+				c.VulnerabilitySource = "harbor"
+				c.HarborURL = "https://harbor.example.com"
+				c.RetainedFindingSeverities = []string{"CRITICAL"}
+			},
+			expectedError: "retained finding severities are only supported by the ecr vulnerability source",
+		},
+		{
+			name: "skipping untagged images with the harbor source",
+			modify: func(c *Config) {
+				c.VulnerabilitySource = "harbor"
+				c.HarborURL = "https://harbor.example.com"
+				c.SkipUntaggedImages = true
+			},
+			expectedError: "skipping untagged images is only supported by the ecr vulnerability source",
+		},
+		{
+			name:          "unknown readiness severity threshold",
+			modify:        func(c *Config) { c.ReadinessSeverityThreshold = "SEVERE" },
+			expectedError: "invalid readiness severity threshold 'SEVERE': must be one of CRITICAL, HIGH, MEDIUM, LOW",
+		},
+		{
+			name: "readiness severity threshold from the severity order",
+			modify: func(c *Config) {
+				c.SeverityOrder = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"}
+				c.ReadinessSeverityThreshold = "NEGLIGIBLE"
+			},
+		},
+		{
+			name:          "cache TTL severity outside the severity order",
+			modify:        func(c *Config) { c.SeverityCacheTTLs = map[string]time.Duration{"INFORMATIONAL": time.Hour} },
+			expectedError: "invalid cache TTL severity 'INFORMATIONAL': must be one of CRITICAL, HIGH, MEDIUM, LOW",
+		},
+		{
+			name: "cache TTL severity from the severity order",
+			modify: func(c *Config) {
+				c.SeverityOrder = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL"}
+				c.SeverityCacheTTLs = map[string]time.Duration{"INFORMATIONAL": time.Hour}
+			},
+		},
+		{
+			name:          "negative max header bytes",
+			modify:        func(c *Config) { c.MaxHeaderBytes = -1 },
+			expectedError: "invalid max header bytes -1",
+		},
+		{
+			name:          "negative max request body bytes",
+			modify:        func(c *Config) { c.MaxRequestBodyBytes = -1 },
+			expectedError: "invalid max request body bytes -1",
+		},
+		{
+			name:          "local mode without image list",
+			modify:        func(c *Config) { c.Mode = "local" },
+			expectedError: "image list file is required for local mode",
+		},
+		{
+			name:          "image list outside local mode",
+			modify:        func(c *Config) { c.ImageListFile = "images.json" },
+			expectedError: "image list file is only read in local mode",
+		},
+		{
+			name:          "default image tag outside local mode",
+			modify:        func(c *Config) { c.DefaultImageTag = "latest" },
+			expectedError: "default image tag only applies to the local mode image list",
+		},
+		{
+			name: "default image tag with a separator",
+			modify: func(c *Config) {
+				c.Mode = "local"
+				c.ImageListFile = "images.json"
+				c.DefaultImageTag = "app:latest"
+			},
+			expectedError: "invalid default image tag 'app:latest'",
+		},
+		{
+			name:          "invalid repository allowlist pattern",
+			modify:        func(c *Config) { c.RepositoryAllowlist = []string{"team-[a"} },
+			expectedError: "invalid repository allowlist pattern",
+		},
+		{
+			name:          "invalid ignored container pattern",
+			modify:        func(c *Config) { c.IgnoreContainers = []string{"[sidecar"} },
+			expectedError: "invalid ignored container pattern",
+		},
+		{
+			name:          "invalid field selector",
+			modify:        func(c *Config) { c.FieldSelector = "metadata.name" },
+			expectedError: "invalid field selector",
+		},
+		{
+			name:          "negative max images per cycle",
+			modify:        func(c *Config) { c.MaxImagesPerCycle = -1 },
+			expectedError: "invalid max images per cycle",
+		},
+		{
+			name: "max images per cycle with discovery pipelining",
+			modify: func(c *Config) {
+				c.MaxImagesPerCycle = 10
+				c.PipelineDiscovery = true
+			},
+			expectedError: "can't be combined with discovery pipelining",
+		},
+		{
+			name:          "negative max retained findings",
+			modify:        func(c *Config) { c.MaxRetainedFindings = -1 },
+			expectedError: "invalid max retained findings",
+		},
+		{
+			name:          "negative max data age",
+			modify:        func(c *Config) { c.MaxDataAge = -time.Hour },
+			expectedError: "invalid max data age",
+		},
+		{
+			name:          "scan error threshold above 1",
+			modify:        func(c *Config) { c.ScanErrorThreshold = 1.5 },
+			expectedError: "invalid scan error threshold",
+		},
+		{
+			name:          "undroppable metrics label",
+			modify:        func(c *Config) { c.MetricsDropLabels = []string{"image_uri"} },
+			expectedError: "invalid metrics label to drop 'image_uri'",
+		},
+		{
+			name:          "fresh-only metrics without stale data",
+			modify:        func(c *Config) { c.MetricsFreshOnly = true },
+			expectedError: "metrics fresh only has no effect",
+		},
+		{
+			name:          "OIDC without audience",
+			modify:        func(c *Config) { c.OIDCJWKSURL = "https://issuer.example.com/jwks" },
+			expectedError: "OIDC audience is required",
+		},
+		{
+			name:          "OIDC audience without JWKS URL",
+			modify:        func(c *Config) { c.OIDCAudience = "vulnrelay" },
+			expectedError: "OIDC issuer and audience have no effect",
+		},
+		{
+			name:          "debug identity endpoint without OIDC",
+			modify:        func(c *Config) { c.DebugIdentityEndpoint = true },
+			expectedError: "the debug identity endpoint exposes the AWS account and role",
+		},
+		{
+			name: "debug identity endpoint with the JSON endpoints disabled",
+			modify: func(c *Config) {
+				c.OIDCJWKSURL = "https://issuer.example.com/jwks"
+				c.OIDCAudience = "vulnrelay"
+				c.DebugIdentityEndpoint = true
+				c.DisableVulnerabilitiesEndpoint = true
+			},
+			expectedError: "the debug identity endpoint can't be enabled while the JSON endpoints are disabled",
+		},
+		{
+			name: "webhook thresholds without URL",
+			modify: func(c *Config) {
+				c.WebhookSeverityThresholds = []notify.SeverityThreshold{{Pattern: "prod-*", Severity: "HIGH"}}
+			},
+			expectedError: "webhook severity thresholds are set without a webhook URL",
+		},
+		{
+			name: "negative webhook batch size",
+			modify: func(c *Config) {
+				c.WebhookURL = "https://hooks.example.com"
+				c.WebhookBatchSize = -1
+			},
+			expectedError: "invalid webhook batch size",
+		},
+		{
+			name: "negative webhook delivery timeout",
+			modify: func(c *Config) {
+				c.WebhookURL = "https://hooks.example.com"
+				c.WebhookDeliveryTimeout = -time.Second
+			},
+			expectedError: "invalid webhook delivery timeout",
+		},
+		{
+			name: "negative webhook diff concurrency",
+			modify: func(c *Config) {
+				c.WebhookURL = "https://hooks.example.com"
+				c.WebhookDiffConcurrency = -1
+			},
+			expectedError: "invalid webhook diff concurrency",
+		},
+		{
+			name:          "S3 prefix without bucket",
+			modify:        func(c *Config) { c.S3ExportPrefix = "vulnrelay" },
+			expectedError: "S3 export prefix is set without an S3 export bucket",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := validConfig()
+			tt.modify(config)
+
+			err := config.Validate()
+			if tt.expectedError == "" {
+				if err != nil {
+					t.Fatalf("Expected a valid configuration, got: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected error containing %q, got none", tt.expectedError)
+			}
+			if !strings.Contains(err.Error(), tt.expectedError) {
+				t.Errorf("Expected error containing %q, got: %v", tt.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestConfigValidateReportsAllProblems(t *testing.T) {
+	config := &Config{
+		Mode:                "local",
+		VulnerabilitySource: "ecr",
+		ScanErrorThreshold:  2,
+	}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Expected validation errors")
+	}
+	for _, expected := range []string{"ECR account ID and region are required", "image list file is required", "invalid scan error threshold"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error containing %q, got: %v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+// ABOUTME: InfluxDB line protocol exposition of the vulnerability data.
+// ABOUTME: Serves per-image severity counts on /metrics/influx for Telegraf and InfluxDB.
+
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// influxMeasurement is the measurement of the per-image lines
+const influxMeasurement = "ecr_image_vulnerabilities"
+
+var (
+	// influxKeyEscaper escapes measurements, tag keys, tag values and field keys
+	influxKeyEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", `\n`)
+
+	// influxStringEscaper escapes string field values
+	influxStringEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+)
+
+// InfluxHandler serves the data behind /metrics in the InfluxDB line protocol,
+// one line per image, for teams collecting with Telegraf instead of Prometheus
+type InfluxHandler struct {
+	collector       VulnerabilityDataProvider
+	clusterName     string
+	freshOnly       bool
+	healthyStatuses map[string]bool
+	logger          *logrus.Logger
+}
+
+// NewInfluxHandler creates a line protocol handler; of the Options it honors
+// ClusterName, FreshOnly and HealthyScanStatuses like the metrics handler
+func NewInfluxHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *InfluxHandler {
+	healthyScanStatuses := options.HealthyScanStatuses
+	if len(healthyScanStatuses) == 0 {
+		healthyScanStatuses = DefaultHealthyScanStatuses
+	}
+	healthyStatuses := make(map[string]bool, len(healthyScanStatuses))
+	for _, status := range healthyScanStatuses {
+		healthyStatuses[strings.ToUpper(status)] = true
+	}
+
+	return &InfluxHandler{
+		collector:       collector,
+		clusterName:     options.ClusterName,
+		freshOnly:       options.FreshOnly,
+		healthyStatuses: healthyStatuses,
+		logger:          logger,
+	}
+}
+
+func (h *InfluxHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vulnerabilityData, lastCollectionTime := h.collector.GetVulnerabilityData()
+
+	imageURIs := make([]string, 0, len(vulnerabilityData))
+	for imageURI := range vulnerabilityData {
+		imageURIs = append(imageURIs, imageURI)
+	}
+	sort.Strings(imageURIs)
+
+	// Lines are stamped with the collection time; without one, InfluxDB uses
+	// the time it receives them
+	timestamp := ""
+	if !lastCollectionTime.IsZero() {
+		timestamp = fmt.Sprintf(" %d", lastCollectionTime.UnixNano())
+	}
+
+	var body strings.Builder
+	for _, imageURI := range imageURIs {
+		vulnData := vulnerabilityData[imageURI]
+		if vulnData.ImageVulnerability == nil || (h.freshOnly && vulnData.Stale) {
+			continue
+		}
+		body.WriteString(h.imageLine(imageURI, vulnData))
+		body.WriteString(timestamp)
+		body.WriteByte('\n')
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if _, err := w.Write([]byte(body.String())); err != nil {
+		h.logger.WithError(err).Error("Failed to write InfluxDB line protocol response")
+	}
+}
+
+// imageLine formats an image's line without its timestamp: the image context
+// as tags and the severity counts, their total and the scan status as fields
+func (h *InfluxHandler) imageLine(imageURI string, vulnData *types.ImageVulnerabilityData) string {
+	tags := map[string]string{
+		"image_uri":     imageURI,
+		"namespace":     vulnData.Namespace,
+		"workload":      vulnData.Workload,
+		"workload_type": vulnData.WorkloadType,
+		"cluster":       h.clusterName,
+	}
+	if repo, tag, err := parseImageURI(imageURI); err == nil {
+		tags["repository"] = repo
+		tags["tag"] = tag
+	}
+
+	var line strings.Builder
+	line.WriteString(influxKeyEscaper.Replace(influxMeasurement))
+	for _, key := range sortedKeys(tags) {
+		// The line protocol doesn't allow empty tag values
+		if tags[key] == "" {
+			continue
+		}
+		fmt.Fprintf(&line, ",%s=%s", influxKeyEscaper.Replace(key), influxKeyEscaper.Replace(tags[key]))
+	}
+
+	total := 0
+	counts := make(map[string]int, len(vulnData.Vulnerabilities))
+	for severity, count := range vulnData.Vulnerabilities {
+		counts[strings.ToLower(severity)] = count
+		total += count
+	}
+	healthy := 0
+	if h.healthyStatuses[vulnData.ScanStatus] {
+		healthy = 1
+	}
+
+	fmt.Fprintf(&line, " total=%di,scan_healthy=%di,scan_status=\"%s\"", total, healthy, influxStringEscaper.Replace(vulnData.ScanStatus))
+	for _, severity := range sortedKeys(counts) {
+		fmt.Fprintf(&line, ",%s=%di", influxKeyEscaper.Replace(severity), counts[severity])
+	}
+	return line.String()
+}
+
+// CreateInfluxHandler creates a standard HTTP handler that can be used with http.ServeMux
+func CreateInfluxHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	influxHandler := NewInfluxHandler(dataProvider, options, logger)
+	return influxHandler.ServeHTTP
+}
@@ -0,0 +1,119 @@
+// ABOUTME: Unit tests for the InfluxDB line protocol handler.
+// ABOUTME: Tests measurement, tag and field formatting, escaping and the honored options.
+
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// influxTestData returns two images, one with context that needs escaping
+func influxTestData() map[string]*types.ImageVulnerabilityData {
+	return map[string]*types.ImageVulnerabilityData{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/test-app:v1.0.0": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/test-app:v1.0.0",
+				Vulnerabilities: map[string]int{"CRITICAL": 2, "HIGH": 3, "MEDIUM": 1},
+				ScanStatus:      "COMPLETE",
+			},
+			ImageInfo: types.ImageInfo{Namespace: "production", Workload: "test-app", WorkloadType: "Deployment"},
+		},
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/batch:v2": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:   "123456789012.dkr.ecr.us-east-1.amazonaws.com/batch:v2",
+				ScanStatus: "FAILED",
+			},
+			ImageInfo: types.ImageInfo{Namespace: "jobs", Workload: "nightly report,v2", WorkloadType: "CronJob"},
+			Stale:     true,
+		},
+	}
+}
+
+func TestInfluxHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collected := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+	collector := &MockVulnerabilityDataProvider{data: influxTestData(), lastUpdated: collected}
+
+	rr := httptest.NewRecorder()
+	NewInfluxHandler(collector, Options{}, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/metrics/influx", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "text/plain; charset=utf-8" {
+		t.Errorf("Expected text/plain content type, got %q", contentType)
+	}
+
+	// Lines are ordered by image URI, with tags and severity fields sorted
+	expected := []string{
+		`ecr_image_vulnerabilities,image_uri=123456789012.dkr.ecr.us-east-1.amazonaws.com/batch:v2,namespace=jobs,repository=batch,tag=v2,workload=nightly\ report\,v2,workload_type=CronJob total=0i,scan_healthy=0i,scan_status="FAILED" 1736937000000000000`,
+		`ecr_image_vulnerabilities,image_uri=123456789012.dkr.ecr.us-east-1.amazonaws.com/test-app:v1.0.0,namespace=production,repository=test-app,tag=v1.0.0,workload=test-app,workload_type=Deployment total=6i,scan_healthy=1i,scan_status="COMPLETE",critical=2i,high=3i,medium=1i 1736937000000000000`,
+	}
+	if got := strings.Split(strings.TrimSuffix(rr.Body.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected line protocol output:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestInfluxHandlerOptions(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name      string
+		options   Options
+		collected time.Time
+		contains  []string
+		excludes  []string
+	}{
+		{
+			name:     "cluster tag",
+			options:  Options{ClusterName: "prod-eu"},
+			contains: []string{"ecr_image_vulnerabilities,cluster=prod-eu,image_uri="},
+		},
+		{
+			name:     "fresh only",
+			options:  Options{FreshOnly: true},
+			contains: []string{"repository=test-app"},
+			excludes: []string{"repository=batch"},
+		},
+		{
+			name:     "healthy scan statuses",
+			options:  Options{HealthyScanStatuses: []string{"complete", "failed"}},
+			contains: []string{`scan_healthy=1i,scan_status="FAILED"`},
+		},
+		{
+			name:     "no timestamp before the first collection",
+			contains: []string{"medium=1i\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &MockVulnerabilityDataProvider{data: influxTestData(), lastUpdated: tt.collected}
+			rr := httptest.NewRecorder()
+			CreateInfluxHandler(collector, tt.options, logger)(rr, httptest.NewRequest("GET", "/metrics/influx", nil))
+
+			body := rr.Body.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(body, s) {
+					t.Errorf("Expected output to contain %q, got:\n%s", s, body)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(body, s) {
+					t.Errorf("Expected output not to contain %q, got:\n%s", s, body)
+				}
+			}
+		})
+	}
+}
@@ -4,10 +4,15 @@
 package metrics
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/jfeddern/VulnRelay/internal/types"
 
@@ -20,230 +25,910 @@ type VulnerabilityDataProvider interface {
 	GetVulnerabilityData() (map[string]*types.ImageVulnerabilityData, time.Time)
 }
 
+// CollectionStatsProvider is optionally implemented by data providers that
+// track collection cycle statistics
+type CollectionStatsProvider interface {
+	GetCollectionDurationEMA() time.Duration
+	GetScrapeInterval() time.Duration
+	GetLastTickTime() time.Time
+}
+
+// DiscoveryStatusProvider is optionally implemented by data providers that
+// track whether image discovery keeps coming back empty
+type DiscoveryStatusProvider interface {
+	IsDiscoveryEmpty() bool
+}
+
+// RegistryScanTypeProvider is optionally implemented by data providers that
+// know the registry's scan type
+type RegistryScanTypeProvider interface {
+	GetRegistryScanType() string
+}
+
+// RegistryCollectionProvider is optionally implemented by data providers that
+// collect registries independently and record each registry's outcome
+type RegistryCollectionProvider interface {
+	GetRegistryCollections() []types.RegistryCollection
+}
+
+// FetchConcurrencyProvider is optionally implemented by data providers that
+// adapt the concurrency of source calls per registry
+type FetchConcurrencyProvider interface {
+	GetFetchConcurrency() map[string]int
+}
+
+// maxLabelValueLength caps label values derived from scan data
+const maxLabelValueLength = 200
+
+// unknownFindingType labels findings that carry no type, as with basic scanning
+const unknownFindingType = "UNKNOWN"
+
+// DroppableLabels lists the per-image context labels that Options.DropLabels
+// may remove; image_uri and the metric-specific labels always stay
+var DroppableLabels = []string{"repository", "tag", "namespace", "workload", "workload_type"}
+
+// IsDroppableLabel reports whether a label may be listed in Options.DropLabels
+func IsDroppableLabel(label string) bool {
+	for _, droppable := range DroppableLabels {
+		if label == droppable {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configures the metrics handler
+type Options struct {
+	// RiskScoreWeights overrides the per-severity weights of ecr_image_risk_score;
+	// severities not listed keep their default weight
+	RiskScoreWeights map[string]float64
+
+	// MaxSeriesPerMetric caps the series emitted per metric on each scrape;
+	// series beyond it are dropped and reported via ecr_metrics_truncated
+	// (0 = unlimited)
+	MaxSeriesPerMetric int
+
+	// ReleaseLabel adds a release label with the workload's Helm release to
+	// every per-image metric
+	ReleaseLabel bool
+
+	// FreshOnly omits images whose data is stale, i.e. kept from a previous
+	// collection after their fetch failed
+	FreshOnly bool
+
+	// PlatformLabel adds a platform label with the os/arch scanned for
+	// multi-arch images to every per-image metric
+	PlatformLabel bool
+
+	// MaxImageURILength caps the length of the image_uri label; longer URIs
+	// are truncated (0 = the default label value cap)
+	MaxImageURILength int
+
+	// DropLabels removes these DroppableLabels from every per-image metric to
+	// reduce cardinality, e.g. "tag" when deploying by digest
+	DropLabels []string
+
+	// Collectors are served alongside the vulnerability metrics, e.g. the
+	// AWS API request metrics; they are not subject to MaxSeriesPerMetric
+	Collectors []prometheus.Collector
+
+	// ClusterName adds a constant cluster label with this value to every
+	// served metric, including Collectors, so several clusters can share one
+	// Prometheus (empty = no label)
+	ClusterName string
+
+	// DescriptionInfo moves the CVE description from the description label of
+	// ecr_vulnerability_info to ecr_cve_description_info, which has one series
+	// per CVE instead of one per finding and image
+	DescriptionInfo bool
+
+	// HealthyScanStatuses lists the scan statuses ecr_image_scan_status
+	// reports as 1, e.g. ACTIVE for Inspector continuous scanning; empty
+	// uses DefaultHealthyScanStatuses
+	HealthyScanStatuses []string
+}
+
+// DefaultHealthyScanStatuses are the scan statuses reported as healthy by default
+var DefaultHealthyScanStatuses = []string{"COMPLETE"}
+
+// DefaultRiskScoreWeights returns the default per-severity risk score weights
+func DefaultRiskScoreWeights() map[string]float64 {
+	return map[string]float64{
+		"CRITICAL": 10,
+		"HIGH":     5,
+		"MEDIUM":   2,
+		"LOW":      1,
+	}
+}
+
+// MetricsHandler is a prometheus.Collector that emits metrics lazily from the
+// live data snapshot on each scrape, instead of resetting and repopulating
+// GaugeVecs that hold every series in memory
 type MetricsHandler struct {
 	collector VulnerabilityDataProvider
 	logger    *logrus.Logger
-
-	// Prometheus metrics
-	vulnerabilityCount *prometheus.GaugeVec
-	lastScanTime       *prometheus.GaugeVec
-	scanStatus         *prometheus.GaugeVec
-	collectionInfo     *prometheus.GaugeVec
+	now       func() time.Time   // Clock used to compute finding ages
+	weights   map[string]float64 // Risk score weight per severity
+
+	maxSeries   int                         // Series cap per metric (0 = unlimited)
+	metricNames map[*prometheus.Desc]string // Metric name of each descriptor
+	truncated   *prometheus.Desc
+
+	releaseLabel      bool // Append the Helm release to per-image label sets
+	platformLabel     bool // Append the scanned platform to per-image label sets
+	freshOnly         bool // Skip images flagged as stale
+	maxImageURILength int  // Cap on the image_uri label value
+
+	// keptLabels holds, per descriptor with dropped labels, the positions of
+	// the label values that are kept
+	keptLabels map[*prometheus.Desc][]int
+
+	collectors      []prometheus.Collector // Served alongside on each scrape
+	clusterName     string                 // Constant cluster label value, if set
+	descriptionInfo bool                   // Emit descriptions per CVE, not per finding
+	healthyStatuses map[string]bool        // Scan statuses reported as 1
+
+	// Prometheus metric descriptors
+	vulnerabilityCount *prometheus.Desc
+	lastScanTime       *prometheus.Desc
+	scanStatus         *prometheus.Desc
+	riskScore          *prometheus.Desc
+	findingTypeCount   *prometheus.Desc
+	workloadCount      *prometheus.Desc
+	unfixableCount     *prometheus.Desc
+	collectionInfo     *prometheus.Desc
+	collectionAge      *prometheus.Desc
+
+	collectionDurationEMA *prometheus.Desc
+	scrapeInterval        *prometheus.Desc
+	lastTick              *prometheus.Desc
+	acceptedCount         *prometheus.Desc
+	discoveryEmpty        *prometheus.Desc
+	registryScanType      *prometheus.Desc
+	registrySuccess       *prometheus.Desc
+	registryImages        *prometheus.Desc
+	fetchConcurrency      *prometheus.Desc
 
 	// Detailed vulnerability metrics
-	vulnerabilityInfo    *prometheus.GaugeVec
-	packageVulnerability *prometheus.GaugeVec
-	fixAvailability      *prometheus.GaugeVec
-	exploitAvailability  *prometheus.GaugeVec
+	vulnerabilityInfo    *prometheus.Desc
+	packageVulnerability *prometheus.Desc
+	fixAvailability      *prometheus.Desc
+	exploitAvailability  *prometheus.Desc
+	vulnerabilityAge     *prometheus.Desc
+	cveDescription       *prometheus.Desc
 }
 
-func NewMetricsHandler(collector VulnerabilityDataProvider, logger *logrus.Logger) *MetricsHandler {
+func NewMetricsHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *MetricsHandler {
+	weights := DefaultRiskScoreWeights()
+	for severity, weight := range options.RiskScoreWeights {
+		weights[severity] = weight
+	}
+
+	dropLabels := make(map[string]bool)
+	for _, label := range options.DropLabels {
+		if IsDroppableLabel(label) {
+			dropLabels[label] = true
+		}
+	}
+	if options.DescriptionInfo {
+		// Only ecr_vulnerability_info has a description label
+		dropLabels["description"] = true
+	}
+
+	statuses := options.HealthyScanStatuses
+	if len(statuses) == 0 {
+		statuses = DefaultHealthyScanStatuses
+	}
+	healthyStatuses := make(map[string]bool, len(statuses))
+	for _, status := range statuses {
+		healthyStatuses[strings.ToUpper(status)] = true
+	}
+
+	// Metric names are recorded per descriptor for truncation reporting
+	names := make(map[*prometheus.Desc]string)
+	keptLabels := make(map[*prometheus.Desc][]int)
+	newDesc := func(name, help string, labels []string) *prometheus.Desc {
+		// Per-image metrics are the ones labeled by image_uri
+		var kept []int
+		if len(labels) > 0 && labels[0] == "image_uri" {
+			if options.ReleaseLabel {
+				labels = append(labels, "release")
+			}
+			if options.PlatformLabel {
+				labels = append(labels, "platform")
+			}
+			if len(dropLabels) > 0 {
+				var keptNames []string
+				for i, label := range labels {
+					if !dropLabels[label] {
+						kept = append(kept, i)
+						keptNames = append(keptNames, label)
+					}
+				}
+				labels = keptNames
+			}
+		}
+		desc := prometheus.NewDesc(name, help, labels, nil)
+		names[desc] = name
+		if kept != nil {
+			keptLabels[desc] = kept
+		}
+		return desc
+	}
+
+	maxImageURILength := options.MaxImageURILength
+	if maxImageURILength <= 0 {
+		maxImageURILength = maxLabelValueLength
+	}
+
 	return &MetricsHandler{
-		collector: collector,
-		logger:    logger,
+		collector:   collector,
+		logger:      logger,
+		now:         time.Now,
+		weights:     weights,
+		maxSeries:   options.MaxSeriesPerMetric,
+		metricNames: names,
+
+		releaseLabel:      options.ReleaseLabel,
+		platformLabel:     options.PlatformLabel,
+		freshOnly:         options.FreshOnly,
+		maxImageURILength: maxImageURILength,
+		keptLabels:        keptLabels,
+
+		collectors:      options.Collectors,
+		clusterName:     options.ClusterName,
+		descriptionInfo: options.DescriptionInfo,
+		healthyStatuses: healthyStatuses,
+
+		truncated: prometheus.NewDesc(
+			"ecr_metrics_truncated",
+			"Number of series dropped from a metric because it exceeded the configured maximum series per metric",
+			[]string{"metric"},
+			nil,
+		),
 
-		vulnerabilityCount: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_image_vulnerability_count",
-				Help: "Number of vulnerabilities found in ECR images by severity",
-			},
+		vulnerabilityCount: newDesc(
+			"ecr_image_vulnerability_count",
+			"Number of vulnerabilities found in ECR images by severity",
 			[]string{"image_uri", "repository", "tag", "severity", "namespace", "workload", "workload_type"},
 		),
 
-		lastScanTime: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_image_last_scan_timestamp",
-				Help: "Timestamp of the last vulnerability scan for ECR images",
-			},
+		lastScanTime: newDesc(
+			"ecr_image_last_scan_timestamp",
+			"Timestamp of the last vulnerability scan for ECR images",
 			[]string{"image_uri", "repository", "tag", "namespace", "workload", "workload_type"},
 		),
 
-		scanStatus: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_image_scan_status",
-				Help: "Status of vulnerability scan for ECR images (1=COMPLETE, 0=other)",
-			},
+		scanStatus: newDesc(
+			"ecr_image_scan_status",
+			"Status of vulnerability scan for ECR images (1=healthy status, COMPLETE by default, 0=other)",
 			[]string{"image_uri", "repository", "tag", "status", "namespace", "workload", "workload_type"},
 		),
 
-		collectionInfo: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_vulnerability_collection_info",
-				Help: "Information about vulnerability data collection",
-			},
+		riskScore: newDesc(
+			"ecr_image_risk_score",
+			"Weighted sum of vulnerability counts by severity for ECR images",
+			[]string{"image_uri", "repository", "tag", "namespace", "workload", "workload_type"},
+		),
+
+		findingTypeCount: newDesc(
+			"ecr_image_finding_type_count",
+			"Number of active findings in ECR images by finding type (e.g. PACKAGE_VULNERABILITY)",
+			[]string{"image_uri", "repository", "tag", "type", "namespace", "workload", "workload_type"},
+		),
+
+		workloadCount: newDesc(
+			"ecr_image_workload_count",
+			"Number of distinct workloads using ECR images",
+			[]string{"image_uri", "repository", "tag", "namespace", "workload", "workload_type"},
+		),
+
+		unfixableCount: newDesc(
+			"ecr_image_unfixable_vulnerability_count",
+			"Number of active findings without an available fix in ECR images by severity",
+			[]string{"image_uri", "repository", "tag", "severity", "namespace", "workload", "workload_type"},
+		),
+
+		collectionInfo: newDesc(
+			"ecr_vulnerability_collection_info",
+			"Information about vulnerability data collection",
 			[]string{"info_type"},
 		),
 
-		vulnerabilityInfo: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_vulnerability_info",
-				Help: "Detailed vulnerability information with CVE details",
-			},
+		collectionAge: newDesc(
+			"ecr_vulnerability_seconds_since_last_collection",
+			"Seconds since the last successful vulnerability collection, computed at scrape time",
+			nil,
+		),
+
+		collectionDurationEMA: newDesc(
+			"ecr_vulnerability_collection_duration_ema_seconds",
+			"Exponential moving average of vulnerability collection cycle duration in seconds",
+			nil,
+		),
+
+		scrapeInterval: newDesc(
+			"ecr_vulnerability_scrape_interval_seconds",
+			"Configured interval between vulnerability collection cycles in seconds",
+			nil,
+		),
+
+		lastTick: newDesc(
+			"ecr_vulnerability_last_tick_timestamp",
+			"Timestamp when the last vulnerability collection cycle was triggered",
+			nil,
+		),
+
+		acceptedCount: newDesc(
+			"ecr_vulnerability_accepted_count",
+			"Number of findings suppressed by the accepted CVEs list by severity",
+			[]string{"severity"},
+		),
+
+		discoveryEmpty: newDesc(
+			"ecr_discovery_empty",
+			"Whether image discovery found no images for several consecutive collection cycles (1=empty, 0=images found)",
+			nil,
+		),
+
+		registryScanType: newDesc(
+			"ecr_registry_scan_type",
+			"Scan type of the registry (BASIC or ENHANCED), read at startup; always 1",
+			[]string{"scan_type"},
+		),
+
+		registrySuccess: newDesc(
+			"ecr_registry_collection_success",
+			"Whether the registry's part of the last collection succeeded (1=success, 0=every image failed)",
+			[]string{"registry"},
+		),
+
+		registryImages: newDesc(
+			"ecr_registry_images",
+			"Images of the registry in the last collection by result (collected or failed)",
+			[]string{"registry", "result"},
+		),
+
+		fetchConcurrency: newDesc(
+			"ecr_registry_fetch_concurrency",
+			"Current limit on concurrent vulnerability source calls for the registry, lowered while the source throttles",
+			[]string{"registry"},
+		),
+
+		vulnerabilityInfo: newDesc(
+			"ecr_vulnerability_info",
+			"Detailed vulnerability information with CVE details",
 			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "description", "status", "type", "namespace", "workload", "workload_type"},
 		),
 
-		packageVulnerability: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_package_vulnerability",
-				Help: "Package-level vulnerability information with fix details",
-			},
+		packageVulnerability: newDesc(
+			"ecr_package_vulnerability",
+			"Package-level vulnerability information with fix details",
 			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "package_name", "package_version", "fix_version", "namespace", "workload", "workload_type"},
 		),
 
-		fixAvailability: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_vulnerability_fix_available",
-				Help: "Fix availability for vulnerabilities (1=YES, 0.5=PARTIAL, 0=NO)",
-			},
+		fixAvailability: newDesc(
+			"ecr_vulnerability_fix_available",
+			"Fix availability for vulnerabilities (1=YES, 0.5=PARTIAL, 0=NO)",
 			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "fix_status", "namespace", "workload", "workload_type"},
 		),
 
-		exploitAvailability: prometheus.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "ecr_vulnerability_exploit_available",
-				Help: "Exploit availability for vulnerabilities (1=YES, 0=NO)",
-			},
+		exploitAvailability: newDesc(
+			"ecr_vulnerability_exploit_available",
+			"Exploit availability for vulnerabilities (1=YES, 0=NO)",
 			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "exploit_status", "namespace", "workload", "workload_type"},
 		),
+
+		vulnerabilityAge: newDesc(
+			"ecr_vulnerability_age_seconds",
+			"Seconds since the vulnerability was first seen on the image",
+			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "namespace", "workload", "workload_type"},
+		),
+
+		cveDescription: newDesc(
+			"ecr_cve_description_info",
+			"Description of each CVE found in any image; always 1",
+			[]string{"cve_name", "description"},
+		),
 	}
 }
 
-func (m *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Create a new registry for this request to avoid conflicts
-	registry := prometheus.NewRegistry()
+// Describe implements prometheus.Collector
+func (m *MetricsHandler) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.vulnerabilityCount
+	ch <- m.lastScanTime
+	ch <- m.scanStatus
+	ch <- m.riskScore
+	ch <- m.findingTypeCount
+	ch <- m.workloadCount
+	ch <- m.unfixableCount
+	ch <- m.collectionInfo
+	ch <- m.collectionAge
+	ch <- m.collectionDurationEMA
+	ch <- m.scrapeInterval
+	ch <- m.lastTick
+	ch <- m.acceptedCount
+	ch <- m.discoveryEmpty
+	ch <- m.registryScanType
+	ch <- m.registrySuccess
+	ch <- m.registryImages
+	ch <- m.fetchConcurrency
+	ch <- m.vulnerabilityInfo
+	ch <- m.packageVulnerability
+	ch <- m.fixAvailability
+	ch <- m.exploitAvailability
+	ch <- m.vulnerabilityAge
+	ch <- m.cveDescription
+	ch <- m.truncated
+}
 
-	// Register our metrics
-	registry.MustRegister(m.vulnerabilityCount)
-	registry.MustRegister(m.lastScanTime)
-	registry.MustRegister(m.scanStatus)
-	registry.MustRegister(m.collectionInfo)
-	registry.MustRegister(m.vulnerabilityInfo)
-	registry.MustRegister(m.packageVulnerability)
-	registry.MustRegister(m.fixAvailability)
-	registry.MustRegister(m.exploitAvailability)
-
-	// Reset all metrics to avoid stale data
-	m.vulnerabilityCount.Reset()
-	m.lastScanTime.Reset()
-	m.scanStatus.Reset()
-	m.collectionInfo.Reset()
-	m.vulnerabilityInfo.Reset()
-	m.packageVulnerability.Reset()
-	m.fixAvailability.Reset()
-	m.exploitAvailability.Reset()
+// Collect implements prometheus.Collector, emitting metrics image by image
+func (m *MetricsHandler) Collect(ch chan<- prometheus.Metric) {
+	m.collect(ch, nil)
+}
 
+// collect emits the metrics of all images. With severities set, series
+// labeled by severity are only emitted for those severities.
+func (m *MetricsHandler) collect(ch chan<- prometheus.Metric, severities map[string]bool) {
 	// Get current vulnerability data
 	vulnerabilityData, lastCollectionTime := m.collector.GetVulnerabilityData()
+	now := m.now()
 
-	// Populate metrics
-	for imageURI, vulnDataWithInfo := range vulnerabilityData {
-		vulnData := vulnDataWithInfo.ImageVulnerability
-		namespace := vulnDataWithInfo.Namespace
-		workload := vulnDataWithInfo.Workload
-		workloadType := vulnDataWithInfo.WorkloadType
-
-		repo, tag, err := parseImageURI(imageURI)
-		if err != nil {
-			m.logger.WithError(err).WithField("image_uri", imageURI).Error("Failed to parse image URI for metrics")
+	// Images are visited in a stable order so a series cap keeps the same
+	// series from one scrape to the next
+	imageURIs := make([]string, 0, len(vulnerabilityData))
+	for imageURI := range vulnerabilityData {
+		imageURIs = append(imageURIs, imageURI)
+	}
+	sort.Strings(imageURIs)
+
+	batch := newMetricBatch(m.logger, m.maxSeries)
+	acceptedCounts := make(map[string]int)
+	descriptions := make(map[string]string)
+	for _, imageURI := range imageURIs {
+		vulnDataWithInfo := vulnerabilityData[imageURI]
+		if m.freshOnly && vulnDataWithInfo.Stale {
 			continue
 		}
+		m.collectImage(batch, imageURI, vulnDataWithInfo, now, severities)
+		batch.flush(ch)
 
-		// Vulnerability counts by severity
-		for severity, count := range vulnData.Vulnerabilities {
-			m.vulnerabilityCount.WithLabelValues(imageURI, repo, tag, severity, namespace, workload, workloadType).Set(float64(count))
+		for _, finding := range vulnDataWithInfo.Findings {
+			if !includesSeverity(severities, finding.Severity) {
+				continue
+			}
+			if finding.Accepted {
+				acceptedCounts[finding.Severity]++
+			}
+			// The first image in URI order provides the description
+			if _, seen := descriptions[finding.Name]; m.descriptionInfo && !seen && finding.Description != "" {
+				descriptions[finding.Name] = finding.Description
+			}
 		}
+	}
 
-		// Last scan time
-		if vulnData.LastScanTime != nil {
-			if scanTime, err := time.Parse("2006-01-02T15:04:05Z", *vulnData.LastScanTime); err == nil {
-				m.lastScanTime.WithLabelValues(imageURI, repo, tag, namespace, workload, workloadType).Set(float64(scanTime.Unix()))
-			}
+	// Descriptions by CVE, in a stable order for the series cap
+	cves := make([]string, 0, len(descriptions))
+	for cve := range descriptions {
+		cves = append(cves, cve)
+	}
+	sort.Strings(cves)
+	for _, cve := range cves {
+		batch.add(m.cveDescription, 1, sanitizeLabelValue(cve), sanitizeLabelValue(descriptions[cve]))
+	}
+
+	// Accepted findings, counted separately from the active counts
+	for severity, count := range acceptedCounts {
+		batch.add(m.acceptedCount, float64(count), severity)
+	}
+
+	// Collection info
+	batch.add(m.collectionInfo, float64(lastCollectionTime.Unix()), "last_collection_timestamp")
+	batch.add(m.collectionInfo, float64(len(vulnerabilityData)), "images_monitored")
+	if !lastCollectionTime.IsZero() {
+		batch.add(m.collectionAge, now.Sub(lastCollectionTime).Seconds())
+	}
+
+	if stats, ok := m.collector.(CollectionStatsProvider); ok {
+		batch.add(m.collectionDurationEMA, stats.GetCollectionDurationEMA().Seconds())
+		batch.add(m.scrapeInterval, stats.GetScrapeInterval().Seconds())
+
+		lastTick := float64(0)
+		if tickTime := stats.GetLastTickTime(); !tickTime.IsZero() {
+			lastTick = float64(tickTime.Unix())
 		}
+		batch.add(m.lastTick, lastTick)
+	}
 
-		// Scan status (1 for COMPLETE, 0 for others)
-		statusValue := float64(0)
-		if vulnData.ScanStatus == "COMPLETE" {
-			statusValue = 1
+	if status, ok := m.collector.(DiscoveryStatusProvider); ok {
+		empty := float64(0)
+		if status.IsDiscoveryEmpty() {
+			empty = 1
 		}
-		m.scanStatus.WithLabelValues(imageURI, repo, tag, vulnData.ScanStatus, namespace, workload, workloadType).Set(statusValue)
-
-		// Detailed vulnerability information
-		for _, finding := range vulnData.Findings {
-			// Sanitize strings for Prometheus labels (remove newlines, limit length)
-			cve := sanitizeLabelValue(finding.Name)
-			description := sanitizeLabelValue(finding.Description)
-			status := sanitizeLabelValue(finding.Status)
-			vulnType := sanitizeLabelValue(finding.Type)
-			packageName := sanitizeLabelValue(finding.PackageName)
-			packageVersion := sanitizeLabelValue(finding.PackageVersion)
-			fixVersion := sanitizeLabelValue(finding.FixVersion)
-
-			// Vulnerability info metric (always 1 to indicate presence)
-			m.vulnerabilityInfo.WithLabelValues(
-				imageURI, repo, tag, cve, finding.Severity, description, status, vulnType, namespace, workload, workloadType,
-			).Set(1)
-
-			// Package vulnerability metric (Inspector score if available, otherwise 1)
-			score := finding.Score
-			if score == 0 {
-				score = 1 // Default for basic scanning
-			}
-			m.packageVulnerability.WithLabelValues(
-				imageURI, repo, tag, cve, finding.Severity, packageName, packageVersion, fixVersion, namespace, workload, workloadType,
-			).Set(score)
-
-			// Fix availability metric
-			fixValue := float64(0)
-			switch finding.FixAvailable {
-			case "YES":
-				fixValue = 1
-			case "PARTIAL":
-				fixValue = 0.5
-			case "NO":
-				fixValue = 0
+		batch.add(m.discoveryEmpty, empty)
+	}
+
+	if scanning, ok := m.collector.(RegistryScanTypeProvider); ok {
+		if scanType := scanning.GetRegistryScanType(); scanType != "" {
+			batch.add(m.registryScanType, 1, scanType)
+		}
+	}
+
+	if registries, ok := m.collector.(RegistryCollectionProvider); ok {
+		for _, registry := range registries.GetRegistryCollections() {
+			success := float64(0)
+			if registry.Success {
+				success = 1
 			}
-			m.fixAvailability.WithLabelValues(
-				imageURI, repo, tag, cve, finding.Severity, finding.FixAvailable, namespace, workload, workloadType,
-			).Set(fixValue)
-
-			// Exploit availability metric
-			exploitValue := float64(0)
-			if finding.ExploitAvailable == "YES" {
-				exploitValue = 1
+			batch.add(m.registrySuccess, success, registry.Registry)
+			batch.add(m.registryImages, float64(registry.Collected), registry.Registry, "collected")
+			batch.add(m.registryImages, float64(registry.Failed), registry.Registry, "failed")
+		}
+	}
+
+	if concurrency, ok := m.collector.(FetchConcurrencyProvider); ok {
+		for registry, limit := range concurrency.GetFetchConcurrency() {
+			batch.add(m.fetchConcurrency, float64(limit), registry)
+		}
+	}
+	batch.flush(ch)
+
+	m.reportTruncation(ch, batch.dropped)
+}
+
+// reportTruncation warns about metrics that hit the series cap and exposes the
+// number of dropped series per metric
+func (m *MetricsHandler) reportTruncation(ch chan<- prometheus.Metric, dropped map[*prometheus.Desc]int) {
+	for desc, count := range dropped {
+		name := m.metricNames[desc]
+		m.logger.WithFields(logrus.Fields{
+			"metric":         name,
+			"dropped_series": count,
+			"max_series":     m.maxSeries,
+		}).Warn("Metric exceeded the maximum series per metric; series were dropped")
+		ch <- prometheus.MustNewConstMetric(m.truncated, prometheus.GaugeValue, float64(count), name)
+	}
+}
+
+// collectImage adds all metrics for a single image to the batch
+func (m *MetricsHandler) collectImage(batch *metricBatch, imageURI string, vulnDataWithInfo *types.ImageVulnerabilityData, now time.Time, severities map[string]bool) {
+	vulnData := vulnDataWithInfo.ImageVulnerability
+	namespace := vulnDataWithInfo.Namespace
+	workload := vulnDataWithInfo.Workload
+	workloadType := vulnDataWithInfo.WorkloadType
+
+	repo, tag, err := parseImageURI(imageURI)
+	if err != nil {
+		m.logger.WithError(err).WithField("image_uri", imageURI).Error("Failed to parse image URI for metrics")
+		return
+	}
+	imageLabel := imageURILabel(imageURI, m.maxImageURILength)
+
+	// add appends the release and platform label values when enabled and
+	// removes the values of dropped labels
+	add := func(desc *prometheus.Desc, value float64, labelValues ...string) {
+		if m.releaseLabel {
+			labelValues = append(labelValues, sanitizeLabelValue(vulnDataWithInfo.Release))
+		}
+		if m.platformLabel {
+			// Empty for single-platform images
+			labelValues = append(labelValues, vulnData.Platform)
+		}
+		if kept, ok := m.keptLabels[desc]; ok {
+			values := make([]string, len(kept))
+			for i, index := range kept {
+				values[i] = labelValues[index]
 			}
-			m.exploitAvailability.WithLabelValues(
-				imageURI, repo, tag, cve, finding.Severity, finding.ExploitAvailable, namespace, workload, workloadType,
-			).Set(exploitValue)
+			labelValues = values
 		}
+		batch.add(desc, value, labelValues...)
 	}
 
-	// Collection info
-	m.collectionInfo.WithLabelValues("last_collection_timestamp").Set(float64(lastCollectionTime.Unix()))
-	m.collectionInfo.WithLabelValues("images_monitored").Set(float64(len(vulnerabilityData)))
+	// Vulnerability counts by severity, weighted into a single risk score
+	riskScore := float64(0)
+	for severity, count := range vulnData.Vulnerabilities {
+		if includesSeverity(severities, severity) {
+			add(m.vulnerabilityCount, float64(count), imageLabel, repo, tag, severity, namespace, workload, workloadType)
+		}
+		riskScore += m.weights[severity] * float64(count)
+	}
+	add(m.riskScore, riskScore, imageLabel, repo, tag, namespace, workload, workloadType)
+
+	// Blast radius; the workload labels name the first workload found
+	if vulnDataWithInfo.WorkloadCount > 0 {
+		add(m.workloadCount, float64(vulnDataWithInfo.WorkloadCount), imageLabel, repo, tag, namespace, workload, workloadType)
+	}
+
+	// Last scan time
+	if vulnData.LastScanTime != nil {
+		if scanTime, err := time.Parse("2006-01-02T15:04:05Z", *vulnData.LastScanTime); err == nil {
+			add(m.lastScanTime, float64(scanTime.Unix()), imageLabel, repo, tag, namespace, workload, workloadType)
+		}
+	}
+
+	// Scan status (1 for healthy statuses, 0 for others)
+	statusValue := float64(0)
+	if m.healthyStatuses[vulnData.ScanStatus] {
+		statusValue = 1
+	}
+	add(m.scanStatus, statusValue, imageLabel, repo, tag, vulnData.ScanStatus, namespace, workload, workloadType)
+
+	// Active findings by type; basic scanning leaves the type empty
+	typeCounts := make(map[string]int)
+	for _, finding := range vulnData.Findings {
+		if finding.Accepted {
+			continue
+		}
+		findingType := sanitizeLabelValue(finding.Type)
+		if findingType == "" {
+			findingType = unknownFindingType
+		}
+		typeCounts[findingType]++
+	}
+	findingTypes := make([]string, 0, len(typeCounts))
+	for findingType := range typeCounts {
+		findingTypes = append(findingTypes, findingType)
+	}
+	sort.Strings(findingTypes)
+	for _, findingType := range findingTypes {
+		add(m.findingTypeCount, float64(typeCounts[findingType]), imageLabel, repo, tag, findingType, namespace, workload, workloadType)
+	}
+
+	// Active findings the scanner reports no fix for; basic scanning doesn't
+	// report fix availability, so its findings are never counted
+	unfixableCounts := make(map[string]int)
+	for _, finding := range vulnData.Findings {
+		if !finding.Accepted && finding.FixAvailable == "NO" && includesSeverity(severities, finding.Severity) {
+			unfixableCounts[finding.Severity]++
+		}
+	}
+	for _, severity := range sortedKeys(unfixableCounts) {
+		add(m.unfixableCount, float64(unfixableCounts[severity]), imageLabel, repo, tag, severity, namespace, workload, workloadType)
+	}
+
+	// Detailed vulnerability information
+	for _, finding := range vulnData.Findings {
+		if !includesSeverity(severities, finding.Severity) {
+			continue
+		}
+
+		// Sanitize strings for Prometheus labels (remove newlines, limit length)
+		cve := sanitizeLabelValue(finding.Name)
+		description := sanitizeLabelValue(finding.Description)
+		status := sanitizeLabelValue(finding.Status)
+		vulnType := sanitizeLabelValue(finding.Type)
+		packageName := sanitizeLabelValue(finding.PackageName)
+		packageVersion := sanitizeLabelValue(finding.PackageVersion)
+		fixVersion := sanitizeLabelValue(finding.FixVersion)
+
+		// Vulnerability info metric (always 1 to indicate presence)
+		add(m.vulnerabilityInfo, 1,
+			imageLabel, repo, tag, cve, finding.Severity, description, status, vulnType, namespace, workload, workloadType)
+
+		// Package vulnerability metric (Inspector score if available, otherwise 1)
+		score := finding.Score
+		if score == 0 {
+			score = 1 // Default for basic scanning
+		}
+		add(m.packageVulnerability, score,
+			imageLabel, repo, tag, cve, finding.Severity, packageName, packageVersion, fixVersion, namespace, workload, workloadType)
+
+		// Fix availability metric
+		fixValue := float64(0)
+		switch finding.FixAvailable {
+		case "YES":
+			fixValue = 1
+		case "PARTIAL":
+			fixValue = 0.5
+		case "NO":
+			fixValue = 0
+		}
+		add(m.fixAvailability, fixValue,
+			imageLabel, repo, tag, cve, finding.Severity, finding.FixAvailable, namespace, workload, workloadType)
+
+		// Exploit availability metric
+		exploitValue := float64(0)
+		if finding.ExploitAvailable == "YES" {
+			exploitValue = 1
+		}
+		add(m.exploitAvailability, exploitValue,
+			imageLabel, repo, tag, cve, finding.Severity, finding.ExploitAvailable, namespace, workload, workloadType)
+
+		// Finding age, only known once the engine has recorded a first-seen time
+		if finding.FirstSeen != nil {
+			add(m.vulnerabilityAge, now.Sub(*finding.FirstSeen).Seconds(),
+				imageLabel, repo, tag, cve, finding.Severity, namespace, workload, workloadType)
+		}
+	}
+}
+
+// includesSeverity reports whether a severity passes a scrape's severity
+// filter; a nil filter includes every severity
+func includesSeverity(severities map[string]bool, severity string) bool {
+	return severities == nil || severities[strings.ToUpper(severity)]
+}
+
+// parseSeverityFilter parses the comma-separated severity scrape parameter,
+// returning nil when it lists none
+func parseSeverityFilter(value string) map[string]bool {
+	var severities map[string]bool
+	for _, severity := range strings.Split(value, ",") {
+		severity = strings.ToUpper(strings.TrimSpace(severity))
+		if severity == "" {
+			continue
+		}
+		if severities == nil {
+			severities = make(map[string]bool)
+		}
+		severities[severity] = true
+	}
+	return severities
+}
+
+// severityScrape serves the metrics of one scrape limited to a set of severities
+type severityScrape struct {
+	*MetricsHandler
+	severities map[string]bool
+}
+
+// Collect implements prometheus.Collector
+func (s severityScrape) Collect(ch chan<- prometheus.Metric) {
+	s.collect(ch, s.severities)
+}
+
+func (m *MetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// ?severity=CRITICAL,HIGH limits the severity-labeled series, e.g. to
+	// scrape high-severity data into a separate tenant
+	var collector prometheus.Collector = m
+	if severities := parseSeverityFilter(r.URL.Query().Get("severity")); severities != nil {
+		collector = severityScrape{MetricsHandler: m, severities: severities}
+	}
+
+	// Create a new registry for this request; metrics are generated during the scrape
+	registry := prometheus.NewRegistry()
+	var registerer prometheus.Registerer = registry
+	if m.clusterName != "" {
+		registerer = prometheus.WrapRegistererWith(prometheus.Labels{"cluster": m.clusterName}, registry)
+	}
+	registerer.MustRegister(collector)
+	registerer.MustRegister(m.collectors...)
 
 	// Serve metrics
 	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
 	handler.ServeHTTP(w, r)
 }
 
-// sanitizeLabelValue cleans strings for use as Prometheus labels
+// metricKey identifies a series by descriptor and label values
+type metricKey struct {
+	desc   *prometheus.Desc
+	labels string
+}
+
+// metricBatch buffers the metrics of one image before sending them. Findings
+// can repeat a label set (e.g. the same CVE in two packages); the batch keeps
+// the last value, matching GaugeVec.Set, since a registry rejects duplicates.
+// Only one image is buffered at a time, keeping memory bounded. With a series
+// cap, flush counts the series sent per descriptor across the scrape and drops
+// the rest.
+type metricBatch struct {
+	logger    *logrus.Logger
+	metrics   []prometheus.Metric
+	index     map[metricKey]int
+	maxSeries int
+	sent      map[*prometheus.Desc]int
+	dropped   map[*prometheus.Desc]int
+}
+
+func newMetricBatch(logger *logrus.Logger, maxSeries int) *metricBatch {
+	return &metricBatch{
+		logger:    logger,
+		index:     make(map[metricKey]int),
+		maxSeries: maxSeries,
+		sent:      make(map[*prometheus.Desc]int),
+		dropped:   make(map[*prometheus.Desc]int),
+	}
+}
+
+// add buffers a gauge sample, replacing an earlier sample with the same labels
+func (b *metricBatch) add(desc *prometheus.Desc, value float64, labelValues ...string) {
+	metric, err := prometheus.NewConstMetric(desc, prometheus.GaugeValue, value, labelValues...)
+	if err != nil {
+		b.logger.WithError(err).Error("Failed to create metric")
+		return
+	}
+
+	key := metricKey{desc: desc, labels: strings.Join(labelValues, "\xff")}
+	if i, exists := b.index[key]; exists {
+		b.metrics[i] = metric
+		return
+	}
+	b.index[key] = len(b.metrics)
+	b.metrics = append(b.metrics, metric)
+}
+
+// flush sends the buffered metrics and resets the batch for reuse
+func (b *metricBatch) flush(ch chan<- prometheus.Metric) {
+	for _, metric := range b.metrics {
+		if b.maxSeries > 0 {
+			desc := metric.Desc()
+			if b.sent[desc] >= b.maxSeries {
+				b.dropped[desc]++
+				continue
+			}
+			b.sent[desc]++
+		}
+		ch <- metric
+	}
+	clear(b.metrics)
+	b.metrics = b.metrics[:0]
+	clear(b.index)
+}
+
+// sanitizeLabelValue cleans strings for use as Prometheus labels. Scanner
+// text such as descriptions can contain arbitrary bytes, so the result is
+// always valid UTF-8 without control characters.
 func sanitizeLabelValue(value string) string {
+	value = replaceInvalidRunes(value)
+
+	// Limit length to prevent excessive label sizes
+	if len(value) > maxLabelValueLength {
+		value = truncateUTF8(value, maxLabelValueLength) + "..."
+	}
+
+	// Remove any leading/trailing whitespace
+	value = strings.TrimSpace(value)
 	if value == "" {
 		return "unknown"
 	}
+	return value
+}
 
-	// Remove newlines and carriage returns
-	value = strings.ReplaceAll(value, "\n", " ")
-	value = strings.ReplaceAll(value, "\r", " ")
-	value = strings.ReplaceAll(value, "\t", " ")
+// replaceInvalidRunes replaces invalid UTF-8 sequences, then control
+// characters such as newlines, tabs and escape sequences
+func replaceInvalidRunes(value string) string {
+	value = strings.ToValidUTF8(value, string(utf8.RuneError))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, value)
+}
 
-	// Limit length to prevent excessive label sizes
-	if len(value) > 200 {
-		value = value[:200] + "..."
+// truncateUTF8 cuts a valid UTF-8 value to at most maxLength bytes on a
+// character boundary, so it stays valid UTF-8
+func truncateUTF8(value string, maxLength int) string {
+	if len(value) <= maxLength {
+		return value
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(value[cut]) {
+		cut--
 	}
+	return value[:cut]
+}
 
-	// Remove any leading/trailing whitespace
-	return strings.TrimSpace(value)
+// imageURILabel sanitizes and caps an image URI for use as the image_uri
+// label, like sanitizeLabelValue but with its own limit. Truncated URIs end
+// in a short hash of the full URI, so images sharing a long prefix keep
+// separate series and each image gets the same label on every scrape.
+func imageURILabel(imageURI string, maxLength int) string {
+	label := replaceInvalidRunes(imageURI)
+	if len(label) <= maxLength {
+		return label
+	}
+	sum := sha256.Sum256([]byte(imageURI))
+	return truncateUTF8(label, maxLength) + "..." + hex.EncodeToString(sum[:4])
+}
+
+// sortedKeys returns the keys of m in order, keeping output stable
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
 }
 
 // parseImageURI extracts repository name and tag from a full image URI
@@ -268,7 +953,7 @@ func parseImageURI(imageURI string) (repository, tag string, err error) {
 }
 
 // CreateMetricsHandler creates a standard HTTP handler that can be used with http.ServeMux
-func CreateMetricsHandler(dataProvider VulnerabilityDataProvider, logger *logrus.Logger) http.HandlerFunc {
-	metricsHandler := NewMetricsHandler(dataProvider, logger)
+func CreateMetricsHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	metricsHandler := NewMetricsHandler(dataProvider, options, logger)
 	return metricsHandler.ServeHTTP
 }
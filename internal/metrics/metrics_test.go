@@ -4,14 +4,22 @@
 package metrics
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/jfeddern/VulnRelay/internal/types"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 )
 
@@ -24,7 +32,7 @@ func TestNewMetricsHandler(t *testing.T) {
 		lastUpdated: time.Now(),
 	}
 
-	handler := NewMetricsHandler(mockCollector, logger)
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
 
 	if handler.collector != mockCollector {
 		t.Errorf("NewMetricsHandler() collector = %v, want %v", handler.collector, mockCollector)
@@ -98,7 +106,7 @@ func TestMetricsHandler_ServeHTTP(t *testing.T) {
 		lastUpdated: time.Now(),
 	}
 
-	handler := NewMetricsHandler(mockCollector, logger)
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
 
 	// Create test request
 	req := httptest.NewRequest("GET", "/metrics", nil)
@@ -151,7 +159,7 @@ func TestCreateMetricsHandler(t *testing.T) {
 		lastUpdated: time.Now(),
 	}
 
-	handler := CreateMetricsHandler(mockCollector, logger)
+	handler := CreateMetricsHandler(mockCollector, Options{}, logger)
 
 	// Test that it's a valid HTTP handler
 	req := httptest.NewRequest("GET", "/metrics", nil)
@@ -200,6 +208,26 @@ func TestSanitizeLabelValue(t *testing.T) {
 			input:    "  trimmed  ",
 			expected: "trimmed",
 		},
+		{
+			name:     "control characters",
+			input:    "bell\x07null\x00escape\x1b[31mdel\x7fnext\u0085end",
+			expected: "bell null escape [31mdel next end",
+		},
+		{
+			name:     "invalid UTF-8",
+			input:    "overflow in \xff\xfeparser \xc3",
+			expected: "overflow in \ufffdparser \ufffd",
+		},
+		{
+			name:     "only control characters",
+			input:    "\x00\x01\n",
+			expected: "unknown",
+		},
+		{
+			name:     "long string cut on a character boundary",
+			input:    "a" + strings.Repeat("é", 150),
+			expected: "a" + strings.Repeat("é", 99) + "...",
+		},
 	}
 
 	for _, tt := range tests {
@@ -208,6 +236,9 @@ func TestSanitizeLabelValue(t *testing.T) {
 			if result != tt.expected {
 				t.Errorf("sanitizeLabelValue(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
+			if !utf8.ValidString(result) {
+				t.Errorf("sanitizeLabelValue(%q) = %q is not valid UTF-8", tt.input, result)
+			}
 		})
 	}
 }
@@ -318,7 +349,7 @@ func TestMetricsHandler_FixAvailabilityMetrics(t *testing.T) {
 				lastUpdated: time.Now(),
 			}
 
-			handler := NewMetricsHandler(mockCollector, logger)
+			handler := NewMetricsHandler(mockCollector, Options{}, logger)
 			req := httptest.NewRequest("GET", "/metrics", nil)
 			w := httptest.NewRecorder()
 
@@ -385,7 +416,7 @@ func TestMetricsHandler_ExploitAvailabilityMetrics(t *testing.T) {
 				lastUpdated: time.Now(),
 			}
 
-			handler := NewMetricsHandler(mockCollector, logger)
+			handler := NewMetricsHandler(mockCollector, Options{}, logger)
 			req := httptest.NewRequest("GET", "/metrics", nil)
 			w := httptest.NewRecorder()
 
@@ -404,38 +435,1911 @@ func TestMetricsHandler_ExploitAvailabilityMetrics(t *testing.T) {
 	}
 }
 
-// Mock implementation of VulnerabilityDataProvider
-type MockVulnerabilityDataProvider struct {
-	data        map[string]*types.ImageVulnerabilityData
-	lastUpdated time.Time
+func TestMetricsHandler_VulnerabilityAge(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	firstSeen := now.Add(-36 * time.Hour)
+	imageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/test:latest"
+
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			imageURI: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        imageURI,
+					Vulnerabilities: map[string]int{"HIGH": 2},
+					ScanStatus:      "COMPLETE",
+					Findings: []types.VulnerabilityFinding{
+						{Name: "CVE-2024-AGED", Severity: "HIGH", FirstSeen: &firstSeen},
+						{Name: "CVE-2024-UNKNOWN", Severity: "HIGH"},
+					},
+				},
+				ImageInfo: types.ImageInfo{
+					URI:          imageURI,
+					Namespace:    "default",
+					Workload:     "test",
+					WorkloadType: "Deployment",
+				},
+			},
+		},
+		lastUpdated: now,
+	}
+
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
+	handler.now = func() time.Time { return now }
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	expectedMetric := `ecr_vulnerability_age_seconds{cve_name="CVE-2024-AGED",image_uri="` + imageURI + `",namespace="default",repository="test",severity="HIGH",tag="latest",workload="test",workload_type="Deployment"} 129600`
+	if !strings.Contains(body, expectedMetric) {
+		t.Errorf("Expected metric not found: %s", expectedMetric)
+	}
+
+	// Findings without a first-seen time are skipped
+	if strings.Contains(body, `ecr_vulnerability_age_seconds{cve_name="CVE-2024-UNKNOWN"`) {
+		t.Error("Expected no age metric for a finding without first-seen time")
+	}
 }
 
-func (m *MockVulnerabilityDataProvider) GetVulnerabilityData() (map[string]*types.ImageVulnerabilityData, time.Time) {
-	return m.data, m.lastUpdated
+func TestMetricsHandler_AcceptedCount(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	newImage := func(uri string, vulnerabilities map[string]int, findings ...types.VulnerabilityFinding) *types.ImageVulnerabilityData {
+		return &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: vulnerabilities,
+				ScanStatus:      "COMPLETE",
+				Findings:        findings,
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+		}
+	}
+
+	// Active counts already exclude accepted findings, as produced by the engine
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1": newImage(
+				"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",
+				map[string]int{"CRITICAL": 1, "HIGH": 0},
+				types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"},
+				types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH", Accepted: true},
+			),
+			"123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1": newImage(
+				"123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1",
+				map[string]int{"HIGH": 0, "LOW": 0},
+				types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH", Accepted: true},
+				types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "LOW", Accepted: true},
+			),
+		},
+		lastUpdated: time.Now(),
+	}
+
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`ecr_vulnerability_accepted_count{severity="HIGH"} 2`,
+		`ecr_vulnerability_accepted_count{severity="LOW"} 1`,
+		`ecr_image_vulnerability_count{image_uri="123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",namespace="default",repository="api",severity="CRITICAL",tag="v1",workload="test",workload_type="Deployment"} 1`,
+		`ecr_image_vulnerability_count{image_uri="123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",namespace="default",repository="api",severity="HIGH",tag="v1",workload="test",workload_type="Deployment"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics output", want)
+		}
+	}
+
+	// Active findings are not counted as accepted
+	if strings.Contains(body, `ecr_vulnerability_accepted_count{severity="CRITICAL"}`) {
+		t.Error("Expected no accepted count for active CRITICAL findings")
+	}
 }
 
-// Helper function to format float values consistently
-func formatFloat(f float64) string {
-	if f == 1.0 {
-		return "1"
+func TestMetricsHandler_RiskScore(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/test:latest"
+	counts := map[string]int{"CRITICAL": 2, "HIGH": 3, "MEDIUM": 4, "LOW": 5, "INFORMATIONAL": 7}
+
+	tests := []struct {
+		name     string
+		options  Options
+		expected float64
+	}{
+		{
+			name:     "default weights",
+			options:  Options{},
+			expected: 2*10 + 3*5 + 4*2 + 5*1,
+		},
+		{
+			name:     "overridden weights",
+			options:  Options{RiskScoreWeights: map[string]float64{"CRITICAL": 100, "LOW": 0, "INFORMATIONAL": 0.5}},
+			expected: 2*100 + 3*5 + 4*2 + 5*0 + 7*0.5,
+		},
 	}
-	if f == 0.0 {
-		return "0"
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCollector := &MockVulnerabilityDataProvider{
+				data: map[string]*types.ImageVulnerabilityData{
+					imageURI: {
+						ImageVulnerability: &types.ImageVulnerability{
+							ImageURI:        imageURI,
+							Vulnerabilities: counts,
+							ScanStatus:      "COMPLETE",
+						},
+						ImageInfo: types.ImageInfo{URI: imageURI, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+					},
+				},
+				lastUpdated: time.Now(),
+			}
+
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			expectedMetric := `ecr_image_risk_score{image_uri="` + imageURI + `",namespace="default",repository="test",tag="latest",workload="test",workload_type="Deployment"} ` + formatFloat(tt.expected)
+			if !strings.Contains(w.Body.String(), expectedMetric) {
+				t.Errorf("Expected metric not found: %s", expectedMetric)
+			}
+		})
 	}
-	if f == 0.5 {
-		return "0.5"
+}
+
+func TestMetricsHandler_FindingTypeCount(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/test:latest"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			imageURI: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        imageURI,
+					Vulnerabilities: map[string]int{"HIGH": 4},
+					ScanStatus:      "COMPLETE",
+					Findings: []types.VulnerabilityFinding{
+						{Name: "CVE-2024-0001", Severity: "HIGH", Type: "PACKAGE_VULNERABILITY"},
+						{Name: "CVE-2024-0002", Severity: "HIGH", Type: "PACKAGE_VULNERABILITY"},
+						{Name: "CVE-2024-0003", Severity: "HIGH", Type: "CODE_VULNERABILITY"},
+						{Name: "CVE-2024-0004", Severity: "HIGH"},
+						{Name: "CVE-2024-0005", Severity: "HIGH", Type: "PACKAGE_VULNERABILITY", Accepted: true},
+					},
+				},
+				ImageInfo: types.ImageInfo{URI: imageURI, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	body := w.Body.String()
+	labels := `image_uri="` + imageURI + `",namespace="default",repository="test",tag="latest",type="%s",workload="test",workload_type="Deployment"`
+	for findingType, count := range map[string]int{
+		"PACKAGE_VULNERABILITY": 2, // The accepted finding is not counted
+		"CODE_VULNERABILITY":    1,
+		"UNKNOWN":               1,
+	} {
+		want := sprintf("ecr_image_finding_type_count{"+labels+"} %d", findingType, count)
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics output", want)
+		}
 	}
-	return strings.TrimRight(strings.TrimRight(sprintf("%.1f", f), "0"), ".")
 }
 
-// Simple sprintf replacement for testing
-func sprintf(format string, a ...interface{}) string {
-	if format == "%.1f" && len(a) == 1 {
-		if f, ok := a[0].(float64); ok {
-			if f == 0.5 {
-				return "0.5"
+func TestMetricsHandler_ReleaseLabel(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/test:latest"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			imageURI: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        imageURI,
+					Vulnerabilities: map[string]int{"HIGH": 1},
+					ScanStatus:      "COMPLETE",
+					Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001", Severity: "HIGH"}},
+				},
+				ImageInfo: types.ImageInfo{URI: imageURI, Namespace: "default", Workload: "test", WorkloadType: "Deployment", Release: "checkout"},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		want    string
+	}{
+		{
+			name:    "release label enabled",
+			options: Options{ReleaseLabel: true},
+			want:    `ecr_image_risk_score{image_uri="` + imageURI + `",namespace="default",release="checkout",repository="test",tag="latest",workload="test",workload_type="Deployment"} 5`,
+		},
+		{
+			name:    "release label disabled",
+			options: Options{},
+			want:    `ecr_image_risk_score{image_uri="` + imageURI + `",namespace="default",repository="test",tag="latest",workload="test",workload_type="Deployment"} 5`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			body := w.Body.String()
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("Expected %q in metrics output", tt.want)
+			}
+			// Per-image metrics carry the label only when enabled; global ones never do
+			if got := strings.Contains(body, `release="checkout"`); got != tt.options.ReleaseLabel {
+				t.Errorf("Release label present = %v, want %v", got, tt.options.ReleaseLabel)
 			}
+			if !strings.Contains(body, `ecr_vulnerability_collection_info{info_type="images_monitored"} 1`) {
+				t.Error("Expected collection info without a release label")
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_PlatformLabel(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	multiArch := "123456789012.dkr.ecr.us-east-1.amazonaws.com/multi:v1"
+	single := "123456789012.dkr.ecr.us-east-1.amazonaws.com/single:v1"
+	image := func(uri, platform string) *types.ImageVulnerabilityData {
+		return &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{"HIGH": 1},
+				ScanStatus:      "COMPLETE",
+				Platform:        platform,
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment", Release: "checkout"},
 		}
 	}
-	return "0"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			multiArch: image(multiArch, "linux/arm64"),
+			single:    image(single, ""),
+		},
+		lastUpdated: time.Now(),
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{
+			name:    "platform label enabled",
+			options: Options{PlatformLabel: true},
+			want: []string{
+				`ecr_image_risk_score{image_uri="` + multiArch + `",namespace="default",platform="linux/arm64",repository="multi",tag="v1",workload="test",workload_type="Deployment"} 5`,
+				`ecr_image_risk_score{image_uri="` + single + `",namespace="default",platform="",repository="single",tag="v1",workload="test",workload_type="Deployment"} 5`,
+			},
+		},
+		{
+			name:    "combined with the release label",
+			options: Options{PlatformLabel: true, ReleaseLabel: true},
+			want: []string{
+				`ecr_image_risk_score{image_uri="` + multiArch + `",namespace="default",platform="linux/arm64",release="checkout",repository="multi",tag="v1",workload="test",workload_type="Deployment"} 5`,
+			},
+		},
+		{
+			name:    "platform label disabled",
+			options: Options{},
+			want: []string{
+				`ecr_image_risk_score{image_uri="` + multiArch + `",namespace="default",repository="multi",tag="v1",workload="test",workload_type="Deployment"} 5`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected %q in metrics output", want)
+				}
+			}
+			if got := strings.Contains(body, `platform="linux/arm64"`); got != tt.options.PlatformLabel {
+				t.Errorf("Platform label present = %v, want %v", got, tt.options.PlatformLabel)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_ClusterName(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			uri: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        uri,
+					Vulnerabilities: map[string]int{"HIGH": 1},
+					ScanStatus:      "COMPLETE",
+				},
+				ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+	extra := prometheus.NewGauge(prometheus.GaugeOpts{Name: "test_extra_collector", Help: "Collector served alongside"})
+
+	tests := []struct {
+		name        string
+		clusterName string
+	}{
+		{"cluster label", "prod-eu-1"},
+		{"no cluster label", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, Options{ClusterName: tt.clusterName, Collectors: []prometheus.Collector{extra}}, logger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			// Every series carries the label, including those of extra collectors
+			series := 0
+			for _, line := range strings.Split(w.Body.String(), "\n") {
+				if line == "" || strings.HasPrefix(line, "#") {
+					continue
+				}
+				series++
+				if got := strings.Contains(line, `cluster="`+tt.clusterName+`"`); got != (tt.clusterName != "") {
+					t.Errorf("Cluster label present = %v in %q", got, line)
+				}
+			}
+			if series == 0 {
+				t.Fatal("Expected series in metrics output")
+			}
+
+			want := `ecr_image_risk_score{cluster="prod-eu-1",image_uri="` + uri + `",namespace="default",repository="app",tag="v1",workload="test",workload_type="Deployment"} 5`
+			if got := strings.Contains(w.Body.String(), want); got != (tt.clusterName != "") {
+				t.Errorf("Expected %q present = %v", want, tt.clusterName != "")
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_SeverityParameter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			uri: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        uri,
+					Vulnerabilities: map[string]int{"CRITICAL": 1, "HIGH": 1, "LOW": 1},
+					ScanStatus:      "COMPLETE",
+					Findings: []types.VulnerabilityFinding{
+						{Name: "CVE-2024-0001", Severity: "CRITICAL", FixAvailable: "YES"},
+						{Name: "CVE-2024-0002", Severity: "HIGH", FixAvailable: "NO"},
+						{Name: "CVE-2024-0003", Severity: "LOW", FixAvailable: "NO"},
+						{Name: "CVE-2024-0004", Severity: "LOW", Accepted: true},
+					},
+				},
+				ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
+
+	tests := []struct {
+		name       string
+		query      string
+		severities []string // Severities expected on severity-labeled series
+		absent     []string
+	}{
+		{"no parameter", "", []string{"CRITICAL", "HIGH", "LOW"}, nil},
+		{"critical and high", "?severity=CRITICAL,high", []string{"CRITICAL", "HIGH"}, []string{"LOW"}},
+		{"empty parameter", "?severity=", []string{"CRITICAL", "HIGH", "LOW"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics"+tt.query, nil))
+			body := w.Body.String()
+
+			for _, severity := range tt.severities {
+				for _, metric := range []string{"ecr_image_vulnerability_count", "ecr_vulnerability_info", "ecr_vulnerability_fix_available"} {
+					if !regexp.MustCompile(metric + `\{[^}]*severity="` + severity + `"`).MatchString(body) {
+						t.Errorf("Expected %s series with severity %s", metric, severity)
+					}
+				}
+			}
+			for _, severity := range tt.absent {
+				if strings.Contains(body, `severity="`+severity+`"`) {
+					t.Errorf("Expected no series with severity %s", severity)
+				}
+			}
+
+			// Series without a severity label are not filtered
+			if !strings.Contains(body, "ecr_image_risk_score{") || !strings.Contains(body, "ecr_image_scan_status{") {
+				t.Error("Expected the risk score and scan status series")
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_FreshOnly(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	fresh := "123456789012.dkr.ecr.us-east-1.amazonaws.com/fresh:v1"
+	stale := "123456789012.dkr.ecr.us-east-1.amazonaws.com/stale:v1"
+	image := func(uri string, isStale bool) *types.ImageVulnerabilityData {
+		return &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{"HIGH": 1},
+				ScanStatus:      "COMPLETE",
+				Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001", Severity: "HIGH"}},
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+			Stale:     isStale,
+		}
+	}
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			fresh: image(fresh, false),
+			stale: image(stale, true),
+		},
+		lastUpdated: time.Now(),
+	}
+
+	tests := []struct {
+		name          string
+		options       Options
+		expectedStale bool
+	}{
+		{"stale images included by default", Options{}, true},
+		{"stale images omitted", Options{FreshOnly: true}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			body := w.Body.String()
+			if !strings.Contains(body, `image_uri="`+fresh+`"`) {
+				t.Error("Expected the fresh image in metrics output")
+			}
+			if got := strings.Contains(body, `image_uri="`+stale+`"`); got != tt.expectedStale {
+				t.Errorf("Stale image present = %v, want %v", got, tt.expectedStale)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_MaxImageURILength(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Two digest-pinned images that only differ past the cap
+	prefix := "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + strings.Repeat("nested/", 10) + "service"
+	first := prefix + ":sha256-" + strings.Repeat("a", 64)
+	second := prefix + ":sha256-" + strings.Repeat("b", 64)
+
+	data := make(map[string]*types.ImageVulnerabilityData)
+	for _, uri := range []string{first, second} {
+		data[uri] = &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{"HIGH": 1},
+				ScanStatus:      "COMPLETE",
+				Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001", Severity: "HIGH"}},
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+		}
+	}
+	collector := &MockVulnerabilityDataProvider{data: data, lastUpdated: time.Now()}
+
+	const maxLength = 120
+	handler := NewMetricsHandler(collector, Options{MaxImageURILength: maxLength}, logger)
+	handler.now = func() time.Time { return collector.lastUpdated } // Stable collection age across scrapes
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	body := w.Body.String()
+
+	// Every series of an image carries the same truncated label
+	labels := make(map[string]int)
+	for _, match := range regexp.MustCompile(`image_uri="([^"]*)"`).FindAllStringSubmatch(body, -1) {
+		labels[match[1]]++
+	}
+	if len(labels) != 2 {
+		t.Fatalf("Expected 2 distinct image_uri labels, got %d: %v", len(labels), labels)
+	}
+	var counts []int
+	for label, count := range labels {
+		if !strings.HasPrefix(label, prefix[:maxLength]+"...") {
+			t.Errorf("Expected label %q to start with the first %d characters of the URI", label, maxLength)
+		}
+		if len(label) > maxLength+len("...")+8 {
+			t.Errorf("Label %q exceeds the cap", label)
+		}
+		counts = append(counts, count)
+	}
+	if counts[0] != counts[1] {
+		t.Errorf("Expected the same number of series per image, got %v", counts)
+	}
+
+	// Truncation is stable across scrapes
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Body.String() != body {
+		t.Error("Expected identical labels on a second scrape")
+	}
+
+	// Scrapes don't shorten the collector's data, so the JSON API keeps the
+	// full URI
+	served, _ := collector.GetVulnerabilityData()
+	encoded, err := json.Marshal(served[first])
+	if err != nil {
+		t.Fatalf("Failed to encode image data: %v", err)
+	}
+	var decoded struct {
+		ImageURI string `json:"image_uri"`
+		URI      string
+	}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("Failed to decode image data: %v", err)
+	}
+	if decoded.ImageURI != first || decoded.URI != first {
+		t.Errorf("Expected image_uri and URI %q, got %q and %q", first, decoded.ImageURI, decoded.URI)
+	}
+}
+
+func TestMetricsHandler_MaxImageURILengthNonASCII(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// The cap falls inside a two-byte character
+	uri := "registry.example.com/" + strings.Repeat("ü", 20) + ":v1"
+	data := map[string]*types.ImageVulnerabilityData{
+		uri: {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{"HIGH": 1},
+				ScanStatus:      "COMPLETE",
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "test", WorkloadType: "Deployment"},
+		},
+	}
+	collector := &MockVulnerabilityDataProvider{data: data, lastUpdated: time.Now()}
+
+	w := httptest.NewRecorder()
+	NewMetricsHandler(collector, Options{MaxImageURILength: 30}, logger).ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	// The series is exported with a valid label rather than dropped
+	match := regexp.MustCompile(`ecr_image_scan_status\{[^}]*image_uri="([^"]*)"`).FindStringSubmatch(w.Body.String())
+	if match == nil {
+		t.Fatalf("Expected the image's series to be exported, got:\n%s", w.Body.String())
+	}
+	if !utf8.ValidString(match[1]) || !strings.HasPrefix(match[1], "registry.example.com/"+strings.Repeat("ü", 4)+"...") {
+		t.Errorf("Expected the label to be cut on a character boundary, got %q", match[1])
+	}
+}
+
+func TestImageURILabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "short URI is unchanged",
+			input:    "registry/app:v1",
+			expected: "registry/app:v1",
+		},
+		{
+			name:     "URI at the cap is unchanged",
+			input:    strings.Repeat("a", 20),
+			expected: strings.Repeat("a", 20),
+		},
+		{
+			name:     "long URI is truncated with a hash suffix",
+			input:    strings.Repeat("a", 21),
+			expected: strings.Repeat("a", 20) + "..." + "7df8e299",
+		},
+		{
+			name:     "non-ASCII URI is truncated on a character boundary",
+			input:    "registry/" + strings.Repeat("ü", 10),
+			expected: "registry/" + strings.Repeat("ü", 5) + "..." + "93c72ea8",
+		},
+		{
+			name:     "control characters are replaced",
+			input:    "registry/app\n:v1",
+			expected: "registry/app :v1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := imageURILabel(tt.input, 20); result != tt.expected {
+				t.Errorf("imageURILabel(%q) = %q, want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_MaxSeriesPerMetric(t *testing.T) {
+	var logs bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&logs)
+	logger.SetLevel(logrus.WarnLevel)
+
+	// 10 images with 2 findings each: 10 risk score and 20 info series
+	collector := largeMetricsTestData(10, 2)
+	handler := NewMetricsHandler(collector, Options{MaxSeriesPerMetric: 4}, logger)
+	handler.now = func() time.Time { return time.Unix(1705315800, 0) } // Stable collection age across scrapes
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	body := w.Body.String()
+
+	series := func(name string) int {
+		count := 0
+		for _, line := range strings.Split(body, "\n") {
+			if strings.HasPrefix(line, name+"{") || strings.HasPrefix(line, name+" ") {
+				count++
+			}
+		}
+		return count
+	}
+
+	for _, name := range []string{"ecr_image_risk_score", "ecr_vulnerability_info", "ecr_image_vulnerability_count"} {
+		if got := series(name); got != 4 {
+			t.Errorf("Expected %s to be capped at 4 series, got %d", name, got)
+		}
+	}
+
+	// Metrics below the cap are untouched
+	if got := series("ecr_vulnerability_collection_info"); got != 2 {
+		t.Errorf("Expected 2 collection info series, got %d", got)
+	}
+
+	for _, expected := range []string{
+		`ecr_metrics_truncated{metric="ecr_image_risk_score"} 6`,
+		`ecr_metrics_truncated{metric="ecr_vulnerability_info"} 16`,
+	} {
+		if !strings.Contains(body, expected) {
+			t.Errorf("Expected truncation metric not found: %s", expected)
+		}
+	}
+	if strings.Contains(body, `ecr_metrics_truncated{metric="ecr_vulnerability_collection_info"}`) {
+		t.Error("Expected no truncation metric for a metric below the cap")
+	}
+
+	if !strings.Contains(logs.String(), "exceeded the maximum series per metric") {
+		t.Errorf("Expected a truncation warning, got logs: %s", logs.String())
+	}
+
+	// The same series are kept on every scrape
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Body.String() != body {
+		t.Error("Expected identical output across scrapes with a series cap")
+	}
+
+	// Without a cap nothing is dropped
+	w = httptest.NewRecorder()
+	NewMetricsHandler(collector, Options{}, logger).ServeHTTP(w, req)
+	if strings.Contains(w.Body.String(), "ecr_metrics_truncated") {
+		t.Error("Expected no truncation without a series cap")
+	}
+}
+
+// statsDataProvider also implements CollectionStatsProvider
+type statsDataProvider struct {
+	MockVulnerabilityDataProvider
+	durationEMA    time.Duration
+	scrapeInterval time.Duration
+	lastTick       time.Time
+}
+
+func (s *statsDataProvider) GetCollectionDurationEMA() time.Duration {
+	return s.durationEMA
+}
+
+func (s *statsDataProvider) GetScrapeInterval() time.Duration {
+	return s.scrapeInterval
+}
+
+func (s *statsDataProvider) GetLastTickTime() time.Time {
+	return s.lastTick
+}
+
+func TestMetricsHandler_CollectionDurationEMA(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name      string
+		collector VulnerabilityDataProvider
+		want      string
+	}{
+		{
+			name: "provider with stats",
+			collector: &statsDataProvider{
+				MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+					data:        make(map[string]*types.ImageVulnerabilityData),
+					lastUpdated: time.Now(),
+				},
+				durationEMA: 2500 * time.Millisecond,
+			},
+			want: "ecr_vulnerability_collection_duration_ema_seconds 2.5",
+		},
+		{
+			name: "provider without stats",
+			collector: &MockVulnerabilityDataProvider{
+				data:        make(map[string]*types.ImageVulnerabilityData),
+				lastUpdated: time.Now(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(tt.collector, Options{}, logger)
+
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			body := w.Body.String()
+			if tt.want != "" && !strings.Contains(body, tt.want) {
+				t.Errorf("Expected %q in metrics output", tt.want)
+			}
+			if tt.want == "" && strings.Contains(body, "ecr_vulnerability_collection_duration_ema_seconds") {
+				t.Error("Expected no collection duration EMA metric without stats provider")
+			}
+		})
+	}
+}
+
+// discoveryStatusProvider adds the empty discovery indicator to the mock provider
+type discoveryStatusProvider struct {
+	MockVulnerabilityDataProvider
+	empty bool
+}
+
+func (d *discoveryStatusProvider) IsDiscoveryEmpty() bool {
+	return d.empty
+}
+
+func TestMetricsHandler_DiscoveryEmpty(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name      string
+		collector VulnerabilityDataProvider
+		want      string
+	}{
+		{
+			name: "repeatedly empty discovery",
+			collector: &discoveryStatusProvider{
+				MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+					data:        make(map[string]*types.ImageVulnerabilityData),
+					lastUpdated: time.Now(),
+				},
+				empty: true,
+			},
+			want: "ecr_discovery_empty 1",
+		},
+		{
+			name: "images discovered",
+			collector: &discoveryStatusProvider{
+				MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+					data:        make(map[string]*types.ImageVulnerabilityData),
+					lastUpdated: time.Now(),
+				},
+			},
+			want: "ecr_discovery_empty 0",
+		},
+		{
+			name: "provider without discovery status",
+			collector: &MockVulnerabilityDataProvider{
+				data:        make(map[string]*types.ImageVulnerabilityData),
+				lastUpdated: time.Now(),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(tt.collector, Options{}, logger)
+
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			body := w.Body.String()
+			if tt.want != "" && !strings.Contains(body, tt.want) {
+				t.Errorf("Expected %q in metrics output", tt.want)
+			}
+			if tt.want == "" && strings.Contains(body, "ecr_discovery_empty") {
+				t.Error("Expected no discovery empty metric without discovery status provider")
+			}
+		})
+	}
+}
+
+type scanTypeProvider struct {
+	MockVulnerabilityDataProvider
+	scanType string
+}
+
+func (s *scanTypeProvider) GetRegistryScanType() string {
+	return s.scanType
+}
+
+func TestMetricsHandler_RegistryScanType(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name     string
+		scanType string
+		want     string
+	}{
+		{"enhanced scanning", "ENHANCED", `ecr_registry_scan_type{scan_type="ENHANCED"} 1`},
+		{"basic scanning", "BASIC", `ecr_registry_scan_type{scan_type="BASIC"} 1`},
+		{"not checked", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &scanTypeProvider{
+				MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+					data:        make(map[string]*types.ImageVulnerabilityData),
+					lastUpdated: time.Now(),
+				},
+				scanType: tt.scanType,
+			}
+			handler := NewMetricsHandler(collector, Options{}, logger)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			body := w.Body.String()
+			if tt.want != "" && !strings.Contains(body, tt.want) {
+				t.Errorf("Expected %q in metrics output", tt.want)
+			}
+			if tt.want == "" && strings.Contains(body, "ecr_registry_scan_type") {
+				t.Error("Expected no scan type metric before the registry was checked")
+			}
+		})
+	}
+}
+
+type registryCollectionProvider struct {
+	MockVulnerabilityDataProvider
+	registries []types.RegistryCollection
+}
+
+func (r *registryCollectionProvider) GetRegistryCollections() []types.RegistryCollection {
+	return r.registries
+}
+
+func TestMetricsHandler_RegistryCollections(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &registryCollectionProvider{
+		MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+			data:        make(map[string]*types.ImageVulnerabilityData),
+			lastUpdated: time.Now(),
+		},
+		registries: []types.RegistryCollection{
+			{Registry: "111111111111.dkr.ecr.us-east-1.amazonaws.com", Collected: 3, Failed: 1, Success: true},
+			{Registry: "222222222222.dkr.ecr.eu-west-1.amazonaws.com", Failed: 2},
+		},
+	}
+	handler := NewMetricsHandler(collector, Options{}, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := w.Body.String()
+	for _, want := range []string{
+		`ecr_registry_collection_success{registry="111111111111.dkr.ecr.us-east-1.amazonaws.com"} 1`,
+		`ecr_registry_collection_success{registry="222222222222.dkr.ecr.eu-west-1.amazonaws.com"} 0`,
+		`ecr_registry_images{registry="111111111111.dkr.ecr.us-east-1.amazonaws.com",result="collected"} 3`,
+		`ecr_registry_images{registry="111111111111.dkr.ecr.us-east-1.amazonaws.com",result="failed"} 1`,
+		`ecr_registry_images{registry="222222222222.dkr.ecr.eu-west-1.amazonaws.com",result="failed"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics output", want)
+		}
+	}
+
+	// Providers without per-registry collection emit no registry metrics
+	handler = NewMetricsHandler(&collector.MockVulnerabilityDataProvider, Options{}, logger)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), "ecr_registry_collection_success") {
+		t.Error("Expected no registry metrics without a registry collection provider")
+	}
+}
+
+func TestMetricsHandler_ScrapeCadence(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		lastTick time.Time
+		want     []string
+	}{
+		{
+			name:     "configured interval and last tick",
+			interval: 5 * time.Minute,
+			lastTick: time.Unix(1705315800, 0),
+			want: []string{
+				"ecr_vulnerability_scrape_interval_seconds 300",
+				"ecr_vulnerability_last_tick_timestamp 1.7053158e+09",
+			},
+		},
+		{
+			name:     "sub-minute interval before first tick",
+			interval: 30 * time.Second,
+			want: []string{
+				"ecr_vulnerability_scrape_interval_seconds 30",
+				"ecr_vulnerability_last_tick_timestamp 0",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &statsDataProvider{
+				MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+					data:        make(map[string]*types.ImageVulnerabilityData),
+					lastUpdated: time.Now(),
+				},
+				scrapeInterval: tt.interval,
+				lastTick:       tt.lastTick,
+			}
+			handler := NewMetricsHandler(collector, Options{}, logger)
+
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected %q in metrics output", want)
+				}
+			}
+		})
+	}
+}
+
+// Mock implementation of VulnerabilityDataProvider
+type fetchConcurrencyProvider struct {
+	MockVulnerabilityDataProvider
+	limits map[string]int
+}
+
+func (f *fetchConcurrencyProvider) GetFetchConcurrency() map[string]int {
+	return f.limits
+}
+
+func TestMetricsHandler_FetchConcurrency(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &fetchConcurrencyProvider{
+		MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+			data:        make(map[string]*types.ImageVulnerabilityData),
+			lastUpdated: time.Now(),
+		},
+		limits: map[string]int{"123456789012.dkr.ecr.us-east-1.amazonaws.com": 3},
+	}
+	handler := NewMetricsHandler(collector, Options{}, logger)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	want := `ecr_registry_fetch_concurrency{registry="123456789012.dkr.ecr.us-east-1.amazonaws.com"} 3`
+	if !strings.Contains(w.Body.String(), want) {
+		t.Errorf("Expected %q in metrics output", want)
+	}
+
+	// Without adaptive concurrency no series is emitted
+	collector.limits = nil
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if strings.Contains(w.Body.String(), "ecr_registry_fetch_concurrency{") {
+		t.Error("Expected no fetch concurrency series without adaptive limits")
+	}
+}
+
+type MockVulnerabilityDataProvider struct {
+	data        map[string]*types.ImageVulnerabilityData
+	lastUpdated time.Time
+}
+
+func (m *MockVulnerabilityDataProvider) GetVulnerabilityData() (map[string]*types.ImageVulnerabilityData, time.Time) {
+	return m.data, m.lastUpdated
+}
+
+// Helper function to format float values consistently
+func formatFloat(f float64) string {
+	if f == 1.0 {
+		return "1"
+	}
+	if f == 0.0 {
+		return "0"
+	}
+	if f == 0.5 {
+		return "0.5"
+	}
+	return strings.TrimRight(strings.TrimRight(sprintf("%.1f", f), "0"), ".")
+}
+
+// Simple sprintf replacement for testing
+func sprintf(format string, a ...interface{}) string {
+	if format == "%.1f" && len(a) == 1 {
+		if f, ok := a[0].(float64); ok {
+			if f == 0.5 {
+				return "0.5"
+			}
+		}
+	}
+	return "0"
+}
+
+// gaugeVecMetricsHandler is the previous reset-and-repopulate implementation,
+// kept as a reference for output equivalence and allocation benchmarks
+type gaugeVecMetricsHandler struct {
+	collector VulnerabilityDataProvider
+	logger    *logrus.Logger
+
+	// Prometheus metrics
+	vulnerabilityCount *prometheus.GaugeVec
+	lastScanTime       *prometheus.GaugeVec
+	scanStatus         *prometheus.GaugeVec
+	collectionInfo     *prometheus.GaugeVec
+
+	collectionDurationEMA prometheus.Gauge
+	scrapeInterval        prometheus.Gauge
+	lastTick              prometheus.Gauge
+
+	// Detailed vulnerability metrics
+	vulnerabilityInfo    *prometheus.GaugeVec
+	packageVulnerability *prometheus.GaugeVec
+	fixAvailability      *prometheus.GaugeVec
+	exploitAvailability  *prometheus.GaugeVec
+}
+
+func newGaugeVecMetricsHandler(collector VulnerabilityDataProvider, logger *logrus.Logger) *gaugeVecMetricsHandler {
+	return &gaugeVecMetricsHandler{
+		collector: collector,
+		logger:    logger,
+
+		vulnerabilityCount: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_image_vulnerability_count",
+				Help: "Number of vulnerabilities found in ECR images by severity",
+			},
+			[]string{"image_uri", "repository", "tag", "severity", "namespace", "workload", "workload_type"},
+		),
+
+		lastScanTime: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_image_last_scan_timestamp",
+				Help: "Timestamp of the last vulnerability scan for ECR images",
+			},
+			[]string{"image_uri", "repository", "tag", "namespace", "workload", "workload_type"},
+		),
+
+		scanStatus: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_image_scan_status",
+				Help: "Status of vulnerability scan for ECR images (1=healthy status, COMPLETE by default, 0=other)",
+			},
+			[]string{"image_uri", "repository", "tag", "status", "namespace", "workload", "workload_type"},
+		),
+
+		collectionInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_collection_info",
+				Help: "Information about vulnerability data collection",
+			},
+			[]string{"info_type"},
+		),
+
+		collectionDurationEMA: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_collection_duration_ema_seconds",
+				Help: "Exponential moving average of vulnerability collection cycle duration in seconds",
+			},
+		),
+
+		scrapeInterval: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_scrape_interval_seconds",
+				Help: "Configured interval between vulnerability collection cycles in seconds",
+			},
+		),
+
+		lastTick: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_last_tick_timestamp",
+				Help: "Timestamp when the last vulnerability collection cycle was triggered",
+			},
+		),
+
+		vulnerabilityInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_info",
+				Help: "Detailed vulnerability information with CVE details",
+			},
+			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "description", "status", "type", "namespace", "workload", "workload_type"},
+		),
+
+		packageVulnerability: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_package_vulnerability",
+				Help: "Package-level vulnerability information with fix details",
+			},
+			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "package_name", "package_version", "fix_version", "namespace", "workload", "workload_type"},
+		),
+
+		fixAvailability: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_fix_available",
+				Help: "Fix availability for vulnerabilities (1=YES, 0.5=PARTIAL, 0=NO)",
+			},
+			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "fix_status", "namespace", "workload", "workload_type"},
+		),
+
+		exploitAvailability: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "ecr_vulnerability_exploit_available",
+				Help: "Exploit availability for vulnerabilities (1=YES, 0=NO)",
+			},
+			[]string{"image_uri", "repository", "tag", "cve_name", "severity", "exploit_status", "namespace", "workload", "workload_type"},
+		),
+	}
+}
+
+func (m *gaugeVecMetricsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Create a new registry for this request to avoid conflicts
+	registry := prometheus.NewRegistry()
+
+	// Register our metrics
+	registry.MustRegister(m.vulnerabilityCount)
+	registry.MustRegister(m.lastScanTime)
+	registry.MustRegister(m.scanStatus)
+	registry.MustRegister(m.collectionInfo)
+	registry.MustRegister(m.vulnerabilityInfo)
+	registry.MustRegister(m.packageVulnerability)
+	registry.MustRegister(m.fixAvailability)
+	registry.MustRegister(m.exploitAvailability)
+
+	stats, hasStats := m.collector.(CollectionStatsProvider)
+	if hasStats {
+		registry.MustRegister(m.collectionDurationEMA)
+		registry.MustRegister(m.scrapeInterval)
+		registry.MustRegister(m.lastTick)
+	}
+
+	// Reset all metrics to avoid stale data
+	m.vulnerabilityCount.Reset()
+	m.lastScanTime.Reset()
+	m.scanStatus.Reset()
+	m.collectionInfo.Reset()
+	m.vulnerabilityInfo.Reset()
+	m.packageVulnerability.Reset()
+	m.fixAvailability.Reset()
+	m.exploitAvailability.Reset()
+
+	// Get current vulnerability data
+	vulnerabilityData, lastCollectionTime := m.collector.GetVulnerabilityData()
+
+	// Populate metrics
+	for imageURI, vulnDataWithInfo := range vulnerabilityData {
+		vulnData := vulnDataWithInfo.ImageVulnerability
+		namespace := vulnDataWithInfo.Namespace
+		workload := vulnDataWithInfo.Workload
+		workloadType := vulnDataWithInfo.WorkloadType
+
+		repo, tag, err := parseImageURI(imageURI)
+		if err != nil {
+			m.logger.WithError(err).WithField("image_uri", imageURI).Error("Failed to parse image URI for metrics")
+			continue
+		}
+
+		// Vulnerability counts by severity
+		for severity, count := range vulnData.Vulnerabilities {
+			m.vulnerabilityCount.WithLabelValues(imageURI, repo, tag, severity, namespace, workload, workloadType).Set(float64(count))
+		}
+
+		// Last scan time
+		if vulnData.LastScanTime != nil {
+			if scanTime, err := time.Parse("2006-01-02T15:04:05Z", *vulnData.LastScanTime); err == nil {
+				m.lastScanTime.WithLabelValues(imageURI, repo, tag, namespace, workload, workloadType).Set(float64(scanTime.Unix()))
+			}
+		}
+
+		// Scan status (1 for COMPLETE, 0 for others)
+		statusValue := float64(0)
+		if vulnData.ScanStatus == "COMPLETE" {
+			statusValue = 1
+		}
+		m.scanStatus.WithLabelValues(imageURI, repo, tag, vulnData.ScanStatus, namespace, workload, workloadType).Set(statusValue)
+
+		// Detailed vulnerability information
+		for _, finding := range vulnData.Findings {
+			// Sanitize strings for Prometheus labels (remove newlines, limit length)
+			cve := sanitizeLabelValue(finding.Name)
+			description := sanitizeLabelValue(finding.Description)
+			status := sanitizeLabelValue(finding.Status)
+			vulnType := sanitizeLabelValue(finding.Type)
+			packageName := sanitizeLabelValue(finding.PackageName)
+			packageVersion := sanitizeLabelValue(finding.PackageVersion)
+			fixVersion := sanitizeLabelValue(finding.FixVersion)
+
+			// Vulnerability info metric (always 1 to indicate presence)
+			m.vulnerabilityInfo.WithLabelValues(
+				imageURI, repo, tag, cve, finding.Severity, description, status, vulnType, namespace, workload, workloadType,
+			).Set(1)
+
+			// Package vulnerability metric (Inspector score if available, otherwise 1)
+			score := finding.Score
+			if score == 0 {
+				score = 1 // Default for basic scanning
+			}
+			m.packageVulnerability.WithLabelValues(
+				imageURI, repo, tag, cve, finding.Severity, packageName, packageVersion, fixVersion, namespace, workload, workloadType,
+			).Set(score)
+
+			// Fix availability metric
+			fixValue := float64(0)
+			switch finding.FixAvailable {
+			case "YES":
+				fixValue = 1
+			case "PARTIAL":
+				fixValue = 0.5
+			case "NO":
+				fixValue = 0
+			}
+			m.fixAvailability.WithLabelValues(
+				imageURI, repo, tag, cve, finding.Severity, finding.FixAvailable, namespace, workload, workloadType,
+			).Set(fixValue)
+
+			// Exploit availability metric
+			exploitValue := float64(0)
+			if finding.ExploitAvailable == "YES" {
+				exploitValue = 1
+			}
+			m.exploitAvailability.WithLabelValues(
+				imageURI, repo, tag, cve, finding.Severity, finding.ExploitAvailable, namespace, workload, workloadType,
+			).Set(exploitValue)
+		}
+	}
+
+	// Collection info
+	m.collectionInfo.WithLabelValues("last_collection_timestamp").Set(float64(lastCollectionTime.Unix()))
+	m.collectionInfo.WithLabelValues("images_monitored").Set(float64(len(vulnerabilityData)))
+	if hasStats {
+		m.collectionDurationEMA.Set(stats.GetCollectionDurationEMA().Seconds())
+		m.scrapeInterval.Set(stats.GetScrapeInterval().Seconds())
+
+		lastTick := float64(0)
+		if tickTime := stats.GetLastTickTime(); !tickTime.IsZero() {
+			lastTick = float64(tickTime.Unix())
+		}
+		m.lastTick.Set(lastTick)
+	}
+
+	// Serve metrics
+	handler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+	handler.ServeHTTP(w, r)
+}
+
+// largeMetricsTestData builds a cluster-sized snapshot, including findings that
+// repeat a label set within an image
+func largeMetricsTestData(images, findingsPerImage int) *statsDataProvider {
+	scanTime := "2025-01-15T10:30:00Z"
+	severities := []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+	fixStatuses := []string{"YES", "PARTIAL", "NO"}
+
+	data := make(map[string]*types.ImageVulnerabilityData, images)
+	for i := 0; i < images; i++ {
+		uri := fmt.Sprintf("123456789012.dkr.ecr.us-east-1.amazonaws.com/app-%d:v%d", i, i%3)
+
+		findings := make([]types.VulnerabilityFinding, 0, findingsPerImage)
+		counts := make(map[string]int)
+		for f := 0; f < findingsPerImage; f++ {
+			severity := severities[f%len(severities)]
+			counts[severity]++
+			findings = append(findings, types.VulnerabilityFinding{
+				// Every fifth finding repeats the previous CVE in another package
+				Name:             fmt.Sprintf("CVE-2024-%05d", f-boolToInt(f%5 == 4)),
+				Description:      fmt.Sprintf("Vulnerability %d\nwith details", f),
+				Severity:         severity,
+				Status:           "ACTIVE",
+				Type:             "PACKAGE_VULNERABILITY",
+				PackageName:      fmt.Sprintf("pkg-%d", f%7),
+				PackageVersion:   "1.0.0",
+				FixVersion:       "1.0.1",
+				FixAvailable:     fixStatuses[f%len(fixStatuses)],
+				ExploitAvailable: map[bool]string{true: "YES", false: "NO"}[f%4 == 0],
+				Score:            float64(f%10) + 0.5,
+			})
+		}
+
+		data[uri] = &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: counts,
+				TotalCount:      findingsPerImage,
+				Findings:        findings,
+				ScanStatus:      "COMPLETE",
+				LastScanTime:    &scanTime,
+			},
+			ImageInfo: types.ImageInfo{
+				URI:          uri,
+				Namespace:    fmt.Sprintf("team-%d", i%5),
+				Workload:     fmt.Sprintf("app-%d", i),
+				WorkloadType: "Deployment",
+			},
+		}
+	}
+
+	return &statsDataProvider{
+		MockVulnerabilityDataProvider: MockVulnerabilityDataProvider{
+			data:        data,
+			lastUpdated: time.Unix(1705315800, 0),
+		},
+		durationEMA:    42 * time.Second,
+		scrapeInterval: 5 * time.Minute,
+		lastTick:       time.Unix(1705315700, 0),
+	}
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// metricsAddedAfterGaugeVec lists metrics the legacy implementation never had
+var metricsAddedAfterGaugeVec = []string{"ecr_image_risk_score", "ecr_image_finding_type_count", "ecr_image_workload_count", "ecr_image_unfixable_vulnerability_count", "ecr_vulnerability_seconds_since_last_collection"}
+
+// withoutMetricFamilies drops the HELP, TYPE and sample lines of the named
+// metric families from text exposition output
+func withoutMetricFamilies(body string, names ...string) string {
+	var kept []string
+	for _, line := range strings.SplitAfter(body, "\n") {
+		drop := false
+		for _, name := range names {
+			if strings.HasPrefix(line, "# HELP "+name+" ") || strings.HasPrefix(line, "# TYPE "+name+" ") ||
+				strings.HasPrefix(line, name+"{") || strings.HasPrefix(line, name+" ") {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "")
+}
+
+func TestMetricsHandler_MatchesGaugeVecOutput(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	collectors := map[string]VulnerabilityDataProvider{
+		"large snapshot with duplicate label sets": largeMetricsTestData(50, 40),
+		"invalid image URI skipped": &MockVulnerabilityDataProvider{
+			data: map[string]*types.ImageVulnerabilityData{
+				"invalid-uri": {
+					ImageVulnerability: &types.ImageVulnerability{
+						ImageURI:        "invalid-uri",
+						Vulnerabilities: map[string]int{"HIGH": 1},
+						ScanStatus:      "FAILED",
+					},
+				},
+			},
+			lastUpdated: time.Unix(1705315800, 0),
+		},
+		"empty snapshot": &MockVulnerabilityDataProvider{
+			data:        make(map[string]*types.ImageVulnerabilityData),
+			lastUpdated: time.Unix(1705315800, 0),
+		},
+	}
+
+	for name, collector := range collectors {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/metrics", nil)
+
+			expected := httptest.NewRecorder()
+			newGaugeVecMetricsHandler(collector, logger).ServeHTTP(expected, req)
+
+			actual := httptest.NewRecorder()
+			NewMetricsHandler(collector, Options{}, logger).ServeHTTP(actual, req)
+
+			if actual.Code != expected.Code {
+				t.Fatalf("Status %d, want %d", actual.Code, expected.Code)
+			}
+			got := withoutMetricFamilies(actual.Body.String(), metricsAddedAfterGaugeVec...)
+			if got != expected.Body.String() {
+				t.Errorf("Collector output differs from GaugeVec output\n--- got ---\n%.2000s\n--- want ---\n%.2000s",
+					got, expected.Body.String())
+			}
+		})
+	}
+}
+
+func BenchmarkMetricsHandler(b *testing.B) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	collector := largeMetricsTestData(500, 50)
+	constructors := map[string]func() http.Handler{
+		"collector": func() http.Handler { return NewMetricsHandler(collector, Options{}, logger) },
+		"gaugevec":  func() http.Handler { return newGaugeVecMetricsHandler(collector, logger) },
+	}
+
+	for _, name := range []string{"collector", "gaugevec"} {
+		newHandler := constructors[name]
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			baseline := heapAlloc()
+			handler := newHandler()
+			for i := 0; i < b.N; i++ {
+				req := httptest.NewRequest("GET", "/metrics", nil)
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, req)
+			}
+			b.StopTimer()
+
+			// Heap still held by the handler between scrapes
+			retained := float64(heapAlloc()) - float64(baseline)
+			b.ReportMetric(max(retained, 0), "retained-B")
+			runtime.KeepAlive(handler)
+		})
+	}
+}
+
+// heapAlloc returns live heap bytes after full garbage collections; the second
+// cycle also drops sync.Pool victim caches
+func heapAlloc() uint64 {
+	runtime.GC()
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
+
+func TestMetricsHandler_Collectors(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	requests := prometheus.NewCounter(prometheus.CounterOpts{Name: "ecr_api_requests_total", Help: "test"})
+	requests.Add(3)
+
+	collector := &MockVulnerabilityDataProvider{data: map[string]*types.ImageVulnerabilityData{}, lastUpdated: time.Now()}
+	handler := NewMetricsHandler(collector, Options{Collectors: []prometheus.Collector{requests}}, logger)
+
+	// Collectors are registered per scrape, so repeated scrapes must not conflict
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if !strings.Contains(w.Body.String(), "ecr_api_requests_total 3") {
+			t.Errorf("Scrape %d: expected the extra collector in the output, got %s", i+1, w.Body.String())
+		}
+	}
+}
+
+func TestMetricsHandler_DropLabels(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:latest"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			uri: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        uri,
+					Vulnerabilities: map[string]int{"HIGH": 2},
+					ScanStatus:      "COMPLETE",
+					Findings: []types.VulnerabilityFinding{{
+						Name: "CVE-2024-0001", Severity: "HIGH", Description: "Heap overflow", Status: "ACTIVE", Type: "PACKAGE_VULNERABILITY",
+					}},
+				},
+				ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: "api", WorkloadType: "Deployment", Release: "checkout"},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+		absent  []string
+	}{
+		{
+			name:    "tag dropped",
+			options: Options{DropLabels: []string{"tag"}},
+			want: []string{
+				`ecr_image_vulnerability_count{image_uri="` + uri + `",namespace="default",repository="api",severity="HIGH",workload="api",workload_type="Deployment"} 2`,
+				`ecr_image_scan_status{image_uri="` + uri + `",namespace="default",repository="api",status="COMPLETE",workload="api",workload_type="Deployment"} 1`,
+			},
+			absent: []string{`tag="latest"`},
+		},
+		{
+			name:    "several labels dropped alongside the release label",
+			options: Options{DropLabels: []string{"tag", "workload_type"}, ReleaseLabel: true},
+			want: []string{
+				`ecr_image_vulnerability_count{image_uri="` + uri + `",namespace="default",release="checkout",repository="api",severity="HIGH",workload="api"} 2`,
+				`ecr_vulnerability_info{cve_name="CVE-2024-0001",description="Heap overflow",image_uri="` + uri + `",namespace="default",release="checkout",repository="api",severity="HIGH",status="ACTIVE",type="PACKAGE_VULNERABILITY",workload="api"} 1`,
+			},
+			absent: []string{`tag="latest"`, `workload_type="Deployment"`},
+		},
+		{
+			name:    "unknown labels are ignored",
+			options: Options{DropLabels: []string{"image_uri", "severity"}},
+			want: []string{
+				`ecr_image_vulnerability_count{image_uri="` + uri + `",namespace="default",repository="api",severity="HIGH",tag="latest",workload="api",workload_type="Deployment"} 2`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected %q in metrics output", want)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(body, absent) {
+					t.Errorf("Expected %q to be absent from metrics output", absent)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_DescriptionInfo(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Two images share a CVE, so its description would repeat per image
+	data := make(map[string]*types.ImageVulnerabilityData)
+	for name, cve := range map[string]string{"api": "CVE-2024-0002", "web": "CVE-2024-0003"} {
+		uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + ":v1"
+		data[uri] = &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{"HIGH": 2},
+				ScanStatus:      "COMPLETE",
+				Findings: []types.VulnerabilityFinding{
+					{Name: "CVE-2024-0001", Severity: "HIGH", Description: "Heap overflow", Status: "ACTIVE", Type: "PACKAGE_VULNERABILITY"},
+					{Name: cve, Severity: "HIGH", Description: "Use after free in " + name, Status: "ACTIVE", Type: "PACKAGE_VULNERABILITY"},
+				},
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "default", Workload: name, WorkloadType: "Deployment"},
+		}
+	}
+	mockCollector := &MockVulnerabilityDataProvider{data: data, lastUpdated: time.Now()}
+
+	tests := []struct {
+		name               string
+		options            Options
+		descriptionSeries  int
+		descriptionInLabel bool
+	}{
+		{"description label by default", Options{}, 0, true},
+		{"description info metric", Options{DescriptionInfo: true}, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+			body := w.Body.String()
+
+			// One info series per finding and image either way
+			if got := strings.Count(body, "\necr_vulnerability_info{"); got != 4 {
+				t.Errorf("Expected 4 ecr_vulnerability_info series, got %d", got)
+			}
+			if got := strings.Count(body, "\necr_cve_description_info{"); got != tt.descriptionSeries {
+				t.Errorf("Expected %d ecr_cve_description_info series, got %d", tt.descriptionSeries, got)
+			}
+			if got := strings.Contains(body, `description="Heap overflow",image_uri=`); got != tt.descriptionInLabel {
+				t.Errorf("Expected description label on ecr_vulnerability_info = %v, got %v", tt.descriptionInLabel, got)
+			}
+			if tt.descriptionSeries == 0 {
+				return
+			}
+
+			for _, want := range []string{
+				`ecr_cve_description_info{cve_name="CVE-2024-0001",description="Heap overflow"} 1`,
+				`ecr_cve_description_info{cve_name="CVE-2024-0003",description="Use after free in web"} 1`,
+				`ecr_vulnerability_info{cve_name="CVE-2024-0001",image_uri="123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v1",namespace="default",repository="web",severity="HIGH",status="ACTIVE",tag="v1",type="PACKAGE_VULNERABILITY",workload="web",workload_type="Deployment"} 1`,
+			} {
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected %q in metrics output", want)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_HealthyScanStatuses(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	data := make(map[string]*types.ImageVulnerabilityData)
+	for name, status := range map[string]string{"batch": "COMPLETE", "web": "ACTIVE", "api": "IN_PROGRESS"} {
+		uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + ":v1"
+		data[uri] = &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{ImageURI: uri, ScanStatus: status},
+			ImageInfo:          types.ImageInfo{URI: uri, Namespace: "default", Workload: name, WorkloadType: "Deployment"},
+		}
+	}
+	mockCollector := &MockVulnerabilityDataProvider{data: data, lastUpdated: time.Now()}
+
+	tests := []struct {
+		name     string
+		options  Options
+		expected map[string]int // Metric value per status
+	}{
+		{"only COMPLETE by default", Options{}, map[string]int{"COMPLETE": 1, "ACTIVE": 0, "IN_PROGRESS": 0}},
+		{"continuous scanning", Options{HealthyScanStatuses: []string{"COMPLETE", "ACTIVE"}}, map[string]int{"COMPLETE": 1, "ACTIVE": 1, "IN_PROGRESS": 0}},
+		{"case-insensitive", Options{HealthyScanStatuses: []string{"active"}}, map[string]int{"COMPLETE": 0, "ACTIVE": 1, "IN_PROGRESS": 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMetricsHandler(mockCollector, tt.options, logger)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+			body := w.Body.String()
+
+			for status, value := range tt.expected {
+				want := fmt.Sprintf(`status="%s",tag="v1",workload=`, status)
+				found := false
+				for _, line := range strings.Split(body, "\n") {
+					if strings.HasPrefix(line, "ecr_image_scan_status{") && strings.Contains(line, want) {
+						found = true
+						if !strings.HasSuffix(line, fmt.Sprintf("} %d", value)) {
+							t.Errorf("Expected status %s to map to %d, got %q", status, value, line)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("Expected an ecr_image_scan_status series for status %s", status)
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsHandler_WorkloadCount(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	shared := "123456789012.dkr.ecr.us-east-1.amazonaws.com/base:v1"
+	single := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	standalone := "123456789012.dkr.ecr.us-east-1.amazonaws.com/tool:v1"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			shared: {
+				ImageVulnerability: &types.ImageVulnerability{ImageURI: shared, ScanStatus: "COMPLETE"},
+				ImageInfo:          types.ImageInfo{URI: shared, Namespace: "production", Workload: "web", WorkloadType: "Deployment", WorkloadCount: 3},
+			},
+			single: {
+				ImageVulnerability: &types.ImageVulnerability{ImageURI: single, ScanStatus: "COMPLETE"},
+				ImageInfo:          types.ImageInfo{URI: single, Namespace: "production", Workload: "api", WorkloadType: "Deployment", WorkloadCount: 1},
+			},
+			standalone: {
+				ImageVulnerability: &types.ImageVulnerability{ImageURI: standalone, ScanStatus: "COMPLETE"},
+				ImageInfo:          types.ImageInfo{URI: standalone},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body := w.Body.String()
+
+	for _, want := range []string{
+		`ecr_image_workload_count{image_uri="123456789012.dkr.ecr.us-east-1.amazonaws.com/base:v1",namespace="production",repository="base",tag="v1",workload="web",workload_type="Deployment"} 3`,
+		`ecr_image_workload_count{image_uri="123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",namespace="production",repository="api",tag="v1",workload="api",workload_type="Deployment"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %q in metrics output", want)
+		}
+	}
+
+	// Images not used by any workload have no series
+	if got := strings.Count(body, "\necr_image_workload_count{"); got != 2 {
+		t.Errorf("Expected 2 ecr_image_workload_count series, got %d", got)
+	}
+}
+
+func TestMetricsHandler_UnfixableCount(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/test-app:v1.0.0"
+	mockCollector := &MockVulnerabilityDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			imageURI: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        imageURI,
+					Vulnerabilities: map[string]int{"CRITICAL": 3, "HIGH": 2, "LOW": 1},
+					ScanStatus:      "COMPLETE",
+					Findings: []types.VulnerabilityFinding{
+						{Name: "CVE-2024-0001", Severity: "CRITICAL", FixAvailable: "NO"},
+						{Name: "CVE-2024-0002", Severity: "CRITICAL", FixAvailable: "NO"},
+						{Name: "CVE-2024-0003", Severity: "CRITICAL", FixAvailable: "YES"},
+						{Name: "CVE-2024-0004", Severity: "HIGH", FixAvailable: "NO"},
+						{Name: "CVE-2024-0005", Severity: "HIGH", FixAvailable: "PARTIAL"},
+						{Name: "CVE-2024-0006", Severity: "LOW", FixAvailable: "YES"},
+						{Name: "CVE-2024-0007", Severity: "MEDIUM", FixAvailable: "NO", Accepted: true},
+						{Name: "CVE-2024-0008", Severity: "LOW"}, // Basic scanning reports no fix availability
+					},
+				},
+				ImageInfo: types.ImageInfo{URI: imageURI, Namespace: "production", Workload: "test-app", WorkloadType: "Deployment"},
+			},
+		},
+		lastUpdated: time.Now(),
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected map[string]int
+	}{
+		{"all severities", "", map[string]int{"CRITICAL": 2, "HIGH": 1}},
+		{"severity parameter", "?severity=high", map[string]int{"HIGH": 1}},
+	}
+
+	handler := NewMetricsHandler(mockCollector, Options{}, logger)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics"+tt.query, nil))
+			body := w.Body.String()
+
+			for severity, count := range tt.expected {
+				want := fmt.Sprintf(`ecr_image_unfixable_vulnerability_count{image_uri="%s",namespace="production",repository="test-app",severity="%s",tag="v1.0.0",workload="test-app",workload_type="Deployment"} %d`, imageURI, severity, count)
+				if !strings.Contains(body, want) {
+					t.Errorf("Expected %q in metrics output", want)
+				}
+			}
+
+			// Fixable, partially fixable, accepted and unknown findings have no series
+			if got := strings.Count(body, "\necr_image_unfixable_vulnerability_count{"); got != len(tt.expected) {
+				t.Errorf("Expected %d ecr_image_unfixable_vulnerability_count series, got %d", len(tt.expected), got)
+			}
+		})
+	}
+}
+
+func TestIsDroppableLabel(t *testing.T) {
+	for _, label := range DroppableLabels {
+		if !IsDroppableLabel(label) {
+			t.Errorf("Expected %q to be droppable", label)
+		}
+	}
+	for _, label := range []string{"image_uri", "severity", "cve_name", "release", ""} {
+		if IsDroppableLabel(label) {
+			t.Errorf("Expected %q not to be droppable", label)
+		}
+	}
+}
+
+func TestMetricsHandler_SecondsSinceLastCollection(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	lastCollection := now.Add(-90 * time.Second)
+
+	tests := []struct {
+		name           string
+		lastUpdated    time.Time
+		expectedMetric string
+	}{
+		{
+			name:           "after a collection",
+			lastUpdated:    lastCollection,
+			expectedMetric: "ecr_vulnerability_seconds_since_last_collection 90",
+		},
+		{
+			name:        "before the first collection",
+			lastUpdated: time.Time{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockCollector := &MockVulnerabilityDataProvider{
+				data:        map[string]*types.ImageVulnerabilityData{},
+				lastUpdated: tt.lastUpdated,
+			}
+			handler := NewMetricsHandler(mockCollector, Options{}, logger)
+			handler.now = func() time.Time { return now }
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+			body := w.Body.String()
+
+			if tt.expectedMetric == "" {
+				if strings.Contains(body, "ecr_vulnerability_seconds_since_last_collection ") {
+					t.Error("Expected no collection age before the first collection")
+				}
+				return
+			}
+			if !strings.Contains(body, tt.expectedMetric) {
+				t.Errorf("Expected metric not found: %s", tt.expectedMetric)
+			}
+
+			// Consistent with the recorded last collection time
+			expectedTimestamp := fmt.Sprintf(`ecr_vulnerability_collection_info{info_type="last_collection_timestamp"} %g`, float64(tt.lastUpdated.Unix()))
+			if !strings.Contains(body, expectedTimestamp) {
+				t.Errorf("Expected metric not found: %s", expectedTimestamp)
+			}
+		})
+	}
 }
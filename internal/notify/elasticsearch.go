@@ -0,0 +1,222 @@
+// ABOUTME: Elasticsearch sink posting every finding in _bulk NDJSON format after each collection.
+// ABOUTME: Indexes one document per finding with its image and workload context.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultElasticsearchIndex receives the findings when no index is configured
+const DefaultElasticsearchIndex = "vulnrelay-findings"
+
+// defaultBulkBatchSize caps the documents sent per _bulk request
+const defaultBulkBatchSize = 1000
+
+// ElasticsearchOptions configures the Elasticsearch sink
+type ElasticsearchOptions struct {
+	URL       string        // _bulk endpoint, e.g. https://es:9200/_bulk
+	Index     string        // Target index (default vulnrelay-findings)
+	BatchSize int           // Documents per request (default 1000)
+	Timeout   time.Duration // Per-request timeout (default 10s)
+}
+
+// FindingDocument is the indexed document of one finding
+type FindingDocument struct {
+	Timestamp        time.Time  `json:"@timestamp"`
+	ImageURI         string     `json:"image_uri"`
+	Repository       string     `json:"repository"`
+	Tag              string     `json:"tag"`
+	Namespace        string     `json:"namespace"`
+	Workload         string     `json:"workload"`
+	WorkloadType     string     `json:"workload_type"`
+	Release          string     `json:"release,omitempty"`
+	CVE              string     `json:"cve"`
+	Severity         string     `json:"severity"`
+	Description      string     `json:"description,omitempty"`
+	PackageName      string     `json:"package_name,omitempty"`
+	PackageVersion   string     `json:"package_version,omitempty"`
+	FixVersion       string     `json:"fix_version,omitempty"`
+	FixAvailable     string     `json:"fix_available,omitempty"`
+	ExploitAvailable string     `json:"exploit_available,omitempty"`
+	Score            float64    `json:"score"`
+	Status           string     `json:"status,omitempty"`
+	Accepted         bool       `json:"accepted"`
+	FirstSeen        *time.Time `json:"first_seen,omitempty"`
+}
+
+// bulkAction is the action line preceding each document
+type bulkAction struct {
+	Index struct {
+		Index string `json:"_index"`
+	} `json:"index"`
+}
+
+// ElasticsearchSink indexes the findings of every collection. Documents carry
+// the collection time, so each collection adds a point-in-time copy.
+type ElasticsearchSink struct {
+	options     ElasticsearchOptions
+	redactedURL string // URL without its basic auth credentials, for errors
+	client      *http.Client
+	now         func() time.Time
+	logger      *logrus.Logger
+}
+
+// NewElasticsearchSink validates the options and creates a sink
+func NewElasticsearchSink(options ElasticsearchOptions, logger *logrus.Logger) (*ElasticsearchSink, error) {
+	parsed, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch URL must be an http(s) URL")
+	}
+	if parsed.User != nil {
+		parsed.User = url.User("REDACTED")
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("elasticsearch URL must be an http(s) URL, got %q", parsed.String())
+	}
+	if options.Index == "" {
+		options.Index = DefaultElasticsearchIndex
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBulkBatchSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultRequestTimeout
+	}
+
+	return &ElasticsearchSink{
+		options:     options,
+		redactedURL: parsed.String(),
+		client:      &http.Client{Timeout: options.Timeout},
+		now:         time.Now,
+		logger:      logger,
+	}, nil
+}
+
+// Export indexes every finding of a collection, including accepted ones,
+// which are flagged. Failed batches are logged and later batches are still sent.
+func (s *ElasticsearchSink) Export(ctx context.Context, data map[string]*types.ImageVulnerabilityData) {
+	documents := findingDocuments(data, s.now().UTC())
+	if len(documents) == 0 {
+		return
+	}
+
+	failed := 0
+	for start := 0; start < len(documents); start += s.options.BatchSize {
+		end := min(start+s.options.BatchSize, len(documents))
+		if err := s.send(ctx, documents[start:end]); err != nil {
+			s.logger.WithError(err).WithField("documents", end-start).Error("Failed to index findings in Elasticsearch")
+			failed += end - start
+		}
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"documents": len(documents) - failed,
+		"failed":    failed,
+		"index":     s.options.Index,
+	}).Info("Indexed findings in Elasticsearch")
+}
+
+// findingDocuments flattens a collection into documents, sorted by image and CVE
+func findingDocuments(data map[string]*types.ImageVulnerabilityData, timestamp time.Time) []FindingDocument {
+	var documents []FindingDocument
+	for uri, vulnData := range data {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+		for _, finding := range vulnData.Findings {
+			documents = append(documents, FindingDocument{
+				Timestamp:        timestamp,
+				ImageURI:         uri,
+				Repository:       vulnData.Repository,
+				Tag:              vulnData.Tag,
+				Namespace:        vulnData.Namespace,
+				Workload:         vulnData.Workload,
+				WorkloadType:     vulnData.WorkloadType,
+				Release:          vulnData.Release,
+				CVE:              finding.Name,
+				Severity:         finding.Severity,
+				Description:      finding.Description,
+				PackageName:      finding.PackageName,
+				PackageVersion:   finding.PackageVersion,
+				FixVersion:       finding.FixVersion,
+				FixAvailable:     finding.FixAvailable,
+				ExploitAvailable: finding.ExploitAvailable,
+				Score:            finding.Score,
+				Status:           finding.Status,
+				Accepted:         finding.Accepted,
+				FirstSeen:        finding.FirstSeen,
+			})
+		}
+	}
+
+	sort.SliceStable(documents, func(i, j int) bool {
+		if documents[i].ImageURI != documents[j].ImageURI {
+			return documents[i].ImageURI < documents[j].ImageURI
+		}
+		return documents[i].CVE < documents[j].CVE
+	})
+	return documents
+}
+
+// send posts one _bulk request. Elasticsearch reports per-document failures
+// in the response body, so a 2xx response can still fail.
+func (s *ElasticsearchSink) send(ctx context.Context, documents []FindingDocument) error {
+	var action bulkAction
+	action.Index.Index = s.options.Index
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, document := range documents {
+		if err := encoder.Encode(action); err != nil {
+			return fmt.Errorf("failed to encode bulk action: %w", err)
+		}
+		if err := encoder.Encode(document); err != nil {
+			return fmt.Errorf("failed to encode finding: %w", err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.options.URL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create bulk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// Client errors repeat the URL, which may carry basic auth credentials
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = s.redactedURL
+		}
+		return fmt.Errorf("bulk request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("elasticsearch returned status %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode bulk response: %w", err)
+	}
+	if result.Errors {
+		return fmt.Errorf("elasticsearch rejected some documents")
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+// ABOUTME: Unit tests for the Elasticsearch bulk sink.
+// ABOUTME: Uses a stub _bulk endpoint to check the NDJSON payload, batching and error handling.
+
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// bulkRecorder captures the lines of each _bulk request posted to a stub server
+type bulkRecorder struct {
+	mu           sync.Mutex
+	requests     [][]string
+	contentTypes []string
+	response     string
+}
+
+func (b *bulkRecorder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var lines []string
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	b.mu.Lock()
+	b.requests = append(b.requests, lines)
+	b.contentTypes = append(b.contentTypes, r.Header.Get("Content-Type"))
+	response := b.response
+	b.mu.Unlock()
+
+	if response == "" {
+		response = `{"took":3,"errors":false,"items":[]}`
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write([]byte(response))
+}
+
+func newTestElasticsearchSink(t *testing.T, options ElasticsearchOptions, recorder *bulkRecorder) *ElasticsearchSink {
+	t.Helper()
+
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	options.URL = server.URL + "/_bulk"
+	sink, err := NewElasticsearchSink(options, logger)
+	if err != nil {
+		t.Fatalf("NewElasticsearchSink() failed: %v", err)
+	}
+	sink.now = func() time.Time { return time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC) }
+	return sink
+}
+
+func TestElasticsearchSinkExport(t *testing.T) {
+	recorder := &bulkRecorder{}
+	sink := newTestElasticsearchSink(t, ElasticsearchOptions{Index: "security-findings"}, recorder)
+
+	data := map[string]*types.ImageVulnerabilityData{
+		"registry/web:v2": imageData("registry/web:v2", "production",
+			types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH", PackageName: "openssl", FixVersion: "3.0.8"},
+			types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "LOW", Accepted: true},
+		),
+		"registry/api:v1": imageData("registry/api:v1", "staging",
+			types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "CRITICAL", Score: 9.8},
+		),
+		"registry/clean:v1": imageData("registry/clean:v1", "production"),
+	}
+	sink.Export(context.Background(), data)
+
+	if len(recorder.requests) != 1 {
+		t.Fatalf("Expected 1 bulk request, got %d", len(recorder.requests))
+	}
+	if recorder.contentTypes[0] != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON content type, got %q", recorder.contentTypes[0])
+	}
+
+	// Action and document lines alternate, one pair per finding
+	lines := recorder.requests[0]
+	if len(lines) != 6 {
+		t.Fatalf("Expected 6 lines, got %d: %v", len(lines), lines)
+	}
+	expected := []struct {
+		imageURI  string
+		namespace string
+		cve       string
+		severity  string
+		accepted  bool
+	}{
+		{"registry/api:v1", "staging", "CVE-2024-0003", "CRITICAL", false},
+		{"registry/web:v2", "production", "CVE-2024-0001", "LOW", true},
+		{"registry/web:v2", "production", "CVE-2024-0002", "HIGH", false},
+	}
+	for i, want := range expected {
+		var action map[string]map[string]string
+		if err := json.Unmarshal([]byte(lines[2*i]), &action); err != nil {
+			t.Fatalf("Failed to decode action line %q: %v", lines[2*i], err)
+		}
+		if action["index"]["_index"] != "security-findings" {
+			t.Errorf("Expected index action for security-findings, got %v", action)
+		}
+
+		var document FindingDocument
+		if err := json.Unmarshal([]byte(lines[2*i+1]), &document); err != nil {
+			t.Fatalf("Failed to decode document line %q: %v", lines[2*i+1], err)
+		}
+		if document.ImageURI != want.imageURI || document.Namespace != want.namespace || document.CVE != want.cve ||
+			document.Severity != want.severity || document.Accepted != want.accepted {
+			t.Errorf("Document %d: expected %+v, got %+v", i, want, document)
+		}
+		if document.Workload != "app" || document.WorkloadType != "Deployment" {
+			t.Errorf("Document %d: expected workload context, got %+v", i, document)
+		}
+		if !document.Timestamp.Equal(time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)) {
+			t.Errorf("Document %d: unexpected timestamp %v", i, document.Timestamp)
+		}
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal([]byte(lines[5]), &raw); err != nil {
+		t.Fatalf("Failed to decode document: %v", err)
+	}
+	if raw["@timestamp"] != "2024-03-05T14:30:00Z" || raw["package_name"] != "openssl" || raw["fix_version"] != "3.0.8" {
+		t.Errorf("Unexpected document fields %v", raw)
+	}
+}
+
+func TestElasticsearchSinkBatches(t *testing.T) {
+	recorder := &bulkRecorder{}
+	sink := newTestElasticsearchSink(t, ElasticsearchOptions{BatchSize: 2}, recorder)
+
+	sink.Export(context.Background(), map[string]*types.ImageVulnerabilityData{
+		"registry/api:v1": imageData("registry/api:v1", "production",
+			types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "HIGH"},
+			types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH"},
+			types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "HIGH"},
+		),
+	})
+
+	if len(recorder.requests) != 2 {
+		t.Fatalf("Expected 2 bulk requests, got %d", len(recorder.requests))
+	}
+	if len(recorder.requests[0]) != 4 || len(recorder.requests[1]) != 2 {
+		t.Errorf("Expected batches of 2 and 1 documents, got %d and %d lines", len(recorder.requests[0]), len(recorder.requests[1]))
+	}
+	if recorder.requests[0][0] != `{"index":{"_index":"vulnrelay-findings"}}` {
+		t.Errorf("Expected the default index, got %s", recorder.requests[0][0])
+	}
+}
+
+func TestElasticsearchSinkSend(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		expectError bool
+	}{
+		{"accepted", `{"errors":false,"items":[]}`, false},
+		{"rejected documents", `{"errors":true,"items":[{"index":{"status":400}}]}`, true},
+		{"invalid response", `not json`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := newTestElasticsearchSink(t, ElasticsearchOptions{}, &bulkRecorder{response: tt.response})
+			err := sink.send(context.Background(), []FindingDocument{{ImageURI: "registry/api:v1", CVE: "CVE-2024-0001"}})
+			if (err != nil) != tt.expectError {
+				t.Errorf("send() error = %v, expectError %v", err, tt.expectError)
+			}
+		})
+	}
+}
+
+func TestNewElasticsearchSinkValidatesURL(t *testing.T) {
+	for _, url := range []string{"", "es:9200/_bulk", "ftp://es/_bulk"} {
+		if _, err := NewElasticsearchSink(ElasticsearchOptions{URL: url}, logrus.New()); err == nil {
+			t.Errorf("Expected an error for URL %q", url)
+		}
+	}
+}
+
+func TestElasticsearchSinkErrorsRedactCredentials(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	address := strings.TrimPrefix(server.URL, "http://")
+	server.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// A request to the closed server fails with an error repeating the URL
+	sink, err := NewElasticsearchSink(ElasticsearchOptions{URL: "http://elastic:s3cret@" + address + "/_bulk"}, logger)
+	if err != nil {
+		t.Fatalf("NewElasticsearchSink() failed: %v", err)
+	}
+	err = sink.send(context.Background(), []FindingDocument{{ImageURI: "registry/api:v1", CVE: "CVE-2024-0001"}})
+	if err == nil {
+		t.Fatal("Expected the request to fail")
+	}
+	if strings.Contains(err.Error(), "s3cret") || !strings.Contains(err.Error(), "REDACTED@"+address) {
+		t.Errorf("Expected the credentials to be redacted, got %v", err)
+	}
+
+	_, err = NewElasticsearchSink(ElasticsearchOptions{URL: "ftp://elastic:s3cret@es/_bulk"}, logger)
+	if err == nil || strings.Contains(err.Error(), "s3cret") {
+		t.Errorf("Expected a validation error without the credentials, got %v", err)
+	}
+}
@@ -0,0 +1,388 @@
+// ABOUTME: Webhook notifier posting newly detected vulnerabilities after each collection.
+// ABOUTME: Applies per-namespace severity thresholds matched by glob pattern.
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/severity"
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultRequestTimeout bounds each webhook delivery
+const defaultRequestTimeout = 10 * time.Second
+
+// DefaultWebhookDeliveryTimeout bounds all deliveries of a collection,
+// retries included, so a failing receiver doesn't hold up the following hooks
+const DefaultWebhookDeliveryTimeout = time.Minute
+
+// defaultMinSeverity applies to namespaces without a matching threshold
+const defaultMinSeverity = "CRITICAL"
+
+// Retries of failed webhook deliveries; the backoff doubles per attempt up
+// to maxRetryBackoff and is jittered so replicas don't retry in lockstep
+const (
+	DefaultWebhookMaxRetries = 3
+	defaultRetryBackoff      = time.Second
+	maxRetryBackoff          = 30 * time.Second
+)
+
+// SeverityThreshold is the lowest severity that alerts for namespaces matching
+// a glob pattern
+type SeverityThreshold struct {
+	Pattern  string // path.Match glob, e.g. prod-*
+	Severity string
+}
+
+// ParseSeverityThresholds parses a comma-separated list of PATTERN=SEVERITY
+// pairs, e.g. prod-*=HIGH,sandbox-*=CRITICAL, naming severities of the given
+// order. Order is kept: the first matching pattern wins.
+func ParseSeverityThresholds(value string, severities severity.Order) ([]SeverityThreshold, error) {
+	var thresholds []SeverityThreshold
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		pattern, level, found := strings.Cut(pair, "=")
+		pattern = strings.TrimSpace(pattern)
+		level = strings.ToUpper(strings.TrimSpace(level))
+		if !found || pattern == "" {
+			return nil, fmt.Errorf("invalid threshold %q: expected PATTERN=SEVERITY", pair)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern %q: %w", pattern, err)
+		}
+		if !severities.Known(level) {
+			return nil, fmt.Errorf("invalid severity %q for pattern %q: must be one of %s", level, pattern, strings.Join(severities.Severities(), ", "))
+		}
+		thresholds = append(thresholds, SeverityThreshold{Pattern: pattern, Severity: level})
+	}
+	return thresholds, nil
+}
+
+// WebhookOptions configures the webhook notifier
+type WebhookOptions struct {
+	URL                 string
+	MinSeverity         string              // Threshold for namespaces without a match (default CRITICAL)
+	NamespaceThresholds []SeverityThreshold // Per-namespace overrides, first match wins
+	SeverityOrder       []string            // Ranks the thresholds, most severe first (empty = severity.DefaultOrder)
+	Timeout             time.Duration       // Per-request timeout (default 10s)
+
+	// MaxRetries is the number of retries after a failed delivery, e.g.
+	// DefaultWebhookMaxRetries; only network errors, 429 and 5xx responses
+	// are retried (0 = no retries)
+	MaxRetries      int
+	RetryBackoff    time.Duration // Wait before the first retry (default 1s)
+	DeliveryTimeout time.Duration // Bounds a collection's deliveries, retries included (default 1m)
+
+	// NotifyAll skips the diff against the previous collection and notifies
+	// every finding meeting its threshold after each collection
+	NotifyAll       bool
+	BatchSize       int // Findings per notification, larger sets are split (0 = one notification)
+	DiffConcurrency int // Workers diffing a collection's images (default 1)
+}
+
+// Notification is the JSON body posted to the webhook. Text makes it directly
+// usable as a Slack incoming webhook payload.
+type Notification struct {
+	Text     string            `json:"text"`
+	Findings []NotifiedFinding `json:"findings"`
+}
+
+// NotifiedFinding is a newly detected finding that met its namespace threshold
+type NotifiedFinding struct {
+	ImageURI     string `json:"image_uri"`
+	Namespace    string `json:"namespace"`
+	Workload     string `json:"workload"`
+	WorkloadType string `json:"workload_type"`
+	CVE          string `json:"cve"`
+	Severity     string `json:"severity"`
+	PackageName  string `json:"package_name,omitempty"`
+	FixVersion   string `json:"fix_version,omitempty"`
+}
+
+// WebhookNotifier posts findings that appeared since the previous collection
+// and meet the severity threshold of their namespace
+type WebhookNotifier struct {
+	options    WebhookOptions
+	severities severity.Order
+	client     *http.Client
+	logger     *logrus.Logger
+
+	mutex    sync.Mutex
+	previous map[string]map[string]bool // CVEs per image URI; nil until the first collection sets the baseline
+}
+
+// NewWebhookNotifier validates the options and creates a notifier
+func NewWebhookNotifier(options WebhookOptions, logger *logrus.Logger) (*WebhookNotifier, error) {
+	parsed, err := url.Parse(options.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("webhook URL must be an http(s) URL, got %q", options.URL)
+	}
+
+	options.MinSeverity = strings.ToUpper(options.MinSeverity)
+	if options.MinSeverity == "" {
+		options.MinSeverity = defaultMinSeverity
+	}
+	severities := severity.NewOrder(options.SeverityOrder)
+	if !severities.Known(options.MinSeverity) {
+		return nil, fmt.Errorf("invalid webhook minimum severity %q: must be one of %s", options.MinSeverity, strings.Join(severities.Severities(), ", "))
+	}
+	for _, threshold := range options.NamespaceThresholds {
+		if !severities.Known(threshold.Severity) {
+			return nil, fmt.Errorf("invalid webhook severity %q for pattern %q: must be one of %s", threshold.Severity, threshold.Pattern, strings.Join(severities.Severities(), ", "))
+		}
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultRequestTimeout
+	}
+	if options.MaxRetries < 0 {
+		options.MaxRetries = 0
+	}
+	if options.RetryBackoff <= 0 {
+		options.RetryBackoff = defaultRetryBackoff
+	}
+	if options.DeliveryTimeout <= 0 {
+		options.DeliveryTimeout = DefaultWebhookDeliveryTimeout
+	}
+
+	return &WebhookNotifier{
+		options:    options,
+		severities: severities,
+		client:     &http.Client{Timeout: options.Timeout},
+		logger:     logger,
+	}, nil
+}
+
+// thresholdFor returns the minimum alerting severity for a namespace
+func (n *WebhookNotifier) thresholdFor(namespace string) string {
+	for _, threshold := range n.options.NamespaceThresholds {
+		if matched, _ := path.Match(threshold.Pattern, namespace); matched {
+			return threshold.Severity
+		}
+	}
+	return n.options.MinSeverity
+}
+
+// meetsThreshold reports whether a severity is at or above the threshold;
+// severities outside the order never alert
+func (n *WebhookNotifier) meetsThreshold(level, threshold string) bool {
+	level = strings.ToUpper(level)
+	return n.severities.Known(level) && !n.severities.MoreSevere(threshold, level)
+}
+
+// Notify compares a collection with the previous one and posts new findings
+// meeting their namespace threshold. The first collection only records a
+// baseline, so restarts don't re-announce every existing finding. Accepted
+// findings never alert. With NotifyAll every qualifying finding is posted, and
+// with BatchSize large sets are split across several notifications. Delivery
+// runs within the collection hook, so it is bounded by DeliveryTimeout;
+// notifications not delivered in time are logged as dead letters.
+func (n *WebhookNotifier) Notify(ctx context.Context, data map[string]*types.ImageVulnerabilityData) {
+	n.mutex.Lock()
+	findings := n.diff(data)
+	n.mutex.Unlock()
+
+	if len(findings) == 0 {
+		return
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].ImageURI != findings[j].ImageURI {
+			return findings[i].ImageURI < findings[j].ImageURI
+		}
+		return findings[i].CVE < findings[j].CVE
+	})
+
+	ctx, cancel := context.WithTimeout(ctx, n.options.DeliveryTimeout)
+	defer cancel()
+
+	batchSize := n.options.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(findings)
+	}
+	for start := 0; start < len(findings); start += batchSize {
+		end := min(start+batchSize, len(findings))
+		n.post(ctx, findings[start:end], len(findings))
+	}
+}
+
+// diff indexes the findings of a collection, returning those missing from
+// the previous collection's index that meet their threshold. Images are split
+// across DiffConcurrency workers that share the previous index read-only. The
+// index replaces the previous one, so each collection is compared with the
+// last; images missing from the collection keep their previous entries.
+// Callers hold the mutex, so concurrent notifications diff one at a time.
+func (n *WebhookNotifier) diff(data map[string]*types.ImageVulnerabilityData) []NotifiedFinding {
+	uris := make([]string, 0, len(data))
+	for uri := range data {
+		uris = append(uris, uri)
+	}
+
+	// Each worker indexes every workers-th image into its own part
+	type part struct {
+		current  map[string]map[string]bool
+		findings []NotifiedFinding
+	}
+	workers := max(min(n.options.DiffConcurrency, len(uris)), 1)
+	parts := make([]part, workers)
+	var wg sync.WaitGroup
+	for w := range parts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := &parts[w]
+			p.current = make(map[string]map[string]bool, len(uris)/workers+1)
+			for i := w; i < len(uris); i += workers {
+				p.findings = n.diffImage(uris[i], data[uris[i]], p.current, p.findings)
+			}
+		}()
+	}
+	wg.Wait()
+
+	current := make(map[string]map[string]bool, len(data))
+	var findings []NotifiedFinding
+	for _, p := range parts {
+		maps.Copy(current, p.current)
+		findings = append(findings, p.findings...)
+	}
+	for uri, cves := range n.previous {
+		if _, ok := current[uri]; !ok {
+			current[uri] = cves
+		}
+	}
+	n.previous = current
+	return findings
+}
+
+// diffImage indexes an image's CVEs into current and appends its findings
+// that are new and meet their threshold. Images whose findings were dropped
+// aren't indexed, so they keep their previous entry like missing images.
+func (n *WebhookNotifier) diffImage(uri string, vulnData *types.ImageVulnerabilityData, current map[string]map[string]bool, findings []NotifiedFinding) []NotifiedFinding {
+	if vulnData.ImageVulnerability == nil || vulnData.FindingsDropped {
+		return findings
+	}
+	cves := make(map[string]bool, len(vulnData.Findings))
+	current[uri] = cves
+	threshold := n.thresholdFor(vulnData.Namespace)
+	for _, finding := range vulnData.Findings {
+		if cves[finding.Name] {
+			continue
+		}
+		cves[finding.Name] = true
+
+		if !n.options.NotifyAll && (n.previous == nil || n.previous[uri][finding.Name]) {
+			continue
+		}
+		if finding.Accepted || !n.meetsThreshold(finding.Severity, threshold) {
+			continue
+		}
+		findings = append(findings, NotifiedFinding{
+			ImageURI:     uri,
+			Namespace:    vulnData.Namespace,
+			Workload:     vulnData.Workload,
+			WorkloadType: vulnData.WorkloadType,
+			CVE:          finding.Name,
+			Severity:     finding.Severity,
+			PackageName:  finding.PackageName,
+			FixVersion:   finding.FixVersion,
+		})
+	}
+	return findings
+}
+
+// post delivers one notification of a batch of findings, logging the payload
+// as a dead letter when every attempt fails
+func (n *WebhookNotifier) post(ctx context.Context, findings []NotifiedFinding, total int) {
+	text := fmt.Sprintf("VulnRelay detected %d new vulnerabilities", total)
+	if n.options.NotifyAll {
+		text = fmt.Sprintf("VulnRelay found %d vulnerabilities", total)
+	}
+	body, err := json.Marshal(Notification{Text: text, Findings: findings})
+	if err != nil {
+		n.logger.WithError(err).Error("Failed to encode webhook notification")
+		return
+	}
+
+	if err := n.send(ctx, body); err != nil {
+		// Dead letter: the undelivered payload is logged so it can be replayed
+		n.logger.WithError(err).WithFields(logrus.Fields{
+			"findings":     len(findings),
+			"dead_letter":  true,
+			"notification": string(body),
+		}).Error("Failed to deliver webhook notification, giving up")
+		return
+	}
+	n.logger.WithField("findings", len(findings)).Info("Sent webhook notification for new vulnerabilities")
+}
+
+// send posts a notification body, retrying transient failures with jittered
+// exponential backoff. It gives up early when the wait would pass the
+// context's deadline.
+func (n *WebhookNotifier) send(ctx context.Context, body []byte) error {
+	backoff := n.options.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := n.deliver(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt > n.options.MaxRetries {
+			return err
+		}
+
+		// Waits between half and the full backoff
+		wait := backoff/2 + rand.N(backoff/2+1)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("webhook delivery timed out after %d attempts: %w", attempt, err)
+		}
+		n.logger.WithError(err).WithFields(logrus.Fields{
+			"attempt":  attempt,
+			"retry_in": wait,
+		}).Warn("Webhook delivery failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("webhook delivery cancelled after %d attempts: %w", attempt, err)
+		case <-time.After(wait):
+		}
+		backoff = min(2*backoff, maxRetryBackoff)
+	}
+}
+
+// deliver makes a single delivery attempt and reports whether a failure is
+// worth retrying
+func (n *WebhookNotifier) deliver(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.options.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return false, nil
+}
@@ -0,0 +1,544 @@
+// ABOUTME: Unit tests for the webhook notifier and namespace severity thresholds.
+// ABOUTME: Uses a stub HTTP server to capture delivered notifications.
+
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/severity"
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// webhookRecorder captures the notifications posted to a stub webhook
+type webhookRecorder struct {
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func (w *webhookRecorder) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	var notification Notification
+	if err := json.NewDecoder(r.Body).Decode(&notification); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.mu.Lock()
+	w.notifications = append(w.notifications, notification)
+	w.mu.Unlock()
+	rw.WriteHeader(http.StatusOK)
+}
+
+func (w *webhookRecorder) received() []Notification {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]Notification(nil), w.notifications...)
+}
+
+func newTestNotifier(t *testing.T, options WebhookOptions) (*WebhookNotifier, *webhookRecorder) {
+	t.Helper()
+
+	recorder := &webhookRecorder{}
+	server := httptest.NewServer(recorder)
+	t.Cleanup(server.Close)
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	options.URL = server.URL
+	notifier, err := NewWebhookNotifier(options, logger)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() failed: %v", err)
+	}
+	return notifier, recorder
+}
+
+func imageData(uri, namespace string, findings ...types.VulnerabilityFinding) *types.ImageVulnerabilityData {
+	return &types.ImageVulnerabilityData{
+		ImageVulnerability: &types.ImageVulnerability{ImageURI: uri, Findings: findings},
+		ImageInfo:          types.ImageInfo{URI: uri, Namespace: namespace, Workload: "app", WorkloadType: "Deployment"},
+	}
+}
+
+func TestParseSeverityThresholds(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         string
+		severityOrder []string
+		expected      []SeverityThreshold
+		expectError   bool
+	}{
+		{
+			name:  "ordered patterns",
+			value: "prod-*=high, sandbox-*=CRITICAL,*=MEDIUM",
+			expected: []SeverityThreshold{
+				{Pattern: "prod-*", Severity: "HIGH"},
+				{Pattern: "sandbox-*", Severity: "CRITICAL"},
+				{Pattern: "*", Severity: "MEDIUM"},
+			},
+		},
+		{name: "empty", value: ""},
+		{name: "missing severity", value: "prod-*", expectError: true},
+		{name: "missing pattern", value: "=HIGH", expectError: true},
+		{name: "unknown severity", value: "prod-*=URGENT", expectError: true},
+		{name: "severity outside the default order", value: "dev-*=INFORMATIONAL", expectError: true},
+		{
+			name:          "extended severity order",
+			value:         "dev-*=informational",
+			severityOrder: []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL"},
+			expected:      []SeverityThreshold{{Pattern: "dev-*", Severity: "INFORMATIONAL"}},
+		},
+		{name: "malformed pattern", value: "prod-[=HIGH", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thresholds, err := ParseSeverityThresholds(tt.value, severity.NewOrder(tt.severityOrder))
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseSeverityThresholds() failed: %v", err)
+			}
+			if !reflect.DeepEqual(thresholds, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, thresholds)
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierNamespaceThresholds(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{
+		MinSeverity: "CRITICAL",
+		NamespaceThresholds: []SeverityThreshold{
+			{Pattern: "prod-*", Severity: "HIGH"},
+			{Pattern: "sandbox-*", Severity: "CRITICAL"},
+		},
+	})
+	ctx := context.Background()
+
+	prodImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	sandboxImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:dev"
+	otherImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/batch:v1"
+
+	// The first collection only sets the baseline
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{
+		prodImage:    imageData(prodImage, "prod-eu"),
+		sandboxImage: imageData(sandboxImage, "sandbox-alice"),
+		otherImage:   imageData(otherImage, "batch", types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}),
+	})
+	if got := recorder.received(); len(got) != 0 {
+		t.Fatalf("Expected no notification for the baseline collection, got %v", got)
+	}
+
+	high := types.VulnerabilityFinding{Name: "CVE-2024-1000", Severity: "HIGH", PackageName: "openssl"}
+	critical := types.VulnerabilityFinding{Name: "CVE-2024-2000", Severity: "CRITICAL"}
+	accepted := types.VulnerabilityFinding{Name: "CVE-2024-3000", Severity: "CRITICAL", Accepted: true}
+
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{
+		prodImage:    imageData(prodImage, "prod-eu", high, accepted),
+		sandboxImage: imageData(sandboxImage, "sandbox-alice", high, critical),
+		otherImage:   imageData(otherImage, "batch", types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}, high),
+	})
+
+	received := recorder.received()
+	if len(received) != 1 {
+		t.Fatalf("Expected one notification, got %d", len(received))
+	}
+
+	// HIGH alerts in prod only; sandbox and unmatched namespaces need CRITICAL.
+	// Accepted and previously seen findings never alert.
+	expected := []NotifiedFinding{
+		{ImageURI: sandboxImage, Namespace: "sandbox-alice", Workload: "app", WorkloadType: "Deployment", CVE: "CVE-2024-2000", Severity: "CRITICAL"},
+		{ImageURI: prodImage, Namespace: "prod-eu", Workload: "app", WorkloadType: "Deployment", CVE: "CVE-2024-1000", Severity: "HIGH", PackageName: "openssl"},
+	}
+	if !reflect.DeepEqual(received[0].Findings, expected) {
+		t.Errorf("Unexpected notified findings:\n got  %+v\n want %+v", received[0].Findings, expected)
+	}
+	if received[0].Text == "" {
+		t.Error("Expected notification text")
+	}
+
+	// Unchanged findings are not announced again
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{
+		prodImage:    imageData(prodImage, "prod-eu", high, accepted),
+		sandboxImage: imageData(sandboxImage, "sandbox-alice", high, critical),
+	})
+	if got := recorder.received(); len(got) != 1 {
+		t.Errorf("Expected no further notifications, got %d", len(got)-1)
+	}
+}
+
+func TestWebhookNotifierSeverityOrder(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{
+		MinSeverity:   "NEGLIGIBLE",
+		SeverityOrder: []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"},
+	})
+	ctx := context.Background()
+
+	image := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{image: imageData(image, "default")})
+
+	// A threshold at the configured lowest level alerts for it, while
+	// severities outside the order never alert
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{
+		image: imageData(image, "default",
+			types.VulnerabilityFinding{Name: "CVE-2024-1000", Severity: "negligible"},
+			types.VulnerabilityFinding{Name: "CVE-2024-2000", Severity: "UNDEFINED"},
+		),
+	})
+
+	received := recorder.received()
+	if len(received) != 1 || len(received[0].Findings) != 1 || received[0].Findings[0].CVE != "CVE-2024-1000" {
+		t.Errorf("Expected only the NEGLIGIBLE finding to be notified, got %+v", received)
+	}
+}
+
+func TestWebhookNotifierLargeDiff(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{MinSeverity: "CRITICAL"})
+	ctx := context.Background()
+
+	// 200 images with 25 findings each
+	collection := func(extra ...types.VulnerabilityFinding) map[string]*types.ImageVulnerabilityData {
+		data := make(map[string]*types.ImageVulnerabilityData)
+		for i := range 200 {
+			uri := fmt.Sprintf("registry/app-%03d:v1", i)
+			var findings []types.VulnerabilityFinding
+			for j := range 25 {
+				findings = append(findings, types.VulnerabilityFinding{Name: fmt.Sprintf("CVE-2024-%05d", i*25+j), Severity: "CRITICAL"})
+			}
+			if i == 42 {
+				findings = append(findings, extra...)
+			}
+			data[uri] = imageData(uri, "production", findings...)
+		}
+		return data
+	}
+
+	notifier.Notify(ctx, collection())
+	notifier.Notify(ctx, collection(types.VulnerabilityFinding{Name: "CVE-2024-99999", Severity: "CRITICAL"}))
+
+	received := recorder.received()
+	if len(received) != 1 {
+		t.Fatalf("Expected one notification, got %d", len(received))
+	}
+	expected := []NotifiedFinding{
+		{ImageURI: "registry/app-042:v1", Namespace: "production", Workload: "app", WorkloadType: "Deployment", CVE: "CVE-2024-99999", Severity: "CRITICAL"},
+	}
+	if !reflect.DeepEqual(received[0].Findings, expected) {
+		t.Errorf("Unexpected notified findings:\n got  %+v\n want %+v", received[0].Findings, expected)
+	}
+}
+
+func TestWebhookNotifierConcurrentDiff(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{MinSeverity: "CRITICAL", DiffConcurrency: 4})
+	ctx := context.Background()
+
+	collection := func(extra ...types.VulnerabilityFinding) map[string]*types.ImageVulnerabilityData {
+		data := make(map[string]*types.ImageVulnerabilityData)
+		for i := range 500 {
+			uri := fmt.Sprintf("registry/app-%03d:v1", i)
+			findings := []types.VulnerabilityFinding{{Name: fmt.Sprintf("CVE-2024-%05d", i), Severity: "CRITICAL"}}
+			if i == 42 || i == 317 {
+				findings = append(findings, extra...)
+			}
+			data[uri] = imageData(uri, "production", findings...)
+		}
+		return data
+	}
+	notifier.Notify(ctx, collection())
+
+	// Overlapping notifications of the same collection diff one at a time, so
+	// only the first announces the new finding
+	updated := collection(types.VulnerabilityFinding{Name: "CVE-2024-99999", Severity: "CRITICAL"})
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			notifier.Notify(ctx, updated)
+		}()
+	}
+	wg.Wait()
+
+	received := recorder.received()
+	if len(received) != 1 {
+		t.Fatalf("Expected one notification, got %d", len(received))
+	}
+	var images []string
+	for _, finding := range received[0].Findings {
+		images = append(images, finding.ImageURI)
+	}
+	if expected := []string{"registry/app-042:v1", "registry/app-317:v1"}; !reflect.DeepEqual(images, expected) {
+		t.Errorf("Expected the new finding in %v, got %v", expected, images)
+	}
+}
+
+func TestWebhookNotifierMissingImageKeepsBaseline(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{MinSeverity: "CRITICAL"})
+	ctx := context.Background()
+
+	const api, web = "registry/api:v1", "registry/web:v1"
+	critical := types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}
+	present := map[string]*types.ImageVulnerabilityData{
+		api: imageData(api, "production", critical),
+		web: imageData(web, "production"),
+	}
+
+	// The api image is missing from the second collection, e.g. because its
+	// fetch failed, and returns unchanged in the third
+	notifier.Notify(ctx, present)
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{web: present[web]})
+	notifier.Notify(ctx, present)
+
+	if got := recorder.received(); len(got) != 0 {
+		t.Errorf("Expected no notification for a returning image's existing findings, got %+v", got)
+	}
+}
+
+func TestWebhookNotifierFindingsDroppedKeepsBaseline(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{MinSeverity: "CRITICAL"})
+	ctx := context.Background()
+
+	const api = "registry/api:v1"
+	critical := types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}
+	retained := map[string]*types.ImageVulnerabilityData{api: imageData(api, "production", critical)}
+	dropped := imageData(api, "production")
+	dropped.FindingsDropped = true
+
+	// The api image's findings are dropped in the second collection to bound
+	// memory and retained again in the third
+	notifier.Notify(ctx, retained)
+	notifier.Notify(ctx, map[string]*types.ImageVulnerabilityData{api: dropped})
+	notifier.Notify(ctx, retained)
+
+	if got := recorder.received(); len(got) != 0 {
+		t.Errorf("Expected no notification for findings that were only dropped, got %+v", got)
+	}
+}
+
+func TestWebhookNotifierNotifyAllBatches(t *testing.T) {
+	notifier, recorder := newTestNotifier(t, WebhookOptions{MinSeverity: "HIGH", NotifyAll: true, BatchSize: 2})
+
+	data := map[string]*types.ImageVulnerabilityData{
+		"registry/api:v1": imageData("registry/api:v1", "production",
+			types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"},
+			types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH"},
+			types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "LOW"},
+		),
+		"registry/web:v1": imageData("registry/web:v1", "production",
+			types.VulnerabilityFinding{Name: "CVE-2024-0004", Severity: "HIGH"},
+		),
+	}
+
+	// Without the diff, the first collection and unchanged findings notify too
+	notifier.Notify(context.Background(), data)
+	notifier.Notify(context.Background(), data)
+
+	received := recorder.received()
+	if len(received) != 4 {
+		t.Fatalf("Expected 2 batches per collection, got %d notifications", len(received))
+	}
+	var cves []string
+	for _, notification := range received[:2] {
+		for _, finding := range notification.Findings {
+			cves = append(cves, finding.CVE)
+		}
+	}
+	if expected := []string{"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0004"}; !reflect.DeepEqual(cves, expected) {
+		t.Errorf("Expected findings %v, got %v", expected, cves)
+	}
+	if len(received[0].Findings) != 2 || len(received[1].Findings) != 1 {
+		t.Errorf("Expected batches of 2 and 1 findings, got %d and %d", len(received[0].Findings), len(received[1].Findings))
+	}
+}
+
+func TestNewWebhookNotifierValidation(t *testing.T) {
+	logger := logrus.New()
+
+	tests := []struct {
+		name    string
+		options WebhookOptions
+	}{
+		{"missing URL", WebhookOptions{}},
+		{"non-http URL", WebhookOptions{URL: "ftp://hooks.example.com"}},
+		{"unknown minimum severity", WebhookOptions{URL: "https://hooks.example.com", MinSeverity: "URGENT"}},
+		{"minimum severity outside the order", WebhookOptions{URL: "https://hooks.example.com", MinSeverity: "INFORMATIONAL"}},
+		{"unknown namespace severity", WebhookOptions{URL: "https://hooks.example.com", NamespaceThresholds: []SeverityThreshold{{Pattern: "prod-*", Severity: "URGENT"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewWebhookNotifier(tt.options, logger); err == nil {
+				t.Error("Expected error but got none")
+			}
+		})
+	}
+}
+
+// flakyWebhook fails the first attempts with a status, then records deliveries
+type flakyWebhook struct {
+	webhookRecorder
+	failures int
+	status   int
+
+	mu       sync.Mutex
+	attempts int
+}
+
+func (f *flakyWebhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.attempts++
+	fail := f.attempts <= f.failures
+	f.mu.Unlock()
+
+	if fail {
+		rw.WriteHeader(f.status)
+		return
+	}
+	f.webhookRecorder.ServeHTTP(rw, r)
+}
+
+func TestWebhookNotifierRetries(t *testing.T) {
+	tests := []struct {
+		name             string
+		failures         int
+		status           int
+		maxRetries       int
+		expectedAttempts int
+		expectDelivered  bool
+	}{
+		{"recovers after transient failures", 2, http.StatusServiceUnavailable, 3, 3, true},
+		{"rate limited", 1, http.StatusTooManyRequests, 3, 2, true},
+		{"retries exhausted", 5, http.StatusBadGateway, 2, 3, false},
+		{"client errors are not retried", 1, http.StatusForbidden, 3, 1, false},
+		{"retries disabled", 1, http.StatusServiceUnavailable, 0, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := &flakyWebhook{failures: tt.failures, status: tt.status}
+			server := httptest.NewServer(webhook)
+			defer server.Close()
+
+			logger, hook := logtest.NewNullLogger()
+			notifier, err := NewWebhookNotifier(WebhookOptions{
+				URL:          server.URL,
+				MaxRetries:   tt.maxRetries,
+				RetryBackoff: time.Millisecond,
+			}, logger)
+			if err != nil {
+				t.Fatalf("NewWebhookNotifier() failed: %v", err)
+			}
+
+			uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+			notifier.Notify(context.Background(), map[string]*types.ImageVulnerabilityData{uri: imageData(uri, "production")})
+			notifier.Notify(context.Background(), map[string]*types.ImageVulnerabilityData{
+				uri: imageData(uri, "production", types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}),
+			})
+
+			if webhook.attempts != tt.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tt.expectedAttempts, webhook.attempts)
+			}
+			if delivered := len(webhook.received()) == 1; delivered != tt.expectDelivered {
+				t.Errorf("Delivered = %v, expected %v", delivered, tt.expectDelivered)
+			}
+
+			// Undelivered notifications end in a dead-letter entry with the payload
+			var deadLetter *logrus.Entry
+			for _, entry := range hook.AllEntries() {
+				if entry.Data["dead_letter"] == true {
+					deadLetter = entry
+				}
+			}
+			if (deadLetter != nil) == tt.expectDelivered {
+				t.Fatalf("Dead-letter entry logged = %v, expected %v", deadLetter != nil, !tt.expectDelivered)
+			}
+			if deadLetter != nil {
+				var notification Notification
+				if err := json.Unmarshal([]byte(deadLetter.Data["notification"].(string)), &notification); err != nil {
+					t.Fatalf("Failed to decode dead-letter payload: %v", err)
+				}
+				if len(notification.Findings) != 1 || notification.Findings[0].CVE != "CVE-2024-0001" {
+					t.Errorf("Unexpected dead-letter payload %+v", notification)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookNotifierRetryCancelled(t *testing.T) {
+	webhook := &flakyWebhook{failures: 10, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	logger, _ := logtest.NewNullLogger()
+	notifier, err := NewWebhookNotifier(WebhookOptions{URL: server.URL, MaxRetries: 5, RetryBackoff: time.Hour}, logger)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := notifier.send(ctx, []byte(`{}`)); err == nil {
+		t.Fatal("Expected an error when the context is cancelled during backoff")
+	}
+	if webhook.attempts != 1 {
+		t.Errorf("Expected 1 attempt before cancellation, got %d", webhook.attempts)
+	}
+}
+
+func TestWebhookNotifierDeliveryTimeout(t *testing.T) {
+	webhook := &flakyWebhook{failures: 10, status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(webhook)
+	defer server.Close()
+
+	logger, hook := logtest.NewNullLogger()
+	notifier, err := NewWebhookNotifier(WebhookOptions{
+		URL:             server.URL,
+		MaxRetries:      5,
+		RetryBackoff:    time.Hour,
+		DeliveryTimeout: time.Second,
+	}, logger)
+	if err != nil {
+		t.Fatalf("NewWebhookNotifier() failed: %v", err)
+	}
+
+	uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	notifier.Notify(context.Background(), map[string]*types.ImageVulnerabilityData{uri: imageData(uri, "production")})
+
+	// A retry waiting past the delivery timeout isn't attempted, so the hook
+	// returns right away instead of sleeping through the backoff
+	start := time.Now()
+	notifier.Notify(context.Background(), map[string]*types.ImageVulnerabilityData{
+		uri: imageData(uri, "production", types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}),
+	})
+	if elapsed := time.Since(start); elapsed >= time.Second {
+		t.Errorf("Expected Notify to give up before the delivery timeout, took %v", elapsed)
+	}
+	if webhook.attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", webhook.attempts)
+	}
+
+	deadLetters := 0
+	for _, entry := range hook.AllEntries() {
+		if entry.Data["dead_letter"] == true {
+			deadLetters++
+		}
+	}
+	if deadLetters != 1 {
+		t.Errorf("Expected the notification to be dead-lettered once, got %d", deadLetters)
+	}
+}
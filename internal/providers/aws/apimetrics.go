@@ -0,0 +1,86 @@
+// ABOUTME: Prometheus instrumentation of AWS API calls made for ECR scanning.
+// ABOUTME: An AWS SDK middleware counts and times every request attempt by operation and status.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// apiMetricsMiddlewareID names the middleware in the SDK stack
+const apiMetricsMiddlewareID = "VulnRelayAPIMetrics"
+
+// APIMetrics counts and times AWS API requests. It implements
+// prometheus.Collector so it can be served alongside the vulnerability metrics.
+type APIMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewAPIMetrics creates empty AWS API request metrics
+func NewAPIMetrics() *APIMetrics {
+	labels := []string{"operation", "status"}
+	return &APIMetrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ecr_api_requests_total",
+			Help: "AWS API requests made for ECR scanning, by operation and status (success or the AWS error code)",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ecr_api_request_duration_seconds",
+			Help:    "Latency of AWS API requests made for ECR scanning, by operation and status",
+			Buckets: prometheus.DefBuckets,
+		}, labels),
+	}
+}
+
+// DefaultAPIMetrics records the requests of every ECR source in the process
+var DefaultAPIMetrics = NewAPIMetrics()
+
+// Describe implements prometheus.Collector
+func (m *APIMetrics) Describe(ch chan<- *prometheus.Desc) {
+	m.requests.Describe(ch)
+	m.duration.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (m *APIMetrics) Collect(ch chan<- prometheus.Metric) {
+	m.requests.Collect(ch)
+	m.duration.Collect(ch)
+}
+
+// AddMiddleware registers the instrumentation on an SDK client's stack; use it
+// as an entry of aws.Config.APIOptions
+func (m *APIMetrics) AddMiddleware(stack *middleware.Stack) error {
+	// Registered in the deserialize step, so each retry attempt is recorded
+	// with the error the response deserialized into
+	return stack.Deserialize.Add(middleware.DeserializeMiddlewareFunc(apiMetricsMiddlewareID,
+		func(ctx context.Context, in middleware.DeserializeInput, next middleware.DeserializeHandler) (middleware.DeserializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleDeserialize(ctx, in)
+
+			operation := middleware.GetOperationName(ctx)
+			status := apiStatus(err)
+			m.requests.WithLabelValues(operation, status).Inc()
+			m.duration.WithLabelValues(operation, status).Observe(time.Since(start).Seconds())
+			return out, metadata, err
+		}), middleware.Before)
+}
+
+// apiStatus labels a request outcome: "success", the AWS error code such as
+// "ThrottlingException", or "error" for failures without a response
+func apiStatus(err error) string {
+	if err == nil {
+		return "success"
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() != "" {
+		return apiErr.ErrorCode()
+	}
+	return "error"
+}
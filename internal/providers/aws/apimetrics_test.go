@@ -0,0 +1,121 @@
+// ABOUTME: Tests for the AWS API request metrics middleware.
+// ABOUTME: Calls a real ECR client against a local server and checks counts and latencies.
+
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestAPIMetricsMiddleware(t *testing.T) {
+	throttle := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		if throttle {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ThrottlingException","message":"Rate exceeded"}`))
+			return
+		}
+		w.Write([]byte(`{"imageScanStatus":{"status":"COMPLETE"},"imageScanFindings":{"findings":[]}}`))
+	}))
+	defer server.Close()
+
+	apiMetrics := NewAPIMetrics()
+	client := ecr.New(ecr.Options{
+		Region:           "us-east-1",
+		BaseEndpoint:     aws.String(server.URL),
+		Credentials:      credentials.NewStaticCredentialsProvider("AKID", "SECRET", ""),
+		RetryMaxAttempts: 1,
+		APIOptions:       []func(*middleware.Stack) error{apiMetrics.AddMiddleware},
+	})
+	input := &ecr.DescribeImageScanFindingsInput{
+		RepositoryName: aws.String("api"),
+		ImageId:        &ecrtypes.ImageIdentifier{ImageTag: aws.String("v1")},
+	}
+
+	if _, err := client.DescribeImageScanFindings(context.Background(), input); err != nil {
+		t.Fatalf("DescribeImageScanFindings() failed: %v", err)
+	}
+	throttle = true
+	if _, err := client.DescribeImageScanFindings(context.Background(), input); err == nil {
+		t.Fatal("Expected a throttling error")
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(apiMetrics)
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() failed: %v", err)
+	}
+
+	// Series are keyed by metric name and status; every series has operation
+	// DescribeImageScanFindings
+	counts := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			labels := make(map[string]string)
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["operation"] != "DescribeImageScanFindings" {
+				t.Errorf("Unexpected operation label %q", labels["operation"])
+			}
+			key := family.GetName() + " " + labels["status"]
+			switch family.GetName() {
+			case "ecr_api_requests_total":
+				counts[key] = metric.GetCounter().GetValue()
+			case "ecr_api_request_duration_seconds":
+				counts[key] = float64(metric.GetHistogram().GetSampleCount())
+				if metric.GetHistogram().GetSampleSum() <= 0 {
+					t.Errorf("Expected recorded latency for %s", key)
+				}
+			}
+		}
+	}
+
+	expected := map[string]float64{
+		"ecr_api_requests_total success":                       1,
+		"ecr_api_requests_total ThrottlingException":           1,
+		"ecr_api_request_duration_seconds success":             1,
+		"ecr_api_request_duration_seconds ThrottlingException": 1,
+	}
+	if len(counts) != len(expected) {
+		t.Errorf("Expected series %v, got %v", expected, counts)
+	}
+	for key, value := range expected {
+		if counts[key] != value {
+			t.Errorf("Expected %s = %v, got %v", key, value, counts[key])
+		}
+	}
+}
+
+func TestAPIStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{"success", nil, "success"},
+		{"API error", &ecrtypes.RepositoryNotFoundException{Message: aws.String("missing")}, "RepositoryNotFoundException"},
+		{"transport error", errors.New("connection reset"), "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := apiStatus(tt.err); got != tt.expected {
+				t.Errorf("apiStatus() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -5,9 +5,12 @@ package aws
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
+	"path"
 	"strings"
+	"sync"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -15,31 +18,141 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
 	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
 )
 
+// untriagedSeverity counts enhanced findings that Inspector reports without a severity
+const untriagedSeverity = "UNTRIAGED"
+
+// DefaultImagePlatform is the platform scanned when a tag references a multi-arch manifest list
+const DefaultImagePlatform = "linux/amd64"
+
+// Media types for multi-arch manifest lists and their single-platform manifests
+const (
+	mediaTypeDockerManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIImageIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIManifest        = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// ecrAPI is the subset of the ECR client used by ECRSource
+type ecrAPI interface {
+	DescribeImageScanFindings(ctx context.Context, params *ecr.DescribeImageScanFindingsInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImageScanFindingsOutput, error)
+	BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error)
+	DescribeRepositories(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error)
+	GetRegistryScanningConfiguration(ctx context.Context, params *ecr.GetRegistryScanningConfigurationInput, optFns ...func(*ecr.Options)) (*ecr.GetRegistryScanningConfigurationOutput, error)
+	DescribePullThroughCacheRules(ctx context.Context, params *ecr.DescribePullThroughCacheRulesInput, optFns ...func(*ecr.Options)) (*ecr.DescribePullThroughCacheRulesOutput, error)
+	StartImageScan(ctx context.Context, params *ecr.StartImageScanInput, optFns ...func(*ecr.Options)) (*ecr.StartImageScanOutput, error)
+}
+
+// stsAPI is the subset of the STS client used to report the effective identity
+type stsAPI interface {
+	GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error)
+}
+
 // ECRSource implements VulnerabilitySource for Amazon ECR
 type ECRSource struct {
-	client    *ecr.Client
+	client    ecrAPI
+	identity  stsAPI // Uses the same credentials as client
 	accountID string
 	region    string
+	platform  string   // os/arch[/variant] scanned for multi-arch images
+	allowlist []string // Repository name globs; empty scans every repository
+	digests   bool     // Report the scanned manifest digest
+	untagged  bool     // Skip images referenced by digest only
 	logger    *logrus.Logger
+
+	// retained lists the severities whose findings are kept; findings of
+	// other severities are only counted (nil keeps every finding)
+	retained map[string]bool
+
+	// scanTriggers limits scans started for images without one; nil when
+	// scan triggering is disabled
+	scanTriggers *scanTriggerLimiter
+
+	// Upstream registries of the pull-through cache rules by repository
+	// prefix, listed on first use. A failed listing is retried next cycle.
+	pullThroughMutex     sync.Mutex
+	pullThrough          map[string]ecrtypes.UpstreamRegistry // nil until listed
+	pullThroughAttempted bool                                 // Listed, or failed this cycle
+}
+
+// DefaultCrossAccountRoleName is the role assumed in the target account when none is configured
+const DefaultCrossAccountRoleName = "ECRVulnerabilityExporterRole"
+
+// ECROptions holds optional settings for the ECR vulnerability source
+type ECROptions struct {
+	AssumeRoleARN string // Explicit role to assume, skips cross-account detection when set
+
+	// CrossAccountRole is the role assumed when the caller is in a different account.
+	// Either a role name, or a full ARN template where {account_id} is substituted.
+	CrossAccountRole string
+
+	// Platform selects the manifest scanned for multi-arch images, e.g. "linux/arm64"
+	Platform string
+
+	// RepositoryAllowlist restricts scanning to repositories matching these
+	// globs, e.g. "team-a/*". Entries are resolved against the registry's
+	// repositories once per cycle.
+	RepositoryAllowlist []string
+
+	// ResolveDigests reports the digest of the scanned manifest with each
+	// result, so findings of a mutable tag can be tied to one image
+	ResolveDigests bool
+
+	// TriggerScans starts a scan of images that have none, at most once per
+	// ScanTriggerWindow per image
+	TriggerScans bool
+
+	// RetainedSeverities keeps only the findings of these severities, to save
+	// memory; the severity counts still include every finding. Empty keeps
+	// every finding.
+	RetainedSeverities []string
+
+	// SkipUntaggedImages drops images referenced by digest only, e.g.
+	// repository@sha256:..., before they are looked up
+	SkipUntaggedImages bool
+}
+
+// crossAccountRoleARN builds the role ARN to assume in the target account
+func crossAccountRoleARN(accountID, role string) string {
+	if role == "" {
+		role = DefaultCrossAccountRoleName
+	}
+	if strings.HasPrefix(role, "arn:") {
+		return strings.ReplaceAll(role, "{account_id}", accountID)
+	}
+	return fmt.Sprintf("arn:aws:iam::%s:role/%s", accountID, role)
+}
+
+// loadConfig loads the default AWS credential chain for a region, with
+// requests recorded by DefaultAPIMetrics
+func loadConfig(ctx context.Context, region string) (aws.Config, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region),
+		config.WithAPIOptions([]func(*middleware.Stack) error{DefaultAPIMetrics.AddMiddleware}))
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return cfg, nil
 }
 
 // NewECRSource creates a new ECR vulnerability source
-func NewECRSource(ctx context.Context, accountID, region string, logger *logrus.Logger) (*ECRSource, error) {
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
+func NewECRSource(ctx context.Context, accountID, region string, opts ECROptions, logger *logrus.Logger) (*ECRSource, error) {
+	// STS clients are created from copies of cfg, so role assumption is instrumented too
+	cfg, err := loadConfig(ctx, region)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+		return nil, err
 	}
 
 	// Handle role assumption for cross-account access
 	var ecrClient *ecr.Client
 
-	// Check if we need to assume a role based on AWS_IAM_ASSUME_ROLE_ARN environment variable
-	if assumeRoleARN := os.Getenv("AWS_IAM_ASSUME_ROLE_ARN"); assumeRoleARN != "" {
-		logger.WithField("role_arn", assumeRoleARN).Info("Assuming role from AWS_IAM_ASSUME_ROLE_ARN environment variable")
+	// Check if an explicit role to assume was configured
+	if assumeRoleARN := opts.AssumeRoleARN; assumeRoleARN != "" {
+		logger.WithField("role_arn", assumeRoleARN).Info("Assuming configured IAM role")
 
 		currentCfg := cfg.Copy()
 		stsClient := sts.NewFromConfig(currentCfg)
@@ -64,7 +177,7 @@ func NewECRSource(ctx context.Context, accountID, region string, logger *logrus.
 
 			// If we're in a different account, assume we need to assume a role
 			if currentAccountID != accountID {
-				roleARN := fmt.Sprintf("arn:aws:iam::%s:role/ECRVulnerabilityExporterRole", accountID)
+				roleARN := crossAccountRoleARN(accountID, opts.CrossAccountRole)
 				logger.WithField("role_arn", roleARN).Info("Assuming cross-account role")
 
 				// Create STS credentials for role assumption
@@ -76,14 +189,63 @@ func NewECRSource(ctx context.Context, accountID, region string, logger *logrus.
 
 	ecrClient = ecr.NewFromConfig(cfg)
 
-	return &ECRSource{
+	platform := opts.Platform
+	if platform == "" {
+		platform = DefaultImagePlatform
+	}
+
+	source := &ECRSource{
 		client:    ecrClient,
+		identity:  sts.NewFromConfig(cfg),
 		accountID: accountID,
 		region:    region,
+		platform:  platform,
+		allowlist: opts.RepositoryAllowlist,
+		digests:   opts.ResolveDigests,
+		untagged:  opts.SkipUntaggedImages,
 		logger:    logger,
+	}
+	if opts.TriggerScans {
+		source.scanTriggers = newScanTriggerLimiter(ScanTriggerWindow)
+	}
+	if len(opts.RetainedSeverities) > 0 {
+		source.retained = make(map[string]bool, len(opts.RetainedSeverities))
+		for _, severity := range opts.RetainedSeverities {
+			source.retained[strings.ToUpper(severity)] = true
+		}
+	}
+	return source, nil
+}
+
+// CallerIdentity returns the AWS identity ECR requests are made with, which is
+// the assumed role when role assumption is in effect
+func (e *ECRSource) CallerIdentity(ctx context.Context) (*types.CallerIdentity, error) {
+	output, err := e.identity.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller identity: %w", err)
+	}
+
+	return &types.CallerIdentity{
+		Account: aws.ToString(output.Account),
+		ARN:     aws.ToString(output.Arn),
+		UserID:  aws.ToString(output.UserId),
 	}, nil
 }
 
+// RegistryScanType returns the registry's scan type: "BASIC" or "ENHANCED"
+// (Amazon Inspector)
+func (e *ECRSource) RegistryScanType(ctx context.Context) (string, error) {
+	output, err := e.client.GetRegistryScanningConfiguration(ctx, &ecr.GetRegistryScanningConfigurationInput{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get registry scanning configuration: %w", err)
+	}
+	if output.ScanningConfiguration == nil {
+		return "", errors.New("registry scanning configuration is empty")
+	}
+
+	return string(output.ScanningConfiguration.ScanType), nil
+}
+
 // Name returns the vulnerability source name
 func (e *ECRSource) Name() string {
 	return "aws-ecr"
@@ -91,7 +253,17 @@ func (e *ECRSource) Name() string {
 
 // ParseImageURI extracts repository name and tag from a full ECR image URI
 // Expected format: account.dkr.ecr.region.amazonaws.com/repository:tag
+// Pull-through cache repositories keep their prefix, e.g. ecr-public/nginx/nginx.
 func (e *ECRSource) ParseImageURI(imageURI string) (repository, tag string, err error) {
+	repository, tag, err = parseImageURI(imageURI)
+	if err != nil {
+		return "", "", err
+	}
+	return e.pullThroughRepository(repository), tag, nil
+}
+
+// parseImageURI splits an image URI into the path after the registry host and the tag
+func parseImageURI(imageURI string) (repository, tag string, err error) {
 	// Split by '/' to get the repository part
 	parts := strings.Split(imageURI, "/")
 	if len(parts) < 2 {
@@ -110,15 +282,228 @@ func (e *ECRSource) ParseImageURI(imageURI string) (repository, tag string, err
 	return repoParts[0], repoParts[1], nil
 }
 
+// pullThroughRepository returns the repository an image is cached in. Docker
+// Hub official images are cached under library/, so a reference to
+// docker-hub/nginx is looked up in the docker-hub/library/nginx repository.
+func (e *ECRSource) pullThroughRepository(repository string) string {
+	prefix, name, found := strings.Cut(repository, "/")
+	if !found || strings.Contains(name, "/") {
+		return repository
+	}
+	if upstream, _ := e.upstreamRegistry(prefix); upstream == ecrtypes.UpstreamRegistryDockerHub {
+		return prefix + "/library/" + name
+	}
+	return repository
+}
+
+// pullThroughRulesPageSize is the largest page DescribePullThroughCacheRules returns
+const pullThroughRulesPageSize = 1000
+
+// loadPullThroughRules lists the registry's pull-through cache rules once.
+// When they can't be listed, e.g. without ecr:DescribePullThroughCacheRules,
+// cached repositories are treated like any other until a retry succeeds.
+func (e *ECRSource) loadPullThroughRules(ctx context.Context) {
+	e.pullThroughMutex.Lock()
+	defer e.pullThroughMutex.Unlock()
+
+	if e.pullThroughAttempted {
+		return
+	}
+	e.pullThroughAttempted = true
+
+	rules := make(map[string]ecrtypes.UpstreamRegistry)
+	input := &ecr.DescribePullThroughCacheRulesInput{
+		RegistryId: aws.String(e.accountID),
+		MaxResults: aws.Int32(pullThroughRulesPageSize),
+	}
+	for {
+		output, err := e.client.DescribePullThroughCacheRules(ctx, input)
+		if err != nil {
+			e.logger.WithError(err).Warn("Could not list pull-through cache rules, treating all repositories as regular until the next cycle")
+			return
+		}
+		for _, rule := range output.PullThroughCacheRules {
+			rules[aws.ToString(rule.EcrRepositoryPrefix)] = rule.UpstreamRegistry
+		}
+		if aws.ToString(output.NextToken) == "" {
+			break
+		}
+		input.NextToken = output.NextToken
+	}
+
+	if len(rules) > 0 {
+		e.logger.WithField("pull_through_prefixes", len(rules)).Info("Loaded pull-through cache rules")
+	}
+	e.pullThrough = rules
+}
+
+// retryPullThroughRules lets the next use list the pull-through cache rules
+// again if the last listing failed
+func (e *ECRSource) retryPullThroughRules() {
+	e.pullThroughMutex.Lock()
+	defer e.pullThroughMutex.Unlock()
+
+	if e.pullThrough == nil {
+		e.pullThroughAttempted = false
+	}
+}
+
+// upstreamRegistry returns the upstream registry of a pull-through cache
+// rule's repository prefix
+func (e *ECRSource) upstreamRegistry(prefix string) (ecrtypes.UpstreamRegistry, bool) {
+	e.pullThroughMutex.Lock()
+	defer e.pullThroughMutex.Unlock()
+
+	upstream, ok := e.pullThrough[prefix]
+	return upstream, ok
+}
+
+// isPullThroughCache reports whether a repository is created by a
+// pull-through cache rule
+func (e *ECRSource) isPullThroughCache(repository string) bool {
+	prefix, _, found := strings.Cut(repository, "/")
+	if !found {
+		return false
+	}
+	_, ok := e.upstreamRegistry(prefix)
+	return ok
+}
+
+// dropUntagged removes images referenced by digest only
+func (e *ECRSource) dropUntagged(images []types.ImageInfo) []types.ImageInfo {
+	tagged := make([]types.ImageInfo, 0, len(images))
+	for _, image := range images {
+		if !isUntagged(image.URI) {
+			tagged = append(tagged, image)
+		}
+	}
+
+	if skipped := len(images) - len(tagged); skipped > 0 {
+		e.logger.WithField("skipped_images", skipped).Debug("Skipped untagged images")
+	}
+	return tagged
+}
+
+// isUntagged reports whether an image is referenced by digest only, as in
+// repository@sha256:...; repository:tag@sha256:... still has a tag
+func isUntagged(imageURI string) bool {
+	name, _, hasDigest := strings.Cut(imageURI, "@")
+	if !hasDigest {
+		return false
+	}
+	repository := name[strings.LastIndex(name, "/")+1:]
+	return !strings.Contains(repository, ":")
+}
+
+// describeRepositoriesPageSize is the largest page DescribeRepositories returns
+const describeRepositoriesPageSize = 1000
+
+// ResolveImageFilter returns the filter applied to a registry's discovered
+// images, dropping untagged images when they are skipped and images from
+// repositories outside the repository allowlist. The registry's repositories
+// are listed once here, so every batch of images filtered during a
+// collection cycle shares the resolved allowlist.
+func (e *ECRSource) ResolveImageFilter(ctx context.Context, registry string) (func([]types.ImageInfo) []types.ImageInfo, error) {
+	// Filters are resolved at the start of every cycle, which is when a
+	// failed listing of the pull-through cache rules is retried
+	e.retryPullThroughRules()
+
+	if len(e.allowlist) == 0 {
+		return e.imageFilter(nil), nil
+	}
+	e.loadPullThroughRules(ctx)
+
+	repositories, err := e.listRepositories(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool)
+	for _, pattern := range e.allowlist {
+		matched := false
+		for _, repository := range repositories {
+			if ok, _ := path.Match(pattern, repository); ok {
+				allowed[repository] = true
+				matched = true
+			}
+		}
+		if !matched {
+			e.logger.WithField("pattern", pattern).Warn("Repository allowlist entry matches no ECR repository")
+		}
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"registry":             registry,
+		"repositories":         len(repositories),
+		"allowed_repositories": len(allowed),
+	}).Debug("Resolved repository allowlist")
+
+	return e.imageFilter(allowed), nil
+}
+
+// imageFilter returns a filter keeping the images of allowed repositories,
+// or of every repository when allowed is nil
+func (e *ECRSource) imageFilter(allowed map[string]bool) func([]types.ImageInfo) []types.ImageInfo {
+	return func(images []types.ImageInfo) []types.ImageInfo {
+		if e.untagged {
+			images = e.dropUntagged(images)
+		}
+		if allowed == nil {
+			return images
+		}
+
+		filtered := make([]types.ImageInfo, 0, len(images))
+		for _, image := range images {
+			repo, _, err := e.ParseImageURI(image.URI)
+			if err != nil || !allowed[repo] {
+				continue
+			}
+			filtered = append(filtered, image)
+		}
+
+		if skipped := len(images) - len(filtered); skipped > 0 {
+			e.logger.WithField("skipped_images", skipped).Debug("Applied repository allowlist")
+		}
+		return filtered
+	}
+}
+
+// listRepositories returns the names of all repositories in the registry,
+// following DescribeRepositories pagination
+func (e *ECRSource) listRepositories(ctx context.Context) ([]string, error) {
+	var repositories []string
+	input := &ecr.DescribeRepositoriesInput{
+		RegistryId: aws.String(e.accountID),
+		MaxResults: aws.Int32(describeRepositoriesPageSize),
+	}
+
+	for {
+		output, err := e.client.DescribeRepositories(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe repositories: %w", err)
+		}
+		for _, repository := range output.Repositories {
+			repositories = append(repositories, aws.ToString(repository.RepositoryName))
+		}
+
+		if aws.ToString(output.NextToken) == "" {
+			return repositories, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
 // GetImageVulnerabilities retrieves vulnerability data for a container image from ECR
 func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
 	logger := e.logger.WithField("image_uri", imageURI)
 
 	// Parse the image URI to extract repository and tag
-	repo, tag, err := e.ParseImageURI(imageURI)
+	repo, tag, err := parseImageURI(imageURI)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse image URI: %w", err)
 	}
+	e.loadPullThroughRules(ctx)
+	repo = e.pullThroughRepository(repo)
 
 	logger = logger.WithFields(logrus.Fields{
 		"repository": repo,
@@ -136,6 +521,50 @@ func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string
 	}
 
 	output, err := e.client.DescribeImageScanFindings(ctx, input)
+
+	// Manifest lists aren't scanned themselves, only their platform-specific images
+	var platform string
+	if err != nil && isManifestListScanError(err) {
+		digest, resolveErr := e.resolvePlatformDigest(ctx, repo, tag)
+		if resolveErr != nil {
+			logger.WithError(resolveErr).Debug("Could not resolve platform-specific manifest")
+		} else if digest != "" {
+			logger.WithFields(logrus.Fields{
+				"platform":     e.platform,
+				"image_digest": digest,
+			}).Debug("Scanning platform-specific manifest of multi-arch image")
+
+			input.ImageId = &ecrtypes.ImageIdentifier{
+				ImageDigest: aws.String(digest),
+			}
+			output, err = e.client.DescribeImageScanFindings(ctx, input)
+			platform = e.platform
+		}
+	}
+
+	// An image without a scan is reported as in progress once a scan has
+	// been triggered. Pull-through cache images are stored on first pull and
+	// have no scan until the registry's scanning picks them up.
+	var scanNotFound *ecrtypes.ScanNotFoundException
+	if err != nil && errors.As(err, &scanNotFound) {
+		status := ""
+		if e.scanTriggers != nil && e.triggerScan(ctx, logger, repo, input.ImageId) {
+			status = string(ecrtypes.ScanStatusInProgress)
+		} else if e.isPullThroughCache(repo) {
+			logger.Info("Pull-through cache image has not been scanned yet")
+			status = string(ecrtypes.ScanStatusPending)
+		}
+		if status != "" {
+			return &types.ImageVulnerability{
+				ImageURI:        imageURI,
+				Repository:      repo,
+				Tag:             tag,
+				Vulnerabilities: make(map[string]int),
+				ScanStatus:      status,
+			}, nil
+		}
+	}
+
 	if err != nil {
 		logger.WithError(err).Error("Failed to describe image scan findings")
 		return &types.ImageVulnerability{
@@ -160,6 +589,9 @@ func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string
 			severity := string(finding.Severity)
 			findingsCounts[severity]++
 			findingsTotalCount++
+			if !e.retainsFinding(severity) {
+				continue
+			}
 
 			// Create detailed finding
 			detailedFinding := types.VulnerabilityFinding{
@@ -181,63 +613,69 @@ func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string
 			detailedFindings = append(detailedFindings, detailedFinding)
 		}
 
-		// Process enhanced scanning findings (Amazon Inspector)
+		// Process enhanced scanning findings (Amazon Inspector). Findings
+		// without a severity are still real vulnerabilities, so they are
+		// counted as UNTRIAGED rather than dropped.
 		for _, enhancedFinding := range output.ImageScanFindings.EnhancedFindings {
-			if enhancedFinding.Severity != nil {
-				severity := *enhancedFinding.Severity
-				findingsCounts[severity]++
-				findingsTotalCount++
-
-				// Create detailed finding with enhanced data
-				detailedFinding := types.VulnerabilityFinding{
-					Severity:         severity,
-					Score:            enhancedFinding.Score,
-					ExploitAvailable: "unknown",
-					FixAvailable:     "unknown",
-				}
+			severity := aws.ToString(enhancedFinding.Severity)
+			if severity == "" {
+				severity = untriagedSeverity
+			}
+			findingsCounts[severity]++
+			findingsTotalCount++
+			if !e.retainsFinding(severity) {
+				continue
+			}
 
-				if enhancedFinding.Title != nil {
-					detailedFinding.Name = *enhancedFinding.Title
-				}
-				if enhancedFinding.Description != nil {
-					detailedFinding.Description = *enhancedFinding.Description
-				}
-				if enhancedFinding.Status != nil {
-					detailedFinding.Status = *enhancedFinding.Status
-				}
-				if enhancedFinding.Type != nil {
-					detailedFinding.Type = *enhancedFinding.Type
-				}
-				if enhancedFinding.ExploitAvailable != nil {
-					detailedFinding.ExploitAvailable = *enhancedFinding.ExploitAvailable
-				}
-				if enhancedFinding.FixAvailable != nil {
-					detailedFinding.FixAvailable = *enhancedFinding.FixAvailable
-				}
+			// Create detailed finding with enhanced data
+			detailedFinding := types.VulnerabilityFinding{
+				Severity:         severity,
+				Score:            enhancedFinding.Score,
+				ExploitAvailable: "unknown",
+				FixAvailable:     "unknown",
+			}
 
-				// Extract package information from vulnerability details
-				if enhancedFinding.PackageVulnerabilityDetails != nil {
-					if enhancedFinding.PackageVulnerabilityDetails.Source != nil {
-						detailedFinding.Name = *enhancedFinding.PackageVulnerabilityDetails.Source
-					}
-					if enhancedFinding.PackageVulnerabilityDetails.VulnerablePackages != nil {
-						for _, pkg := range enhancedFinding.PackageVulnerabilityDetails.VulnerablePackages {
-							if pkg.Name != nil {
-								detailedFinding.PackageName = *pkg.Name
-							}
-							if pkg.Version != nil {
-								detailedFinding.PackageVersion = *pkg.Version
-							}
-							if pkg.FixedInVersion != nil {
-								detailedFinding.FixVersion = *pkg.FixedInVersion
-							}
-							break // Use first package for simplicity
+			if enhancedFinding.Title != nil {
+				detailedFinding.Name = *enhancedFinding.Title
+			}
+			if enhancedFinding.Description != nil {
+				detailedFinding.Description = *enhancedFinding.Description
+			}
+			if enhancedFinding.Status != nil {
+				detailedFinding.Status = *enhancedFinding.Status
+			}
+			if enhancedFinding.Type != nil {
+				detailedFinding.Type = *enhancedFinding.Type
+			}
+			if enhancedFinding.ExploitAvailable != nil {
+				detailedFinding.ExploitAvailable = *enhancedFinding.ExploitAvailable
+			}
+			if enhancedFinding.FixAvailable != nil {
+				detailedFinding.FixAvailable = *enhancedFinding.FixAvailable
+			}
+
+			// Extract package information from vulnerability details
+			if enhancedFinding.PackageVulnerabilityDetails != nil {
+				if enhancedFinding.PackageVulnerabilityDetails.Source != nil {
+					detailedFinding.Name = *enhancedFinding.PackageVulnerabilityDetails.Source
+				}
+				if enhancedFinding.PackageVulnerabilityDetails.VulnerablePackages != nil {
+					for _, pkg := range enhancedFinding.PackageVulnerabilityDetails.VulnerablePackages {
+						if pkg.Name != nil {
+							detailedFinding.PackageName = *pkg.Name
+						}
+						if pkg.Version != nil {
+							detailedFinding.PackageVersion = *pkg.Version
 						}
+						if pkg.FixedInVersion != nil {
+							detailedFinding.FixVersion = *pkg.FixedInVersion
+						}
+						break // Use first package for simplicity
 					}
 				}
-
-				detailedFindings = append(detailedFindings, detailedFinding)
 			}
+
+			detailedFindings = append(detailedFindings, detailedFinding)
 		}
 
 		// Use FindingSeverityCounts from API for comparison
@@ -273,6 +711,7 @@ func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string
 
 	var scanStatus string
 	var lastScanTime *string
+	var digest string
 
 	if output.ImageScanStatus != nil {
 		scanStatus = string(output.ImageScanStatus.Status)
@@ -283,6 +722,16 @@ func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string
 		lastScanTime = &timeStr
 	}
 
+	// ECR echoes the scanned image's digest, so resolving it needs no extra call
+	if e.digests {
+		if output.ImageId != nil {
+			digest = aws.ToString(output.ImageId.ImageDigest)
+		}
+		if digest == "" {
+			digest = aws.ToString(input.ImageId.ImageDigest)
+		}
+	}
+
 	logger.WithFields(logrus.Fields{
 		"total_vulnerabilities": totalCount,
 		"scan_status":           scanStatus,
@@ -299,5 +748,131 @@ func (e *ECRSource) GetImageVulnerabilities(ctx context.Context, imageURI string
 		ScanStatus:      scanStatus,
 		LastScanTime:    lastScanTime,
 		Findings:        detailedFindings,
+		Platform:        platform,
+		Digest:          digest,
 	}, nil
 }
+
+// retainsFinding reports whether findings of a severity are kept, rather than
+// only counted
+func (e *ECRSource) retainsFinding(severity string) bool {
+	return e.retained == nil || e.retained[strings.ToUpper(severity)]
+}
+
+// triggerScan starts a scan of an image that has none, unless one was
+// triggered within ScanTriggerWindow, and reports whether a scan was started.
+// Failed triggers count against the window too, so an image that can't be
+// scanned, or that ECR refuses to rescan, isn't retried every cycle.
+func (e *ECRSource) triggerScan(ctx context.Context, logger *logrus.Entry, repo string, imageID *ecrtypes.ImageIdentifier) bool {
+	image := repo + ":" + aws.ToString(imageID.ImageTag)
+	if digest := aws.ToString(imageID.ImageDigest); digest != "" {
+		image = repo + "@" + digest
+	}
+	if !e.scanTriggers.allow(image) {
+		logger.Debug("Image scan was already triggered within the rescan window")
+		return false
+	}
+
+	_, err := e.client.StartImageScan(ctx, &ecr.StartImageScanInput{
+		RepositoryName: aws.String(repo),
+		ImageId:        imageID,
+	})
+	if err != nil {
+		logger.WithError(err).Warn("Failed to trigger image scan")
+		return false
+	}
+	logger.Info("Triggered image scan")
+	return true
+}
+
+// isManifestListScanError reports whether a scan lookup failed in a way that
+// indicates the tag may reference a manifest list rather than a single image
+func isManifestListScanError(err error) bool {
+	var scanNotFound *ecrtypes.ScanNotFoundException
+	var unsupported *ecrtypes.UnsupportedImageTypeException
+	return errors.As(err, &scanNotFound) || errors.As(err, &unsupported)
+}
+
+// throttlingErrorCodes are the AWS error codes of requests rejected for
+// exceeding the API quota
+var throttlingErrorCodes = map[string]bool{
+	"ThrottlingException":      true,
+	"TooManyRequestsException": true,
+	"RequestLimitExceeded":     true,
+}
+
+// IsThrottled reports whether ECR rejected a request for exceeding the API
+// quota, after the SDK's own retries
+func (e *ECRSource) IsThrottled(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && throttlingErrorCodes[apiErr.ErrorCode()]
+}
+
+// manifestList is the minimal structure shared by Docker manifest lists and OCI image indexes
+type manifestList struct {
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			Architecture string `json:"architecture"`
+			OS           string `json:"os"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+// resolvePlatformDigest returns the digest of the manifest matching the configured
+// platform when the tag references a manifest list, or "" for single-platform images
+func (e *ECRSource) resolvePlatformDigest(ctx context.Context, repo, tag string) (string, error) {
+	output, err := e.client.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		RepositoryName: aws.String(repo),
+		ImageIds: []ecrtypes.ImageIdentifier{
+			{ImageTag: aws.String(tag)},
+		},
+		AcceptedMediaTypes: []string{
+			mediaTypeDockerManifestList,
+			mediaTypeOCIImageIndex,
+			mediaTypeDockerManifest,
+			mediaTypeOCIManifest,
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get image manifest: %w", err)
+	}
+	if len(output.Images) == 0 || output.Images[0].ImageManifest == nil {
+		return "", fmt.Errorf("no manifest found for %s:%s", repo, tag)
+	}
+
+	image := output.Images[0]
+	mediaType := aws.ToString(image.ImageManifestMediaType)
+	if mediaType != mediaTypeDockerManifestList && mediaType != mediaTypeOCIImageIndex {
+		return "", nil
+	}
+
+	var list manifestList
+	if err := json.Unmarshal([]byte(aws.ToString(image.ImageManifest)), &list); err != nil {
+		return "", fmt.Errorf("failed to parse manifest list: %w", err)
+	}
+
+	platformOS, arch, variant := parsePlatform(e.platform)
+	for _, manifest := range list.Manifests {
+		p := manifest.Platform
+		if p.OS == platformOS && p.Architecture == arch && (variant == "" || p.Variant == variant) {
+			return manifest.Digest, nil
+		}
+	}
+
+	return "", fmt.Errorf("manifest list has no entry for platform %s", e.platform)
+}
+
+// parsePlatform splits an os/arch[/variant] platform string
+func parsePlatform(platform string) (platformOS, arch, variant string) {
+	parts := strings.SplitN(platform, "/", 3)
+	platformOS = parts[0]
+	if len(parts) > 1 {
+		arch = parts[1]
+	}
+	if len(parts) > 2 {
+		variant = parts[2]
+	}
+	return platformOS, arch, variant
+}
@@ -5,13 +5,171 @@ package aws
 
 import (
 	"context"
-	"os"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
 )
 
+// mockECRClient is a programmable ecrAPI implementation for testing
+type mockECRClient struct {
+	describeFunc      func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error)
+	batchGetImageFunc func(input *ecr.BatchGetImageInput) (*ecr.BatchGetImageOutput, error)
+	repositoriesFunc  func(input *ecr.DescribeRepositoriesInput) (*ecr.DescribeRepositoriesOutput, error)
+	scanningFunc      func() (*ecr.GetRegistryScanningConfigurationOutput, error)
+	pullThroughFunc   func(input *ecr.DescribePullThroughCacheRulesInput) (*ecr.DescribePullThroughCacheRulesOutput, error)
+	startScanCalls    []*ecr.StartImageScanInput
+	describeCalls     []*ecr.DescribeImageScanFindingsInput
+	repositoryCalls   []*ecr.DescribeRepositoriesInput
+}
+
+func (m *mockECRClient) DescribeImageScanFindings(ctx context.Context, params *ecr.DescribeImageScanFindingsInput, optFns ...func(*ecr.Options)) (*ecr.DescribeImageScanFindingsOutput, error) {
+	m.describeCalls = append(m.describeCalls, params)
+	return m.describeFunc(params)
+}
+
+func (m *mockECRClient) BatchGetImage(ctx context.Context, params *ecr.BatchGetImageInput, optFns ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	if m.batchGetImageFunc == nil {
+		return &ecr.BatchGetImageOutput{}, nil
+	}
+	return m.batchGetImageFunc(params)
+}
+
+func (m *mockECRClient) DescribeRepositories(ctx context.Context, params *ecr.DescribeRepositoriesInput, optFns ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	input := *params
+	m.repositoryCalls = append(m.repositoryCalls, &input)
+	return m.repositoriesFunc(params)
+}
+
+func (m *mockECRClient) GetRegistryScanningConfiguration(ctx context.Context, params *ecr.GetRegistryScanningConfigurationInput, optFns ...func(*ecr.Options)) (*ecr.GetRegistryScanningConfigurationOutput, error) {
+	return m.scanningFunc()
+}
+
+func (m *mockECRClient) DescribePullThroughCacheRules(ctx context.Context, params *ecr.DescribePullThroughCacheRulesInput, optFns ...func(*ecr.Options)) (*ecr.DescribePullThroughCacheRulesOutput, error) {
+	if m.pullThroughFunc == nil {
+		return &ecr.DescribePullThroughCacheRulesOutput{}, nil
+	}
+	return m.pullThroughFunc(params)
+}
+
+func (m *mockECRClient) StartImageScan(ctx context.Context, params *ecr.StartImageScanInput, optFns ...func(*ecr.Options)) (*ecr.StartImageScanOutput, error) {
+	m.startScanCalls = append(m.startScanCalls, params)
+	return &ecr.StartImageScanOutput{}, nil
+}
+
+// stubSTSClient returns a fixed caller identity
+type stubSTSClient struct {
+	output *sts.GetCallerIdentityOutput
+	err    error
+}
+
+func (s *stubSTSClient) GetCallerIdentity(ctx context.Context, params *sts.GetCallerIdentityInput, optFns ...func(*sts.Options)) (*sts.GetCallerIdentityOutput, error) {
+	return s.output, s.err
+}
+
+func TestECRSourceCallerIdentity(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &ECRSource{
+		identity: &stubSTSClient{output: &sts.GetCallerIdentityOutput{
+			Account: aws.String("210987654321"),
+			Arn:     aws.String("arn:aws:sts::210987654321:assumed-role/ECRVulnerabilityExporterRole/vulnrelay"),
+			UserId:  aws.String("AROAEXAMPLE:vulnrelay"),
+		}},
+		accountID: "210987654321",
+		region:    "us-east-1",
+		logger:    logger,
+	}
+
+	identity, err := source.CallerIdentity(context.Background())
+	if err != nil {
+		t.Fatalf("CallerIdentity() failed: %v", err)
+	}
+	expected := types.CallerIdentity{
+		Account: "210987654321",
+		ARN:     "arn:aws:sts::210987654321:assumed-role/ECRVulnerabilityExporterRole/vulnrelay",
+		UserID:  "AROAEXAMPLE:vulnrelay",
+	}
+	if *identity != expected {
+		t.Errorf("Expected identity %+v, got %+v", expected, *identity)
+	}
+
+	source.identity = &stubSTSClient{err: errors.New("ExpiredToken")}
+	if _, err := source.CallerIdentity(context.Background()); err == nil {
+		t.Error("Expected an error when STS fails")
+	}
+}
+
+func TestECRSourceRegistryScanType(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name          string
+		output        *ecr.GetRegistryScanningConfigurationOutput
+		err           error
+		expected      string
+		expectedError bool
+	}{
+		{
+			name: "enhanced scanning",
+			output: &ecr.GetRegistryScanningConfigurationOutput{
+				RegistryId:            aws.String("123456789012"),
+				ScanningConfiguration: &ecrtypes.RegistryScanningConfiguration{ScanType: ecrtypes.ScanTypeEnhanced},
+			},
+			expected: "ENHANCED",
+		},
+		{
+			name: "basic scanning",
+			output: &ecr.GetRegistryScanningConfigurationOutput{
+				ScanningConfiguration: &ecrtypes.RegistryScanningConfiguration{ScanType: ecrtypes.ScanTypeBasic},
+			},
+			expected: "BASIC",
+		},
+		{
+			name:          "missing configuration",
+			output:        &ecr.GetRegistryScanningConfigurationOutput{},
+			expectedError: true,
+		},
+		{
+			name:          "access denied",
+			err:           errors.New("AccessDeniedException"),
+			expectedError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &ECRSource{
+				client: &mockECRClient{scanningFunc: func() (*ecr.GetRegistryScanningConfigurationOutput, error) {
+					return tt.output, tt.err
+				}},
+				logger: logger,
+			}
+
+			scanType, err := source.RegistryScanType(context.Background())
+			if (err != nil) != tt.expectedError {
+				t.Fatalf("Expected error %v, got %v", tt.expectedError, err)
+			}
+			if scanType != tt.expected {
+				t.Errorf("Expected scan type %q, got %q", tt.expected, scanType)
+			}
+		})
+	}
+}
+
 func TestECRSourceName(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -29,6 +187,31 @@ func TestECRSourceName(t *testing.T) {
 	}
 }
 
+func TestECRSourceIsThrottled(t *testing.T) {
+	source := &ECRSource{}
+
+	tests := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"throttling", &smithy.GenericAPIError{Code: "ThrottlingException"}, true},
+		{"wrapped throttling", fmt.Errorf("scan lookup: %w", &smithy.GenericAPIError{Code: "ThrottlingException"}), true},
+		{"too many requests", &smithy.GenericAPIError{Code: "TooManyRequestsException"}, true},
+		{"access denied", &smithy.GenericAPIError{Code: "AccessDeniedException"}, false},
+		{"scan not found", &ecrtypes.ScanNotFoundException{}, false},
+		{"network error", errors.New("connection reset"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := source.IsThrottled(tt.err); got != tt.expected {
+				t.Errorf("IsThrottled(%v) = %v, expected %v", tt.err, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestECRSourceParseImageURI(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -126,6 +309,168 @@ func TestECRSourceParseImageURI(t *testing.T) {
 	}
 }
 
+func TestECRSourceParseImageURIPullThroughCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &ECRSource{
+		pullThrough: map[string]ecrtypes.UpstreamRegistry{
+			"docker-hub": ecrtypes.UpstreamRegistryDockerHub,
+			"ecr-public": ecrtypes.UpstreamRegistryEcrPublic,
+		},
+		logger: logger,
+	}
+
+	tests := []struct {
+		name         string
+		imageURI     string
+		expectedRepo string
+		expectedTag  string
+	}{
+		{"ECR Public upstream", "123456789012.dkr.ecr.us-east-1.amazonaws.com/ecr-public/nginx/nginx:1.25", "ecr-public/nginx/nginx", "1.25"},
+		{"ECR Public single segment", "123456789012.dkr.ecr.us-east-1.amazonaws.com/ecr-public/busybox:latest", "ecr-public/busybox", "latest"},
+		{"Docker Hub official image", "123456789012.dkr.ecr.us-east-1.amazonaws.com/docker-hub/nginx:1.25", "docker-hub/library/nginx", "1.25"},
+		{"Docker Hub library path", "123456789012.dkr.ecr.us-east-1.amazonaws.com/docker-hub/library/nginx:1.25", "docker-hub/library/nginx", "1.25"},
+		{"Docker Hub user image", "123456789012.dkr.ecr.us-east-1.amazonaws.com/docker-hub/bitnami/redis:7.2", "docker-hub/bitnami/redis", "7.2"},
+		{"regular repository with the same name", "123456789012.dkr.ecr.us-east-1.amazonaws.com/team/nginx:1.25", "team/nginx", "1.25"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, tag, err := source.ParseImageURI(tt.imageURI)
+			if err != nil {
+				t.Fatalf("ParseImageURI(%q) unexpected error: %v", tt.imageURI, err)
+			}
+			if repo != tt.expectedRepo || tag != tt.expectedTag {
+				t.Errorf("ParseImageURI(%q) = %q, %q, want %q, %q", tt.imageURI, repo, tag, tt.expectedRepo, tt.expectedTag)
+			}
+		})
+	}
+}
+
+func TestGetImageVulnerabilitiesPullThroughCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// Only docker-hub/library/nginx:1.25 has been scanned
+	newClient := func() *mockECRClient {
+		return &mockECRClient{
+			pullThroughFunc: func(input *ecr.DescribePullThroughCacheRulesInput) (*ecr.DescribePullThroughCacheRulesOutput, error) {
+				if aws.ToString(input.NextToken) == "" {
+					return &ecr.DescribePullThroughCacheRulesOutput{
+						PullThroughCacheRules: []ecrtypes.PullThroughCacheRule{
+							{EcrRepositoryPrefix: aws.String("ecr-public"), UpstreamRegistry: ecrtypes.UpstreamRegistryEcrPublic},
+						},
+						NextToken: aws.String("page-2"),
+					}, nil
+				}
+				return &ecr.DescribePullThroughCacheRulesOutput{
+					PullThroughCacheRules: []ecrtypes.PullThroughCacheRule{
+						{EcrRepositoryPrefix: aws.String("docker-hub"), UpstreamRegistry: ecrtypes.UpstreamRegistryDockerHub},
+					},
+				}, nil
+			},
+			describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+				if aws.ToString(input.RepositoryName) != "docker-hub/library/nginx" {
+					return nil, &ecrtypes.ScanNotFoundException{Message: aws.String("no scan")}
+				}
+				return &ecr.DescribeImageScanFindingsOutput{
+					ImageScanStatus: &ecrtypes.ImageScanStatus{Status: ecrtypes.ScanStatusComplete},
+					ImageScanFindings: &ecrtypes.ImageScanFindings{
+						Findings: []ecrtypes.ImageScanFinding{{Name: aws.String("CVE-2024-0001"), Severity: ecrtypes.FindingSeverityHigh}},
+					},
+				}, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		imageURI       string
+		expectedRepo   string
+		expectedStatus string
+		expectError    bool
+	}{
+		{"scanned official image", "123456789012.dkr.ecr.us-east-1.amazonaws.com/docker-hub/nginx:1.25", "docker-hub/library/nginx", "COMPLETE", false},
+		{"unscanned cached image", "123456789012.dkr.ecr.us-east-1.amazonaws.com/ecr-public/nginx/nginx:1.25", "ecr-public/nginx/nginx", "PENDING", false},
+		{"unscanned regular image", "123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1", "team/api", "FAILED", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient()
+			source := &ECRSource{client: client, accountID: "123456789012", platform: DefaultImagePlatform, logger: logger}
+
+			vuln, err := source.GetImageVulnerabilities(context.Background(), tt.imageURI)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("GetImageVulnerabilities() error = %v, expectError %v", err, tt.expectError)
+			}
+			if got := aws.ToString(client.describeCalls[0].RepositoryName); got != tt.expectedRepo {
+				t.Errorf("Expected scan lookup in repository %q, got %q", tt.expectedRepo, got)
+			}
+			if vuln.ScanStatus != tt.expectedStatus {
+				t.Errorf("Expected scan status %s, got %s", tt.expectedStatus, vuln.ScanStatus)
+			}
+		})
+	}
+}
+
+func TestPullThroughRulesRetriedNextCycle(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	// Listing the rules fails once, e.g. while ECR is throttling
+	listings := 0
+	client := &mockECRClient{
+		pullThroughFunc: func(input *ecr.DescribePullThroughCacheRulesInput) (*ecr.DescribePullThroughCacheRulesOutput, error) {
+			listings++
+			if listings == 1 {
+				return nil, errors.New("throttled")
+			}
+			return &ecr.DescribePullThroughCacheRulesOutput{
+				PullThroughCacheRules: []ecrtypes.PullThroughCacheRule{
+					{EcrRepositoryPrefix: aws.String("docker-hub"), UpstreamRegistry: ecrtypes.UpstreamRegistryDockerHub},
+				},
+			}, nil
+		},
+		describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+			return &ecr.DescribeImageScanFindingsOutput{
+				ImageScanStatus:   &ecrtypes.ImageScanStatus{Status: ecrtypes.ScanStatusComplete},
+				ImageScanFindings: &ecrtypes.ImageScanFindings{},
+			}, nil
+		},
+	}
+	source := &ECRSource{client: client, accountID: "123456789012", platform: DefaultImagePlatform, logger: logger}
+
+	const imageURI = "123456789012.dkr.ecr.us-east-1.amazonaws.com/docker-hub/nginx:1.25"
+	const registry = "123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	cycle := func() string {
+		t.Helper()
+		if _, err := source.ResolveImageFilter(context.Background(), registry); err != nil {
+			t.Fatalf("ResolveImageFilter() failed: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := source.GetImageVulnerabilities(context.Background(), imageURI); err != nil {
+				t.Fatalf("GetImageVulnerabilities() failed: %v", err)
+			}
+		}
+		return aws.ToString(client.describeCalls[len(client.describeCalls)-1].RepositoryName)
+	}
+
+	// The failed listing isn't repeated for every image of the cycle
+	if repo := cycle(); repo != "docker-hub/nginx" || listings != 1 {
+		t.Errorf("Expected one failed listing and a regular lookup, got %d listings and repository %q", listings, repo)
+	}
+
+	// The next cycle retries it, and a successful listing is kept
+	if repo := cycle(); repo != "docker-hub/library/nginx" || listings != 2 {
+		t.Errorf("Expected the rules to be listed again, got %d listings and repository %q", listings, repo)
+	}
+	if repo := cycle(); repo != "docker-hub/library/nginx" || listings != 2 {
+		t.Errorf("Expected the listed rules to be kept, got %d listings and repository %q", listings, repo)
+	}
+}
+
 func TestNewECRSourceError(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -153,7 +498,7 @@ func TestNewECRSourceError(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			// This test verifies that NewECRSource handles parameters correctly
 			// In a real test environment without AWS credentials, this should fail
-			source, err := NewECRSource(ctx, tt.accountID, tt.region, logger)
+			source, err := NewECRSource(ctx, tt.accountID, tt.region, ECROptions{}, logger)
 
 			if err == nil && source != nil {
 				t.Log("NewECRSource succeeded - likely running with AWS credentials")
@@ -356,20 +701,10 @@ func TestNewECRSourceWithAssumeRole(t *testing.T) {
 	logger.SetLevel(logrus.ErrorLevel)
 
 	// Test with explicit assume role ARN
-	originalAssumeRole := os.Getenv("AWS_IAM_ASSUME_ROLE_ARN")
 	testRoleArn := "arn:aws:iam::123456789012:role/TestRole"
 
-	os.Setenv("AWS_IAM_ASSUME_ROLE_ARN", testRoleArn)
-	defer func() {
-		if originalAssumeRole == "" {
-			os.Unsetenv("AWS_IAM_ASSUME_ROLE_ARN")
-		} else {
-			os.Setenv("AWS_IAM_ASSUME_ROLE_ARN", originalAssumeRole)
-		}
-	}()
-
 	ctx := context.Background()
-	source, err := NewECRSource(ctx, "123456789012", "us-east-1", logger)
+	source, err := NewECRSource(ctx, "123456789012", "us-east-1", ECROptions{AssumeRoleARN: testRoleArn}, logger)
 
 	// In test environment, this may fail due to no AWS credentials
 	if err != nil {
@@ -389,6 +724,48 @@ func TestNewECRSourceWithAssumeRole(t *testing.T) {
 	}
 }
 
+func TestCrossAccountRoleARN(t *testing.T) {
+	tests := []struct {
+		name      string
+		accountID string
+		role      string
+		expected  string
+	}{
+		{
+			name:      "default role name",
+			accountID: "123456789012",
+			role:      "",
+			expected:  "arn:aws:iam::123456789012:role/ECRVulnerabilityExporterRole",
+		},
+		{
+			name:      "configured role name",
+			accountID: "123456789012",
+			role:      "org-security-ecr-reader",
+			expected:  "arn:aws:iam::123456789012:role/org-security-ecr-reader",
+		},
+		{
+			name:      "role name with path",
+			accountID: "987654321098",
+			role:      "security/VulnRelay",
+			expected:  "arn:aws:iam::987654321098:role/security/VulnRelay",
+		},
+		{
+			name:      "ARN template",
+			accountID: "987654321098",
+			role:      "arn:aws-us-gov:iam::{account_id}:role/VulnRelay",
+			expected:  "arn:aws-us-gov:iam::987654321098:role/VulnRelay",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := crossAccountRoleARN(tt.accountID, tt.role); got != tt.expected {
+				t.Errorf("crossAccountRoleARN(%q, %q) = %q, want %q", tt.accountID, tt.role, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestGetImageVulnerabilitiesErrorPaths(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -441,3 +818,459 @@ func TestGetImageVulnerabilitiesErrorPaths(t *testing.T) {
 		t.Log("URI parsing works correctly - API error handling would require mock AWS client")
 	})
 }
+
+func TestGetImageVulnerabilitiesManifestList(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const (
+		amd64Digest = "sha256:aaaa000000000000000000000000000000000000000000000000000000000001"
+		arm64Digest = "sha256:bbbb000000000000000000000000000000000000000000000000000000000002"
+	)
+
+	manifestListJSON := `{
+		"schemaVersion": 2,
+		"mediaType": "application/vnd.docker.distribution.manifest.list.v2+json",
+		"manifests": [
+			{"digest": "` + amd64Digest + `", "platform": {"architecture": "amd64", "os": "linux"}},
+			{"digest": "` + arm64Digest + `", "platform": {"architecture": "arm64", "os": "linux", "variant": "v8"}}
+		]
+	}`
+
+	newClient := func() *mockECRClient {
+		return &mockECRClient{
+			describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+				// The manifest list tag itself has no scan
+				if input.ImageId.ImageTag != nil {
+					return nil, &ecrtypes.ScanNotFoundException{Message: aws.String("scan not found")}
+				}
+				return &ecr.DescribeImageScanFindingsOutput{
+					ImageScanStatus: &ecrtypes.ImageScanStatus{Status: ecrtypes.ScanStatusComplete},
+					ImageScanFindings: &ecrtypes.ImageScanFindings{
+						Findings: []ecrtypes.ImageScanFinding{
+							{Name: aws.String("CVE-2024-0001"), Severity: ecrtypes.FindingSeverityHigh},
+						},
+					},
+				}, nil
+			},
+			batchGetImageFunc: func(input *ecr.BatchGetImageInput) (*ecr.BatchGetImageOutput, error) {
+				return &ecr.BatchGetImageOutput{
+					Images: []ecrtypes.Image{
+						{
+							ImageManifest:          aws.String(manifestListJSON),
+							ImageManifestMediaType: aws.String(mediaTypeDockerManifestList),
+						},
+					},
+				}, nil
+			},
+		}
+	}
+
+	tests := []struct {
+		name           string
+		platform       string
+		expectedDigest string
+	}{
+		{
+			name:           "default platform",
+			platform:       DefaultImagePlatform,
+			expectedDigest: amd64Digest,
+		},
+		{
+			name:           "configured arm64 platform with variant",
+			platform:       "linux/arm64/v8",
+			expectedDigest: arm64Digest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newClient()
+			source := &ECRSource{
+				client:    client,
+				accountID: "123456789012",
+				region:    "us-east-1",
+				platform:  tt.platform,
+				digests:   true,
+				logger:    logger,
+			}
+
+			vuln, err := source.GetImageVulnerabilities(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/multi-arch:v1.0.0")
+			if err != nil {
+				t.Fatalf("GetImageVulnerabilities() failed: %v", err)
+			}
+
+			if len(client.describeCalls) != 2 {
+				t.Fatalf("Expected 2 DescribeImageScanFindings calls, got %d", len(client.describeCalls))
+			}
+			if got := aws.ToString(client.describeCalls[1].ImageId.ImageDigest); got != tt.expectedDigest {
+				t.Errorf("Expected sub-manifest %s to be queried, got %s", tt.expectedDigest, got)
+			}
+
+			if vuln.ScanStatus != "COMPLETE" {
+				t.Errorf("Expected scan status COMPLETE, got %s", vuln.ScanStatus)
+			}
+			if vuln.Vulnerabilities["HIGH"] != 1 {
+				t.Errorf("Expected 1 HIGH vulnerability, got %d", vuln.Vulnerabilities["HIGH"])
+			}
+			if vuln.Tag != "v1.0.0" {
+				t.Errorf("Expected tag v1.0.0 to be preserved, got %s", vuln.Tag)
+			}
+			if vuln.Platform != tt.platform {
+				t.Errorf("Expected resolved platform %s to be recorded, got %q", tt.platform, vuln.Platform)
+			}
+			if vuln.Digest != tt.expectedDigest {
+				t.Errorf("Expected the platform-specific digest %s, got %q", tt.expectedDigest, vuln.Digest)
+			}
+		})
+	}
+
+	t.Run("platform missing from manifest list", func(t *testing.T) {
+		client := newClient()
+		source := &ECRSource{
+			client:   client,
+			platform: "windows/amd64",
+			logger:   logger,
+		}
+
+		vuln, err := source.GetImageVulnerabilities(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/multi-arch:v1.0.0")
+		if err == nil {
+			t.Fatal("Expected error when no manifest matches the platform")
+		}
+		if vuln.ScanStatus != "FAILED" {
+			t.Errorf("Expected scan status FAILED, got %s", vuln.ScanStatus)
+		}
+		if len(client.describeCalls) != 1 {
+			t.Errorf("Expected only the tag lookup, got %d calls", len(client.describeCalls))
+		}
+	})
+}
+
+func TestGetImageVulnerabilitiesDigest(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const digest = "sha256:cccc000000000000000000000000000000000000000000000000000000000003"
+
+	tests := []struct {
+		name           string
+		resolveDigests bool
+		expectedDigest string
+	}{
+		{"resolution enabled", true, digest},
+		{"resolution disabled", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &ECRSource{
+				client: &mockECRClient{
+					describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+						return &ecr.DescribeImageScanFindingsOutput{
+							ImageId:         &ecrtypes.ImageIdentifier{ImageTag: input.ImageId.ImageTag, ImageDigest: aws.String(digest)},
+							ImageScanStatus: &ecrtypes.ImageScanStatus{Status: ecrtypes.ScanStatusComplete},
+						}, nil
+					},
+				},
+				digests: tt.resolveDigests,
+				logger:  logger,
+			}
+
+			vuln, err := source.GetImageVulnerabilities(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:latest")
+			if err != nil {
+				t.Fatalf("GetImageVulnerabilities() failed: %v", err)
+			}
+			if vuln.Digest != tt.expectedDigest {
+				t.Errorf("Expected digest %q, got %q", tt.expectedDigest, vuln.Digest)
+			}
+
+			body, err := json.Marshal(vuln)
+			if err != nil {
+				t.Fatalf("Failed to encode result: %v", err)
+			}
+			if got := strings.Contains(string(body), `"digest":`); got != tt.resolveDigests {
+				t.Errorf("Expected digest in JSON = %v, got %s", tt.resolveDigests, body)
+			}
+		})
+	}
+}
+
+func TestGetImageVulnerabilitiesEnhancedFindingWithoutSeverity(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &ECRSource{
+		client: &mockECRClient{
+			describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+				return &ecr.DescribeImageScanFindingsOutput{
+					ImageScanStatus: &ecrtypes.ImageScanStatus{Status: ecrtypes.ScanStatusComplete},
+					ImageScanFindings: &ecrtypes.ImageScanFindings{
+						EnhancedFindings: []ecrtypes.EnhancedImageScanFinding{
+							{Title: aws.String("CVE-2024-0001 - openssl"), Severity: aws.String("HIGH")},
+							{
+								Title:       aws.String("CVE-2024-0002 - zlib"),
+								Description: aws.String("Not yet triaged"),
+								Score:       7.5,
+								PackageVulnerabilityDetails: &ecrtypes.PackageVulnerabilityDetails{
+									VulnerablePackages: []ecrtypes.VulnerablePackage{
+										{Name: aws.String("zlib"), Version: aws.String("1.2.11"), FixedInVersion: aws.String("1.2.12")},
+									},
+								},
+							},
+						},
+					},
+				}, nil
+			},
+		},
+		logger: logger,
+	}
+
+	vuln, err := source.GetImageVulnerabilities(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1")
+	if err != nil {
+		t.Fatalf("GetImageVulnerabilities() failed: %v", err)
+	}
+
+	if vuln.TotalCount != 2 {
+		t.Errorf("Expected both findings counted, got %d", vuln.TotalCount)
+	}
+	if vuln.Vulnerabilities["UNTRIAGED"] != 1 || vuln.Vulnerabilities["HIGH"] != 1 {
+		t.Errorf("Expected 1 UNTRIAGED and 1 HIGH vulnerability, got %v", vuln.Vulnerabilities)
+	}
+	if len(vuln.Findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d", len(vuln.Findings))
+	}
+	untriaged := vuln.Findings[1]
+	if untriaged.Severity != "UNTRIAGED" || untriaged.Description != "Not yet triaged" || untriaged.PackageName != "zlib" || untriaged.FixVersion != "1.2.12" || untriaged.Score != 7.5 {
+		t.Errorf("Expected the untriaged finding with its details, got %+v", untriaged)
+	}
+}
+
+func TestGetImageVulnerabilitiesRetainedSeverities(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source := &ECRSource{
+		client: &mockECRClient{
+			describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+				return &ecr.DescribeImageScanFindingsOutput{
+					ImageScanStatus: &ecrtypes.ImageScanStatus{Status: ecrtypes.ScanStatusComplete},
+					ImageScanFindings: &ecrtypes.ImageScanFindings{
+						EnhancedFindings: []ecrtypes.EnhancedImageScanFinding{
+							{Title: aws.String("CVE-2024-0001 - openssl"), Severity: aws.String("CRITICAL")},
+							{Title: aws.String("CVE-2024-0002 - zlib"), Severity: aws.String("HIGH")},
+							{Title: aws.String("CVE-2024-0003 - curl"), Severity: aws.String("LOW")},
+							{Title: aws.String("CVE-2024-0004 - bash"), Severity: aws.String("LOW")},
+							{Title: aws.String("CVE-2024-0005 - tar"), Severity: aws.String("MEDIUM")},
+						},
+					},
+				}, nil
+			},
+		},
+		retained: map[string]bool{"CRITICAL": true, "HIGH": true},
+		logger:   logger,
+	}
+
+	vuln, err := source.GetImageVulnerabilities(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1")
+	if err != nil {
+		t.Fatalf("GetImageVulnerabilities() failed: %v", err)
+	}
+
+	// Counts include every finding
+	expectedCounts := map[string]int{"CRITICAL": 1, "HIGH": 1, "MEDIUM": 1, "LOW": 2}
+	if !reflect.DeepEqual(vuln.Vulnerabilities, expectedCounts) {
+		t.Errorf("Expected counts %v, got %v", expectedCounts, vuln.Vulnerabilities)
+	}
+	if vuln.TotalCount != 5 {
+		t.Errorf("Expected total count 5, got %d", vuln.TotalCount)
+	}
+
+	// Only findings of the retained severities are kept
+	if len(vuln.Findings) != 2 {
+		t.Fatalf("Expected 2 retained findings, got %d", len(vuln.Findings))
+	}
+	for _, finding := range vuln.Findings {
+		if finding.Severity != "CRITICAL" && finding.Severity != "HIGH" {
+			t.Errorf("Expected only CRITICAL and HIGH findings, got %+v", finding)
+		}
+	}
+}
+
+func TestGetImageVulnerabilitiesTriggerScans(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	client := &mockECRClient{
+		describeFunc: func(input *ecr.DescribeImageScanFindingsInput) (*ecr.DescribeImageScanFindingsOutput, error) {
+			return nil, &ecrtypes.ScanNotFoundException{Message: aws.String("no scan")}
+		},
+	}
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	limiter := newScanTriggerLimiter(ScanTriggerWindow)
+	limiter.now = func() time.Time { return now }
+	source := &ECRSource{client: client, platform: DefaultImagePlatform, scanTriggers: limiter, logger: logger}
+
+	steps := []struct {
+		name           string
+		advance        time.Duration
+		expectedStatus string
+		expectedScans  int
+	}{
+		{"first lookup triggers a scan", 0, "IN_PROGRESS", 1},
+		{"lookup within the window", 6 * time.Hour, "FAILED", 1},
+		{"lookup after the window", 24 * time.Hour, "IN_PROGRESS", 2},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		vuln, err := source.GetImageVulnerabilities(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1")
+		if (err != nil) != (step.expectedStatus == "FAILED") {
+			t.Errorf("%s: unexpected error %v", step.name, err)
+		}
+		if vuln.ScanStatus != step.expectedStatus {
+			t.Errorf("%s: expected scan status %s, got %s", step.name, step.expectedStatus, vuln.ScanStatus)
+		}
+		if len(client.startScanCalls) != step.expectedScans {
+			t.Errorf("%s: expected %d StartImageScan calls, got %d", step.name, step.expectedScans, len(client.startScanCalls))
+		}
+	}
+
+	if input := client.startScanCalls[0]; aws.ToString(input.RepositoryName) != "app" || aws.ToString(input.ImageId.ImageTag) != "v1" {
+		t.Errorf("Expected a scan of app:v1, got %s:%s", aws.ToString(input.RepositoryName), aws.ToString(input.ImageId.ImageTag))
+	}
+}
+
+// filterImages resolves the source's image filter and applies it to images
+func filterImages(t *testing.T, source *ECRSource, images []types.ImageInfo) []types.ImageInfo {
+	t.Helper()
+
+	filter, err := source.ResolveImageFilter(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com")
+	if err != nil {
+		t.Fatalf("ResolveImageFilter() failed: %v", err)
+	}
+	return filter(images)
+}
+
+func TestECRSourceFilterImagesPaginatesRepositories(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	pages := map[string]*ecr.DescribeRepositoriesOutput{
+		"": {
+			Repositories: []ecrtypes.Repository{
+				{RepositoryName: aws.String("team-a/api")},
+				{RepositoryName: aws.String("team-b/api")},
+			},
+			NextToken: aws.String("page-2"),
+		},
+		"page-2": {
+			Repositories: []ecrtypes.Repository{
+				{RepositoryName: aws.String("team-a/worker")},
+				{RepositoryName: aws.String("payments")},
+			},
+		},
+	}
+	client := &mockECRClient{
+		repositoriesFunc: func(input *ecr.DescribeRepositoriesInput) (*ecr.DescribeRepositoriesOutput, error) {
+			return pages[aws.ToString(input.NextToken)], nil
+		},
+	}
+	source := &ECRSource{
+		client:    client,
+		accountID: "123456789012",
+		region:    "us-east-1",
+		allowlist: []string{"team-a/*", "payments", "missing"},
+		logger:    logger,
+	}
+
+	registry := "123456789012.dkr.ecr.us-east-1.amazonaws.com/"
+	images := []types.ImageInfo{
+		{URI: registry + "team-a/api:v1"},
+		{URI: registry + "team-b/api:v1"},
+		{URI: registry + "team-a/worker:v2"},
+		{URI: registry + "payments:latest"},
+		{URI: registry + "unknown:v1"},
+	}
+
+	// Batches filtered during a cycle share one listing of the repositories
+	filter, err := source.ResolveImageFilter(context.Background(), "123456789012.dkr.ecr.us-east-1.amazonaws.com")
+	if err != nil {
+		t.Fatalf("ResolveImageFilter() failed: %v", err)
+	}
+	filtered := append(filter(images[:2]), filter(images[2:])...)
+
+	// team-a/worker and payments are only on the second page
+	var uris []string
+	for _, image := range filtered {
+		uris = append(uris, image.URI)
+	}
+	expected := []string{registry + "team-a/api:v1", registry + "team-a/worker:v2", registry + "payments:latest"}
+	if strings.Join(uris, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected %v, got %v", expected, uris)
+	}
+
+	if len(client.repositoryCalls) != 2 {
+		t.Fatalf("Expected 2 DescribeRepositories calls, got %d", len(client.repositoryCalls))
+	}
+	if client.repositoryCalls[0].NextToken != nil || aws.ToString(client.repositoryCalls[1].NextToken) != "page-2" {
+		t.Error("Expected the second call to pass the first page's NextToken")
+	}
+	if aws.ToString(client.repositoryCalls[0].RegistryId) != "123456789012" {
+		t.Errorf("Expected registry ID 123456789012, got %s", aws.ToString(client.repositoryCalls[0].RegistryId))
+	}
+}
+
+func TestECRSourceFilterImagesWithoutAllowlist(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	client := &mockECRClient{
+		repositoriesFunc: func(input *ecr.DescribeRepositoriesInput) (*ecr.DescribeRepositoriesOutput, error) {
+			t.Error("Expected no DescribeRepositories call without an allowlist")
+			return &ecr.DescribeRepositoriesOutput{}, nil
+		},
+	}
+	source := &ECRSource{client: client, accountID: "123456789012", region: "us-east-1", logger: logger}
+
+	images := []types.ImageInfo{{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"}}
+	if filtered := filterImages(t, source, images); len(filtered) != 1 {
+		t.Errorf("Expected all images without an allowlist, got %d", len(filtered))
+	}
+}
+
+func TestECRSourceFilterImagesUntagged(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const digest = "sha256:4f1c4cfb3a1b2f6e5a6f0b0e8a2c7d3e9f1a2b3c4d5e6f708192a3b4c5d6e7f8"
+	registry := "123456789012.dkr.ecr.us-east-1.amazonaws.com/"
+	images := []types.ImageInfo{
+		{URI: registry + "app:v1"},
+		{URI: registry + "app@" + digest},
+		{URI: registry + "app:v1@" + digest},
+		{URI: "localhost:5000/team/app@" + digest},
+	}
+
+	tests := []struct {
+		name     string
+		untagged bool
+		expected []string
+	}{
+		{"untagged images scanned by default", false, []string{registry + "app:v1", registry + "app@" + digest, registry + "app:v1@" + digest, "localhost:5000/team/app@" + digest}},
+		{"untagged images skipped", true, []string{registry + "app:v1", registry + "app:v1@" + digest}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &ECRSource{client: &mockECRClient{}, accountID: "123456789012", region: "us-east-1", untagged: tt.untagged, logger: logger}
+
+			filtered := filterImages(t, source, images)
+			var uris []string
+			for _, image := range filtered {
+				uris = append(uris, image.URI)
+			}
+			if !reflect.DeepEqual(uris, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, uris)
+			}
+		})
+	}
+}
@@ -6,91 +6,560 @@ package aws
 import (
 	"context"
 	"fmt"
+	"path"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// rolloutGVR identifies the Argo Rollouts CRD (rollouts.argoproj.io)
+var rolloutGVR = schema.GroupVersionResource{
+	Group:    "argoproj.io",
+	Version:  "v1alpha1",
+	Resource: "rollouts",
+}
+
+// deploymentConfigGVR identifies OpenShift DeploymentConfigs (deploymentconfigs.apps.openshift.io)
+var deploymentConfigGVR = schema.GroupVersionResource{
+	Group:    "apps.openshift.io",
+	Version:  "v1",
+	Resource: "deploymentconfigs",
+}
+
+// Metadata identifying the Helm release that manages a workload
+const (
+	helmReleaseAnnotation = "meta.helm.sh/release-name"
+	helmInstanceLabel     = "app.kubernetes.io/instance"
+)
+
+// Workload annotations controlling whether a workload's images are scanned
+const (
+	skipAnnotation = "vulnrelay.io/skip"
+	scanAnnotation = "vulnrelay.io/scan"
+)
+
+// EKSOptions configures workload discovery for the EKS provider
+type EKSOptions struct {
+	// FieldSelector restricts listed workloads, e.g. metadata.name=api or
+	// metadata.namespace!=kube-system. Empty lists all workloads.
+	FieldSelector string
+
+	// RegistryHostSuffixes lists extra registry hosts treated as ECR, such as
+	// DNS aliases in front of ECR. A suffix matches the host or any subdomain.
+	RegistryHostSuffixes []string
+
+	// OnlyRunning restricts discovery to images used by running pods, skipping
+	// workloads scaled to zero
+	OnlyRunning bool
+
+	// SkipSuspendedCronJobs excludes CronJobs whose schedule is suspended
+	SkipSuspendedCronJobs bool
+
+	// IgnoreContainers lists container name globs whose images are skipped,
+	// e.g. istio-proxy or *-sidecar
+	IgnoreContainers []string
+
+	// Namespaces restricts discovery to these namespaces, listed concurrently.
+	// Empty lists workloads across all namespaces.
+	Namespaces []string
+
+	// DiscoveryConcurrency caps the namespaces listed at once (0 = default)
+	DiscoveryConcurrency int
+
+	// RequireScanAnnotation only discovers workloads annotated with
+	// vulnrelay.io/scan: "true". Workloads annotated with vulnrelay.io/skip:
+	// "true" are always excluded.
+	RequireScanAnnotation bool
+
+	// Kubeconfig and KubeContext select the cluster outside a pod, e.g. for
+	// local development. Setting either skips the in-cluster config.
+	Kubeconfig  string
+	KubeContext string
+
+	// StartupTimeout bounds how long client construction and the initial
+	// API connectivity check are retried. Zero makes a single attempt.
+	StartupTimeout time.Duration
+}
+
+// DefaultDiscoveryConcurrency is the number of namespaces listed at once
+// when discovery is restricted to a namespace list
+const DefaultDiscoveryConcurrency = 4
+
+// Backoff between Kubernetes connection attempts during startup
+const (
+	startupInitialBackoff = time.Second
+	startupMaxBackoff     = 15 * time.Second
+)
+
+// kubeConnector builds Kubernetes clients; replaced in tests to simulate an
+// unreachable API server
+type kubeConnector struct {
+	buildConfig    func() (*rest.Config, error)
+	newClients     func(config *rest.Config) (kubernetes.Interface, dynamic.Interface, error)
+	initialBackoff time.Duration
+}
+
+// kubeConfigSource decides where the Kubernetes client config comes from;
+// the loaders are replaced in tests
+type kubeConfigSource struct {
+	kubeconfig     string // Explicit kubeconfig path
+	context        string // Explicit kubeconfig context
+	inCluster      func() (*rest.Config, error)
+	loadKubeconfig func(path, context string) (*rest.Config, error)
+}
+
+// build uses the in-cluster config, falling back to kubeconfig. An explicit
+// kubeconfig path or context skips the in-cluster config.
+func (s kubeConfigSource) build(logger *logrus.Logger) (*rest.Config, error) {
+	if s.kubeconfig == "" && s.context == "" {
+		// Try in-cluster config first (for pod deployment)
+		config, err := s.inCluster()
+		if err == nil {
+			return config, nil
+		}
+
+		// Fallback to kubeconfig (for local development)
+		logger.Info("In-cluster config not available, trying kubeconfig")
+	}
+
+	config, err := s.loadKubeconfig(s.kubeconfig, s.context)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes config: %w", err)
+	}
+	return config, nil
+}
+
+// loadKubeconfig loads a kubeconfig like kubectl does: the given path, else
+// $KUBECONFIG, else ~/.kube/config, using the given or the current context
+func loadKubeconfig(path, context string) (*rest.Config, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = path
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides).ClientConfig()
+}
+
+// defaultKubeConnector builds clients from the in-cluster config or kubeconfig
+func defaultKubeConnector(opts EKSOptions, logger *logrus.Logger) kubeConnector {
+	source := kubeConfigSource{
+		kubeconfig:     opts.Kubeconfig,
+		context:        opts.KubeContext,
+		inCluster:      rest.InClusterConfig,
+		loadKubeconfig: loadKubeconfig,
+	}
+
+	return kubeConnector{
+		buildConfig: func() (*rest.Config, error) {
+			return source.build(logger)
+		},
+		newClients: func(config *rest.Config) (kubernetes.Interface, dynamic.Interface, error) {
+			clientset, err := kubernetes.NewForConfig(config)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+			}
+			dynamicClient, err := dynamic.NewForConfig(config)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to create kubernetes dynamic client: %w", err)
+			}
+			return clientset, dynamicClient, nil
+		},
+		initialBackoff: startupInitialBackoff,
+	}
+}
+
 // EKSProvider implements CloudProvider for Amazon EKS
 type EKSProvider struct {
-	clientset kubernetes.Interface
-	logger    *logrus.Logger
+	clientset     kubernetes.Interface
+	dynamicClient dynamic.Interface // Optional, used for CRD-based workloads like Argo Rollouts
+	fieldSelector string
+	hostSuffixes  []string // Extra registry host suffixes treated as ECR
+	onlyRunning   bool     // Only include images of running pods
+	skipSuspended bool     // Skip CronJobs with spec.suspend set
+	ignored       []string // Container name globs whose images are skipped
+	requireOptIn  bool     // Only include workloads annotated with vulnrelay.io/scan
+	namespaces    []string // Namespaces to discover; empty means all
+	concurrency   int      // Namespaces listed at once
+	logger        *logrus.Logger
 }
 
 // NewEKSProvider creates a new EKS cloud provider
-func NewEKSProvider(logger *logrus.Logger) (*EKSProvider, error) {
-	var config *rest.Config
-	var err error
+func NewEKSProvider(opts EKSOptions, logger *logrus.Logger) (*EKSProvider, error) {
+	return newEKSProvider(opts, defaultKubeConnector(opts, logger), logger)
+}
 
-	// Try in-cluster config first (for pod deployment)
-	config, err = rest.InClusterConfig()
+func newEKSProvider(opts EKSOptions, connector kubeConnector, logger *logrus.Logger) (*EKSProvider, error) {
+	fieldSelector, err := ParseFieldSelector(opts.FieldSelector)
 	if err != nil {
-		// Fallback to kubeconfig (for local development)
-		logger.Info("In-cluster config not available, trying kubeconfig")
-		config, err = clientcmd.BuildConfigFromFlags("", clientcmd.RecommendedHomeFile)
-		if err != nil {
-			return nil, fmt.Errorf("failed to build kubernetes config: %w", err)
+		return nil, err
+	}
+	for _, pattern := range opts.IgnoreContainers {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid ignored container pattern %q: %w", pattern, err)
 		}
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, dynamicClient, err := connector.connect(opts.StartupTimeout, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create kubernetes clientset: %w", err)
+		return nil, err
 	}
 
 	logger.Info("Successfully connected to EKS cluster")
 	return &EKSProvider{
-		clientset: clientset,
-		logger:    logger,
+		clientset:     clientset,
+		dynamicClient: dynamicClient,
+		fieldSelector: fieldSelector,
+		hostSuffixes:  normalizeHostSuffixes(opts.RegistryHostSuffixes),
+		onlyRunning:   opts.OnlyRunning,
+		skipSuspended: opts.SkipSuspendedCronJobs,
+		ignored:       opts.IgnoreContainers,
+		requireOptIn:  opts.RequireScanAnnotation,
+		namespaces:    opts.Namespaces,
+		concurrency:   opts.DiscoveryConcurrency,
+		logger:        logger,
 	}, nil
 }
 
+// connect builds the clients and checks the API server is reachable,
+// retrying with exponential backoff until the timeout so a briefly
+// unreachable API server during startup doesn't crash the pod
+func (k kubeConnector) connect(timeout time.Duration, logger *logrus.Logger) (kubernetes.Interface, dynamic.Interface, error) {
+	deadline := time.Now().Add(timeout)
+	backoff := k.initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		clientset, dynamicClient, err := k.attempt()
+		if err == nil {
+			return clientset, dynamicClient, nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return nil, nil, err
+		}
+
+		wait := min(backoff, remaining)
+		logger.WithError(err).WithFields(logrus.Fields{
+			"attempt":  attempt,
+			"retry_in": wait,
+		}).Warn("Kubernetes API not reachable yet, retrying")
+		time.Sleep(wait)
+		backoff = min(2*backoff, startupMaxBackoff)
+	}
+}
+
+// attempt makes a single connection attempt including a connectivity check
+func (k kubeConnector) attempt() (kubernetes.Interface, dynamic.Interface, error) {
+	config, err := k.buildConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clientset, dynamicClient, err := k.newClients(config)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err := clientset.Discovery().ServerVersion(); err != nil {
+		return nil, nil, fmt.Errorf("failed to reach kubernetes API server: %w", err)
+	}
+	return clientset, dynamicClient, nil
+}
+
+// ParseFieldSelector validates a Kubernetes field selector and returns its
+// canonical form. An empty selector is valid and matches everything.
+func ParseFieldSelector(selector string) (string, error) {
+	if strings.TrimSpace(selector) == "" {
+		return "", nil
+	}
+	parsed, err := fields.ParseSelector(selector)
+	if err != nil {
+		return "", fmt.Errorf("invalid field selector %q: %w", selector, err)
+	}
+	return parsed.String(), nil
+}
+
+// listOptions returns the ListOptions applied to every workload query
+func (e *EKSProvider) listOptions() metav1.ListOptions {
+	return metav1.ListOptions{FieldSelector: e.fieldSelector}
+}
+
 // Name returns the provider name
 func (e *EKSProvider) Name() string {
 	return "aws-eks"
 }
 
-// IsRegistryImage checks if the image is from ECR registry
+// IsRegistryImage checks if the image is from ECR registry or a trusted alias host
 func (e *EKSProvider) IsRegistryImage(imageURI string) bool {
-	return strings.Contains(imageURI, ".dkr.ecr.") && strings.Contains(imageURI, ".amazonaws.com/")
+	if strings.Contains(imageURI, ".dkr.ecr.") && strings.Contains(imageURI, ".amazonaws.com/") {
+		return true
+	}
+
+	host, _, found := strings.Cut(imageURI, "/")
+	if !found || len(e.hostSuffixes) == 0 {
+		return false
+	}
+	host = strings.ToLower(host)
+	if h, _, hasPort := strings.Cut(host, ":"); hasPort {
+		host = h
+	}
+
+	for _, suffix := range e.hostSuffixes {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
 }
 
-// DiscoverImages discovers container images from EKS workloads
+// normalizeHostSuffixes lowercases suffixes and strips leading dots so that
+// "ecr.example.com" and ".ecr.example.com" are equivalent
+func normalizeHostSuffixes(suffixes []string) []string {
+	var normalized []string
+	for _, suffix := range suffixes {
+		suffix = strings.ToLower(strings.TrimLeft(strings.TrimSpace(suffix), "."))
+		if suffix != "" {
+			normalized = append(normalized, suffix)
+		}
+	}
+	return normalized
+}
+
+// DiscoverImages discovers container images from EKS workloads. With an
+// explicit namespace list, namespaces are listed concurrently.
 func (e *EKSProvider) DiscoverImages(ctx context.Context) ([]types.ImageInfo, error) {
 	logger := e.logger.WithField("operation", "discover_images")
+	namespaces := e.discoveryNamespaces()
+
+	// Results are kept per namespace so the aggregate follows the configured order
+	results := make([][]types.ImageInfo, len(namespaces))
+	errs := make([]error, len(namespaces))
+	e.forEachNamespace(ctx, namespaces, func(index int, images []types.ImageInfo, err error) {
+		results[index], errs[index] = images, err
+	})
+
+	var images []types.ImageInfo
+	for index, namespaceImages := range results {
+		if errs[index] != nil {
+			logger.WithError(errs[index]).WithField("namespace", namespaces[index]).Error("Failed to discover images")
+			return nil, errs[index]
+		}
+		images = append(images, namespaceImages...)
+	}
+
+	logger.WithField("image_count", len(images)).Info("Image discovery completed")
+	return images, nil
+}
+
+// StreamImages discovers images like DiscoverImages, but sends each
+// namespace's images as soon as they are listed, so vulnerabilities can be
+// fetched while other namespaces are still being listed. Without an explicit
+// namespace list, the whole cluster is a single batch.
+func (e *EKSProvider) StreamImages(ctx context.Context, batches chan<- []types.ImageInfo) error {
+	logger := e.logger.WithField("operation", "stream_images")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	namespaces := e.discoveryNamespaces()
+	var mutex sync.Mutex
+	var firstErr error
+	imageCount := 0
+	e.forEachNamespace(ctx, namespaces, func(index int, images []types.ImageInfo, err error) {
+		if err != nil {
+			mutex.Lock()
+			if firstErr == nil {
+				firstErr = err
+				logger.WithError(err).WithField("namespace", namespaces[index]).Error("Failed to discover images")
+			}
+			mutex.Unlock()
+			// The remaining namespaces would be discarded anyway
+			cancel()
+			return
+		}
+		if len(images) == 0 {
+			return
+		}
+
+		select {
+		case batches <- images:
+			mutex.Lock()
+			imageCount += len(images)
+			mutex.Unlock()
+		case <-ctx.Done():
+		}
+	})
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	logger.WithField("image_count", imageCount).Info("Image discovery completed")
+	return nil
+}
+
+// discoveryNamespaces returns the namespaces to list, metav1.NamespaceAll
+// when none are configured
+func (e *EKSProvider) discoveryNamespaces() []string {
+	if len(e.namespaces) == 0 {
+		return []string{metav1.NamespaceAll}
+	}
+	return e.namespaces
+}
+
+// forEachNamespace discovers namespaces concurrently, up to the discovery
+// concurrency, passing each namespace's index and result to done as it
+// completes. done is called from several goroutines.
+func (e *EKSProvider) forEachNamespace(ctx context.Context, namespaces []string, done func(index int, images []types.ImageInfo, err error)) {
+	concurrency := e.concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultDiscoveryConcurrency
+	}
+	concurrency = min(concurrency, len(namespaces))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range jobs {
+				images, err := e.discoverNamespace(ctx, namespaces[index])
+				done(index, images, err)
+			}
+		}()
+	}
+
+	for index := range namespaces {
+		jobs <- index
+	}
+	close(jobs)
+
+	wg.Wait()
+}
 
+// discoverNamespace discovers images from all workload types in one namespace;
+// metav1.NamespaceAll covers the whole cluster
+func (e *EKSProvider) discoverNamespace(ctx context.Context, namespace string) ([]types.ImageInfo, error) {
 	var images []types.ImageInfo
 
 	// Discover images from Deployments
-	deploymentImages, err := e.discoverFromDeployments(ctx)
+	deploymentImages, err := e.discoverFromDeployments(ctx, namespace)
 	if err != nil {
-		logger.WithError(err).Error("Failed to discover images from deployments")
 		return nil, err
 	}
 	images = append(images, deploymentImages...)
 
 	// Discover images from StatefulSets
-	statefulSetImages, err := e.discoverFromStatefulSets(ctx)
+	statefulSetImages, err := e.discoverFromStatefulSets(ctx, namespace)
 	if err != nil {
-		logger.WithError(err).Error("Failed to discover images from statefulsets")
 		return nil, err
 	}
 	images = append(images, statefulSetImages...)
 
-	logger.WithField("image_count", len(images)).Info("Image discovery completed")
+	// Discover images from CronJobs
+	cronJobImages, err := e.discoverFromCronJobs(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, cronJobImages...)
+
+	// Discover images from Argo Rollouts (skipped if the CRD is not installed)
+	rolloutImages, err := e.discoverFromRollouts(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, rolloutImages...)
+
+	// Discover images from OpenShift DeploymentConfigs (skipped outside OpenShift)
+	deploymentConfigImages, err := e.discoverFromDeploymentConfigs(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	images = append(images, deploymentConfigImages...)
+
+	// Drop images that no running pod uses, e.g. from workloads scaled to zero
+	if e.onlyRunning {
+		running, err := e.runningImages(ctx, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		var runningImages []types.ImageInfo
+		for _, img := range images {
+			if running[runningImageKey{namespace: img.Namespace, image: img.URI}] {
+				runningImages = append(runningImages, img)
+			}
+		}
+		e.logger.WithFields(logrus.Fields{
+			"namespace":     namespace,
+			"skipped_count": len(images) - len(runningImages),
+		}).Debug("Skipped images without running pods")
+		images = runningImages
+	}
+
 	return images, nil
 }
 
-func (e *EKSProvider) discoverFromDeployments(ctx context.Context) ([]types.ImageInfo, error) {
+// runningImageKey identifies an image used in a namespace
+type runningImageKey struct {
+	namespace string
+	image     string
+}
+
+// runningImages returns the images referenced by running pods, per namespace
+func (e *EKSProvider) runningImages(ctx context.Context, namespace string) (map[runningImageKey]bool, error) {
+	pods, err := e.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("status.phase", string(corev1.PodRunning)).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	running := make(map[runningImageKey]bool)
+	for _, pod := range pods.Items {
+		// Checked client-side as well in case the selector was not applied
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.InitContainers {
+			running[runningImageKey{namespace: pod.Namespace, image: container.Image}] = true
+		}
+		for _, container := range pod.Spec.Containers {
+			running[runningImageKey{namespace: pod.Namespace, image: container.Image}] = true
+		}
+	}
+
+	e.logger.WithFields(logrus.Fields{
+		"resource_type": "pods",
+		"pod_count":     len(pods.Items),
+	}).Debug("Processed running pods")
+
+	return running, nil
+}
+
+func (e *EKSProvider) discoverFromDeployments(ctx context.Context, namespace string) ([]types.ImageInfo, error) {
 	logger := e.logger.WithField("resource_type", "deployments")
 
-	deployments, err := e.clientset.AppsV1().Deployments("").List(ctx, metav1.ListOptions{})
+	deployments, err := e.clientset.AppsV1().Deployments(namespace).List(ctx, e.listOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list deployments: %w", err)
 	}
@@ -99,22 +568,25 @@ func (e *EKSProvider) discoverFromDeployments(ctx context.Context) ([]types.Imag
 
 	var images []types.ImageInfo
 	for _, deployment := range deployments.Items {
+		if e.skipWorkload(&deployment, "Deployment") {
+			continue
+		}
 		deploymentImages := e.extractImagesFromPodSpec(
 			deployment.Spec.Template.Spec,
 			deployment.Namespace,
 			deployment.Name,
 			"Deployment",
 		)
-		images = append(images, deploymentImages...)
+		images = append(images, withRelease(deploymentImages, helmRelease(&deployment))...)
 	}
 
 	return images, nil
 }
 
-func (e *EKSProvider) discoverFromStatefulSets(ctx context.Context) ([]types.ImageInfo, error) {
+func (e *EKSProvider) discoverFromStatefulSets(ctx context.Context, namespace string) ([]types.ImageInfo, error) {
 	logger := e.logger.WithField("resource_type", "statefulsets")
 
-	statefulSets, err := e.clientset.AppsV1().StatefulSets("").List(ctx, metav1.ListOptions{})
+	statefulSets, err := e.clientset.AppsV1().StatefulSets(namespace).List(ctx, e.listOptions())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
 	}
@@ -123,56 +595,244 @@ func (e *EKSProvider) discoverFromStatefulSets(ctx context.Context) ([]types.Ima
 
 	var images []types.ImageInfo
 	for _, statefulSet := range statefulSets.Items {
+		if e.skipWorkload(&statefulSet, "StatefulSet") {
+			continue
+		}
 		statefulSetImages := e.extractImagesFromPodSpec(
 			statefulSet.Spec.Template.Spec,
 			statefulSet.Namespace,
 			statefulSet.Name,
 			"StatefulSet",
 		)
-		images = append(images, statefulSetImages...)
+		images = append(images, withRelease(statefulSetImages, helmRelease(&statefulSet))...)
+	}
+
+	return images, nil
+}
+
+func (e *EKSProvider) discoverFromCronJobs(ctx context.Context, namespace string) ([]types.ImageInfo, error) {
+	logger := e.logger.WithField("resource_type", "cronjobs")
+
+	cronJobs, err := e.clientset.BatchV1().CronJobs(namespace).List(ctx, e.listOptions())
+	if err != nil {
+		// Roles without access to CronJobs, or clusters not serving batch/v1
+		// CronJobs, still get the other workloads scanned
+		if apierrors.IsForbidden(err) || apierrors.IsNotFound(err) {
+			logger.WithError(err).Warn("CronJobs not available, skipping cronjob discovery")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list cronjobs: %w", err)
+	}
+
+	logger.WithField("cronjob_count", len(cronJobs.Items)).Info("Processing cronjobs")
+
+	var images []types.ImageInfo
+	for _, cronJob := range cronJobs.Items {
+		if e.skipSuspended && cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend {
+			logger.WithFields(logrus.Fields{
+				"namespace": cronJob.Namespace,
+				"cronjob":   cronJob.Name,
+			}).Debug("Skipping suspended cronjob")
+			continue
+		}
+		if e.skipWorkload(&cronJob, "CronJob") {
+			continue
+		}
+
+		cronJobImages := e.extractImagesFromPodSpec(
+			cronJob.Spec.JobTemplate.Spec.Template.Spec,
+			cronJob.Namespace,
+			cronJob.Name,
+			"CronJob",
+		)
+		images = append(images, withRelease(cronJobImages, helmRelease(&cronJob))...)
 	}
 
 	return images, nil
 }
 
+func (e *EKSProvider) discoverFromRollouts(ctx context.Context, namespace string) ([]types.ImageInfo, error) {
+	logger := e.logger.WithField("resource_type", "rollouts")
+
+	if e.dynamicClient == nil {
+		return nil, nil
+	}
+
+	rollouts, err := e.dynamicClient.Resource(rolloutGVR).Namespace(namespace).List(ctx, e.listOptions())
+	if err != nil {
+		// Clusters without Argo Rollouts installed don't serve the CRD
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			logger.Debug("Argo Rollouts CRD not available, skipping rollout discovery")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list rollouts: %w", err)
+	}
+
+	logger.WithField("rollout_count", len(rollouts.Items)).Info("Processing rollouts")
+
+	var images []types.ImageInfo
+	for _, rollout := range rollouts.Items {
+		if e.skipWorkload(&rollout, "Rollout") {
+			continue
+		}
+		podSpec, found, err := templatePodSpec(rollout)
+		if err != nil {
+			logger.WithError(err).WithField("rollout", rollout.GetName()).Warn("Failed to parse rollout pod template")
+			continue
+		}
+		if !found {
+			// Rollouts using workloadRef have no inline pod template
+			continue
+		}
+
+		rolloutImages := e.extractImagesFromPodSpec(
+			podSpec,
+			rollout.GetNamespace(),
+			rollout.GetName(),
+			"Rollout",
+		)
+		images = append(images, withRelease(rolloutImages, helmRelease(&rollout))...)
+	}
+
+	return images, nil
+}
+
+func (e *EKSProvider) discoverFromDeploymentConfigs(ctx context.Context, namespace string) ([]types.ImageInfo, error) {
+	logger := e.logger.WithField("resource_type", "deploymentconfigs")
+
+	if e.dynamicClient == nil {
+		return nil, nil
+	}
+
+	deploymentConfigs, err := e.dynamicClient.Resource(deploymentConfigGVR).Namespace(namespace).List(ctx, e.listOptions())
+	if err != nil {
+		// Only OpenShift clusters serve the apps.openshift.io API
+		if apierrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			logger.Debug("OpenShift DeploymentConfig API not available, skipping deployment config discovery")
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list deployment configs: %w", err)
+	}
+
+	logger.WithField("deployment_config_count", len(deploymentConfigs.Items)).Info("Processing deployment configs")
+
+	var images []types.ImageInfo
+	for _, dc := range deploymentConfigs.Items {
+		if e.skipWorkload(&dc, "DeploymentConfig") {
+			continue
+		}
+		podSpec, found, err := templatePodSpec(dc)
+		if err != nil {
+			logger.WithError(err).WithField("deployment_config", dc.GetName()).Warn("Failed to parse deployment config pod template")
+			continue
+		}
+		if !found {
+			continue
+		}
+
+		dcImages := e.extractImagesFromPodSpec(
+			podSpec,
+			dc.GetNamespace(),
+			dc.GetName(),
+			"DeploymentConfig",
+		)
+		images = append(images, withRelease(dcImages, helmRelease(&dc))...)
+	}
+
+	return images, nil
+}
+
+// skipWorkload reports whether a workload opted out of scanning with the skip
+// annotation or, when opt-in is required, lacks the scan annotation
+func (e *EKSProvider) skipWorkload(obj metav1.Object, workloadType string) bool {
+	annotations := obj.GetAnnotations()
+	skip := annotations[skipAnnotation] == "true"
+	if !skip && e.requireOptIn {
+		skip = annotations[scanAnnotation] != "true"
+	}
+	if skip {
+		e.logger.WithFields(logrus.Fields{
+			"namespace":     obj.GetNamespace(),
+			"workload":      obj.GetName(),
+			"workload_type": workloadType,
+		}).Debug("Skipping workload based on scan annotations")
+	}
+	return skip
+}
+
+// helmRelease returns the Helm release managing a workload, preferring the
+// release-name annotation Helm sets over the conventional instance label
+func helmRelease(obj metav1.Object) string {
+	if release := obj.GetAnnotations()[helmReleaseAnnotation]; release != "" {
+		return release
+	}
+	return obj.GetLabels()[helmInstanceLabel]
+}
+
+// withRelease sets the Helm release on images discovered from one workload
+func withRelease(images []types.ImageInfo, release string) []types.ImageInfo {
+	for i := range images {
+		images[i].Release = release
+	}
+	return images
+}
+
+// templatePodSpec extracts the pod spec from the spec.template of a CRD-based
+// workload such as a Rollout or DeploymentConfig
+func templatePodSpec(workload unstructured.Unstructured) (corev1.PodSpec, bool, error) {
+	var podSpec corev1.PodSpec
+
+	specMap, found, err := unstructured.NestedMap(workload.Object, "spec", "template", "spec")
+	if err != nil || !found {
+		return podSpec, false, err
+	}
+
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(specMap, &podSpec); err != nil {
+		return podSpec, false, err
+	}
+
+	return podSpec, true, nil
+}
+
 func (e *EKSProvider) extractImagesFromPodSpec(podSpec corev1.PodSpec, namespace, workload, workloadType string) []types.ImageInfo {
 	var images []types.ImageInfo
 
+	addContainer := func(name, image string) {
+		if !e.IsRegistryImage(image) || e.isIgnoredContainer(name) {
+			return
+		}
+		images = append(images, types.ImageInfo{
+			URI:          image,
+			Namespace:    namespace,
+			Workload:     workload,
+			WorkloadType: workloadType,
+		})
+	}
+
 	// Extract from main containers
 	for _, container := range podSpec.Containers {
-		if e.IsRegistryImage(container.Image) {
-			images = append(images, types.ImageInfo{
-				URI:          container.Image,
-				Namespace:    namespace,
-				Workload:     workload,
-				WorkloadType: workloadType,
-			})
-		}
+		addContainer(container.Name, container.Image)
 	}
 
 	// Extract from init containers
 	for _, container := range podSpec.InitContainers {
-		if e.IsRegistryImage(container.Image) {
-			images = append(images, types.ImageInfo{
-				URI:          container.Image,
-				Namespace:    namespace,
-				Workload:     workload,
-				WorkloadType: workloadType,
-			})
-		}
+		addContainer(container.Name, container.Image)
 	}
 
 	// Extract from ephemeral containers (if any)
 	for _, container := range podSpec.EphemeralContainers {
-		if e.IsRegistryImage(container.Image) {
-			images = append(images, types.ImageInfo{
-				URI:          container.Image,
-				Namespace:    namespace,
-				Workload:     workload,
-				WorkloadType: workloadType,
-			})
-		}
+		addContainer(container.Name, container.Image)
 	}
 
 	return images
 }
+
+// isIgnoredContainer reports whether a container name matches an ignore pattern
+func (e *EKSProvider) isIgnoredContainer(name string) bool {
+	for _, pattern := range e.ignored {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
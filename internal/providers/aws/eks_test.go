@@ -5,16 +5,33 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/fake"
+	appsv1client "k8s.io/client-go/kubernetes/typed/apps/v1"
+	"k8s.io/client-go/rest"
 	ktesting "k8s.io/client-go/testing"
 )
 
@@ -314,6 +331,90 @@ func TestEKSProviderDiscoverImagesWithErrors(t *testing.T) {
 	}
 }
 
+func TestEKSProviderIsRegistryImageWithHostSuffixes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	provider := &EKSProvider{
+		clientset:    fake.NewSimpleClientset(),
+		hostSuffixes: normalizeHostSuffixes([]string{".ECR.Example.com", " registry.internal ", ""}),
+		logger:       logger,
+	}
+
+	tests := []struct {
+		name     string
+		imageURI string
+		expected bool
+	}{
+		{"standard ECR image", "123456789012.dkr.ecr.us-east-1.amazonaws.com/my-app:latest", true},
+		{"alias host subdomain", "prod.ecr.example.com/my-app:v1", true},
+		{"alias host exact", "ecr.example.com/team/my-app:v1", true},
+		{"alias host with port", "registry.internal:5000/my-app:v1", true},
+		{"alias host mixed case", "Prod.ECR.example.com/my-app:v1", true},
+		{"suffix without dot boundary", "evilecr.example.com/my-app:v1", false},
+		{"suffix only in path", "docker.io/ecr.example.com/my-app:v1", false},
+		{"foreign registry", "gcr.io/my-project/my-app:latest", false},
+		{"Docker Hub image", "nginx:latest", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := provider.IsRegistryImage(tt.imageURI); result != tt.expected {
+				t.Errorf("IsRegistryImage(%q) = %v, want %v", tt.imageURI, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestEKSProviderDiscoverImagesWithHostSuffixes(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-app", Namespace: "production"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{Name: "web", Image: "ecr.example.com/web-app:v1.0.0"},
+						{Name: "proxy", Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/proxy:v2"},
+						{Name: "metrics", Image: "quay.io/prometheus/node-exporter:v1.8.0"},
+					},
+				},
+			},
+		},
+	}
+
+	provider := &EKSProvider{
+		clientset:    fake.NewSimpleClientset(deployment),
+		hostSuffixes: normalizeHostSuffixes([]string{"ecr.example.com"}),
+		logger:       logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+
+	found := make(map[string]bool)
+	for _, img := range images {
+		found[img.URI] = true
+	}
+
+	if len(images) != 2 {
+		t.Errorf("Expected 2 images, got %d: %v", len(images), found)
+	}
+	if !found["ecr.example.com/web-app:v1.0.0"] {
+		t.Error("Expected image from trusted alias host to be discovered")
+	}
+	if !found["123456789012.dkr.ecr.us-east-1.amazonaws.com/proxy:v2"] {
+		t.Error("Expected standard ECR image to be discovered")
+	}
+	if found["quay.io/prometheus/node-exporter:v1.8.0"] {
+		t.Error("Expected foreign registry image to be excluded")
+	}
+}
+
 func TestExtractImagesFromPodSpec(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -387,13 +488,74 @@ func TestExtractImagesFromPodSpec(t *testing.T) {
 	}
 }
 
+func TestExtractImagesFromPodSpecIgnoreContainers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	provider := &EKSProvider{
+		clientset: fake.NewSimpleClientset(),
+		logger:    logger,
+		ignored:   []string{"istio-*", "linkerd-proxy"},
+	}
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{
+				Name:  "app",
+				Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1",
+			},
+			{
+				Name:  "istio-proxy",
+				Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/proxyv2:1.22",
+			},
+			{
+				Name:  "linkerd-proxy",
+				Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/linkerd:2.14",
+			},
+		},
+		InitContainers: []corev1.Container{
+			{
+				Name:  "istio-init",
+				Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/proxyv2:1.22",
+			},
+			{
+				Name:  "migrate",
+				Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1-migrate",
+			},
+		},
+	}
+
+	images := provider.extractImagesFromPodSpec(podSpec, "test-namespace", "test-workload", "Deployment")
+
+	var uris []string
+	for _, img := range images {
+		uris = append(uris, img.URI)
+	}
+	expected := []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1-migrate",
+	}
+	if !reflect.DeepEqual(uris, expected) {
+		t.Errorf("Expected images %v, got %v", expected, uris)
+	}
+}
+
+func TestNewEKSProviderInvalidIgnoreContainers(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	if _, err := NewEKSProvider(EKSOptions{IgnoreContainers: []string{"istio-["}}, logger); err == nil {
+		t.Error("Expected error for invalid ignored container pattern")
+	}
+}
+
 func TestNewEKSProviderError(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
 
 	// This test verifies that NewEKSProvider handles configuration errors gracefully
 	// In a real test environment without Kubernetes access, this should fail
-	_, err := NewEKSProvider(logger)
+	_, err := NewEKSProvider(EKSOptions{}, logger)
 	if err == nil {
 		t.Log("NewEKSProvider succeeded - likely running in Kubernetes environment")
 	} else {
@@ -402,6 +564,88 @@ func TestNewEKSProviderError(t *testing.T) {
 	}
 }
 
+// flakyKubeConnector fails to build a config and then to reach the API server
+// before connecting
+func flakyKubeConnector(configFailures, versionFailures int, clientset *fake.Clientset) (kubeConnector, *int) {
+	attempts := 0
+	clientset.PrependReactor("get", "version", func(action ktesting.Action) (bool, runtime.Object, error) {
+		if versionFailures > 0 {
+			versionFailures--
+			return true, nil, fmt.Errorf("connection refused")
+		}
+		return false, nil, nil
+	})
+
+	return kubeConnector{
+		buildConfig: func() (*rest.Config, error) {
+			attempts++
+			if attempts <= configFailures {
+				return nil, fmt.Errorf("no route to host")
+			}
+			return &rest.Config{Host: "https://kubernetes.default"}, nil
+		},
+		newClients: func(config *rest.Config) (kubernetes.Interface, dynamic.Interface, error) {
+			return clientset, nil, nil
+		},
+		initialBackoff: time.Millisecond,
+	}, &attempts
+}
+
+func TestNewEKSProviderRetriesStartup(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	clientset := fake.NewSimpleClientset()
+	connector, attempts := flakyKubeConnector(1, 1, clientset)
+
+	provider, err := newEKSProvider(EKSOptions{StartupTimeout: time.Second}, connector, logger)
+	if err != nil {
+		t.Fatalf("Expected transient failures to be retried, got %v", err)
+	}
+	if provider.clientset != clientset {
+		t.Error("Expected provider to use the connected clientset")
+	}
+	// One config failure, one unreachable API server, then success
+	if *attempts != 3 {
+		t.Errorf("Expected 3 connection attempts, got %d", *attempts)
+	}
+}
+
+func TestNewEKSProviderStartupTimeout(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	tests := []struct {
+		name             string
+		timeout          time.Duration
+		expectedAttempts int
+	}{
+		{name: "zero timeout makes a single attempt", timeout: 0, expectedAttempts: 1},
+		{name: "retries until the timeout", timeout: 50 * time.Millisecond, expectedAttempts: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			connector, attempts := flakyKubeConnector(0, 1000, fake.NewSimpleClientset())
+			connector.initialBackoff = 30 * time.Millisecond
+
+			start := time.Now()
+			if _, err := newEKSProvider(EKSOptions{StartupTimeout: tt.timeout}, connector, logger); err == nil {
+				t.Fatal("Expected error when the API server stays unreachable")
+			}
+			if *attempts < tt.expectedAttempts {
+				t.Errorf("Expected at least %d attempts, got %d", tt.expectedAttempts, *attempts)
+			}
+			if tt.timeout == 0 && *attempts != 1 {
+				t.Errorf("Expected a single attempt without a timeout, got %d", *attempts)
+			}
+			if elapsed := time.Since(start); elapsed > tt.timeout+time.Second {
+				t.Errorf("Expected retries to stop at the timeout, took %v", elapsed)
+			}
+		})
+	}
+}
+
 func TestEKSProviderEmptyCluster(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -424,3 +668,1033 @@ func TestEKSProviderEmptyCluster(t *testing.T) {
 		t.Errorf("Expected 0 images in empty cluster, got %d", len(images))
 	}
 }
+
+func newWorkloadDynamicClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			rolloutGVR:          "RolloutList",
+			deploymentConfigGVR: "DeploymentConfigList",
+		},
+		objects...,
+	)
+}
+
+func TestEKSProviderDiscoverRollouts(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	rollout := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Rollout",
+			"metadata": map[string]interface{}{
+				"name":      "canary-app",
+				"namespace": "production",
+			},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"name":  "app",
+								"image": "123456789012.dkr.ecr.us-east-1.amazonaws.com/canary-app:v2.0.0",
+							},
+							map[string]interface{}{
+								"name":  "proxy",
+								"image": "envoyproxy/envoy:v1.28.0", // Non-ECR, should be filtered
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	// Rollouts referencing an existing workload have no inline template
+	workloadRefRollout := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "argoproj.io/v1alpha1",
+			"kind":       "Rollout",
+			"metadata": map[string]interface{}{
+				"name":      "ref-app",
+				"namespace": "production",
+			},
+			"spec": map[string]interface{}{
+				"workloadRef": map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"name":       "ref-app",
+				},
+			},
+		},
+	}
+
+	provider := &EKSProvider{
+		clientset:     fake.NewSimpleClientset(),
+		dynamicClient: newWorkloadDynamicClient(rollout, workloadRefRollout),
+		logger:        logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+
+	if len(images) != 1 {
+		t.Fatalf("Expected 1 image, got %d", len(images))
+	}
+
+	expected := types.ImageInfo{
+		URI:          "123456789012.dkr.ecr.us-east-1.amazonaws.com/canary-app:v2.0.0",
+		Namespace:    "production",
+		Workload:     "canary-app",
+		WorkloadType: "Rollout",
+	}
+	if images[0] != expected {
+		t.Errorf("Expected image %+v, got %+v", expected, images[0])
+	}
+}
+
+func TestEKSProviderDiscoverRolloutsWithoutCRD(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	dynamicClient := newWorkloadDynamicClient()
+	dynamicClient.PrependReactor("list", "rollouts", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewNotFound(rolloutGVR.GroupResource(), "")
+	})
+
+	provider := &EKSProvider{
+		clientset:     fake.NewSimpleClientset(),
+		dynamicClient: dynamicClient,
+		logger:        logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() should tolerate a missing Rollout CRD, got: %v", err)
+	}
+
+	if len(images) != 0 {
+		t.Errorf("Expected 0 images, got %d", len(images))
+	}
+}
+
+func TestEKSProviderDiscoverRolloutsListError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	dynamicClient := newWorkloadDynamicClient()
+	dynamicClient.PrependReactor("list", "rollouts", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, fmt.Errorf("rollouts list error: internal server error")
+	})
+
+	provider := &EKSProvider{
+		clientset:     fake.NewSimpleClientset(),
+		dynamicClient: dynamicClient,
+		logger:        logger,
+	}
+
+	if _, err := provider.DiscoverImages(context.Background()); err == nil {
+		t.Error("Expected error when listing rollouts fails")
+	}
+}
+
+func TestEKSProviderDiscoverDeploymentConfigs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	deploymentConfig := func(name string, annotations map[string]interface{}) *unstructured.Unstructured {
+		return &unstructured.Unstructured{
+			Object: map[string]interface{}{
+				"apiVersion": "apps.openshift.io/v1",
+				"kind":       "DeploymentConfig",
+				"metadata": map[string]interface{}{
+					"name":        name,
+					"namespace":   "production",
+					"annotations": annotations,
+				},
+				"spec": map[string]interface{}{
+					"replicas": int64(2),
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"initContainers": []interface{}{
+								map[string]interface{}{
+									"name":  "migrate",
+									"image": "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + "-migrate:v1.0.0",
+								},
+							},
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name":  "app",
+									"image": "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + ":v1.0.0",
+								},
+								map[string]interface{}{
+									"name":  "sidecar",
+									"image": "registry.redhat.io/ubi9/ubi:latest", // Non-ECR, should be filtered
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	provider := &EKSProvider{
+		clientset: fake.NewSimpleClientset(),
+		dynamicClient: newWorkloadDynamicClient(
+			deploymentConfig("legacy-app", map[string]interface{}{helmReleaseAnnotation: "legacy"}),
+			deploymentConfig("skipped-app", map[string]interface{}{skipAnnotation: "true"}),
+		),
+		logger: logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+
+	expected := map[string]types.ImageInfo{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/legacy-app:v1.0.0": {
+			URI:          "123456789012.dkr.ecr.us-east-1.amazonaws.com/legacy-app:v1.0.0",
+			Namespace:    "production",
+			Workload:     "legacy-app",
+			WorkloadType: "DeploymentConfig",
+			Release:      "legacy",
+		},
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/legacy-app-migrate:v1.0.0": {
+			URI:          "123456789012.dkr.ecr.us-east-1.amazonaws.com/legacy-app-migrate:v1.0.0",
+			Namespace:    "production",
+			Workload:     "legacy-app",
+			WorkloadType: "DeploymentConfig",
+			Release:      "legacy",
+		},
+	}
+	if len(images) != len(expected) {
+		t.Fatalf("Expected %d images, got %d: %+v", len(expected), len(images), images)
+	}
+	for _, img := range images {
+		if want, ok := expected[img.URI]; !ok || img != want {
+			t.Errorf("Unexpected image %+v", img)
+		}
+	}
+}
+
+func TestEKSProviderDiscoverDeploymentConfigsOutsideOpenShift(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	dynamicClient := newWorkloadDynamicClient()
+	dynamicClient.PrependReactor("list", "deploymentconfigs", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewNotFound(deploymentConfigGVR.GroupResource(), "")
+	})
+
+	provider := &EKSProvider{
+		clientset:     fake.NewSimpleClientset(),
+		dynamicClient: dynamicClient,
+		logger:        logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() should tolerate a missing DeploymentConfig API, got: %v", err)
+	}
+
+	if len(images) != 0 {
+		t.Errorf("Expected 0 images, got %d", len(images))
+	}
+}
+
+func TestEKSProviderDiscoverImagesHelmRelease(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	podSpec := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: image}},
+			},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "web-app",
+				Namespace:   "production",
+				Labels:      map[string]string{"app.kubernetes.io/instance": "web-label"},
+				Annotations: map[string]string{"meta.helm.sh/release-name": "web"},
+			},
+			Spec: appsv1.DeploymentSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/web-app:v1.0.0")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "api",
+				Namespace: "production",
+				Labels:    map[string]string{"app.kubernetes.io/instance": "backend"},
+			},
+			Spec: appsv1.DeploymentSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v2.0.0")},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "production"},
+			Spec:       appsv1.StatefulSetSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/postgres:14")},
+		},
+	)
+
+	provider := &EKSProvider{
+		clientset: clientset,
+		logger:    logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+
+	releases := make(map[string]string)
+	for _, img := range images {
+		releases[img.Workload] = img.Release
+	}
+	expected := map[string]string{
+		"web-app":  "web", // The Helm annotation wins over the instance label
+		"api":      "backend",
+		"database": "",
+	}
+	if !reflect.DeepEqual(releases, expected) {
+		t.Errorf("Expected releases %v, got %v", expected, releases)
+	}
+}
+
+func TestEKSProviderDiscoverImagesScanAnnotations(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	podSpec := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: image}},
+			},
+		}
+	}
+	objects := []runtime.Object{
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "plain", Namespace: "production"},
+			Spec:       appsv1.DeploymentSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/plain:v1")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "skipped",
+				Namespace:   "production",
+				Annotations: map[string]string{"vulnrelay.io/skip": "true"},
+			},
+			Spec: appsv1.DeploymentSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/skipped:v1")},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "opted-in",
+				Namespace:   "production",
+				Annotations: map[string]string{"vulnrelay.io/scan": "true"},
+			},
+			Spec: appsv1.StatefulSetSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/opted-in:v1")},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "not-opted-in",
+				Namespace:   "production",
+				Annotations: map[string]string{"vulnrelay.io/scan": "false"},
+			},
+			Spec: appsv1.StatefulSetSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/not-opted-in:v1")},
+		},
+		&batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "conflicting",
+				Namespace: "batch",
+				Annotations: map[string]string{
+					"vulnrelay.io/scan": "true",
+					"vulnrelay.io/skip": "true",
+				},
+			},
+			Spec: batchv1.CronJobSpec{
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/conflicting:v1")},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		requireOptIn      bool
+		expectedWorkloads []string
+	}{
+		{
+			name:              "opt-out",
+			requireOptIn:      false,
+			expectedWorkloads: []string{"not-opted-in", "opted-in", "plain"},
+		},
+		{
+			name:              "opt-in required",
+			requireOptIn:      true,
+			expectedWorkloads: []string{"opted-in"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &EKSProvider{
+				clientset:    fake.NewSimpleClientset(objects...),
+				requireOptIn: tt.requireOptIn,
+				logger:       logger,
+			}
+
+			images, err := provider.DiscoverImages(context.Background())
+			if err != nil {
+				t.Fatalf("DiscoverImages() failed: %v", err)
+			}
+
+			var workloads []string
+			for _, img := range images {
+				workloads = append(workloads, img.Workload)
+			}
+			sort.Strings(workloads)
+			if !reflect.DeepEqual(workloads, tt.expectedWorkloads) {
+				t.Errorf("Expected workloads %v, got %v", tt.expectedWorkloads, workloads)
+			}
+		})
+	}
+}
+
+// inflightTracker records the highest number of concurrent calls
+type inflightTracker struct {
+	mu      sync.Mutex
+	current int
+	max     int
+}
+
+func (t *inflightTracker) enter() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current++
+	t.max = max(t.max, t.current)
+}
+
+func (t *inflightTracker) leave() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.current--
+}
+
+// concurrencyClientset measures concurrent deployment list calls, which the
+// fake clientset itself serializes
+type concurrencyClientset struct {
+	*fake.Clientset
+	tracker *inflightTracker
+}
+
+func (c *concurrencyClientset) AppsV1() appsv1client.AppsV1Interface {
+	return &concurrencyAppsV1{AppsV1Interface: c.Clientset.AppsV1(), tracker: c.tracker}
+}
+
+type concurrencyAppsV1 struct {
+	appsv1client.AppsV1Interface
+	tracker *inflightTracker
+}
+
+func (a *concurrencyAppsV1) Deployments(namespace string) appsv1client.DeploymentInterface {
+	return &concurrencyDeployments{DeploymentInterface: a.AppsV1Interface.Deployments(namespace), tracker: a.tracker}
+}
+
+type concurrencyDeployments struct {
+	appsv1client.DeploymentInterface
+	tracker *inflightTracker
+}
+
+func (d *concurrencyDeployments) List(ctx context.Context, opts metav1.ListOptions) (*appsv1.DeploymentList, error) {
+	d.tracker.enter()
+	defer d.tracker.leave()
+	time.Sleep(20 * time.Millisecond) // Keep the call in flight long enough to overlap
+	return d.DeploymentInterface.List(ctx, opts)
+}
+
+func TestEKSProviderDiscoverImagesNamespaces(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	namespaces := []string{"team-a", "team-b", "team-c", "team-d", "team-e", "team-f"}
+	var objects []runtime.Object
+	for _, namespace := range append([]string{"kube-system"}, namespaces...) {
+		objects = append(objects, &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: namespace},
+			Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{{Name: "app", Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + namespace + ":v1"}},
+					},
+				},
+			},
+		})
+	}
+
+	const concurrency = 2
+	tracker := &inflightTracker{}
+	provider := &EKSProvider{
+		clientset:   &concurrencyClientset{Clientset: fake.NewSimpleClientset(objects...), tracker: tracker},
+		namespaces:  namespaces,
+		concurrency: concurrency,
+		logger:      logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+
+	// Every listed namespace is discovered, in the configured order, and
+	// namespaces outside the list are not
+	var discovered []string
+	for _, img := range images {
+		discovered = append(discovered, img.Namespace)
+	}
+	if !reflect.DeepEqual(discovered, namespaces) {
+		t.Errorf("Expected images from %v, got %v", namespaces, discovered)
+	}
+
+	if tracker.max > concurrency {
+		t.Errorf("Expected at most %d concurrent namespace lists, got %d", concurrency, tracker.max)
+	}
+	if tracker.max < 2 {
+		t.Errorf("Expected namespaces to be listed concurrently, got at most %d at once", tracker.max)
+	}
+}
+
+func TestEKSProviderStreamImages(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	namespaces := []string{"team-a", "team-b", "team-c", "empty"}
+	var objects []runtime.Object
+	for _, namespace := range namespaces[:3] {
+		for _, name := range []string{"api", "web"} {
+			objects = append(objects, &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+				Spec: appsv1.DeploymentSpec{
+					Template: corev1.PodTemplateSpec{
+						Spec: corev1.PodSpec{
+							Containers: []corev1.Container{{Name: name, Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + ":v1"}},
+						},
+					},
+				},
+			})
+		}
+	}
+
+	provider := &EKSProvider{
+		clientset:   fake.NewSimpleClientset(objects...),
+		namespaces:  namespaces,
+		concurrency: 2,
+		logger:      logger,
+	}
+
+	batches := make(chan []types.ImageInfo)
+	errc := make(chan error, 1)
+	go func() {
+		errc <- provider.StreamImages(context.Background(), batches)
+		close(batches)
+	}()
+
+	// Each namespace with images is one batch, in completion order
+	var streamed []string
+	for batch := range batches {
+		if len(batch) != 2 {
+			t.Fatalf("Expected a batch of the namespace's 2 images, got %+v", batch)
+		}
+		if batch[0].Namespace != batch[1].Namespace {
+			t.Errorf("Expected one namespace per batch, got %q and %q", batch[0].Namespace, batch[1].Namespace)
+		}
+		streamed = append(streamed, batch[0].Namespace)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("StreamImages() failed: %v", err)
+	}
+
+	sort.Strings(streamed)
+	if expected := []string{"team-a", "team-b", "team-c"}; !reflect.DeepEqual(streamed, expected) {
+		t.Errorf("Expected batches for %v, got %v", expected, streamed)
+	}
+}
+
+func TestEKSProviderStreamImagesError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	clientset := fake.NewSimpleClientset()
+	clientset.PrependReactor("list", "deployments", func(action ktesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("forbidden")
+	})
+	provider := &EKSProvider{clientset: clientset, namespaces: []string{"team-a", "team-b"}, logger: logger}
+
+	batches := make(chan []types.ImageInfo)
+	go func() {
+		for range batches {
+		}
+	}()
+	defer close(batches)
+
+	if err := provider.StreamImages(context.Background(), batches); err == nil || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("Expected the list error, got %v", err)
+	}
+}
+
+// fieldSelectorReactor emulates server-side field selector filtering, which the
+// fake clientset does not implement, and records the selectors it receives
+func fieldSelectorReactor(tracker ktesting.ObjectTracker, received *[]string) ktesting.ReactionFunc {
+	return func(action ktesting.Action) (bool, runtime.Object, error) {
+		listAction, ok := action.(ktesting.ListActionImpl)
+		if !ok {
+			return false, nil, nil
+		}
+		selector := listAction.GetListRestrictions().Fields
+		*received = append(*received, selector.String())
+
+		list, err := tracker.List(listAction.GetResource(), listAction.GetKind(), listAction.GetNamespace())
+		if err != nil {
+			return true, nil, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return true, nil, err
+		}
+
+		var matched []runtime.Object
+		for _, item := range items {
+			accessor, err := meta.Accessor(item)
+			if err != nil {
+				return true, nil, err
+			}
+			if selector.Matches(fields.Set{
+				"metadata.name":      accessor.GetName(),
+				"metadata.namespace": accessor.GetNamespace(),
+			}) {
+				matched = append(matched, item)
+			}
+		}
+		if err := meta.SetList(list, matched); err != nil {
+			return true, nil, err
+		}
+		return true, list, nil
+	}
+}
+
+func TestEKSProviderDiscoverImagesWithFieldSelector(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	podSpec := func(image string) corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "app", Image: image}},
+			},
+		}
+	}
+
+	clientset := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-app", Namespace: "production"},
+			Spec:       appsv1.DeploymentSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/web-app:v1.0.0")},
+		},
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "api-service", Namespace: "production"},
+			Spec:       appsv1.DeploymentSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v2.0.0")},
+		},
+		&appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "database", Namespace: "production"},
+			Spec:       appsv1.StatefulSetSpec{Template: podSpec("123456789012.dkr.ecr.us-east-1.amazonaws.com/postgres:14")},
+		},
+	)
+
+	var received []string
+	clientset.PrependReactor("list", "*", fieldSelectorReactor(clientset.Tracker(), &received))
+
+	fieldSelector, err := ParseFieldSelector("metadata.name=web-app")
+	if err != nil {
+		t.Fatalf("ParseFieldSelector() failed: %v", err)
+	}
+
+	provider := &EKSProvider{
+		clientset:     clientset,
+		fieldSelector: fieldSelector,
+		logger:        logger,
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+
+	if len(images) != 1 {
+		t.Fatalf("Expected 1 image, got %d: %+v", len(images), images)
+	}
+	if images[0].Workload != "web-app" || images[0].WorkloadType != "Deployment" {
+		t.Errorf("Expected only web-app deployment, got %s %s", images[0].WorkloadType, images[0].Workload)
+	}
+
+	// Deployments, statefulsets and cronjobs must be listed with the selector
+	if len(received) != 3 {
+		t.Fatalf("Expected 3 list calls, got %d", len(received))
+	}
+	for _, selector := range received {
+		if selector != "metadata.name=web-app" {
+			t.Errorf("Expected field selector metadata.name=web-app, got %q", selector)
+		}
+	}
+}
+
+func TestParseFieldSelector(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     string
+		wantErr  bool
+	}{
+		{"empty", "", "", false},
+		{"whitespace", "   ", "", false},
+		{"name equality", "metadata.name=web-app", "metadata.name=web-app", false},
+		{"multiple requirements", "metadata.namespace!=kube-system,metadata.name=api", "metadata.name=api,metadata.namespace!=kube-system", false},
+		{"missing operator", "metadata.name", "", true},
+		{"invalid operator", "metadata.name~web", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFieldSelector(tt.selector)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFieldSelector(%q) error = %v, wantErr %v", tt.selector, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFieldSelector(%q) = %q, want %q", tt.selector, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEKSProviderInvalidFieldSelector(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	if _, err := NewEKSProvider(EKSOptions{FieldSelector: "metadata.name"}, logger); err == nil {
+		t.Error("Expected error for invalid field selector")
+	}
+}
+
+func TestEKSProviderDiscoverImagesOnlyRunning(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	const (
+		webImage    = "123456789012.dkr.ecr.us-east-1.amazonaws.com/web-app:v1.0.0"
+		workerImage = "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1.0.0"
+		jobImage    = "123456789012.dkr.ecr.us-east-1.amazonaws.com/job:v1.0.0"
+	)
+
+	podSpec := func(image string) corev1.PodSpec {
+		return corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}}
+	}
+	zero := int32(0)
+
+	newClientset := func() *fake.Clientset {
+		return fake.NewSimpleClientset(
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-app", Namespace: "production"},
+				Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec(webImage)}},
+			},
+			// Scaled to zero, so no pods run this image
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker", Namespace: "production"},
+				Spec: appsv1.DeploymentSpec{
+					Replicas: &zero,
+					Template: corev1.PodTemplateSpec{Spec: podSpec(workerImage)},
+				},
+			},
+			// Only a completed pod uses this image
+			&appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{Name: "job", Namespace: "production"},
+				Spec:       appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: podSpec(jobImage)}},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "web-app-abc12", Namespace: "production"},
+				Spec:       podSpec(webImage),
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "job-xyz98", Namespace: "production"},
+				Spec:       podSpec(jobImage),
+				Status:     corev1.PodStatus{Phase: corev1.PodSucceeded},
+			},
+			// Same image running in another namespace must not count
+			&corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Name: "worker-def34", Namespace: "staging"},
+				Spec:       podSpec(workerImage),
+				Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+			},
+		)
+	}
+
+	tests := []struct {
+		name          string
+		onlyRunning   bool
+		expectedNames []string
+	}{
+		{
+			name:          "all workloads when disabled",
+			onlyRunning:   false,
+			expectedNames: []string{"web-app", "worker", "job"},
+		},
+		{
+			name:          "only running workloads when enabled",
+			onlyRunning:   true,
+			expectedNames: []string{"web-app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &EKSProvider{
+				clientset:   newClientset(),
+				onlyRunning: tt.onlyRunning,
+				logger:      logger,
+			}
+
+			images, err := provider.DiscoverImages(context.Background())
+			if err != nil {
+				t.Fatalf("DiscoverImages() failed: %v", err)
+			}
+
+			workloads := make(map[string]bool)
+			for _, img := range images {
+				workloads[img.Workload] = true
+			}
+			if len(workloads) != len(tt.expectedNames) {
+				t.Errorf("Expected workloads %v, got %+v", tt.expectedNames, images)
+			}
+			for _, name := range tt.expectedNames {
+				if !workloads[name] {
+					t.Errorf("Expected workload %s to be discovered", name)
+				}
+			}
+		})
+	}
+}
+
+func TestEKSProviderDiscoverCronJobs(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	cronJob := func(name, image string, suspend *bool) *batchv1.CronJob {
+		return &batchv1.CronJob{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "batch"},
+			Spec: batchv1.CronJobSpec{
+				Schedule: "0 * * * *",
+				Suspend:  suspend,
+				JobTemplate: batchv1.JobTemplateSpec{
+					Spec: batchv1.JobSpec{
+						Template: corev1.PodTemplateSpec{
+							Spec: corev1.PodSpec{
+								Containers: []corev1.Container{{Name: "job", Image: image}},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	suspended, active := true, false
+
+	tests := []struct {
+		name          string
+		skipSuspended bool
+		expectedNames []string
+	}{
+		{
+			name:          "suspended cronjobs included by default",
+			skipSuspended: false,
+			expectedNames: []string{"nightly-report", "hourly-sync", "cleanup"},
+		},
+		{
+			name:          "suspended cronjobs skipped",
+			skipSuspended: true,
+			expectedNames: []string{"hourly-sync", "cleanup"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &EKSProvider{
+				clientset: fake.NewSimpleClientset(
+					cronJob("nightly-report", "123456789012.dkr.ecr.us-east-1.amazonaws.com/report:v1", &suspended),
+					cronJob("hourly-sync", "123456789012.dkr.ecr.us-east-1.amazonaws.com/sync:v1", &active),
+					cronJob("cleanup", "123456789012.dkr.ecr.us-east-1.amazonaws.com/cleanup:v1", nil),
+				),
+				skipSuspended: tt.skipSuspended,
+				logger:        logger,
+			}
+
+			images, err := provider.DiscoverImages(context.Background())
+			if err != nil {
+				t.Fatalf("DiscoverImages() failed: %v", err)
+			}
+
+			workloads := make(map[string]bool)
+			for _, img := range images {
+				if img.WorkloadType != "CronJob" {
+					t.Errorf("Expected workload type CronJob, got %s", img.WorkloadType)
+				}
+				workloads[img.Workload] = true
+			}
+			if len(workloads) != len(tt.expectedNames) {
+				t.Errorf("Expected cronjobs %v, got %+v", tt.expectedNames, images)
+			}
+			for _, name := range tt.expectedNames {
+				if !workloads[name] {
+					t.Errorf("Expected cronjob %s to be discovered", name)
+				}
+			}
+		})
+	}
+}
+
+func TestEKSProviderDiscoverCronJobsListError(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "api", Image: "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"}},
+				},
+			},
+		},
+	}
+	cronJobs := schema.GroupResource{Group: "batch", Resource: "cronjobs"}
+
+	tests := []struct {
+		name        string
+		listErr     error
+		expectError bool
+	}{
+		{
+			name:    "forbidden",
+			listErr: apierrors.NewForbidden(cronJobs, "", fmt.Errorf("missing RBAC permission")),
+		},
+		{
+			name:    "not served",
+			listErr: apierrors.NewNotFound(cronJobs, ""),
+		},
+		{
+			name:        "other errors fail discovery",
+			listErr:     fmt.Errorf("cronjobs list error: internal server error"),
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(deployment)
+			clientset.PrependReactor("list", "cronjobs", func(action ktesting.Action) (handled bool, ret runtime.Object, err error) {
+				return true, nil, tt.listErr
+			})
+			provider := &EKSProvider{clientset: clientset, logger: logger}
+
+			images, err := provider.DiscoverImages(context.Background())
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected error when listing cronjobs fails")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DiscoverImages() should skip unavailable cronjobs, got: %v", err)
+			}
+			if len(images) != 1 || images[0].Workload != "api" {
+				t.Errorf("Expected the deployment image to still be discovered, got %+v", images)
+			}
+		})
+	}
+}
+
+func TestKubeConfigSourceBuild(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	inClusterConfig := &rest.Config{Host: "https://kubernetes.default"}
+	kubeconfigConfig := &rest.Config{Host: "https://eks.example.com"}
+
+	tests := []struct {
+		name             string
+		kubeconfig       string
+		context          string
+		inClusterErr     error
+		expectedConfig   *rest.Config
+		expectedLoadArgs []string // nil when the kubeconfig must not be loaded
+	}{
+		{
+			name:           "in-cluster config",
+			expectedConfig: inClusterConfig,
+		},
+		{
+			name:             "fallback to default kubeconfig",
+			inClusterErr:     fmt.Errorf("not running in a pod"),
+			expectedConfig:   kubeconfigConfig,
+			expectedLoadArgs: []string{"", ""},
+		},
+		{
+			name:             "explicit path and context skip in-cluster config",
+			kubeconfig:       "/etc/vulnrelay/kubeconfig",
+			context:          "staging",
+			expectedConfig:   kubeconfigConfig,
+			expectedLoadArgs: []string{"/etc/vulnrelay/kubeconfig", "staging"},
+		},
+		{
+			name:             "explicit context only",
+			context:          "production",
+			expectedConfig:   kubeconfigConfig,
+			expectedLoadArgs: []string{"", "production"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var loadArgs []string
+			source := kubeConfigSource{
+				kubeconfig: tt.kubeconfig,
+				context:    tt.context,
+				inCluster: func() (*rest.Config, error) {
+					return inClusterConfig, tt.inClusterErr
+				},
+				loadKubeconfig: func(path, context string) (*rest.Config, error) {
+					loadArgs = []string{path, context}
+					return kubeconfigConfig, nil
+				},
+			}
+
+			config, err := source.build(logger)
+			if err != nil {
+				t.Fatalf("build() failed: %v", err)
+			}
+			if config != tt.expectedConfig {
+				t.Errorf("Expected config for %s, got %s", tt.expectedConfig.Host, config.Host)
+			}
+			if len(loadArgs) != len(tt.expectedLoadArgs) {
+				t.Fatalf("Expected kubeconfig load with %v, got %v", tt.expectedLoadArgs, loadArgs)
+			}
+			for i := range loadArgs {
+				if loadArgs[i] != tt.expectedLoadArgs[i] {
+					t.Errorf("Expected kubeconfig load with %v, got %v", tt.expectedLoadArgs, loadArgs)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+// ABOUTME: S3 reader fetching small objects such as image lists published by CI.
+// ABOUTME: Used by local mode when the image list is an s3:// URL.
+
+package aws
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// maxS3ObjectSize caps the objects read, which are expected to be small documents
+const maxS3ObjectSize = 10 << 20
+
+// s3GetAPI is the subset of the S3 client used by S3Reader
+type s3GetAPI interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+}
+
+// S3Reader reads whole objects from S3
+type S3Reader struct {
+	client s3GetAPI
+}
+
+// NewS3Reader creates an S3 reader using the default AWS credential chain.
+// An empty region uses the AWS environment's default region.
+func NewS3Reader(ctx context.Context, region string) (*S3Reader, error) {
+	cfg, err := loadConfig(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Reader{client: s3.NewFromConfig(cfg)}, nil
+}
+
+// ReadObject returns the content of an object, failing for objects larger
+// than maxS3ObjectSize
+func (r *S3Reader) ReadObject(ctx context.Context, bucket, key string) ([]byte, error) {
+	output, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get s3://%s/%s: %w", bucket, key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(output.Body, maxS3ObjectSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read s3://%s/%s: %w", bucket, key, err)
+	}
+	if len(data) > maxS3ObjectSize {
+		return nil, fmt.Errorf("s3://%s/%s exceeds %d bytes", bucket, key, maxS3ObjectSize)
+	}
+	return data, nil
+}
@@ -0,0 +1,78 @@
+// ABOUTME: Tests for the S3 reader used for remote image lists.
+// ABOUTME: Uses a mocked S3 client to check requests, size limits and errors.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// mockS3GetClient serves a single object body
+type mockS3GetClient struct {
+	body   []byte
+	err    error
+	inputs []*s3.GetObjectInput
+}
+
+func (m *mockS3GetClient) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	m.inputs = append(m.inputs, params)
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(m.body))}, nil
+}
+
+func TestS3ReaderReadObject(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        *mockS3GetClient
+		expectedError string
+	}{
+		{
+			name:   "object read",
+			client: &mockS3GetClient{body: []byte(`["registry.example.com/app:v1"]`)},
+		},
+		{
+			name:          "get failure",
+			client:        &mockS3GetClient{err: errors.New("AccessDenied")},
+			expectedError: "failed to get s3://ci-artifacts/images/prod.json",
+		},
+		{
+			name:          "object too large",
+			client:        &mockS3GetClient{body: bytes.Repeat([]byte("a"), maxS3ObjectSize+1)},
+			expectedError: "exceeds",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := &S3Reader{client: tt.client}
+			data, err := reader.ReadObject(context.Background(), "ci-artifacts", "images/prod.json")
+
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("Expected error containing %q, got: %v", tt.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadObject() failed: %v", err)
+			}
+			if string(data) != string(tt.client.body) {
+				t.Errorf("Expected object body, got %q", data)
+			}
+			input := tt.client.inputs[0]
+			if aws.ToString(input.Bucket) != "ci-artifacts" || aws.ToString(input.Key) != "images/prod.json" {
+				t.Errorf("Unexpected request for s3://%s/%s", aws.ToString(input.Bucket), aws.ToString(input.Key))
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+// ABOUTME: S3 sink uploading the collected vulnerability dataset after each collection.
+// ABOUTME: Writes one timestamped JSON object per collection for long-term retention.
+
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// s3UploadTimeout bounds each upload of a collection
+const s3UploadTimeout = 2 * time.Minute
+
+// s3KeyTimeFormat names objects by collection time, so keys sort chronologically
+const s3KeyTimeFormat = "20060102T150405Z"
+
+// s3API is the subset of the S3 client used by S3Sink
+type s3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3SinkOptions configures the S3 sink
+type S3SinkOptions struct {
+	Bucket string
+	Prefix string // Key prefix, e.g. "vulnrelay/prod"; empty writes to the bucket root
+	Region string // Bucket region; empty uses the AWS environment's default region
+}
+
+// S3Export is the JSON document uploaded for each collection
+type S3Export struct {
+	CollectedAt time.Time                      `json:"collected_at"`
+	Images      []types.ImageVulnerabilityData `json:"images"`
+}
+
+// S3Sink uploads the full dataset of every collection to an S3 bucket
+type S3Sink struct {
+	client s3API
+	bucket string
+	prefix string
+	now    func() time.Time
+	logger *logrus.Logger
+}
+
+// NewS3Sink creates an S3 sink using the same AWS credential chain as the ECR
+// source. Role assumption for ECR does not apply, so the bucket is written
+// with the service's own identity.
+func NewS3Sink(ctx context.Context, opts S3SinkOptions, logger *logrus.Logger) (*S3Sink, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3 bucket is required")
+	}
+
+	cfg, err := loadConfig(ctx, opts.Region)
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Sink{
+		client: s3.NewFromConfig(cfg),
+		bucket: opts.Bucket,
+		prefix: opts.Prefix,
+		now:    time.Now,
+		logger: logger,
+	}, nil
+}
+
+// objectKey returns the key of the object for a collection at the given time
+func (s *S3Sink) objectKey(collectedAt time.Time) string {
+	return path.Join(s.prefix, "vulnerabilities-"+collectedAt.UTC().Format(s3KeyTimeFormat)+".json")
+}
+
+// Export uploads a collection's data as a new object. Failures are logged and
+// the next collection is uploaded as usual.
+func (s *S3Sink) Export(ctx context.Context, data map[string]*types.ImageVulnerabilityData) {
+	collectedAt := s.now()
+	key := s.objectKey(collectedAt)
+	logger := s.logger.WithFields(logrus.Fields{
+		"bucket": s.bucket,
+		"key":    key,
+	})
+
+	if err := s.upload(ctx, key, collectedAt, data); err != nil {
+		logger.WithError(err).Error("Failed to export vulnerability data to S3")
+		return
+	}
+	logger.WithField("images", len(data)).Info("Exported vulnerability data to S3")
+}
+
+// upload encodes the data, sorted by image URI, and puts it under key
+func (s *S3Sink) upload(ctx context.Context, key string, collectedAt time.Time, data map[string]*types.ImageVulnerabilityData) error {
+	export := S3Export{
+		CollectedAt: collectedAt.UTC(),
+		Images:      make([]types.ImageVulnerabilityData, 0, len(data)),
+	}
+	uris := make([]string, 0, len(data))
+	for uri := range data {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		export.Images = append(export.Images, *data[uri])
+	}
+
+	body, err := json.Marshal(export)
+	if err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s3UploadTimeout)
+	defer cancel()
+
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ContentType: aws.String("application/json"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put object: %w", err)
+	}
+	return nil
+}
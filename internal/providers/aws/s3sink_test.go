@@ -0,0 +1,141 @@
+// ABOUTME: Tests for the S3 sink exporting collected vulnerability data.
+// ABOUTME: Uses a mocked S3 client to check object keys, bodies and failure handling.
+
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// mockS3Client records the objects put into it
+type mockS3Client struct {
+	inputs []*s3.PutObjectInput
+	bodies [][]byte
+	err    error
+}
+
+func (m *mockS3Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	m.inputs = append(m.inputs, params)
+	m.bodies = append(m.bodies, body)
+	return &s3.PutObjectOutput{}, nil
+}
+
+func TestS3SinkExport(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	collectedAt := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	data := map[string]*types.ImageVulnerabilityData{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v2": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v2",
+				Vulnerabilities: map[string]int{"HIGH": 1},
+				TotalCount:      1,
+				Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001", Severity: "HIGH"}},
+			},
+			ImageInfo: types.ImageInfo{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v2", Namespace: "production"},
+		},
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",
+				Vulnerabilities: map[string]int{},
+			},
+			ImageInfo: types.ImageInfo{URI: "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1", Namespace: "production"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		prefix      string
+		expectedKey string
+	}{
+		{"bucket root", "", "vulnerabilities-20240305T143000Z.json"},
+		{"prefix", "vulnrelay/prod", "vulnrelay/prod/vulnerabilities-20240305T143000Z.json"},
+		{"prefix with trailing slash", "vulnrelay/", "vulnrelay/vulnerabilities-20240305T143000Z.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := &mockS3Client{}
+			sink := &S3Sink{
+				client: client,
+				bucket: "security-archive",
+				prefix: tt.prefix,
+				now:    func() time.Time { return collectedAt },
+				logger: logger,
+			}
+
+			sink.Export(context.Background(), data)
+
+			if len(client.inputs) != 1 {
+				t.Fatalf("Expected 1 object, got %d", len(client.inputs))
+			}
+			input := client.inputs[0]
+			if aws.ToString(input.Bucket) != "security-archive" {
+				t.Errorf("Expected bucket security-archive, got %q", aws.ToString(input.Bucket))
+			}
+			if aws.ToString(input.Key) != tt.expectedKey {
+				t.Errorf("Expected key %q, got %q", tt.expectedKey, aws.ToString(input.Key))
+			}
+			if aws.ToString(input.ContentType) != "application/json" {
+				t.Errorf("Expected JSON content type, got %q", aws.ToString(input.ContentType))
+			}
+
+			var export S3Export
+			if err := json.Unmarshal(client.bodies[0], &export); err != nil {
+				t.Fatalf("Failed to decode uploaded body: %v", err)
+			}
+			if !export.CollectedAt.Equal(collectedAt) {
+				t.Errorf("Expected collected_at %v, got %v", collectedAt, export.CollectedAt)
+			}
+			if len(export.Images) != 2 {
+				t.Fatalf("Expected 2 images, got %d", len(export.Images))
+			}
+			// Images are sorted by URI
+			if export.Images[0].ImageURI != "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1" {
+				t.Errorf("Expected api image first, got %q", export.Images[0].ImageURI)
+			}
+			if len(export.Images[1].Findings) != 1 || export.Images[1].Findings[0].Name != "CVE-2024-0001" {
+				t.Errorf("Expected web image findings to be exported, got %+v", export.Images[1].Findings)
+			}
+		})
+	}
+}
+
+func TestS3SinkExportFailure(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	sink := &S3Sink{
+		client: &mockS3Client{err: errors.New("AccessDenied")},
+		bucket: "security-archive",
+		now:    time.Now,
+		logger: logger,
+	}
+
+	// Failures are logged without panicking
+	sink.Export(context.Background(), map[string]*types.ImageVulnerabilityData{})
+}
+
+func TestNewS3SinkRequiresBucket(t *testing.T) {
+	if _, err := NewS3Sink(context.Background(), S3SinkOptions{}, logrus.New()); err == nil {
+		t.Error("Expected an error without a bucket")
+	}
+}
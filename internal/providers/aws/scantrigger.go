@@ -0,0 +1,49 @@
+// ABOUTME: Per-image rate limiting of ECR scan triggers.
+// ABOUTME: Suppresses StartImageScan calls within ECR's 24 hour rescan window.
+
+package aws
+
+import (
+	"sync"
+	"time"
+)
+
+// ScanTriggerWindow is how often ECR basic scanning allows an image to be scanned
+const ScanTriggerWindow = 24 * time.Hour
+
+// scanTriggerLimiter records when a scan was last triggered per image and
+// refuses further triggers within the window. Records are kept in memory, so
+// after a restart ECR's own limit rejects early triggers instead.
+type scanTriggerLimiter struct {
+	mutex  sync.Mutex
+	window time.Duration
+	last   map[string]time.Time
+	now    func() time.Time
+}
+
+func newScanTriggerLimiter(window time.Duration) *scanTriggerLimiter {
+	return &scanTriggerLimiter{
+		window: window,
+		last:   make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// allow reports whether a scan of the image may be triggered and, if so,
+// records the trigger. Expired records are pruned as triggers are recorded.
+func (l *scanTriggerLimiter) allow(image string) bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if last, ok := l.last[image]; ok && now.Sub(last) < l.window {
+		return false
+	}
+	for key, last := range l.last {
+		if now.Sub(last) >= l.window {
+			delete(l.last, key)
+		}
+	}
+	l.last[image] = now
+	return true
+}
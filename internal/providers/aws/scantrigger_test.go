@@ -0,0 +1,42 @@
+// ABOUTME: Tests for the per-image scan trigger rate limiting.
+// ABOUTME: Uses a fake clock to check triggers inside and after the rescan window.
+
+package aws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScanTriggerLimiter(t *testing.T) {
+	now := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	limiter := newScanTriggerLimiter(ScanTriggerWindow)
+	limiter.now = func() time.Time { return now }
+
+	steps := []struct {
+		name     string
+		advance  time.Duration
+		image    string
+		expected bool
+	}{
+		{"first trigger", 0, "app:v1", true},
+		{"second trigger within the window", time.Hour, "app:v1", false},
+		{"other image", 0, "api:v1", true},
+		{"just before the window ends", 23*time.Hour - time.Second, "app:v1", false},
+		{"after the window", time.Second, "app:v1", true},
+		{"again within the new window", time.Hour, "app:v1", false},
+		{"new image", 0, "web:v1", true},
+	}
+
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		if got := limiter.allow(step.image); got != step.expected {
+			t.Errorf("%s: allow(%q) = %v, expected %v", step.name, step.image, got, step.expected)
+		}
+	}
+
+	// The api:v1 record expired and was pruned when web:v1 was triggered
+	if _, ok := limiter.last["api:v1"]; ok {
+		t.Error("Expected the expired record to be pruned")
+	}
+}
@@ -6,9 +6,15 @@ package providers
 import (
 	"context"
 	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/jfeddern/VulnRelay/internal/engine"
 	"github.com/jfeddern/VulnRelay/internal/providers/aws"
+	"github.com/jfeddern/VulnRelay/internal/providers/harbor"
 	"github.com/jfeddern/VulnRelay/internal/providers/local"
 	"github.com/jfeddern/VulnRelay/internal/providers/mock"
 	"github.com/sirupsen/logrus"
@@ -16,46 +22,296 @@ import (
 
 // ProviderConfig holds configuration for creating providers
 type ProviderConfig struct {
-	Mode          string
-	ECRAccountID  string
-	ECRRegion     string
-	ImageListFile string
-	MockMode      bool // Enable mock providers for local testing
+	Mode             string // Registered cloud provider: "cluster", "local", "mock" or one added via RegisterCloudProvider
+	ECRAccountID     string
+	ECRRegion        string
+	AssumeRoleARN    string
+	CrossAccountRole string
+	ImagePlatform    string
+	ResolveDigests   bool // Report the scanned manifest digest of each image
+	TriggerScans     bool // Start a scan of images without one, at most once per 24h each
+	ImageListFile    string
+	DefaultImageTag  string   // Tag applied to image list entries without one
+	FieldSelector    string   // Kubernetes field selector restricting discovered workloads
+	RegistryHosts    []string // Extra registry host suffixes treated as ECR
+	OnlyRunning      bool     // Only include images of running pods
+	SkipSuspended    bool     // Skip suspended CronJobs
+	MockMode         bool     // Enable mock providers for local testing
+
+	// RepositoryAllowlist restricts ECR scanning to repositories matching these globs
+	RepositoryAllowlist []string
+
+	// RetainedSeverities keeps only ECR findings of these severities; counts
+	// still include every finding
+	RetainedSeverities []string
+
+	// SkipUntaggedImages skips ECR images referenced by digest only
+	SkipUntaggedImages bool
+
+	// KubeStartupTimeout bounds retries while connecting to the Kubernetes API
+	KubeStartupTimeout time.Duration
+
+	// IgnoreContainers lists container name globs whose images are not discovered
+	IgnoreContainers []string
+
+	// Namespaces restricts cluster discovery to these namespaces (empty = all)
+	Namespaces []string
+
+	// DiscoveryConcurrency caps the namespaces listed at once
+	DiscoveryConcurrency int
+
+	// RequireScanAnnotation only discovers workloads annotated with vulnrelay.io/scan: "true"
+	RequireScanAnnotation bool
+
+	// Kubeconfig and KubeContext select the cluster instead of the in-cluster config
+	Kubeconfig  string
+	KubeContext string
+
+	// VulnerabilitySource selects a registered scanner by name: "ecr"
+	// (default), "harbor" or any source added via RegisterVulnerabilitySource
+	VulnerabilitySource string
+	HarborURL           string
+	HarborUsername      string
+	HarborPassword      string
+}
+
+// CloudProviderConstructor builds a cloud provider from the provider
+// configuration
+type CloudProviderConstructor func(config *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error)
+
+var (
+	cloudProvidersMu sync.RWMutex
+	cloudProviders   = map[string]CloudProviderConstructor{
+		"cluster": newClusterProvider,
+		"local":   newLocalProvider,
+		"mock":    newMockProvider,
+	}
+)
+
+// RegisterCloudProvider makes a cloud provider selectable by name through
+// ProviderConfig.Mode. Like RegisterVulnerabilitySource it panics on an empty
+// name, a nil constructor or a duplicate registration.
+func RegisterCloudProvider(mode string, constructor CloudProviderConstructor) {
+	cloudProvidersMu.Lock()
+	defer cloudProvidersMu.Unlock()
+
+	if mode == "" {
+		panic("providers: cloud provider mode is empty")
+	}
+	if constructor == nil {
+		panic("providers: cloud provider constructor for " + mode + " is nil")
+	}
+	if _, exists := cloudProviders[mode]; exists {
+		panic("providers: cloud provider " + mode + " registered twice")
+	}
+	cloudProviders[mode] = constructor
+}
+
+// CloudProviders returns the modes of all registered cloud providers in
+// sorted order
+func CloudProviders() []string {
+	cloudProvidersMu.RLock()
+	defer cloudProvidersMu.RUnlock()
+
+	modes := make([]string, 0, len(cloudProviders))
+	for mode := range cloudProviders {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// HasCloudProvider reports whether a cloud provider is registered for the
+// given mode
+func HasCloudProvider(mode string) bool {
+	cloudProvidersMu.RLock()
+	defer cloudProvidersMu.RUnlock()
+
+	_, exists := cloudProviders[mode]
+	return exists
 }
 
 // CreateCloudProvider creates a cloud provider based on configuration
 func CreateCloudProvider(config *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error) {
 	// Check for mock mode first
 	if config.MockMode {
-		logger.Info("Using mock cloud provider for testing")
-		return mock.NewMockEKSProvider(logger), nil
-	}
-
-	switch config.Mode {
-	case "cluster":
-		// For now, assume EKS for cluster mode
-		// TODO: Add provider detection or explicit configuration
-		return aws.NewEKSProvider(logger)
-	case "local":
-		return local.NewLocalProvider(config.ImageListFile, logger), nil
-	default:
+		return newMockProvider(config, logger)
+	}
+
+	cloudProvidersMu.RLock()
+	constructor, exists := cloudProviders[config.Mode]
+	cloudProvidersMu.RUnlock()
+
+	if !exists {
 		return nil, fmt.Errorf("unsupported mode: %s", config.Mode)
 	}
+	return constructor(config, logger)
+}
+
+func newClusterProvider(config *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error) {
+	// For now, assume EKS for cluster mode
+	// TODO: Add provider detection or explicit configuration
+	registryHosts := config.RegistryHosts
+	if config.VulnerabilitySource == "harbor" {
+		// Images pulled from Harbor must be discovered alongside ECR images
+		if host := harborHost(config.HarborURL); host != "" {
+			registryHosts = append(append([]string{}, registryHosts...), host)
+		}
+	}
+	return aws.NewEKSProvider(aws.EKSOptions{
+		FieldSelector:         config.FieldSelector,
+		RegistryHostSuffixes:  registryHosts,
+		OnlyRunning:           config.OnlyRunning,
+		SkipSuspendedCronJobs: config.SkipSuspended,
+		IgnoreContainers:      config.IgnoreContainers,
+		RequireScanAnnotation: config.RequireScanAnnotation,
+		Namespaces:            config.Namespaces,
+		DiscoveryConcurrency:  config.DiscoveryConcurrency,
+		Kubeconfig:            config.Kubeconfig,
+		KubeContext:           config.KubeContext,
+		StartupTimeout:        config.KubeStartupTimeout,
+	}, logger)
+}
+
+func newLocalProvider(config *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error) {
+	provider := local.NewLocalProvider(config.ImageListFile, logger)
+	provider.SetDefaultTag(config.DefaultImageTag)
+	if strings.HasPrefix(config.ImageListFile, "s3://") {
+		// Uses the service's own identity, like the S3 export
+		reader, err := aws.NewS3Reader(context.Background(), config.ECRRegion)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create S3 reader for the image list: %w", err)
+		}
+		provider.SetObjectReader(reader)
+	}
+	return provider, nil
+}
+
+func newMockProvider(_ *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error) {
+	logger.Info("Using mock cloud provider for testing")
+	return mock.NewMockEKSProvider(logger), nil
+}
+
+// VulnerabilitySourceConstructor builds a vulnerability source from the
+// provider configuration
+type VulnerabilitySourceConstructor func(ctx context.Context, config *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error)
+
+// defaultVulnerabilitySource is used when no source is configured
+const defaultVulnerabilitySource = "ecr"
+
+var (
+	vulnerabilitySourcesMu sync.RWMutex
+	vulnerabilitySources   = map[string]VulnerabilitySourceConstructor{
+		"ecr":    newECRSource,
+		"harbor": newHarborSource,
+		"mock":   newMockSource,
+	}
+)
+
+// RegisterVulnerabilitySource makes a vulnerability source selectable by name
+// through ProviderConfig.VulnerabilitySource. It panics if the name is empty,
+// the constructor is nil or the name is already registered, so conflicting
+// registrations surface at startup.
+func RegisterVulnerabilitySource(name string, constructor VulnerabilitySourceConstructor) {
+	vulnerabilitySourcesMu.Lock()
+	defer vulnerabilitySourcesMu.Unlock()
+
+	if name == "" {
+		panic("providers: vulnerability source name is empty")
+	}
+	if constructor == nil {
+		panic("providers: vulnerability source constructor for " + name + " is nil")
+	}
+	if _, exists := vulnerabilitySources[name]; exists {
+		panic("providers: vulnerability source " + name + " registered twice")
+	}
+	vulnerabilitySources[name] = constructor
+}
+
+// VulnerabilitySources returns the names of all registered vulnerability
+// sources in sorted order
+func VulnerabilitySources() []string {
+	vulnerabilitySourcesMu.RLock()
+	defer vulnerabilitySourcesMu.RUnlock()
+
+	names := make([]string, 0, len(vulnerabilitySources))
+	for name := range vulnerabilitySources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// HasVulnerabilitySource reports whether a vulnerability source is registered
+// under the given name
+func HasVulnerabilitySource(name string) bool {
+	vulnerabilitySourcesMu.RLock()
+	defer vulnerabilitySourcesMu.RUnlock()
+
+	_, exists := vulnerabilitySources[name]
+	return exists
 }
 
 // CreateVulnerabilitySource creates a vulnerability source based on configuration
 func CreateVulnerabilitySource(ctx context.Context, config *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error) {
 	// Check for mock mode first
 	if config.MockMode {
-		logger.Info("Using mock vulnerability source for testing")
-		return mock.NewMockECRSource(logger), nil
+		return newMockSource(ctx, config, logger)
+	}
+
+	name := config.VulnerabilitySource
+	if name == "" {
+		name = defaultVulnerabilitySource
 	}
 
-	// For now, only ECR is supported
-	// TODO: Add support for other vulnerability sources
-	if config.ECRAccountID != "" && config.ECRRegion != "" {
-		return aws.NewECRSource(ctx, config.ECRAccountID, config.ECRRegion, logger)
+	vulnerabilitySourcesMu.RLock()
+	constructor, exists := vulnerabilitySources[name]
+	vulnerabilitySourcesMu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unsupported vulnerability source: %s", name)
+	}
+	return constructor(ctx, config, logger)
+}
+
+func newECRSource(ctx context.Context, config *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error) {
+	if config.ECRAccountID == "" || config.ECRRegion == "" {
+		return nil, fmt.Errorf("no vulnerability source configured: ECR account ID and region are required")
 	}
+	opts := aws.ECROptions{
+		AssumeRoleARN:    config.AssumeRoleARN,
+		CrossAccountRole: config.CrossAccountRole,
+		Platform:         config.ImagePlatform,
 
-	return nil, fmt.Errorf("no vulnerability source configured")
+		RepositoryAllowlist: config.RepositoryAllowlist,
+		ResolveDigests:      config.ResolveDigests,
+		TriggerScans:        config.TriggerScans,
+		RetainedSeverities:  config.RetainedSeverities,
+		SkipUntaggedImages:  config.SkipUntaggedImages,
+	}
+	return aws.NewECRSource(ctx, config.ECRAccountID, config.ECRRegion, opts, logger)
+}
+
+func newHarborSource(_ context.Context, config *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error) {
+	logger.WithField("harbor_url", config.HarborURL).Info("Using Harbor vulnerability source")
+	return harbor.NewHarborSource(harbor.HarborOptions{
+		URL:      config.HarborURL,
+		Username: config.HarborUsername,
+		Password: config.HarborPassword,
+	}, logger)
+}
+
+func newMockSource(_ context.Context, _ *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error) {
+	logger.Info("Using mock vulnerability source for testing")
+	return mock.NewMockECRSource(logger), nil
+}
+
+// harborHost returns the registry hostname of a Harbor base URL; ports are
+// ignored because registry host matching ignores them too
+func harborHost(harborURL string) string {
+	parsed, err := url.Parse(harborURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Hostname()
 }
@@ -6,9 +6,12 @@ package providers
 import (
 	"context"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/engine"
+	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
 )
 
@@ -50,6 +53,14 @@ func TestCreateCloudProvider(t *testing.T) {
 			expectError: false, // May succeed in environments with k8s access
 			expectType:  "aws-eks",
 		},
+		{
+			name: "mock provider by mode",
+			config: &ProviderConfig{
+				Mode: "mock",
+			},
+			expectError: false,
+			expectType:  "mock-eks",
+		},
 		{
 			name: "unsupported mode",
 			config: &ProviderConfig{
@@ -124,6 +135,92 @@ func TestCreateCloudProvider(t *testing.T) {
 	}
 }
 
+// fakeCloudProvider is a minimal provider used to exercise the registry
+type fakeCloudProvider struct {
+	images []types.ImageInfo
+}
+
+func (f *fakeCloudProvider) Name() string { return "fake-cloud" }
+
+func (f *fakeCloudProvider) DiscoverImages(ctx context.Context) ([]types.ImageInfo, error) {
+	return f.images, nil
+}
+
+func (f *fakeCloudProvider) IsRegistryImage(imageURI string) bool { return true }
+
+func TestRegisterCloudProvider(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	RegisterCloudProvider("fake", func(config *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error) {
+		return &fakeCloudProvider{images: []types.ImageInfo{{URI: config.ImageListFile}}}, nil
+	})
+	t.Cleanup(func() {
+		cloudProvidersMu.Lock()
+		delete(cloudProviders, "fake")
+		cloudProvidersMu.Unlock()
+	})
+
+	if !HasCloudProvider("fake") {
+		t.Fatal("Expected fake provider to be registered")
+	}
+	if modes := CloudProviders(); !reflect.DeepEqual(modes, []string{"cluster", "fake", "local", "mock"}) {
+		t.Errorf("Unexpected registered providers: %v", modes)
+	}
+
+	provider, err := CreateCloudProvider(&ProviderConfig{Mode: "fake", ImageListFile: "registry.example.com/app:v1"}, logger)
+	if err != nil {
+		t.Fatalf("CreateCloudProvider() failed: %v", err)
+	}
+	if provider.Name() != "fake-cloud" {
+		t.Errorf("Expected fake-cloud provider, got %s", provider.Name())
+	}
+
+	images, err := provider.DiscoverImages(context.Background())
+	if err != nil {
+		t.Fatalf("DiscoverImages() failed: %v", err)
+	}
+	if len(images) != 1 || images[0].URI != "registry.example.com/app:v1" {
+		t.Errorf("Expected image from config, got %v", images)
+	}
+
+	// Mock mode still takes precedence over the configured mode
+	provider, err = CreateCloudProvider(&ProviderConfig{Mode: "fake", MockMode: true}, logger)
+	if err != nil {
+		t.Fatalf("CreateCloudProvider() failed: %v", err)
+	}
+	if provider.Name() != "mock-eks" {
+		t.Errorf("Expected mock-eks provider in mock mode, got %s", provider.Name())
+	}
+}
+
+func TestRegisterCloudProviderPanics(t *testing.T) {
+	constructor := func(config *ProviderConfig, logger *logrus.Logger) (engine.CloudProvider, error) {
+		return &fakeCloudProvider{}, nil
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		constructor CloudProviderConstructor
+	}{
+		{"empty mode", "", constructor},
+		{"nil constructor", "fake", nil},
+		{"duplicate mode", "cluster", constructor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected RegisterCloudProvider to panic")
+				}
+			}()
+			RegisterCloudProvider(tt.mode, tt.constructor)
+		})
+	}
+}
+
 func TestCreateVulnerabilitySource(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -178,6 +275,43 @@ func TestCreateVulnerabilitySource(t *testing.T) {
 			expectError: true,
 			expectType:  "",
 		},
+		{
+			name: "mock source by name",
+			config: &ProviderConfig{
+				VulnerabilitySource: "mock",
+			},
+			expectError: false,
+			expectType:  "mock-ecr",
+		},
+		{
+			name: "harbor source",
+			config: &ProviderConfig{
+				VulnerabilitySource: "harbor",
+				HarborURL:           "https://harbor.example.com",
+				HarborUsername:      "robot$vulnrelay",
+				HarborPassword:      "secret",
+			},
+			expectError: false,
+			expectType:  "harbor",
+		},
+		{
+			name: "harbor source without URL",
+			config: &ProviderConfig{
+				VulnerabilitySource: "harbor",
+			},
+			expectError: true,
+			expectType:  "",
+		},
+		{
+			name: "unsupported source",
+			config: &ProviderConfig{
+				VulnerabilitySource: "trivy",
+				ECRAccountID:        "123456789012",
+				ECRRegion:           "us-east-1",
+			},
+			expectError: true,
+			expectType:  "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -217,6 +351,10 @@ func TestCreateVulnerabilitySource(t *testing.T) {
 
 			// Test basic functionality
 			testImageURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/test:latest"
+			if tt.expectType == "harbor" {
+				// Harbor repositories always live in a project
+				testImageURI = "harbor.example.com/library/test:latest"
+			}
 
 			// Test ParseImageURI
 			repo, tag, err := source.ParseImageURI(testImageURI)
@@ -247,6 +385,103 @@ func TestCreateVulnerabilitySource(t *testing.T) {
 	}
 }
 
+// fakeVulnerabilitySource is a minimal source used to exercise the registry
+type fakeVulnerabilitySource struct {
+	endpoint string
+}
+
+func (f *fakeVulnerabilitySource) Name() string { return "fake" }
+
+func (f *fakeVulnerabilitySource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	return &types.ImageVulnerability{ImageURI: imageURI}, nil
+}
+
+func (f *fakeVulnerabilitySource) ParseImageURI(imageURI string) (string, string, error) {
+	return "fake/repo", "latest", nil
+}
+
+func TestRegisterVulnerabilitySource(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	var received *ProviderConfig
+	RegisterVulnerabilitySource("fake", func(ctx context.Context, config *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error) {
+		received = config
+		return &fakeVulnerabilitySource{endpoint: config.HarborURL}, nil
+	})
+	t.Cleanup(func() {
+		vulnerabilitySourcesMu.Lock()
+		delete(vulnerabilitySources, "fake")
+		vulnerabilitySourcesMu.Unlock()
+	})
+
+	if !HasVulnerabilitySource("fake") {
+		t.Fatal("Expected fake source to be registered")
+	}
+	if names := VulnerabilitySources(); !reflect.DeepEqual(names, []string{"ecr", "fake", "harbor", "mock"}) {
+		t.Errorf("Unexpected registered sources: %v", names)
+	}
+
+	config := &ProviderConfig{VulnerabilitySource: "fake", HarborURL: "https://scanner.example.com"}
+	source, err := CreateVulnerabilitySource(context.Background(), config, logger)
+	if err != nil {
+		t.Fatalf("CreateVulnerabilitySource() failed: %v", err)
+	}
+	if source.Name() != "fake" {
+		t.Errorf("Expected fake source, got %s", source.Name())
+	}
+	if received != config {
+		t.Error("Expected constructor to receive the provider configuration")
+	}
+	if fake := source.(*fakeVulnerabilitySource); fake.endpoint != "https://scanner.example.com" {
+		t.Errorf("Expected endpoint from config, got %q", fake.endpoint)
+	}
+}
+
+func TestRegisterVulnerabilitySourcePanics(t *testing.T) {
+	constructor := func(ctx context.Context, config *ProviderConfig, logger *logrus.Logger) (engine.VulnerabilitySource, error) {
+		return &fakeVulnerabilitySource{}, nil
+	}
+
+	tests := []struct {
+		name        string
+		source      string
+		constructor VulnerabilitySourceConstructor
+	}{
+		{"empty name", "", constructor},
+		{"nil constructor", "fake", nil},
+		{"duplicate name", "ecr", constructor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("Expected RegisterVulnerabilitySource to panic")
+				}
+			}()
+			RegisterVulnerabilitySource(tt.source, tt.constructor)
+		})
+	}
+}
+
+func TestHarborHost(t *testing.T) {
+	tests := []struct {
+		url      string
+		expected string
+	}{
+		{"https://harbor.example.com", "harbor.example.com"},
+		{"https://harbor.example.com:8443/", "harbor.example.com"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := harborHost(tt.url); got != tt.expected {
+			t.Errorf("harborHost(%q) = %q, want %q", tt.url, got, tt.expected)
+		}
+	}
+}
+
 func TestProviderConfigValidation(t *testing.T) {
 	logger := logrus.New()
 	logger.SetLevel(logrus.ErrorLevel)
@@ -0,0 +1,278 @@
+// ABOUTME: Harbor vulnerability source implementation for self-hosted registries.
+// ABOUTME: Retrieves Trivy scan results from the Harbor API and maps them to common findings.
+
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// vulnerabilityReportMimeType is the report format requested from Harbor
+const vulnerabilityReportMimeType = "application/vnd.security.vulnerability.report; version=1.1"
+
+// defaultRequestTimeout bounds each Harbor API call
+const defaultRequestTimeout = 30 * time.Second
+
+// HarborOptions holds connection settings for the Harbor vulnerability source
+type HarborOptions struct {
+	URL      string // Harbor base URL, e.g. https://harbor.example.com
+	Username string // Optional user or robot account for basic auth
+	Password string
+}
+
+// HarborSource implements VulnerabilitySource for Harbor registries
+type HarborSource struct {
+	client   *http.Client
+	baseURL  string
+	username string
+	password string
+	logger   *logrus.Logger
+}
+
+// vulnerabilityReport is the Harbor report for one artifact
+type vulnerabilityReport struct {
+	GeneratedAt     string          `json:"generated_at"`
+	Severity        string          `json:"severity"`
+	Vulnerabilities []vulnerability `json:"vulnerabilities"`
+}
+
+// vulnerability is a single Harbor vulnerability item
+type vulnerability struct {
+	ID            string   `json:"id"`
+	Package       string   `json:"package"`
+	Version       string   `json:"version"`
+	FixVersion    string   `json:"fix_version"`
+	Severity      string   `json:"severity"`
+	Description   string   `json:"description"`
+	Links         []string `json:"links"`
+	PreferredCVSS *struct {
+		ScoreV3 *float64 `json:"score_v3"`
+		ScoreV2 *float64 `json:"score_v2"`
+	} `json:"preferred_cvss"`
+}
+
+// NewHarborSource creates a new Harbor vulnerability source
+func NewHarborSource(opts HarborOptions, logger *logrus.Logger) (*HarborSource, error) {
+	baseURL := strings.TrimRight(opts.URL, "/")
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid Harbor URL %q: must be an http(s) URL", opts.URL)
+	}
+
+	return &HarborSource{
+		client:   &http.Client{Timeout: defaultRequestTimeout},
+		baseURL:  baseURL,
+		username: opts.Username,
+		password: opts.Password,
+		logger:   logger,
+	}, nil
+}
+
+// Name returns the vulnerability source name
+func (h *HarborSource) Name() string {
+	return "harbor"
+}
+
+// ParseImageURI extracts repository and reference from a Harbor image URI
+// Expected format: harbor.example.com/project/repository:tag or .../repository@sha256:digest
+// The returned repository includes the project, e.g. "project/repository".
+func (h *HarborSource) ParseImageURI(imageURI string) (repository, tag string, err error) {
+	_, path, found := strings.Cut(imageURI, "/")
+	if !found || path == "" {
+		return "", "", fmt.Errorf("invalid image URI format: %s", imageURI)
+	}
+
+	// Digest references take precedence over tags
+	if repo, digest, isDigest := strings.Cut(path, "@"); isDigest {
+		repository, tag = repo, digest
+	} else {
+		lastSlash := strings.LastIndex(path, "/")
+		lastColon := strings.LastIndex(path, ":")
+		if lastColon <= lastSlash {
+			return "", "", fmt.Errorf("invalid image URI format, missing tag: %s", imageURI)
+		}
+		repository, tag = path[:lastColon], path[lastColon+1:]
+	}
+
+	if tag == "" {
+		return "", "", fmt.Errorf("invalid image URI format, missing tag: %s", imageURI)
+	}
+	if project, repo, ok := strings.Cut(repository, "/"); !ok || project == "" || repo == "" {
+		return "", "", fmt.Errorf("invalid image URI format, missing Harbor project: %s", imageURI)
+	}
+
+	return repository, tag, nil
+}
+
+// GetImageVulnerabilities retrieves vulnerability data for a container image from Harbor
+func (h *HarborSource) GetImageVulnerabilities(ctx context.Context, imageURI string) (*types.ImageVulnerability, error) {
+	logger := h.logger.WithField("image_uri", imageURI)
+
+	repo, tag, err := h.ParseImageURI(imageURI)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image URI: %w", err)
+	}
+
+	logger = logger.WithFields(logrus.Fields{
+		"repository": repo,
+		"tag":        tag,
+	})
+	logger.Debug("Fetching vulnerability data from Harbor")
+
+	reports, err := h.fetchReports(ctx, repo, tag)
+	if err != nil {
+		logger.WithError(err).Error("Failed to fetch Harbor vulnerability report")
+		return &types.ImageVulnerability{
+			ImageURI:        imageURI,
+			Repository:      repo,
+			Tag:             tag,
+			Vulnerabilities: make(map[string]int),
+			TotalCount:      0,
+			ScanStatus:      "FAILED",
+		}, err
+	}
+
+	vuln := convertReport(reports, imageURI, repo, tag)
+
+	logger.WithFields(logrus.Fields{
+		"total_vulnerabilities": vuln.TotalCount,
+		"scan_status":           vuln.ScanStatus,
+		"vulnerabilities":       vuln.Vulnerabilities,
+	}).Info("Retrieved vulnerability data")
+
+	return vuln, nil
+}
+
+// fetchReports calls the Harbor vulnerabilities addition for an artifact
+func (h *HarborSource) fetchReports(ctx context.Context, repository, reference string) (map[string]vulnerabilityReport, error) {
+	project, repo, _ := strings.Cut(repository, "/")
+
+	// Harbor requires slashes in repository names to be double-encoded
+	endpoint := fmt.Sprintf("%s/api/v2.0/projects/%s/repositories/%s/artifacts/%s/additions/vulnerabilities",
+		h.baseURL,
+		url.PathEscape(project),
+		url.PathEscape(url.PathEscape(repo)),
+		url.PathEscape(reference),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Harbor request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Accept-Vulnerabilities", vulnerabilityReportMimeType)
+	if h.username != "" {
+		req.SetBasicAuth(h.username, h.password)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("harbor request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("harbor returned %s for %s:%s: %s", resp.Status, repository, reference, strings.TrimSpace(string(body)))
+	}
+
+	var reports map[string]vulnerabilityReport
+	if err := json.NewDecoder(resp.Body).Decode(&reports); err != nil {
+		return nil, fmt.Errorf("failed to decode Harbor vulnerability report: %w", err)
+	}
+
+	return reports, nil
+}
+
+// convertReport maps Harbor reports into the common vulnerability type.
+// An empty response means the artifact has not been scanned yet.
+func convertReport(reports map[string]vulnerabilityReport, imageURI, repo, tag string) *types.ImageVulnerability {
+	vuln := &types.ImageVulnerability{
+		ImageURI:        imageURI,
+		Repository:      repo,
+		Tag:             tag,
+		Vulnerabilities: make(map[string]int),
+		ScanStatus:      "NOT_SCANNED",
+	}
+
+	report, ok := reports[vulnerabilityReportMimeType]
+	if !ok {
+		// Fall back to any report format Harbor returned
+		for _, r := range reports {
+			report, ok = r, true
+			break
+		}
+	}
+	if !ok {
+		return vuln
+	}
+
+	vuln.ScanStatus = "COMPLETE"
+	if scanTime, err := time.Parse(time.RFC3339Nano, report.GeneratedAt); err == nil {
+		timeStr := scanTime.UTC().Format("2006-01-02T15:04:05Z")
+		vuln.LastScanTime = &timeStr
+	}
+
+	for _, item := range report.Vulnerabilities {
+		severity := normalizeSeverity(item.Severity)
+		vuln.Vulnerabilities[severity]++
+		vuln.TotalCount++
+
+		finding := types.VulnerabilityFinding{
+			Name:             item.ID,
+			Description:      item.Description,
+			Severity:         severity,
+			PackageName:      item.Package,
+			PackageVersion:   item.Version,
+			FixVersion:       item.FixVersion,
+			Type:             "PACKAGE_VULNERABILITY",
+			ExploitAvailable: "unknown",
+			FixAvailable:     "NO",
+		}
+		if item.FixVersion != "" {
+			finding.FixAvailable = "YES"
+		}
+		if len(item.Links) > 0 {
+			finding.URI = item.Links[0]
+		}
+		if cvss := item.PreferredCVSS; cvss != nil {
+			if cvss.ScoreV3 != nil {
+				finding.Score = *cvss.ScoreV3
+			} else if cvss.ScoreV2 != nil {
+				finding.Score = *cvss.ScoreV2
+			}
+		}
+
+		vuln.Findings = append(vuln.Findings, finding)
+	}
+
+	return vuln
+}
+
+// normalizeSeverity maps Harbor severities to the ECR severity names used elsewhere
+func normalizeSeverity(severity string) string {
+	switch strings.ToLower(severity) {
+	case "critical":
+		return "CRITICAL"
+	case "high":
+		return "HIGH"
+	case "medium":
+		return "MEDIUM"
+	case "low":
+		return "LOW"
+	case "negligible", "none":
+		return "INFORMATIONAL"
+	default:
+		return "UNDEFINED"
+	}
+}
@@ -0,0 +1,239 @@
+// ABOUTME: Tests for the Harbor vulnerability source.
+// ABOUTME: Tests image URI parsing, report conversion from fixtures, and Harbor API requests.
+
+package harbor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestSource(t *testing.T, url string) *HarborSource {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	source, err := NewHarborSource(HarborOptions{URL: url, Username: "robot$vulnrelay", Password: "secret"}, logger)
+	if err != nil {
+		t.Fatalf("NewHarborSource() failed: %v", err)
+	}
+	return source
+}
+
+func TestHarborSourceName(t *testing.T) {
+	source := newTestSource(t, "https://harbor.example.com")
+	if source.Name() != "harbor" {
+		t.Errorf("Expected name 'harbor', got '%s'", source.Name())
+	}
+}
+
+func TestNewHarborSourceInvalidURL(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	for _, url := range []string{"", "harbor.example.com", "ftp://harbor.example.com", "https://"} {
+		if _, err := NewHarborSource(HarborOptions{URL: url}, logger); err == nil {
+			t.Errorf("Expected error for Harbor URL %q", url)
+		}
+	}
+}
+
+func TestHarborSourceParseImageURI(t *testing.T) {
+	source := newTestSource(t, "https://harbor.example.com")
+
+	tests := []struct {
+		name         string
+		imageURI     string
+		expectedRepo string
+		expectedTag  string
+		expectError  bool
+	}{
+		{
+			name:         "project and repository",
+			imageURI:     "harbor.example.com/platform/api:v1.2.0",
+			expectedRepo: "platform/api",
+			expectedTag:  "v1.2.0",
+		},
+		{
+			name:         "nested repository",
+			imageURI:     "harbor.example.com/platform/team/api:latest",
+			expectedRepo: "platform/team/api",
+			expectedTag:  "latest",
+		},
+		{
+			name:         "registry with port",
+			imageURI:     "harbor.example.com:8443/platform/api:v1",
+			expectedRepo: "platform/api",
+			expectedTag:  "v1",
+		},
+		{
+			name:         "digest reference",
+			imageURI:     "harbor.example.com/platform/api@sha256:3f1c4a9e",
+			expectedRepo: "platform/api",
+			expectedTag:  "sha256:3f1c4a9e",
+		},
+		{
+			name:        "missing tag",
+			imageURI:    "harbor.example.com/platform/api",
+			expectError: true,
+		},
+		{
+			name:        "port but missing tag",
+			imageURI:    "harbor.example.com:8443/platform/api",
+			expectError: true,
+		},
+		{
+			name:        "missing project",
+			imageURI:    "harbor.example.com/api:v1",
+			expectError: true,
+		},
+		{
+			name:        "empty tag",
+			imageURI:    "harbor.example.com/platform/api:",
+			expectError: true,
+		},
+		{
+			name:        "no registry host",
+			imageURI:    "api:v1",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, tag, err := source.ParseImageURI(tt.imageURI)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q, got repo=%q tag=%q", tt.imageURI, repo, tag)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if repo != tt.expectedRepo || tag != tt.expectedTag {
+				t.Errorf("ParseImageURI(%q) = (%q, %q), want (%q, %q)", tt.imageURI, repo, tag, tt.expectedRepo, tt.expectedTag)
+			}
+		})
+	}
+}
+
+func loadFixture(t *testing.T) map[string]vulnerabilityReport {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/vulnerabilities.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	var reports map[string]vulnerabilityReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		t.Fatalf("Failed to parse fixture: %v", err)
+	}
+	return reports
+}
+
+func TestConvertReport(t *testing.T) {
+	const imageURI = "harbor.example.com/platform/team/api:v1.2.0"
+	vuln := convertReport(loadFixture(t), imageURI, "platform/team/api", "v1.2.0")
+
+	if vuln.ScanStatus != "COMPLETE" {
+		t.Errorf("Expected scan status COMPLETE, got %s", vuln.ScanStatus)
+	}
+	if vuln.LastScanTime == nil || *vuln.LastScanTime != "2025-01-15T10:30:00Z" {
+		t.Errorf("Expected last scan time 2025-01-15T10:30:00Z, got %v", vuln.LastScanTime)
+	}
+	if vuln.TotalCount != 5 || len(vuln.Findings) != 5 {
+		t.Fatalf("Expected 5 findings, got total=%d findings=%d", vuln.TotalCount, len(vuln.Findings))
+	}
+
+	expectedCounts := map[string]int{"CRITICAL": 1, "HIGH": 1, "MEDIUM": 1, "INFORMATIONAL": 1, "UNDEFINED": 1}
+	for severity, count := range expectedCounts {
+		if vuln.Vulnerabilities[severity] != count {
+			t.Errorf("Expected %d %s vulnerabilities, got %d", count, severity, vuln.Vulnerabilities[severity])
+		}
+	}
+
+	critical := vuln.Findings[0]
+	if critical.Name != "CVE-2024-12345" || critical.Severity != "CRITICAL" {
+		t.Errorf("Unexpected first finding: %+v", critical)
+	}
+	if critical.PackageName != "openssl" || critical.PackageVersion != "3.0.2-0ubuntu1.10" || critical.FixVersion != "3.0.2-0ubuntu1.15" {
+		t.Errorf("Unexpected package details: %+v", critical)
+	}
+	if critical.FixAvailable != "YES" || critical.Score != 9.8 || critical.URI != "https://avd.aquasec.com/nvd/cve-2024-12345" {
+		t.Errorf("Unexpected fix/score/uri: %+v", critical)
+	}
+
+	// CVSS v2 is used when no v3 score is available; no fix version means no fix
+	high := vuln.Findings[1]
+	if high.Score != 6.8 || high.FixAvailable != "NO" || high.URI != "" {
+		t.Errorf("Unexpected high finding: %+v", high)
+	}
+}
+
+func TestConvertReportNotScanned(t *testing.T) {
+	vuln := convertReport(map[string]vulnerabilityReport{}, "harbor.example.com/platform/api:v1", "platform/api", "v1")
+
+	if vuln.ScanStatus != "NOT_SCANNED" {
+		t.Errorf("Expected scan status NOT_SCANNED, got %s", vuln.ScanStatus)
+	}
+	if vuln.TotalCount != 0 || len(vuln.Findings) != 0 {
+		t.Errorf("Expected no findings, got %d", vuln.TotalCount)
+	}
+}
+
+func TestGetImageVulnerabilities(t *testing.T) {
+	fixture, err := os.ReadFile("testdata/vulnerabilities.json")
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	var gotPath, gotAccept string
+	var gotAuth bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.EscapedPath()
+		gotAccept = r.Header.Get("X-Accept-Vulnerabilities")
+		user, pass, ok := r.BasicAuth()
+		gotAuth = ok && user == "robot$vulnrelay" && pass == "secret"
+
+		if r.URL.EscapedPath() != "/api/v2.0/projects/platform/repositories/team%252Fapi/artifacts/v1.2.0/additions/vulnerabilities" {
+			http.Error(w, `{"errors":[{"code":"NOT_FOUND"}]}`, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(fixture)
+	}))
+	defer server.Close()
+
+	source := newTestSource(t, server.URL+"/")
+
+	vuln, err := source.GetImageVulnerabilities(context.Background(), "harbor.example.com/platform/team/api:v1.2.0")
+	if err != nil {
+		t.Fatalf("GetImageVulnerabilities() failed: %v (path %s)", err, gotPath)
+	}
+	if gotAccept != vulnerabilityReportMimeType {
+		t.Errorf("Expected X-Accept-Vulnerabilities %q, got %q", vulnerabilityReportMimeType, gotAccept)
+	}
+	if !gotAuth {
+		t.Error("Expected basic auth credentials to be sent")
+	}
+	if vuln.Repository != "platform/team/api" || vuln.Tag != "v1.2.0" || vuln.TotalCount != 5 {
+		t.Errorf("Unexpected vulnerability data: repo=%s tag=%s total=%d", vuln.Repository, vuln.Tag, vuln.TotalCount)
+	}
+
+	// Unknown artifacts are reported as failed scans
+	vuln, err = source.GetImageVulnerabilities(context.Background(), "harbor.example.com/platform/missing:v1")
+	if err == nil {
+		t.Fatal("Expected error for missing artifact")
+	}
+	if vuln == nil || vuln.ScanStatus != "FAILED" {
+		t.Errorf("Expected FAILED scan status, got %+v", vuln)
+	}
+}
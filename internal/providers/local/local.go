@@ -1,21 +1,83 @@
 // ABOUTME: Local file-based provider for development and testing purposes.
-// ABOUTME: Reads container image lists from JSON files without cloud API dependencies.
+// ABOUTME: Reads container image lists from JSON files, HTTP(S) or S3 URLs without discovery APIs.
 
 package local
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/jfeddern/VulnRelay/internal/types"
 	"github.com/sirupsen/logrus"
 )
 
+// imageListTimeout bounds fetching an image list from a URL
+const imageListTimeout = 30 * time.Second
+
+// maxImageListSize caps image lists fetched from a URL
+const maxImageListSize = 10 << 20
+
+// ObjectReader reads whole objects from an object store, used for s3:// image lists
+type ObjectReader interface {
+	ReadObject(ctx context.Context, bucket, key string) ([]byte, error)
+}
+
+// imageListEntry is an entry of the image list: either an image URI string,
+// or an object listing several tags of one repository, e.g.
+// {"repository": "nginx", "tags": ["1.25", "1.26"]}
+type imageListEntry struct {
+	URIs []string
+}
+
+// UnmarshalJSON accepts both entry forms
+func (e *imageListEntry) UnmarshalJSON(data []byte) error {
+	var uri string
+	if err := json.Unmarshal(data, &uri); err == nil {
+		if uri != "" {
+			e.URIs = []string{uri}
+		}
+		return nil
+	}
+
+	var repositoryTags struct {
+		Repository string   `json:"repository"`
+		Tags       []string `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &repositoryTags); err != nil {
+		return fmt.Errorf("image list entry must be an image URI or an object with repository and tags: %w", err)
+	}
+	if repositoryTags.Repository == "" || len(repositoryTags.Tags) == 0 {
+		return fmt.Errorf("image list entry %s needs a repository and at least one tag", data)
+	}
+
+	for _, tag := range repositoryTags.Tags {
+		if tag == "" {
+			continue
+		}
+		// Digests are referenced with @ instead of a tag separator
+		separator := ":"
+		if strings.HasPrefix(tag, "sha256:") {
+			separator = "@"
+		}
+		e.URIs = append(e.URIs, repositoryTags.Repository+separator+tag)
+	}
+	return nil
+}
+
 // LocalProvider implements CloudProvider for local file-based image discovery
 type LocalProvider struct {
-	imageListFile string
+	imageListFile string // File path, http(s):// URL or s3:// URL
+	defaultTag    string // Tag applied to entries without a tag or digest
+	client        *http.Client
+	objects       ObjectReader // Reads s3:// image lists
 	logger        *logrus.Logger
 }
 
@@ -23,10 +85,23 @@ type LocalProvider struct {
 func NewLocalProvider(imageListFile string, logger *logrus.Logger) *LocalProvider {
 	return &LocalProvider{
 		imageListFile: imageListFile,
+		client:        &http.Client{Timeout: imageListTimeout},
 		logger:        logger,
 	}
 }
 
+// SetObjectReader sets the reader of s3:// image lists
+func (l *LocalProvider) SetObjectReader(objects ObjectReader) {
+	l.objects = objects
+}
+
+// SetDefaultTag sets the tag, e.g. latest, applied to image list entries that
+// reference a bare repository. Without it such entries are discovered as-is
+// and fail to scan.
+func (l *LocalProvider) SetDefaultTag(tag string) {
+	l.defaultTag = tag
+}
+
 // Name returns the provider name
 func (l *LocalProvider) Name() string {
 	return "local"
@@ -38,27 +113,32 @@ func (l *LocalProvider) IsRegistryImage(imageURI string) bool {
 	return imageURI != ""
 }
 
-// DiscoverImages reads container images from a JSON file
+// DiscoverImages reads container images from a JSON image list, fetched
+// again on every discovery. Entries are image URIs or repository objects
+// expanding into one image per tag.
 func (l *LocalProvider) DiscoverImages(ctx context.Context) ([]types.ImageInfo, error) {
 	logger := l.logger.WithField("operation", "discover_images_local")
 
-	// Read the image list file
-	data, err := os.ReadFile(l.imageListFile)
+	data, err := l.readImageList(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read image list file '%s': %w", l.imageListFile, err)
+		return nil, err
 	}
 
-	var imageURIs []string
-	if err := json.Unmarshal(data, &imageURIs); err != nil {
+	var entries []imageListEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
 		return nil, fmt.Errorf("failed to parse image list JSON: %w", err)
 	}
 
-	logger.WithField("image_count", len(imageURIs)).Info("Read image list from file")
+	logger.WithField("image_count", len(entries)).Info("Read image list from file")
 
 	// Convert to ImageInfo structs
 	var images []types.ImageInfo
-	for _, uri := range imageURIs {
-		if uri != "" {
+	for _, entry := range entries {
+		for _, uri := range entry.URIs {
+			if l.defaultTag != "" && !hasTagOrDigest(uri) {
+				logger.WithFields(logrus.Fields{"image": uri, "tag": l.defaultTag}).Debug("Applying default tag to untagged image")
+				uri += ":" + l.defaultTag
+			}
 			images = append(images, types.ImageInfo{
 				URI:          uri,
 				Namespace:    "local",
@@ -71,3 +151,107 @@ func (l *LocalProvider) DiscoverImages(ctx context.Context) ([]types.ImageInfo,
 	logger.WithField("valid_images", len(images)).Info("Local image discovery completed")
 	return images, nil
 }
+
+// hasTagOrDigest reports whether an image URI references a tag or digest. A
+// colon before the last slash belongs to a registry port, not a tag.
+func hasTagOrDigest(uri string) bool {
+	if strings.Contains(uri, "@") {
+		return true
+	}
+	return strings.Contains(uri[strings.LastIndex(uri, "/")+1:], ":")
+}
+
+// readImageList reads the image list from a file path, an http(s):// URL or
+// an s3://bucket/key URL
+func (l *LocalProvider) readImageList(ctx context.Context) ([]byte, error) {
+	source := l.imageListFile
+	switch {
+	case strings.HasPrefix(source, "http://"), strings.HasPrefix(source, "https://"):
+		return l.fetchImageList(ctx, source)
+	case strings.HasPrefix(source, "s3://"):
+		parsed, err := url.Parse(source)
+		if err != nil || parsed.Host == "" || strings.TrimPrefix(parsed.Path, "/") == "" {
+			return nil, fmt.Errorf("invalid S3 image list URL '%s': expected s3://bucket/key", source)
+		}
+		if l.objects == nil {
+			return nil, fmt.Errorf("cannot read image list '%s': no S3 reader configured", source)
+		}
+		data, err := l.objects.ReadObject(ctx, parsed.Host, strings.TrimPrefix(parsed.Path, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image list '%s': %w", source, err)
+		}
+		return data, nil
+	default:
+		data, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image list file '%s': %w", source, err)
+		}
+		return data, nil
+	}
+}
+
+// fetchImageList downloads the image list from an HTTP(S) URL. Errors name
+// the URL without the credentials it may carry.
+func (l *LocalProvider) fetchImageList(ctx context.Context, source string) ([]byte, error) {
+	redactedSource := RedactSource(source)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image list URL '%s': %w", redactedSource, redactURLError(err, redactedSource))
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image list '%s': %w", redactedSource, redactURLError(err, redactedSource))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch image list '%s': status %d", redactedSource, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageListSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image list '%s': %w", redactedSource, err)
+	}
+	if len(data) > maxImageListSize {
+		return nil, fmt.Errorf("image list '%s' exceeds %d bytes", redactedSource, maxImageListSize)
+	}
+	return data, nil
+}
+
+// redactedPart replaces the credentials of a redacted image list URL
+const redactedPart = "REDACTED"
+
+// RedactSource returns an image list source safe to log. HTTP(S) URLs lose
+// their user info and query, which carry credentials, tokens or the
+// signature of a pre-signed URL; file paths and s3:// URLs are kept.
+func RedactSource(source string) string {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		return source
+	}
+
+	parsed, err := url.Parse(source)
+	if err != nil {
+		return "[" + redactedPart + "]"
+	}
+	if parsed.User != nil {
+		parsed.User = url.User(redactedPart)
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = redactedPart
+	}
+	parsed.Fragment = ""
+	return parsed.String()
+}
+
+// redactURLError replaces the URL that HTTP client and URL parsing errors
+// repeat with its redacted form
+func redactURLError(err error, redactedSource string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = redactedSource
+	}
+	return err
+}
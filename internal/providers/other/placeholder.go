@@ -6,7 +6,6 @@ package other
 // TODO: Add support for other vulnerability sources like:
 // - Trivy
 // - Grype
-// - Harbor registry scanner
 // - Anchore
 // - Snyk
 // - etc.
@@ -0,0 +1,191 @@
+// ABOUTME: gRPC server exposing vulnerability data alongside the HTTP API.
+// ABOUTME: Implements the VulnerabilityService defined in vulnrelaypb/vulnrelay.proto.
+
+package rpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative -I vulnrelaypb vulnrelaypb/vulnrelay.proto
+
+import (
+	"context"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/rpc/vulnrelaypb"
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Request limits, matching the /vulnerabilities endpoint
+const (
+	maxImageFilterLength = 200
+	maxFindingsLimit     = 10000
+)
+
+// validSeverities lists the severities accepted by the severity filter
+var validSeverities = map[string]bool{
+	"CRITICAL": true,
+	"HIGH":     true,
+	"MEDIUM":   true,
+	"LOW":      true,
+}
+
+type VulnerabilityDataProvider interface {
+	GetVulnerabilityData() (map[string]*types.ImageVulnerabilityData, time.Time)
+}
+
+// VulnerabilityServer implements the VulnerabilityService gRPC API
+type VulnerabilityServer struct {
+	vulnrelaypb.UnimplementedVulnerabilityServiceServer
+
+	collector VulnerabilityDataProvider
+	logger    *logrus.Logger
+}
+
+func NewVulnerabilityServer(collector VulnerabilityDataProvider, logger *logrus.Logger) *VulnerabilityServer {
+	return &VulnerabilityServer{
+		collector: collector,
+		logger:    logger,
+	}
+}
+
+// GetVulnerabilities returns the latest collected images. Images are filtered
+// by URI substring and namespace; with a severity filter only matching
+// findings are returned and images without any are omitted.
+func (s *VulnerabilityServer) GetVulnerabilities(ctx context.Context, req *vulnrelaypb.GetVulnerabilitiesRequest) (*vulnrelaypb.GetVulnerabilitiesResponse, error) {
+	imageFilter := strings.TrimSpace(req.GetImage())
+	severityFilter := strings.ToUpper(strings.TrimSpace(req.GetSeverity()))
+	namespaceFilter := strings.TrimSpace(req.GetNamespace())
+	limit := int(req.GetLimit())
+
+	if len(imageFilter) > maxImageFilterLength {
+		return nil, status.Errorf(codes.InvalidArgument, "image filter too long, maximum is %d characters", maxImageFilterLength)
+	}
+	if severityFilter != "" && !validSeverities[severityFilter] {
+		return nil, status.Error(codes.InvalidArgument, "invalid severity filter, must be one of: CRITICAL, HIGH, MEDIUM, LOW")
+	}
+	if limit < 0 || limit > maxFindingsLimit {
+		return nil, status.Errorf(codes.InvalidArgument, "limit must be between 0 and %d", maxFindingsLimit)
+	}
+
+	vulnerabilityData, lastCollectionTime := s.collector.GetVulnerabilityData()
+
+	// Images are returned in URI order so responses are stable
+	imageURIs := make([]string, 0, len(vulnerabilityData))
+	for imageURI := range vulnerabilityData {
+		imageURIs = append(imageURIs, imageURI)
+	}
+	sort.Strings(imageURIs)
+
+	response := &vulnrelaypb.GetVulnerabilitiesResponse{}
+	if !lastCollectionTime.IsZero() {
+		response.LastUpdated = timestamppb.New(lastCollectionTime)
+	}
+
+	for _, imageURI := range imageURIs {
+		vulnData := vulnerabilityData[imageURI]
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+		if imageFilter != "" && !strings.Contains(vulnData.ImageURI, imageFilter) {
+			continue
+		}
+		if namespaceFilter != "" && vulnData.Namespace != namespaceFilter {
+			continue
+		}
+
+		var findings []*vulnrelaypb.Finding
+		for _, finding := range vulnData.Findings {
+			if severityFilter != "" && finding.Severity != severityFilter {
+				continue
+			}
+			if limit > 0 && len(findings) == limit {
+				break
+			}
+			findings = append(findings, toFinding(finding))
+		}
+		if severityFilter != "" && len(findings) == 0 {
+			continue
+		}
+
+		image := toImage(vulnData)
+		image.Findings = findings
+		response.Images = append(response.Images, image)
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"image_filter":     imageFilter,
+		"severity_filter":  severityFilter,
+		"namespace_filter": namespaceFilter,
+		"limit":            limit,
+		"images":           len(response.Images),
+	}).Debug("Served gRPC vulnerabilities request")
+
+	return response, nil
+}
+
+// toImage converts image data without its findings
+func toImage(vulnData *types.ImageVulnerabilityData) *vulnrelaypb.Image {
+	image := &vulnrelaypb.Image{
+		ImageUri:            vulnData.ImageURI,
+		Repository:          vulnData.Repository,
+		Tag:                 vulnData.Tag,
+		VulnerabilityCounts: make(map[string]int32, len(vulnData.Vulnerabilities)),
+		TotalCount:          int32(vulnData.TotalCount),
+		ScanStatus:          vulnData.ScanStatus,
+		Namespace:           vulnData.Namespace,
+		Workload:            vulnData.Workload,
+		WorkloadType:        vulnData.WorkloadType,
+		Release:             vulnData.Release,
+	}
+	for severity, count := range vulnData.Vulnerabilities {
+		image.VulnerabilityCounts[severity] = int32(count)
+	}
+	if vulnData.LastScanTime != nil {
+		image.LastScanTime = *vulnData.LastScanTime
+	}
+	return image
+}
+
+func toFinding(finding types.VulnerabilityFinding) *vulnrelaypb.Finding {
+	converted := &vulnrelaypb.Finding{
+		Name:             finding.Name,
+		Description:      finding.Description,
+		Severity:         finding.Severity,
+		PackageName:      finding.PackageName,
+		PackageVersion:   finding.PackageVersion,
+		FixVersion:       finding.FixVersion,
+		Status:           finding.Status,
+		Uri:              finding.URI,
+		ExploitAvailable: finding.ExploitAvailable,
+		FixAvailable:     finding.FixAvailable,
+		Score:            finding.Score,
+		Type:             finding.Type,
+		Accepted:         finding.Accepted,
+	}
+	if finding.FirstSeen != nil {
+		converted.FirstSeen = timestamppb.New(*finding.FirstSeen)
+	}
+	return converted
+}
+
+// Serve runs a gRPC server with the VulnerabilityService on the listener until
+// ctx is cancelled, then stops gracefully
+func Serve(ctx context.Context, listener net.Listener, collector VulnerabilityDataProvider, logger *logrus.Logger) error {
+	server := grpc.NewServer()
+	vulnrelaypb.RegisterVulnerabilityServiceServer(server, NewVulnerabilityServer(collector, logger))
+
+	go func() {
+		<-ctx.Done()
+		logger.Info("Shutting down gRPC server")
+		server.GracefulStop()
+	}()
+
+	return server.Serve(listener)
+}
@@ -0,0 +1,218 @@
+// ABOUTME: Tests for the gRPC vulnerability server.
+// ABOUTME: Runs the service in-process over bufconn and calls it through a real client.
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/rpc/vulnrelaypb"
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type mockDataProvider struct {
+	data        map[string]*types.ImageVulnerabilityData
+	lastUpdated time.Time
+}
+
+func (m *mockDataProvider) GetVulnerabilityData() (map[string]*types.ImageVulnerabilityData, time.Time) {
+	return m.data, m.lastUpdated
+}
+
+func testImage(uri, namespace string, findings ...types.VulnerabilityFinding) *types.ImageVulnerabilityData {
+	counts := make(map[string]int)
+	for _, finding := range findings {
+		counts[finding.Severity]++
+	}
+	return &types.ImageVulnerabilityData{
+		ImageVulnerability: &types.ImageVulnerability{
+			ImageURI:        uri,
+			Vulnerabilities: counts,
+			TotalCount:      len(findings),
+			ScanStatus:      "COMPLETE",
+			Findings:        findings,
+		},
+		ImageInfo: types.ImageInfo{URI: uri, Namespace: namespace, Workload: "app", WorkloadType: "Deployment"},
+	}
+}
+
+// newTestClient serves the provider over an in-memory listener and returns a
+// connected client
+func newTestClient(t *testing.T, provider VulnerabilityDataProvider) vulnrelaypb.VulnerabilityServiceClient {
+	t.Helper()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	listener := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- Serve(ctx, listener, provider, logger)
+	}()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to create gRPC client: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() returned error: %v", err)
+		}
+	})
+
+	return vulnrelaypb.NewVulnerabilityServiceClient(conn)
+}
+
+func TestGetVulnerabilities(t *testing.T) {
+	apiImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	workerImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v2"
+	lastUpdated := time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)
+
+	provider := &mockDataProvider{
+		data: map[string]*types.ImageVulnerabilityData{
+			apiImage: testImage(apiImage, "production",
+				types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL", PackageName: "openssl"},
+				types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH"},
+				types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "HIGH"},
+			),
+			workerImage: testImage(workerImage, "batch",
+				types.VulnerabilityFinding{Name: "CVE-2024-0004", Severity: "LOW"},
+			),
+		},
+		lastUpdated: lastUpdated,
+	}
+	client := newTestClient(t, provider)
+
+	tests := []struct {
+		name             string
+		request          *vulnrelaypb.GetVulnerabilitiesRequest
+		expectedFindings map[string][]string // image URI -> CVEs, in response order
+	}{
+		{
+			name:    "all images",
+			request: &vulnrelaypb.GetVulnerabilitiesRequest{},
+			expectedFindings: map[string][]string{
+				apiImage:    {"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"},
+				workerImage: {"CVE-2024-0004"},
+			},
+		},
+		{
+			name:             "image filter",
+			request:          &vulnrelaypb.GetVulnerabilitiesRequest{Image: "worker"},
+			expectedFindings: map[string][]string{workerImage: {"CVE-2024-0004"}},
+		},
+		{
+			name:             "namespace filter",
+			request:          &vulnrelaypb.GetVulnerabilitiesRequest{Namespace: "production"},
+			expectedFindings: map[string][]string{apiImage: {"CVE-2024-0001", "CVE-2024-0002", "CVE-2024-0003"}},
+		},
+		{
+			name:             "severity filter omits images without matches",
+			request:          &vulnrelaypb.GetVulnerabilitiesRequest{Severity: "high"},
+			expectedFindings: map[string][]string{apiImage: {"CVE-2024-0002", "CVE-2024-0003"}},
+		},
+		{
+			name:    "limit per image",
+			request: &vulnrelaypb.GetVulnerabilitiesRequest{Limit: 1},
+			expectedFindings: map[string][]string{
+				apiImage:    {"CVE-2024-0001"},
+				workerImage: {"CVE-2024-0004"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := client.GetVulnerabilities(context.Background(), tt.request)
+			if err != nil {
+				t.Fatalf("GetVulnerabilities() failed: %v", err)
+			}
+
+			if !response.GetLastUpdated().AsTime().Equal(lastUpdated) {
+				t.Errorf("Expected last_updated %v, got %v", lastUpdated, response.GetLastUpdated().AsTime())
+			}
+			if len(response.GetImages()) != len(tt.expectedFindings) {
+				t.Fatalf("Expected %d images, got %d", len(tt.expectedFindings), len(response.GetImages()))
+			}
+
+			for _, image := range response.GetImages() {
+				expected, ok := tt.expectedFindings[image.GetImageUri()]
+				if !ok {
+					t.Errorf("Unexpected image %s", image.GetImageUri())
+					continue
+				}
+				var cves []string
+				for _, finding := range image.GetFindings() {
+					cves = append(cves, finding.GetName())
+				}
+				if len(cves) != len(expected) {
+					t.Errorf("Image %s: expected findings %v, got %v", image.GetImageUri(), expected, cves)
+					continue
+				}
+				for i := range cves {
+					if cves[i] != expected[i] {
+						t.Errorf("Image %s: expected findings %v, got %v", image.GetImageUri(), expected, cves)
+						break
+					}
+				}
+			}
+		})
+	}
+
+	// Image metadata and counts are carried over unchanged
+	response, err := client.GetVulnerabilities(context.Background(), &vulnrelaypb.GetVulnerabilitiesRequest{Image: "api"})
+	if err != nil {
+		t.Fatalf("GetVulnerabilities() failed: %v", err)
+	}
+	image := response.GetImages()[0]
+	if image.GetNamespace() != "production" || image.GetWorkload() != "app" || image.GetWorkloadType() != "Deployment" {
+		t.Errorf("Unexpected workload metadata: %s/%s (%s)", image.GetNamespace(), image.GetWorkload(), image.GetWorkloadType())
+	}
+	if image.GetVulnerabilityCounts()["HIGH"] != 2 || image.GetTotalCount() != 3 {
+		t.Errorf("Unexpected counts: %v total %d", image.GetVulnerabilityCounts(), image.GetTotalCount())
+	}
+	if image.GetFindings()[0].GetPackageName() != "openssl" {
+		t.Errorf("Expected package name openssl, got %q", image.GetFindings()[0].GetPackageName())
+	}
+}
+
+func TestGetVulnerabilitiesInvalidArgument(t *testing.T) {
+	client := newTestClient(t, &mockDataProvider{data: map[string]*types.ImageVulnerabilityData{}})
+
+	tests := []struct {
+		name    string
+		request *vulnrelaypb.GetVulnerabilitiesRequest
+	}{
+		{"unknown severity", &vulnrelaypb.GetVulnerabilitiesRequest{Severity: "URGENT"}},
+		{"negative limit", &vulnrelaypb.GetVulnerabilitiesRequest{Limit: -1}},
+		{"limit too large", &vulnrelaypb.GetVulnerabilitiesRequest{Limit: 10001}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetVulnerabilities(context.Background(), tt.request)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("Expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,546 @@
+// ABOUTME: Protobuf definition of the VulnRelay gRPC API.
+// ABOUTME: Mirrors the /vulnerabilities JSON endpoint, including its filters.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: vulnrelay.proto
+
+package vulnrelaypb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetVulnerabilitiesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only images whose URI contains this string
+	Image string `protobuf:"bytes,1,opt,name=image,proto3" json:"image,omitempty"`
+	// Only findings of this severity: CRITICAL, HIGH, MEDIUM or LOW
+	Severity string `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"`
+	// Only images discovered in this namespace
+	Namespace string `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	// Maximum findings returned per image (0 = unlimited)
+	Limit         int32 `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVulnerabilitiesRequest) Reset() {
+	*x = GetVulnerabilitiesRequest{}
+	mi := &file_vulnrelay_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVulnerabilitiesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVulnerabilitiesRequest) ProtoMessage() {}
+
+func (x *GetVulnerabilitiesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vulnrelay_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVulnerabilitiesRequest.ProtoReflect.Descriptor instead.
+func (*GetVulnerabilitiesRequest) Descriptor() ([]byte, []int) {
+	return file_vulnrelay_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *GetVulnerabilitiesRequest) GetImage() string {
+	if x != nil {
+		return x.Image
+	}
+	return ""
+}
+
+func (x *GetVulnerabilitiesRequest) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *GetVulnerabilitiesRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *GetVulnerabilitiesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetVulnerabilitiesResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Images []*Image               `protobuf:"bytes,1,rep,name=images,proto3" json:"images,omitempty"`
+	// When the data was last collected; unset before the first collection
+	LastUpdated   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=last_updated,json=lastUpdated,proto3" json:"last_updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetVulnerabilitiesResponse) Reset() {
+	*x = GetVulnerabilitiesResponse{}
+	mi := &file_vulnrelay_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetVulnerabilitiesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetVulnerabilitiesResponse) ProtoMessage() {}
+
+func (x *GetVulnerabilitiesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vulnrelay_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetVulnerabilitiesResponse.ProtoReflect.Descriptor instead.
+func (*GetVulnerabilitiesResponse) Descriptor() ([]byte, []int) {
+	return file_vulnrelay_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetVulnerabilitiesResponse) GetImages() []*Image {
+	if x != nil {
+		return x.Images
+	}
+	return nil
+}
+
+func (x *GetVulnerabilitiesResponse) GetLastUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdated
+	}
+	return nil
+}
+
+type Image struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	ImageUri            string                 `protobuf:"bytes,1,opt,name=image_uri,json=imageUri,proto3" json:"image_uri,omitempty"`
+	Repository          string                 `protobuf:"bytes,2,opt,name=repository,proto3" json:"repository,omitempty"`
+	Tag                 string                 `protobuf:"bytes,3,opt,name=tag,proto3" json:"tag,omitempty"`
+	VulnerabilityCounts map[string]int32       `protobuf:"bytes,4,rep,name=vulnerability_counts,json=vulnerabilityCounts,proto3" json:"vulnerability_counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	TotalCount          int32                  `protobuf:"varint,5,opt,name=total_count,json=totalCount,proto3" json:"total_count,omitempty"`
+	ScanStatus          string                 `protobuf:"bytes,6,opt,name=scan_status,json=scanStatus,proto3" json:"scan_status,omitempty"`
+	LastScanTime        string                 `protobuf:"bytes,7,opt,name=last_scan_time,json=lastScanTime,proto3" json:"last_scan_time,omitempty"`
+	Namespace           string                 `protobuf:"bytes,8,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Workload            string                 `protobuf:"bytes,9,opt,name=workload,proto3" json:"workload,omitempty"`
+	WorkloadType        string                 `protobuf:"bytes,10,opt,name=workload_type,json=workloadType,proto3" json:"workload_type,omitempty"`
+	Release             string                 `protobuf:"bytes,11,opt,name=release,proto3" json:"release,omitempty"`
+	Findings            []*Finding             `protobuf:"bytes,12,rep,name=findings,proto3" json:"findings,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Image) Reset() {
+	*x = Image{}
+	mi := &file_vulnrelay_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Image) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Image) ProtoMessage() {}
+
+func (x *Image) ProtoReflect() protoreflect.Message {
+	mi := &file_vulnrelay_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Image.ProtoReflect.Descriptor instead.
+func (*Image) Descriptor() ([]byte, []int) {
+	return file_vulnrelay_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Image) GetImageUri() string {
+	if x != nil {
+		return x.ImageUri
+	}
+	return ""
+}
+
+func (x *Image) GetRepository() string {
+	if x != nil {
+		return x.Repository
+	}
+	return ""
+}
+
+func (x *Image) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *Image) GetVulnerabilityCounts() map[string]int32 {
+	if x != nil {
+		return x.VulnerabilityCounts
+	}
+	return nil
+}
+
+func (x *Image) GetTotalCount() int32 {
+	if x != nil {
+		return x.TotalCount
+	}
+	return 0
+}
+
+func (x *Image) GetScanStatus() string {
+	if x != nil {
+		return x.ScanStatus
+	}
+	return ""
+}
+
+func (x *Image) GetLastScanTime() string {
+	if x != nil {
+		return x.LastScanTime
+	}
+	return ""
+}
+
+func (x *Image) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Image) GetWorkload() string {
+	if x != nil {
+		return x.Workload
+	}
+	return ""
+}
+
+func (x *Image) GetWorkloadType() string {
+	if x != nil {
+		return x.WorkloadType
+	}
+	return ""
+}
+
+func (x *Image) GetRelease() string {
+	if x != nil {
+		return x.Release
+	}
+	return ""
+}
+
+func (x *Image) GetFindings() []*Finding {
+	if x != nil {
+		return x.Findings
+	}
+	return nil
+}
+
+type Finding struct {
+	state            protoimpl.MessageState `protogen:"open.v1"`
+	Name             string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description      string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Severity         string                 `protobuf:"bytes,3,opt,name=severity,proto3" json:"severity,omitempty"`
+	PackageName      string                 `protobuf:"bytes,4,opt,name=package_name,json=packageName,proto3" json:"package_name,omitempty"`
+	PackageVersion   string                 `protobuf:"bytes,5,opt,name=package_version,json=packageVersion,proto3" json:"package_version,omitempty"`
+	FixVersion       string                 `protobuf:"bytes,6,opt,name=fix_version,json=fixVersion,proto3" json:"fix_version,omitempty"`
+	Status           string                 `protobuf:"bytes,7,opt,name=status,proto3" json:"status,omitempty"`
+	Uri              string                 `protobuf:"bytes,8,opt,name=uri,proto3" json:"uri,omitempty"`
+	ExploitAvailable string                 `protobuf:"bytes,9,opt,name=exploit_available,json=exploitAvailable,proto3" json:"exploit_available,omitempty"`
+	FixAvailable     string                 `protobuf:"bytes,10,opt,name=fix_available,json=fixAvailable,proto3" json:"fix_available,omitempty"`
+	Score            float64                `protobuf:"fixed64,11,opt,name=score,proto3" json:"score,omitempty"`
+	Type             string                 `protobuf:"bytes,12,opt,name=type,proto3" json:"type,omitempty"`
+	FirstSeen        *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=first_seen,json=firstSeen,proto3" json:"first_seen,omitempty"`
+	Accepted         bool                   `protobuf:"varint,14,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *Finding) Reset() {
+	*x = Finding{}
+	mi := &file_vulnrelay_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Finding) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Finding) ProtoMessage() {}
+
+func (x *Finding) ProtoReflect() protoreflect.Message {
+	mi := &file_vulnrelay_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Finding.ProtoReflect.Descriptor instead.
+func (*Finding) Descriptor() ([]byte, []int) {
+	return file_vulnrelay_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Finding) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Finding) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Finding) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Finding) GetPackageName() string {
+	if x != nil {
+		return x.PackageName
+	}
+	return ""
+}
+
+func (x *Finding) GetPackageVersion() string {
+	if x != nil {
+		return x.PackageVersion
+	}
+	return ""
+}
+
+func (x *Finding) GetFixVersion() string {
+	if x != nil {
+		return x.FixVersion
+	}
+	return ""
+}
+
+func (x *Finding) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Finding) GetUri() string {
+	if x != nil {
+		return x.Uri
+	}
+	return ""
+}
+
+func (x *Finding) GetExploitAvailable() string {
+	if x != nil {
+		return x.ExploitAvailable
+	}
+	return ""
+}
+
+func (x *Finding) GetFixAvailable() string {
+	if x != nil {
+		return x.FixAvailable
+	}
+	return ""
+}
+
+func (x *Finding) GetScore() float64 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Finding) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Finding) GetFirstSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return nil
+}
+
+func (x *Finding) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+var File_vulnrelay_proto protoreflect.FileDescriptor
+
+const file_vulnrelay_proto_rawDesc = "" +
+	"\n" +
+	"\x0fvulnrelay.proto\x12\fvulnrelay.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x81\x01\n" +
+	"\x19GetVulnerabilitiesRequest\x12\x14\n" +
+	"\x05image\x18\x01 \x01(\tR\x05image\x12\x1a\n" +
+	"\bseverity\x18\x02 \x01(\tR\bseverity\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x14\n" +
+	"\x05limit\x18\x04 \x01(\x05R\x05limit\"\x88\x01\n" +
+	"\x1aGetVulnerabilitiesResponse\x12+\n" +
+	"\x06images\x18\x01 \x03(\v2\x13.vulnrelay.v1.ImageR\x06images\x12=\n" +
+	"\flast_updated\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\vlastUpdated\"\x93\x04\n" +
+	"\x05Image\x12\x1b\n" +
+	"\timage_uri\x18\x01 \x01(\tR\bimageUri\x12\x1e\n" +
+	"\n" +
+	"repository\x18\x02 \x01(\tR\n" +
+	"repository\x12\x10\n" +
+	"\x03tag\x18\x03 \x01(\tR\x03tag\x12_\n" +
+	"\x14vulnerability_counts\x18\x04 \x03(\v2,.vulnrelay.v1.Image.VulnerabilityCountsEntryR\x13vulnerabilityCounts\x12\x1f\n" +
+	"\vtotal_count\x18\x05 \x01(\x05R\n" +
+	"totalCount\x12\x1f\n" +
+	"\vscan_status\x18\x06 \x01(\tR\n" +
+	"scanStatus\x12$\n" +
+	"\x0elast_scan_time\x18\a \x01(\tR\flastScanTime\x12\x1c\n" +
+	"\tnamespace\x18\b \x01(\tR\tnamespace\x12\x1a\n" +
+	"\bworkload\x18\t \x01(\tR\bworkload\x12#\n" +
+	"\rworkload_type\x18\n" +
+	" \x01(\tR\fworkloadType\x12\x18\n" +
+	"\arelease\x18\v \x01(\tR\arelease\x121\n" +
+	"\bfindings\x18\f \x03(\v2\x15.vulnrelay.v1.FindingR\bfindings\x1aF\n" +
+	"\x18VulnerabilityCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"\xc5\x03\n" +
+	"\aFinding\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12\x1a\n" +
+	"\bseverity\x18\x03 \x01(\tR\bseverity\x12!\n" +
+	"\fpackage_name\x18\x04 \x01(\tR\vpackageName\x12'\n" +
+	"\x0fpackage_version\x18\x05 \x01(\tR\x0epackageVersion\x12\x1f\n" +
+	"\vfix_version\x18\x06 \x01(\tR\n" +
+	"fixVersion\x12\x16\n" +
+	"\x06status\x18\a \x01(\tR\x06status\x12\x10\n" +
+	"\x03uri\x18\b \x01(\tR\x03uri\x12+\n" +
+	"\x11exploit_available\x18\t \x01(\tR\x10exploitAvailable\x12#\n" +
+	"\rfix_available\x18\n" +
+	" \x01(\tR\ffixAvailable\x12\x14\n" +
+	"\x05score\x18\v \x01(\x01R\x05score\x12\x12\n" +
+	"\x04type\x18\f \x01(\tR\x04type\x129\n" +
+	"\n" +
+	"first_seen\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tfirstSeen\x12\x1a\n" +
+	"\baccepted\x18\x0e \x01(\bR\baccepted2\x7f\n" +
+	"\x14VulnerabilityService\x12g\n" +
+	"\x12GetVulnerabilities\x12'.vulnrelay.v1.GetVulnerabilitiesRequest\x1a(.vulnrelay.v1.GetVulnerabilitiesResponseB8Z6github.com/jfeddern/VulnRelay/internal/rpc/vulnrelaypbb\x06proto3"
+
+var (
+	file_vulnrelay_proto_rawDescOnce sync.Once
+	file_vulnrelay_proto_rawDescData []byte
+)
+
+func file_vulnrelay_proto_rawDescGZIP() []byte {
+	file_vulnrelay_proto_rawDescOnce.Do(func() {
+		file_vulnrelay_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_vulnrelay_proto_rawDesc), len(file_vulnrelay_proto_rawDesc)))
+	})
+	return file_vulnrelay_proto_rawDescData
+}
+
+var file_vulnrelay_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_vulnrelay_proto_goTypes = []any{
+	(*GetVulnerabilitiesRequest)(nil),  // 0: vulnrelay.v1.GetVulnerabilitiesRequest
+	(*GetVulnerabilitiesResponse)(nil), // 1: vulnrelay.v1.GetVulnerabilitiesResponse
+	(*Image)(nil),                      // 2: vulnrelay.v1.Image
+	(*Finding)(nil),                    // 3: vulnrelay.v1.Finding
+	nil,                                // 4: vulnrelay.v1.Image.VulnerabilityCountsEntry
+	(*timestamppb.Timestamp)(nil),      // 5: google.protobuf.Timestamp
+}
+var file_vulnrelay_proto_depIdxs = []int32{
+	2, // 0: vulnrelay.v1.GetVulnerabilitiesResponse.images:type_name -> vulnrelay.v1.Image
+	5, // 1: vulnrelay.v1.GetVulnerabilitiesResponse.last_updated:type_name -> google.protobuf.Timestamp
+	4, // 2: vulnrelay.v1.Image.vulnerability_counts:type_name -> vulnrelay.v1.Image.VulnerabilityCountsEntry
+	3, // 3: vulnrelay.v1.Image.findings:type_name -> vulnrelay.v1.Finding
+	5, // 4: vulnrelay.v1.Finding.first_seen:type_name -> google.protobuf.Timestamp
+	0, // 5: vulnrelay.v1.VulnerabilityService.GetVulnerabilities:input_type -> vulnrelay.v1.GetVulnerabilitiesRequest
+	1, // 6: vulnrelay.v1.VulnerabilityService.GetVulnerabilities:output_type -> vulnrelay.v1.GetVulnerabilitiesResponse
+	6, // [6:7] is the sub-list for method output_type
+	5, // [5:6] is the sub-list for method input_type
+	5, // [5:5] is the sub-list for extension type_name
+	5, // [5:5] is the sub-list for extension extendee
+	0, // [0:5] is the sub-list for field type_name
+}
+
+func init() { file_vulnrelay_proto_init() }
+func file_vulnrelay_proto_init() {
+	if File_vulnrelay_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_vulnrelay_proto_rawDesc), len(file_vulnrelay_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vulnrelay_proto_goTypes,
+		DependencyIndexes: file_vulnrelay_proto_depIdxs,
+		MessageInfos:      file_vulnrelay_proto_msgTypes,
+	}.Build()
+	File_vulnrelay_proto = out.File
+	file_vulnrelay_proto_goTypes = nil
+	file_vulnrelay_proto_depIdxs = nil
+}
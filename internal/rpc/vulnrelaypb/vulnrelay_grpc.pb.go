@@ -0,0 +1,132 @@
+// ABOUTME: Protobuf definition of the VulnRelay gRPC API.
+// ABOUTME: Mirrors the /vulnerabilities JSON endpoint, including its filters.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: vulnrelay.proto
+
+package vulnrelaypb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VulnerabilityService_GetVulnerabilities_FullMethodName = "/vulnrelay.v1.VulnerabilityService/GetVulnerabilities"
+)
+
+// VulnerabilityServiceClient is the client API for VulnerabilityService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// VulnerabilityService exposes the latest collected vulnerability data
+type VulnerabilityServiceClient interface {
+	// GetVulnerabilities returns images from the latest collection, optionally
+	// filtered like GET /vulnerabilities
+	GetVulnerabilities(ctx context.Context, in *GetVulnerabilitiesRequest, opts ...grpc.CallOption) (*GetVulnerabilitiesResponse, error)
+}
+
+type vulnerabilityServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVulnerabilityServiceClient(cc grpc.ClientConnInterface) VulnerabilityServiceClient {
+	return &vulnerabilityServiceClient{cc}
+}
+
+func (c *vulnerabilityServiceClient) GetVulnerabilities(ctx context.Context, in *GetVulnerabilitiesRequest, opts ...grpc.CallOption) (*GetVulnerabilitiesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetVulnerabilitiesResponse)
+	err := c.cc.Invoke(ctx, VulnerabilityService_GetVulnerabilities_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// VulnerabilityServiceServer is the server API for VulnerabilityService service.
+// All implementations must embed UnimplementedVulnerabilityServiceServer
+// for forward compatibility.
+//
+// VulnerabilityService exposes the latest collected vulnerability data
+type VulnerabilityServiceServer interface {
+	// GetVulnerabilities returns images from the latest collection, optionally
+	// filtered like GET /vulnerabilities
+	GetVulnerabilities(context.Context, *GetVulnerabilitiesRequest) (*GetVulnerabilitiesResponse, error)
+	mustEmbedUnimplementedVulnerabilityServiceServer()
+}
+
+// UnimplementedVulnerabilityServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVulnerabilityServiceServer struct{}
+
+func (UnimplementedVulnerabilityServiceServer) GetVulnerabilities(context.Context, *GetVulnerabilitiesRequest) (*GetVulnerabilitiesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetVulnerabilities not implemented")
+}
+func (UnimplementedVulnerabilityServiceServer) mustEmbedUnimplementedVulnerabilityServiceServer() {}
+func (UnimplementedVulnerabilityServiceServer) testEmbeddedByValue()                              {}
+
+// UnsafeVulnerabilityServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VulnerabilityServiceServer will
+// result in compilation errors.
+type UnsafeVulnerabilityServiceServer interface {
+	mustEmbedUnimplementedVulnerabilityServiceServer()
+}
+
+func RegisterVulnerabilityServiceServer(s grpc.ServiceRegistrar, srv VulnerabilityServiceServer) {
+	// If the following call pancis, it indicates UnimplementedVulnerabilityServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VulnerabilityService_ServiceDesc, srv)
+}
+
+func _VulnerabilityService_GetVulnerabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetVulnerabilitiesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VulnerabilityServiceServer).GetVulnerabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VulnerabilityService_GetVulnerabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VulnerabilityServiceServer).GetVulnerabilities(ctx, req.(*GetVulnerabilitiesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// VulnerabilityService_ServiceDesc is the grpc.ServiceDesc for VulnerabilityService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VulnerabilityService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vulnrelay.v1.VulnerabilityService",
+	HandlerType: (*VulnerabilityServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetVulnerabilities",
+			Handler:    _VulnerabilityService_GetVulnerabilities_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "vulnrelay.proto",
+}
@@ -0,0 +1,148 @@
+// ABOUTME: HTTP handler for the CVE-centric /cves endpoint.
+// ABOUTME: Lists every distinct CVE with the images and workloads it affects.
+
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jfeddern/VulnRelay/internal/severity"
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// AffectedImage is an image a CVE was found in, with its workload context
+type AffectedImage struct {
+	ImageURI     string `json:"image_uri"`
+	Namespace    string `json:"namespace"`
+	Workload     string `json:"workload"`
+	WorkloadType string `json:"workload_type"`
+}
+
+// CVEDetail is one CVE and every image it affects
+type CVEDetail struct {
+	Name        string          `json:"name"`
+	Severity    string          `json:"severity"`
+	Description string          `json:"description"`
+	ImageCount  int             `json:"image_count"`
+	Images      []AffectedImage `json:"images"`
+}
+
+type CVEsResponse struct {
+	CVEs        []CVEDetail `json:"cves"`
+	TotalCVEs   int         `json:"total_cves"`
+	LastUpdated string      `json:"last_updated"`
+}
+
+type CVEsHandler struct {
+	collector  VulnerabilityDataProvider
+	severities severity.Order // Orders CVEs and validates the severity filter
+	pretty     bool           // Indent responses by default
+	logger     *logrus.Logger
+}
+
+func NewCVEsHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *CVEsHandler {
+	return &CVEsHandler{
+		collector:  collector,
+		severities: severity.NewOrder(options.SeverityOrder),
+		pretty:     options.PrettyPrint,
+		logger:     logger,
+	}
+}
+
+func (h *CVEsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.WithField("endpoint", "/cves")
+
+	severityFilter := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("severity")))
+	if severityFilter != "" && !h.severities.Known(severityFilter) {
+		http.Error(w, "Invalid severity filter. Must be one of: "+strings.Join(h.severities.Severities(), ", "), http.StatusBadRequest)
+		return
+	}
+
+	vulnerabilityData, lastCollectionTime := h.collector.GetVulnerabilityData()
+	cves := groupByCVE(vulnerabilityData, severityFilter, h.severities)
+
+	response := CVEsResponse{
+		CVEs:        cves,
+		TotalCVEs:   len(cves),
+		LastUpdated: lastCollectionTime.Format("2006-01-02T15:04:05Z"),
+	}
+
+	if err := writeJSON(w, r, response, h.pretty); err != nil {
+		logger.WithError(err).Error("Failed to encode JSON response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"cves":     len(cves),
+		"severity": severityFilter,
+	}).Info("Served CVEs response")
+}
+
+// groupByCVE collects the images of every active finding by CVE, optionally
+// only for one severity. An image is listed once per CVE, however many of its
+// packages have it. CVEs are ordered by severity, then by the number of
+// affected images, then by name; images by URI.
+func groupByCVE(data map[string]*types.ImageVulnerabilityData, severityFilter string, severities severity.Order) []CVEDetail {
+	byName := make(map[string]*CVEDetail)
+	for uri, vulnData := range data {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+
+		for _, finding := range vulnData.Findings {
+			if finding.Name == "" || finding.Accepted || (severityFilter != "" && finding.Severity != severityFilter) {
+				continue
+			}
+
+			cve, exists := byName[finding.Name]
+			if !exists {
+				cve = &CVEDetail{
+					Name:        finding.Name,
+					Severity:    finding.Severity,
+					Description: finding.Description,
+				}
+				byName[finding.Name] = cve
+			}
+			if n := len(cve.Images); n > 0 && cve.Images[n-1].ImageURI == uri {
+				continue
+			}
+			cve.Images = append(cve.Images, AffectedImage{
+				ImageURI:     uri,
+				Namespace:    vulnData.Namespace,
+				Workload:     vulnData.Workload,
+				WorkloadType: vulnData.WorkloadType,
+			})
+		}
+	}
+
+	cves := make([]CVEDetail, 0, len(byName))
+	for _, cve := range byName {
+		sort.Slice(cve.Images, func(i, j int) bool {
+			return cve.Images[i].ImageURI < cve.Images[j].ImageURI
+		})
+		cve.ImageCount = len(cve.Images)
+		cves = append(cves, *cve)
+	}
+
+	sort.Slice(cves, func(i, j int) bool {
+		if a, b := severities.Position(cves[i].Severity), severities.Position(cves[j].Severity); a != b {
+			return a < b
+		}
+		if cves[i].ImageCount != cves[j].ImageCount {
+			return cves[i].ImageCount > cves[j].ImageCount
+		}
+		return cves[i].Name < cves[j].Name
+	})
+	return cves
+}
+
+// CreateCVEsHandler creates a standard HTTP handler
+func CreateCVEsHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewCVEsHandler(dataProvider, options, logger)
+	return handler.ServeHTTP
+}
@@ -0,0 +1,122 @@
+// ABOUTME: Unit tests for the CVE-centric /cves endpoint.
+// ABOUTME: Tests grouping of images by CVE, ordering and the severity filter.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cveTestData returns three images sharing some CVEs
+func cveTestData() map[string]*types.ImageVulnerabilityData {
+	openssl := types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "HIGH", Description: "OpenSSL overflow", PackageName: "openssl"}
+	libssl := types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "HIGH", Description: "OpenSSL overflow", PackageName: "libssl3"}
+	zlib := types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "CRITICAL", Description: "zlib overflow"}
+	curl := types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "LOW"}
+	accepted := types.VulnerabilityFinding{Name: "CVE-2024-0004", Severity: "HIGH", Accepted: true}
+
+	return map[string]*types.ImageVulnerabilityData{
+		"registry/web:v1":    testImage("registry/web:v1", "production", "web", openssl, libssl, curl),
+		"registry/api:v2":    testImage("registry/api:v2", "production", "api", openssl, zlib, accepted),
+		"registry/worker:v1": testImage("registry/worker:v1", "staging", "worker"),
+	}
+}
+
+func TestCVEsHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &MockVulnerabilityCollector{data: cveTestData(), lastUpdated: time.Now()}
+	handler := NewCVEsHandler(collector, Options{}, logger)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/cves", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	var response CVEsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	// Ordered by severity; the accepted CVE is left out
+	names := make([]string, len(response.CVEs))
+	for i, cve := range response.CVEs {
+		names[i] = cve.Name
+	}
+	if expected := []string{"CVE-2024-0002", "CVE-2024-0001", "CVE-2024-0003"}; !reflect.DeepEqual(names, expected) {
+		t.Fatalf("Expected CVEs %v, got %v", expected, names)
+	}
+	if response.TotalCVEs != 3 {
+		t.Errorf("Expected total_cves 3, got %d", response.TotalCVEs)
+	}
+
+	// A CVE in two images lists both, once each despite two packages in web
+	shared := response.CVEs[1]
+	expectedImages := []AffectedImage{
+		{ImageURI: "registry/api:v2", Namespace: "production", Workload: "api", WorkloadType: "Deployment"},
+		{ImageURI: "registry/web:v1", Namespace: "production", Workload: "web", WorkloadType: "Deployment"},
+	}
+	if !reflect.DeepEqual(shared.Images, expectedImages) {
+		t.Errorf("Expected images %+v, got %+v", expectedImages, shared.Images)
+	}
+	if shared.ImageCount != 2 || shared.Severity != "HIGH" || shared.Description != "OpenSSL overflow" {
+		t.Errorf("Unexpected CVE details %+v", shared)
+	}
+}
+
+func TestCVEsHandlerSeverityFilter(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &MockVulnerabilityCollector{data: cveTestData(), lastUpdated: time.Now()}
+	handler := NewCVEsHandler(collector, Options{}, logger)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCVEs   []string
+	}{
+		{"critical only", "?severity=CRITICAL", http.StatusOK, []string{"CVE-2024-0002"}},
+		{"case-insensitive", "?severity=high", http.StatusOK, []string{"CVE-2024-0001"}},
+		{"no matches", "?severity=MEDIUM", http.StatusOK, []string{}},
+		{"unknown severity", "?severity=SEVERE", http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/cves"+tt.query, nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response CVEsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			names := make([]string, 0, len(response.CVEs))
+			for _, cve := range response.CVEs {
+				names = append(names, cve.Name)
+			}
+			if !reflect.DeepEqual(names, tt.expectedCVEs) {
+				t.Errorf("Expected CVEs %v, got %v", tt.expectedCVEs, names)
+			}
+		})
+	}
+}
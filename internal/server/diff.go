@@ -0,0 +1,169 @@
+// ABOUTME: HTTP handler for the /vulnerabilities/diff change review endpoint.
+// ABOUTME: Lists findings added and resolved per image between the last two collections.
+
+package server
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ImageDiff lists the findings of one image that changed between collections
+type ImageDiff struct {
+	ImageURI     string                       `json:"image_uri"`
+	Namespace    string                       `json:"namespace"`
+	Workload     string                       `json:"workload"`
+	WorkloadType string                       `json:"workload_type"`
+	Added        []types.VulnerabilityFinding `json:"added"`
+	Resolved     []types.VulnerabilityFinding `json:"resolved"`
+}
+
+type DiffSummary struct {
+	ImagesChanged int `json:"images_changed"`
+	Added         int `json:"added"`
+	Resolved      int `json:"resolved"`
+}
+
+type DiffResponse struct {
+	Images             []ImageDiff `json:"images"`
+	Summary            DiffSummary `json:"summary"`
+	CurrentCollection  string      `json:"current_collection"`
+	PreviousCollection string      `json:"previous_collection"`
+}
+
+type DiffHandler struct {
+	collector VulnerabilityDataProvider
+	pretty    bool // Indent responses by default
+	logger    *logrus.Logger
+}
+
+func NewDiffHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *DiffHandler {
+	return &DiffHandler{
+		collector: collector,
+		pretty:    options.PrettyPrint,
+		logger:    logger,
+	}
+}
+
+func (h *DiffHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.WithField("endpoint", "/vulnerabilities/diff")
+
+	snapshots, ok := h.collector.(SnapshotProvider)
+	if !ok {
+		http.Error(w, "Snapshots are not available", http.StatusNotFound)
+		return
+	}
+	previous, previousTime, current, currentTime, ok := snapshots.GetSnapshotAndCurrent(1)
+	if !ok {
+		http.Error(w, "No previous snapshot available", http.StatusNotFound)
+		return
+	}
+
+	images := diffVulnerabilities(previous, current)
+	response := DiffResponse{
+		Images:             images,
+		CurrentCollection:  currentTime.Format("2006-01-02T15:04:05Z"),
+		PreviousCollection: previousTime.Format("2006-01-02T15:04:05Z"),
+	}
+	response.Summary.ImagesChanged = len(images)
+	for _, image := range images {
+		response.Summary.Added += len(image.Added)
+		response.Summary.Resolved += len(image.Resolved)
+	}
+
+	if err := writeJSON(w, r, response, h.pretty); err != nil {
+		logger.WithError(err).Error("Failed to encode JSON response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"images_changed": response.Summary.ImagesChanged,
+		"added":          response.Summary.Added,
+		"resolved":       response.Summary.Resolved,
+	}).Info("Served vulnerabilities diff response")
+}
+
+// diffVulnerabilities compares findings per image by CVE. Images only present
+// in one collection have all their findings added or resolved. Images whose
+// findings were dropped in either collection are skipped, as their CVEs are
+// unknown. Only images with changes are returned, ordered by URI.
+func diffVulnerabilities(previous, current map[string]*types.ImageVulnerabilityData) []ImageDiff {
+	uris := make(map[string]bool, len(current))
+	for uri := range previous {
+		uris[uri] = true
+	}
+	for uri := range current {
+		uris[uri] = true
+	}
+
+	diffs := make([]ImageDiff, 0)
+	for uri := range uris {
+		before, after := previous[uri], current[uri]
+		if findingsDropped(before) || findingsDropped(after) {
+			continue
+		}
+		diff := ImageDiff{
+			Added:    missingFindings(after, before),
+			Resolved: missingFindings(before, after),
+		}
+		if len(diff.Added) == 0 && len(diff.Resolved) == 0 {
+			continue
+		}
+
+		// Workload metadata comes from the latest collection that has the image
+		info := after
+		if info == nil {
+			info = before
+		}
+		diff.ImageURI = uri
+		diff.Namespace = info.Namespace
+		diff.Workload = info.Workload
+		diff.WorkloadType = info.WorkloadType
+		diffs = append(diffs, diff)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].ImageURI < diffs[j].ImageURI
+	})
+	return diffs
+}
+
+// findingsDropped reports whether an image's findings were dropped to bound
+// memory, keeping only its counts
+func findingsDropped(data *types.ImageVulnerabilityData) bool {
+	return data != nil && data.ImageVulnerability != nil && data.FindingsDropped
+}
+
+// missingFindings returns the findings of from whose CVE does not occur in other
+func missingFindings(from, other *types.ImageVulnerabilityData) []types.VulnerabilityFinding {
+	if from == nil || from.ImageVulnerability == nil {
+		return []types.VulnerabilityFinding{}
+	}
+
+	known := make(map[string]bool)
+	if other != nil && other.ImageVulnerability != nil {
+		for _, finding := range other.Findings {
+			known[finding.Name] = true
+		}
+	}
+
+	missing := make([]types.VulnerabilityFinding, 0)
+	for _, finding := range from.Findings {
+		if !known[finding.Name] {
+			missing = append(missing, finding)
+			known[finding.Name] = true // A CVE is listed once per image
+		}
+	}
+	return missing
+}
+
+// CreateDiffHandler creates a standard HTTP handler
+func CreateDiffHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewDiffHandler(dataProvider, options, logger)
+	return handler.ServeHTTP
+}
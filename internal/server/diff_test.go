@@ -0,0 +1,167 @@
+// ABOUTME: Unit tests for the /vulnerabilities/diff endpoint.
+// ABOUTME: Tests added and resolved findings between two collections.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+func findingNames(findings []types.VulnerabilityFinding) []string {
+	names := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		names = append(names, finding.Name)
+	}
+	return names
+}
+
+func TestDiffHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	apiImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	unchangedImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/db:v1"
+	removedImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v1"
+	newImage := "123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:v2"
+
+	// Cycle 2 adds CVE-2024-0003 to api and resolves CVE-2024-0001, and
+	// replaces worker:v1 with worker:v2
+	previous := map[string]*types.ImageVulnerabilityData{
+		apiImage:       testImage(apiImage, "production", "api", highFindings("CVE-2024-0001", "CVE-2024-0002")...),
+		unchangedImage: testImage(unchangedImage, "production", "api", highFindings("CVE-2024-0009")...),
+		removedImage:   testImage(removedImage, "production", "api", highFindings("CVE-2024-0005")...),
+	}
+	current := map[string]*types.ImageVulnerabilityData{
+		apiImage:       testImage(apiImage, "production", "api", highFindings("CVE-2024-0002", "CVE-2024-0003", "CVE-2024-0003")...),
+		unchangedImage: testImage(unchangedImage, "production", "api", highFindings("CVE-2024-0009")...),
+		newImage:       testImage(newImage, "production", "api", highFindings("CVE-2024-0006")...),
+	}
+
+	collector := &snapshotCollector{
+		MockVulnerabilityCollector: MockVulnerabilityCollector{data: current, lastUpdated: time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+		snapshots:                  []map[string]*types.ImageVulnerabilityData{previous},
+		taken:                      []time.Time{time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)},
+	}
+	handler := NewDiffHandler(collector, Options{}, logger)
+
+	req := httptest.NewRequest("GET", "/vulnerabilities/diff", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+
+	var response DiffResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	expected := []struct {
+		uri      string
+		added    []string
+		resolved []string
+	}{
+		{apiImage, []string{"CVE-2024-0003"}, []string{"CVE-2024-0001"}},
+		{removedImage, []string{}, []string{"CVE-2024-0005"}},
+		{newImage, []string{"CVE-2024-0006"}, []string{}},
+	}
+	if len(response.Images) != len(expected) {
+		t.Fatalf("Expected %d changed images, got %d: %+v", len(expected), len(response.Images), response.Images)
+	}
+	for i, want := range expected {
+		got := response.Images[i]
+		if got.ImageURI != want.uri {
+			t.Errorf("Image %d: expected %s, got %s", i, want.uri, got.ImageURI)
+			continue
+		}
+		if added := findingNames(got.Added); !equalStrings(added, want.added) {
+			t.Errorf("%s: expected added %v, got %v", want.uri, want.added, added)
+		}
+		if resolved := findingNames(got.Resolved); !equalStrings(resolved, want.resolved) {
+			t.Errorf("%s: expected resolved %v, got %v", want.uri, want.resolved, resolved)
+		}
+		if got.Namespace != "production" || got.Workload != "api" {
+			t.Errorf("%s: unexpected workload %s/%s", want.uri, got.Namespace, got.Workload)
+		}
+	}
+
+	if response.Summary != (DiffSummary{ImagesChanged: 3, Added: 2, Resolved: 2}) {
+		t.Errorf("Unexpected summary %+v", response.Summary)
+	}
+	if response.CurrentCollection != "2025-01-15T10:35:00Z" || response.PreviousCollection != "2025-01-15T10:30:00Z" {
+		t.Errorf("Unexpected collection times %s and %s", response.CurrentCollection, response.PreviousCollection)
+	}
+}
+
+func TestDiffVulnerabilitiesFindingsDropped(t *testing.T) {
+	image := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+	dropped := func() *types.ImageVulnerabilityData {
+		data := testImage(image, "production", "api")
+		data.FindingsDropped = true
+		return data
+	}
+
+	// Without findings on one side the CVEs are unknown, rather than all
+	// resolved when they are dropped and all added when they come back
+	tests := map[string]struct {
+		previous, current *types.ImageVulnerabilityData
+	}{
+		"dropped in the current collection":  {testImage(image, "production", "api", highFindings("CVE-2024-0001", "CVE-2024-0002")...), dropped()},
+		"dropped in the previous collection": {dropped(), testImage(image, "production", "api", highFindings("CVE-2024-0001", "CVE-2024-0002")...)},
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			diffs := diffVulnerabilities(
+				map[string]*types.ImageVulnerabilityData{image: tt.previous},
+				map[string]*types.ImageVulnerabilityData{image: tt.current},
+			)
+			if len(diffs) != 0 {
+				t.Errorf("Expected no changes for an image with dropped findings, got %+v", diffs)
+			}
+		})
+	}
+}
+
+func TestDiffHandlerWithoutSnapshot(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collectors := map[string]VulnerabilityDataProvider{
+		"no previous snapshot yet":   &snapshotCollector{MockVulnerabilityCollector: MockVulnerabilityCollector{data: map[string]*types.ImageVulnerabilityData{}}},
+		"provider without snapshots": &MockVulnerabilityCollector{data: map[string]*types.ImageVulnerabilityData{}},
+	}
+
+	for name, collector := range collectors {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/vulnerabilities/diff", nil)
+			rr := httptest.NewRecorder()
+			NewDiffHandler(collector, Options{}, logger).ServeHTTP(rr, req)
+
+			if rr.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", rr.Code)
+			}
+		})
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+// ABOUTME: HTTP handler for the /debug/identity diagnostics endpoint.
+// ABOUTME: Reports the cloud identity the vulnerability source authenticates as.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// identityTimeout bounds the identity lookup against the cloud provider
+const identityTimeout = 10 * time.Second
+
+// identityCacheTTL is how long a looked up identity is served before the
+// cloud provider is asked again, so requests don't each call STS
+const identityCacheTTL = 5 * time.Minute
+
+// IdentityProvider reports the identity of the vulnerability source; a nil
+// identity without error means the source does not expose one
+type IdentityProvider interface {
+	CallerIdentity(ctx context.Context) (*types.CallerIdentity, error)
+}
+
+type IdentityHandler struct {
+	provider IdentityProvider
+	logger   *logrus.Logger
+	now      func() time.Time
+
+	mutex     sync.Mutex
+	identity  *types.CallerIdentity // Last identity looked up, nil until the first success
+	fetchedAt time.Time
+}
+
+func NewIdentityHandler(provider IdentityProvider, logger *logrus.Logger) *IdentityHandler {
+	return &IdentityHandler{
+		provider: provider,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// callerIdentity returns the cached identity while it is younger than
+// identityCacheTTL and looks it up otherwise. Failures are not cached.
+func (h *IdentityHandler) callerIdentity(ctx context.Context) (*types.CallerIdentity, error) {
+	h.mutex.Lock()
+	if h.identity != nil && h.now().Sub(h.fetchedAt) < identityCacheTTL {
+		identity := h.identity
+		h.mutex.Unlock()
+		return identity, nil
+	}
+	h.mutex.Unlock()
+
+	identity, err := h.provider.CallerIdentity(ctx)
+	if err != nil || identity == nil {
+		return identity, err
+	}
+
+	h.mutex.Lock()
+	h.identity = identity
+	h.fetchedAt = h.now()
+	h.mutex.Unlock()
+	return identity, nil
+}
+
+func (h *IdentityHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.WithField("endpoint", "/debug/identity")
+
+	ctx, cancel := context.WithTimeout(r.Context(), identityTimeout)
+	defer cancel()
+
+	identity, err := h.callerIdentity(ctx)
+	if err != nil {
+		logger.WithError(err).Error("Failed to look up caller identity")
+		http.Error(w, "Failed to look up caller identity", http.StatusBadGateway)
+		return
+	}
+	if identity == nil {
+		http.Error(w, "The vulnerability source does not expose a caller identity", http.StatusNotFound)
+		return
+	}
+
+	// The identity can change with the next lookup, so clients must not cache it
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(identity); err != nil {
+		logger.WithError(err).Error("Failed to encode identity response")
+	}
+}
+
+// CreateIdentityHandler creates a standard HTTP handler
+func CreateIdentityHandler(provider IdentityProvider, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewIdentityHandler(provider, logger)
+	return handler.ServeHTTP
+}
@@ -0,0 +1,128 @@
+// ABOUTME: Unit tests for the /debug/identity diagnostics endpoint.
+// ABOUTME: Tests identity reporting, sources without an identity and lookup errors.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+type stubIdentityProvider struct {
+	identity *types.CallerIdentity
+	err      error
+	calls    int
+}
+
+func (s *stubIdentityProvider) CallerIdentity(ctx context.Context) (*types.CallerIdentity, error) {
+	s.calls++
+	return s.identity, s.err
+}
+
+func TestIdentityHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	assumedRole := &types.CallerIdentity{
+		Account: "123456789012",
+		ARN:     "arn:aws:sts::123456789012:assumed-role/ECRVulnerabilityExporterRole/vulnrelay",
+		UserID:  "AROAEXAMPLE:vulnrelay",
+	}
+
+	tests := []struct {
+		name           string
+		provider       *stubIdentityProvider
+		expectedStatus int
+		expected       *types.CallerIdentity
+	}{
+		{
+			name:           "reports the source identity",
+			provider:       &stubIdentityProvider{identity: assumedRole},
+			expectedStatus: http.StatusOK,
+			expected:       assumedRole,
+		},
+		{
+			name:           "source without an identity",
+			provider:       &stubIdentityProvider{},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "lookup failure",
+			provider:       &stubIdentityProvider{err: errors.New("ExpiredToken")},
+			expectedStatus: http.StatusBadGateway,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := CreateIdentityHandler(tt.provider, logger)
+
+			req := httptest.NewRequest("GET", "/debug/identity", nil)
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expected == nil {
+				return
+			}
+
+			var identity types.CallerIdentity
+			if err := json.Unmarshal(w.Body.Bytes(), &identity); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if identity != *tt.expected {
+				t.Errorf("Expected identity %+v, got %+v", *tt.expected, identity)
+			}
+			if w.Header().Get("Cache-Control") != "no-store" {
+				t.Errorf("Expected Cache-Control no-store, got %q", w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func TestIdentityHandlerCache(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	provider := &stubIdentityProvider{err: errors.New("ExpiredToken")}
+	now := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	handler := NewIdentityHandler(provider, logger)
+	handler.now = func() time.Time { return now }
+
+	serve := func() int {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/debug/identity", nil))
+		return w.Code
+	}
+
+	// Failures are looked up again on the next request
+	serve()
+	provider.err = nil
+	provider.identity = &types.CallerIdentity{Account: "123456789012"}
+	if code := serve(); code != http.StatusOK || provider.calls != 2 {
+		t.Fatalf("Expected a new lookup after a failure, got status %d after %d calls", code, provider.calls)
+	}
+
+	// A successful lookup is served until it expires
+	now = now.Add(identityCacheTTL - time.Second)
+	serve()
+	if provider.calls != 2 {
+		t.Errorf("Expected the cached identity to be served, got %d calls", provider.calls)
+	}
+	now = now.Add(time.Second)
+	serve()
+	if provider.calls != 3 {
+		t.Errorf("Expected a new lookup once the identity expired, got %d calls", provider.calls)
+	}
+}
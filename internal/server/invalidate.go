@@ -0,0 +1,70 @@
+// ABOUTME: HTTP handler for the /cache/invalidate endpoint.
+// ABOUTME: Drops one image's cached vulnerability data so the next collection re-fetches it.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/sirupsen/logrus"
+)
+
+// CacheInvalidator drops cached vulnerability data of single images
+type CacheInvalidator interface {
+	// InvalidateImage reports whether the image was cached
+	InvalidateImage(imageURI string) bool
+}
+
+// InvalidateResponse confirms which image was invalidated
+type InvalidateResponse struct {
+	ImageURI    string `json:"image_uri"`
+	Invalidated bool   `json:"invalidated"`
+}
+
+type InvalidateHandler struct {
+	invalidator CacheInvalidator
+	logger      *logrus.Logger
+}
+
+func NewInvalidateHandler(invalidator CacheInvalidator, logger *logrus.Logger) *InvalidateHandler {
+	return &InvalidateHandler{
+		invalidator: invalidator,
+		logger:      logger,
+	}
+}
+
+func (h *InvalidateHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger.WithField("endpoint", "/cache/invalidate")
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	imageURI := r.URL.Query().Get("image_uri")
+	if imageURI == "" {
+		http.Error(w, "Missing image_uri parameter", http.StatusBadRequest)
+		return
+	}
+
+	if !h.invalidator.InvalidateImage(imageURI) {
+		http.Error(w, "Image is not cached", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(InvalidateResponse{ImageURI: imageURI, Invalidated: true}); err != nil {
+		logger.WithError(err).Error("Failed to encode invalidate response")
+		return
+	}
+
+	logger.WithField("image", imageURI).Info("Invalidated cached vulnerability data")
+}
+
+// CreateInvalidateHandler creates a standard HTTP handler
+func CreateInvalidateHandler(invalidator CacheInvalidator, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewInvalidateHandler(invalidator, logger)
+	return handler.ServeHTTP
+}
@@ -0,0 +1,102 @@
+// ABOUTME: Unit tests for the /cache/invalidate endpoint.
+// ABOUTME: Tests invalidating cached images, unknown images and invalid requests.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stubInvalidator tracks cached images and records invalidations
+type stubInvalidator struct {
+	cached      map[string]bool
+	invalidated []string
+}
+
+func (s *stubInvalidator) InvalidateImage(imageURI string) bool {
+	s.invalidated = append(s.invalidated, imageURI)
+	cached := s.cached[imageURI]
+	delete(s.cached, imageURI)
+	return cached
+}
+
+func TestInvalidateHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	patched := "123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1"
+
+	tests := []struct {
+		name                string
+		method              string
+		target              string
+		expectedStatus      int
+		expectedInvalidated []string
+	}{
+		{
+			name:                "cached image",
+			method:              "POST",
+			target:              "/cache/invalidate?image_uri=" + patched,
+			expectedStatus:      http.StatusOK,
+			expectedInvalidated: []string{patched},
+		},
+		{
+			name:                "unknown image",
+			method:              "POST",
+			target:              "/cache/invalidate?image_uri=123456789012.dkr.ecr.us-east-1.amazonaws.com/unknown:v1",
+			expectedStatus:      http.StatusNotFound,
+			expectedInvalidated: []string{"123456789012.dkr.ecr.us-east-1.amazonaws.com/unknown:v1"},
+		},
+		{
+			name:           "missing image_uri",
+			method:         "POST",
+			target:         "/cache/invalidate",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "GET is rejected",
+			method:         "GET",
+			target:         "/cache/invalidate?image_uri=" + patched,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalidator := &stubInvalidator{cached: map[string]bool{patched: true}}
+			handler := CreateInvalidateHandler(invalidator, logger)
+
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if len(invalidator.invalidated) != len(tt.expectedInvalidated) {
+				t.Fatalf("Expected invalidations %v, got %v", tt.expectedInvalidated, invalidator.invalidated)
+			}
+			for i := range tt.expectedInvalidated {
+				if invalidator.invalidated[i] != tt.expectedInvalidated[i] {
+					t.Errorf("Expected invalidations %v, got %v", tt.expectedInvalidated, invalidator.invalidated)
+				}
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response InvalidateResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.ImageURI != patched || !response.Invalidated {
+				t.Errorf("Unexpected response %+v", response)
+			}
+		})
+	}
+}
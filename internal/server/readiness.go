@@ -0,0 +1,145 @@
+// ABOUTME: HTTP handler for the /ready readiness endpoint.
+// ABOUTME: Optionally reports not-ready on failed scans or findings at or above a severity threshold.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/jfeddern/VulnRelay/internal/severity"
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReadinessOptions configures when the service reports itself as not ready
+type ReadinessOptions struct {
+	// FailOnScanErrors makes readiness depend on the fraction of failed scans
+	FailOnScanErrors bool
+
+	// ScanErrorThreshold is the highest tolerated fraction (0-1) of failed scans
+	ScanErrorThreshold float64
+
+	// SeverityThreshold makes readiness fail while any image has active
+	// findings of this severity or a more severe one; less severe findings
+	// never affect readiness (empty = findings are ignored)
+	SeverityThreshold string
+
+	// SeverityOrder ranks severities for SeverityThreshold, most severe
+	// first (empty = severity.DefaultOrder)
+	SeverityOrder []string
+}
+
+// notReady reports whether a failed scan fraction or the number of blocking
+// findings makes the service not ready
+func (o ReadinessOptions) notReady(failedFraction float64, blockingFindings int) bool {
+	return (o.FailOnScanErrors && failedFraction > o.ScanErrorThreshold) || blockingFindings > 0
+}
+
+// countBlockingFindings returns the number of active findings at or above the
+// severity threshold; accepted CVEs are already left out of the counts
+func (o ReadinessOptions) countBlockingFindings(data map[string]*types.ImageVulnerabilityData) int {
+	if o.SeverityThreshold == "" {
+		return 0
+	}
+
+	severities := severity.NewOrder(o.SeverityOrder)
+	threshold := strings.ToUpper(o.SeverityThreshold)
+	if !severities.Known(threshold) {
+		return 0
+	}
+
+	blocking := 0
+	for _, vulnData := range data {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+		for severity, count := range vulnData.Vulnerabilities {
+			if severities.Known(severity) && !severities.MoreSevere(threshold, severity) {
+				blocking += count
+			}
+		}
+	}
+	return blocking
+}
+
+type ReadinessResponse struct {
+	Status           string  `json:"status"`
+	FailedScans      int     `json:"failed_scans"`
+	TotalScans       int     `json:"total_scans"`
+	FailedFraction   float64 `json:"failed_fraction"`
+	BlockingFindings int     `json:"blocking_findings"` // Findings at or above the severity threshold
+}
+
+type ReadinessHandler struct {
+	collector VulnerabilityDataProvider
+	options   ReadinessOptions
+	logger    *logrus.Logger
+}
+
+func NewReadinessHandler(collector VulnerabilityDataProvider, options ReadinessOptions, logger *logrus.Logger) *ReadinessHandler {
+	return &ReadinessHandler{
+		collector: collector,
+		options:   options,
+		logger:    logger,
+	}
+}
+
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vulnerabilityData, _ := h.collector.GetVulnerabilityData()
+
+	failed, total := countFailedScans(vulnerabilityData)
+	response := ReadinessResponse{
+		Status:           "ready",
+		FailedScans:      failed,
+		TotalScans:       total,
+		BlockingFindings: h.options.countBlockingFindings(vulnerabilityData),
+	}
+	if total > 0 {
+		response.FailedFraction = float64(failed) / float64(total)
+	}
+
+	status := http.StatusOK
+	if h.options.notReady(response.FailedFraction, response.BlockingFindings) {
+		response.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+
+		h.logger.WithFields(logrus.Fields{
+			"endpoint":           "/ready",
+			"failed_scans":       failed,
+			"total_scans":        total,
+			"failed_fraction":    response.FailedFraction,
+			"threshold":          h.options.ScanErrorThreshold,
+			"blocking_findings":  response.BlockingFindings,
+			"severity_threshold": h.options.SeverityThreshold,
+		}).Warn("Reporting not ready due to failed scans or blocking findings")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.WithError(err).Error("Failed to encode readiness response")
+	}
+}
+
+// countFailedScans returns how many images have a FAILED or unknown scan status
+func countFailedScans(data map[string]*types.ImageVulnerabilityData) (failed, total int) {
+	for _, vulnData := range data {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+		total++
+		if vulnData.ScanStatus == "FAILED" || vulnData.ScanStatus == "" {
+			failed++
+		}
+	}
+	return failed, total
+}
+
+// CreateReadinessHandler creates a standard HTTP handler
+func CreateReadinessHandler(dataProvider VulnerabilityDataProvider, options ReadinessOptions, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewReadinessHandler(dataProvider, options, logger)
+	return handler.ServeHTTP
+}
@@ -0,0 +1,194 @@
+// ABOUTME: Unit tests for the /ready readiness endpoint.
+// ABOUTME: Tests failed-scan fraction calculation and the readiness threshold.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// scanStatusTestData returns one image per given scan status
+func scanStatusTestData(statuses ...string) map[string]*types.ImageVulnerabilityData {
+	data := make(map[string]*types.ImageVulnerabilityData)
+	for i, status := range statuses {
+		uri := fmt.Sprintf("123456789012.dkr.ecr.us-east-1.amazonaws.com/app-%d:v1", i)
+		data[uri] = &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{},
+				ScanStatus:      status,
+			},
+			ImageInfo: types.ImageInfo{URI: uri},
+		}
+	}
+	return data
+}
+
+func TestReadinessHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Three of four scans failed (one with an unknown status)
+	mostlyFailed := scanStatusTestData("FAILED", "FAILED", "", "COMPLETE")
+
+	tests := []struct {
+		name           string
+		data           map[string]*types.ImageVulnerabilityData
+		options        ReadinessOptions
+		expectedStatus int
+		expectedState  string
+	}{
+		{
+			name:           "failed scans ignored by default",
+			data:           mostlyFailed,
+			options:        ReadinessOptions{},
+			expectedStatus: http.StatusOK,
+			expectedState:  "ready",
+		},
+		{
+			name:           "failed fraction above threshold",
+			data:           mostlyFailed,
+			options:        ReadinessOptions{FailOnScanErrors: true, ScanErrorThreshold: 0.5},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedState:  "not_ready",
+		},
+		{
+			name:           "failed fraction within threshold",
+			data:           mostlyFailed,
+			options:        ReadinessOptions{FailOnScanErrors: true, ScanErrorThreshold: 0.8},
+			expectedStatus: http.StatusOK,
+			expectedState:  "ready",
+		},
+		{
+			name:           "zero threshold fails on any failed scan",
+			data:           scanStatusTestData("COMPLETE", "COMPLETE", "FAILED"),
+			options:        ReadinessOptions{FailOnScanErrors: true},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedState:  "not_ready",
+		},
+		{
+			name:           "all scans complete",
+			data:           scanStatusTestData("COMPLETE", "COMPLETE"),
+			options:        ReadinessOptions{FailOnScanErrors: true},
+			expectedStatus: http.StatusOK,
+			expectedState:  "ready",
+		},
+		{
+			name:           "no data yet",
+			data:           scanStatusTestData(),
+			options:        ReadinessOptions{FailOnScanErrors: true},
+			expectedStatus: http.StatusOK,
+			expectedState:  "ready",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &MockVulnerabilityCollector{data: tt.data, lastUpdated: time.Now()}
+			handler := NewReadinessHandler(collector, tt.options, logger)
+
+			req := httptest.NewRequest("GET", "/ready", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+
+			var response ReadinessResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Status != tt.expectedState {
+				t.Errorf("Expected status %q, got %q", tt.expectedState, response.Status)
+			}
+			if response.TotalScans != len(tt.data) {
+				t.Errorf("Expected %d total scans, got %d", len(tt.data), response.TotalScans)
+			}
+		})
+	}
+}
+
+func TestReadinessHandlerSeverityThreshold(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	withCounts := func(counts ...map[string]int) map[string]*types.ImageVulnerabilityData {
+		statuses := make([]string, len(counts))
+		for i := range statuses {
+			statuses[i] = "COMPLETE"
+		}
+		data := scanStatusTestData(statuses...)
+		for i, c := range counts {
+			data[fmt.Sprintf("123456789012.dkr.ecr.us-east-1.amazonaws.com/app-%d:v1", i)].Vulnerabilities = c
+		}
+		return data
+	}
+	noisy := withCounts(map[string]int{"MEDIUM": 4, "LOW": 10}, map[string]int{"LOW": 2, "INFORMATIONAL": 3})
+	withHigh := withCounts(map[string]int{"MEDIUM": 4, "LOW": 10}, map[string]int{"HIGH": 2, "LOW": 1})
+	withCritical := withCounts(map[string]int{"CRITICAL": 1, "LOW": 10})
+
+	tests := []struct {
+		name             string
+		data             map[string]*types.ImageVulnerabilityData
+		options          ReadinessOptions
+		expectedStatus   int
+		expectedBlocking int
+	}{
+		{"findings ignored without threshold", withCritical, ReadinessOptions{}, http.StatusOK, 0},
+		{"medium and low below HIGH", noisy, ReadinessOptions{SeverityThreshold: "HIGH"}, http.StatusOK, 0},
+		{"high at HIGH", withHigh, ReadinessOptions{SeverityThreshold: "HIGH"}, http.StatusServiceUnavailable, 2},
+		{"critical above HIGH", withCritical, ReadinessOptions{SeverityThreshold: "HIGH"}, http.StatusServiceUnavailable, 1},
+		{"high below CRITICAL", withHigh, ReadinessOptions{SeverityThreshold: "CRITICAL"}, http.StatusOK, 0},
+		{"case-insensitive threshold", withHigh, ReadinessOptions{SeverityThreshold: "high"}, http.StatusServiceUnavailable, 2},
+		{"unknown severities never block", noisy, ReadinessOptions{SeverityThreshold: "LOW"}, http.StatusServiceUnavailable, 16},
+		{
+			name:             "custom severity order",
+			data:             noisy,
+			options:          ReadinessOptions{SeverityThreshold: "INFORMATIONAL", SeverityOrder: []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "INFORMATIONAL"}},
+			expectedStatus:   http.StatusServiceUnavailable,
+			expectedBlocking: 19,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &MockVulnerabilityCollector{data: tt.data, lastUpdated: time.Now()}
+			rr := httptest.NewRecorder()
+			NewReadinessHandler(collector, tt.options, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/ready", nil))
+
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			var response ReadinessResponse
+			if err := json.NewDecoder(rr.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.BlockingFindings != tt.expectedBlocking {
+				t.Errorf("Expected %d blocking findings, got %d", tt.expectedBlocking, response.BlockingFindings)
+			}
+		})
+	}
+}
+
+func TestCountFailedScans(t *testing.T) {
+	data := scanStatusTestData("FAILED", "", "COMPLETE", "IN_PROGRESS")
+	data["nil"] = &types.ImageVulnerabilityData{}
+
+	failed, total := countFailedScans(data)
+	if failed != 2 {
+		t.Errorf("Expected 2 failed scans, got %d", failed)
+	}
+	if total != 4 {
+		t.Errorf("Expected 4 total scans, got %d", total)
+	}
+}
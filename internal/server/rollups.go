@@ -0,0 +1,275 @@
+// ABOUTME: HTTP handlers for aggregated vulnerability rollup endpoints.
+// ABOUTME: Groups per-image vulnerability data by namespace or workload for team-level views.
+
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/jfeddern/VulnRelay/internal/severity"
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+type NamespaceRollup struct {
+	Namespace            string         `json:"namespace"`
+	ImageCount           int            `json:"image_count"`
+	TotalVulnerabilities int            `json:"total_vulnerabilities"`
+	SeverityBreakdown    map[string]int `json:"severity_breakdown"`
+}
+
+type NamespacesResponse struct {
+	Namespaces  []NamespaceRollup `json:"namespaces"`
+	LastUpdated string            `json:"last_updated"`
+}
+
+type WorkloadRollup struct {
+	Namespace            string         `json:"namespace"`
+	Workload             string         `json:"workload"`
+	WorkloadType         string         `json:"workload_type"`
+	ImageCount           int            `json:"image_count"`
+	TotalVulnerabilities int            `json:"total_vulnerabilities"`
+	SeverityBreakdown    map[string]int `json:"severity_breakdown"`
+	TopCVEs              []CVESummary   `json:"top_cves"`
+}
+
+type WorkloadsResponse struct {
+	Workloads   []WorkloadRollup `json:"workloads"`
+	LastUpdated string           `json:"last_updated"`
+}
+
+// Rollup counting modes selected with the count_by query parameter
+const (
+	countByImage      = "image"      // Every finding of every image counts
+	countByRepository = "repository" // A CVE counts once per repository, however many tags have it
+)
+
+// rollup accumulates vulnerability counts for a group of images
+type rollup struct {
+	images               []*types.ImageVulnerabilityData
+	totalVulnerabilities int
+	severityBreakdown    map[string]int
+	cves                 map[string]*CVESummary
+	counted              map[repositoryCVE]bool // CVEs already counted, in repository mode
+}
+
+// repositoryCVE identifies a CVE within a repository
+type repositoryCVE struct {
+	repository string
+	cve        string
+}
+
+// parseCountBy validates the count_by query parameter, defaulting to per-image counts
+func parseCountBy(r *http.Request) (string, bool) {
+	countBy := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("count_by")))
+	switch countBy {
+	case "":
+		return countByImage, true
+	case countByImage, countByRepository:
+		return countBy, true
+	default:
+		return "", false
+	}
+}
+
+// groupVulnerabilities aggregates image vulnerability data into rollups keyed
+// by keyFunc. In repository mode counts come from the findings, with each CVE
+// counted once per repository so tags of the same image don't inflate them.
+func groupVulnerabilities(data map[string]*types.ImageVulnerabilityData, keyFunc func(*types.ImageVulnerabilityData) string, countBy string) map[string]*rollup {
+	groups := make(map[string]*rollup)
+
+	for _, vulnData := range data {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+
+		key := keyFunc(vulnData)
+		group, exists := groups[key]
+		if !exists {
+			group = &rollup{
+				severityBreakdown: make(map[string]int),
+				cves:              make(map[string]*CVESummary),
+			}
+			groups[key] = group
+		}
+
+		group.images = append(group.images, vulnData)
+		if countBy == countByRepository {
+			group.countRepositoryCVEs(vulnData)
+		} else {
+			for severity, count := range vulnData.Vulnerabilities {
+				group.severityBreakdown[severity] += count
+				group.totalVulnerabilities += count
+			}
+		}
+		trackCVEs(group.cves, vulnData.Findings)
+	}
+
+	return groups
+}
+
+// countRepositoryCVEs counts the image's active findings whose CVE was not yet
+// counted for its repository
+func (g *rollup) countRepositoryCVEs(vulnData *types.ImageVulnerabilityData) {
+	if g.counted == nil {
+		g.counted = make(map[repositoryCVE]bool)
+	}
+
+	// Sources that don't report a repository fall back to per-image counting
+	repository := vulnData.Repository
+	if repository == "" {
+		repository = vulnData.ImageURI
+	}
+
+	for _, finding := range vulnData.Findings {
+		if finding.Accepted {
+			continue
+		}
+		key := repositoryCVE{repository: repository, cve: finding.Name}
+		if finding.Name != "" && g.counted[key] {
+			continue
+		}
+		g.counted[key] = true
+		g.severityBreakdown[finding.Severity]++
+		g.totalVulnerabilities++
+	}
+}
+
+type NamespacesHandler struct {
+	collector VulnerabilityDataProvider
+	pretty    bool // Indent responses by default
+	logger    *logrus.Logger
+}
+
+func NewNamespacesHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *NamespacesHandler {
+	return &NamespacesHandler{
+		collector: collector,
+		pretty:    options.PrettyPrint,
+		logger:    logger,
+	}
+}
+
+func (n *NamespacesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := n.logger.WithField("endpoint", "/vulnerabilities/namespaces")
+
+	countBy, ok := parseCountBy(r)
+	if !ok {
+		http.Error(w, "Invalid count_by. Must be one of: image, repository", http.StatusBadRequest)
+		return
+	}
+
+	vulnerabilityData, lastCollectionTime := n.collector.GetVulnerabilityData()
+
+	groups := groupVulnerabilities(vulnerabilityData, func(data *types.ImageVulnerabilityData) string {
+		return data.Namespace
+	}, countBy)
+
+	namespaces := make([]NamespaceRollup, 0, len(groups))
+	for namespace, group := range groups {
+		namespaces = append(namespaces, NamespaceRollup{
+			Namespace:            namespace,
+			ImageCount:           len(group.images),
+			TotalVulnerabilities: group.totalVulnerabilities,
+			SeverityBreakdown:    group.severityBreakdown,
+		})
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Namespace < namespaces[j].Namespace
+	})
+
+	response := NamespacesResponse{
+		Namespaces:  namespaces,
+		LastUpdated: lastCollectionTime.Format("2006-01-02T15:04:05Z"),
+	}
+
+	if err := writeJSON(w, r, response, n.pretty); err != nil {
+		logger.WithError(err).Error("Failed to encode JSON response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.WithField("namespaces", len(namespaces)).Info("Served namespace rollup response")
+}
+
+// CreateNamespacesHandler creates a standard HTTP handler
+func CreateNamespacesHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewNamespacesHandler(dataProvider, options, logger)
+	return handler.ServeHTTP
+}
+
+type WorkloadsHandler struct {
+	collector  VulnerabilityDataProvider
+	severities severity.Order // Ranks top CVEs
+	pretty     bool           // Indent responses by default
+	logger     *logrus.Logger
+}
+
+func NewWorkloadsHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *WorkloadsHandler {
+	return &WorkloadsHandler{
+		collector:  collector,
+		severities: severity.NewOrder(options.SeverityOrder),
+		pretty:     options.PrettyPrint,
+		logger:     logger,
+	}
+}
+
+func (wh *WorkloadsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	logger := wh.logger.WithField("endpoint", "/vulnerabilities/workloads")
+
+	countBy, ok := parseCountBy(r)
+	if !ok {
+		http.Error(w, "Invalid count_by. Must be one of: image, repository", http.StatusBadRequest)
+		return
+	}
+
+	vulnerabilityData, lastCollectionTime := wh.collector.GetVulnerabilityData()
+
+	groups := groupVulnerabilities(vulnerabilityData, func(data *types.ImageVulnerabilityData) string {
+		return data.Namespace + "/" + data.WorkloadType + "/" + data.Workload
+	}, countBy)
+
+	workloads := make([]WorkloadRollup, 0, len(groups))
+	for _, group := range groups {
+		info := group.images[0].ImageInfo
+		workloads = append(workloads, WorkloadRollup{
+			Namespace:            info.Namespace,
+			Workload:             info.Workload,
+			WorkloadType:         info.WorkloadType,
+			ImageCount:           len(group.images),
+			TotalVulnerabilities: group.totalVulnerabilities,
+			SeverityBreakdown:    group.severityBreakdown,
+			TopCVEs:              rankCVEs(group.cves, 5, wh.severities),
+		})
+	}
+	sort.Slice(workloads, func(i, j int) bool {
+		if workloads[i].Namespace != workloads[j].Namespace {
+			return workloads[i].Namespace < workloads[j].Namespace
+		}
+		if workloads[i].Workload != workloads[j].Workload {
+			return workloads[i].Workload < workloads[j].Workload
+		}
+		return workloads[i].WorkloadType < workloads[j].WorkloadType
+	})
+
+	response := WorkloadsResponse{
+		Workloads:   workloads,
+		LastUpdated: lastCollectionTime.Format("2006-01-02T15:04:05Z"),
+	}
+
+	if err := writeJSON(w, r, response, wh.pretty); err != nil {
+		logger.WithError(err).Error("Failed to encode JSON response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.WithField("workloads", len(workloads)).Info("Served workload rollup response")
+}
+
+// CreateWorkloadsHandler creates a standard HTTP handler
+func CreateWorkloadsHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewWorkloadsHandler(dataProvider, options, logger)
+	return handler.ServeHTTP
+}
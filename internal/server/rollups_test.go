@@ -0,0 +1,278 @@
+// ABOUTME: Unit tests for aggregated vulnerability rollup endpoints.
+// ABOUTME: Tests grouping of image data by namespace and workload, and response structure.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+// rollupTestData returns images spread across two namespaces
+func rollupTestData() map[string]*types.ImageVulnerabilityData {
+	data := map[string]*types.ImageVulnerabilityData{
+		"web:v1": testImage("123456789012.dkr.ecr.us-east-1.amazonaws.com/web:v1", "production", "web",
+			highFindings("CVE-2024-0001", "CVE-2024-0002")...),
+		"web-sidecar:v1": testImage("123456789012.dkr.ecr.us-east-1.amazonaws.com/web-sidecar:v1", "production", "web",
+			highFindings("CVE-2024-0002")...),
+		"api:v2": testImage("123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v2", "production", "api"),
+		"worker:dev": testImage("123456789012.dkr.ecr.us-east-1.amazonaws.com/worker:dev", "staging", "worker",
+			highFindings("CVE-2024-0003")...),
+	}
+	data["web:v1"].Vulnerabilities = map[string]int{"CRITICAL": 1, "HIGH": 2}
+	data["web-sidecar:v1"].Vulnerabilities = map[string]int{"HIGH": 1, "LOW": 3}
+	data["api:v2"].Vulnerabilities = map[string]int{"MEDIUM": 4}
+	data["worker:dev"].Vulnerabilities = map[string]int{"CRITICAL": 2, "LOW": 1}
+	return data
+}
+
+func TestNamespacesHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &MockVulnerabilityCollector{data: rollupTestData(), lastUpdated: time.Now()}
+	handler := NewNamespacesHandler(collector, Options{}, logger)
+
+	req := httptest.NewRequest("GET", "/vulnerabilities/namespaces", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response NamespacesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Namespaces) != 2 {
+		t.Fatalf("Expected 2 namespaces, got %d", len(response.Namespaces))
+	}
+
+	// Namespaces are sorted by name
+	production := response.Namespaces[0]
+	staging := response.Namespaces[1]
+
+	if production.Namespace != "production" {
+		t.Errorf("Expected first namespace 'production', got '%s'", production.Namespace)
+	}
+	if production.ImageCount != 3 {
+		t.Errorf("Expected 3 images in production, got %d", production.ImageCount)
+	}
+	if production.TotalVulnerabilities != 11 {
+		t.Errorf("Expected 11 vulnerabilities in production, got %d", production.TotalVulnerabilities)
+	}
+	expectedProduction := map[string]int{"CRITICAL": 1, "HIGH": 3, "MEDIUM": 4, "LOW": 3}
+	for severity, count := range expectedProduction {
+		if production.SeverityBreakdown[severity] != count {
+			t.Errorf("Expected production %s=%d, got %d", severity, count, production.SeverityBreakdown[severity])
+		}
+	}
+
+	if staging.Namespace != "staging" {
+		t.Errorf("Expected second namespace 'staging', got '%s'", staging.Namespace)
+	}
+	if staging.ImageCount != 1 {
+		t.Errorf("Expected 1 image in staging, got %d", staging.ImageCount)
+	}
+	if staging.TotalVulnerabilities != 3 {
+		t.Errorf("Expected 3 vulnerabilities in staging, got %d", staging.TotalVulnerabilities)
+	}
+	if staging.SeverityBreakdown["CRITICAL"] != 2 {
+		t.Errorf("Expected staging CRITICAL=2, got %d", staging.SeverityBreakdown["CRITICAL"])
+	}
+}
+
+func TestNamespacesHandlerEmpty(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &MockVulnerabilityCollector{data: map[string]*types.ImageVulnerabilityData{}, lastUpdated: time.Now()}
+	handler := NewNamespacesHandler(collector, Options{}, logger)
+
+	req := httptest.NewRequest("GET", "/vulnerabilities/namespaces", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var response NamespacesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Namespaces == nil || len(response.Namespaces) != 0 {
+		t.Errorf("Expected empty namespaces array, got %v", response.Namespaces)
+	}
+}
+
+func TestWorkloadsHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &MockVulnerabilityCollector{data: rollupTestData(), lastUpdated: time.Now()}
+	handler := NewWorkloadsHandler(collector, Options{}, logger)
+
+	req := httptest.NewRequest("GET", "/vulnerabilities/workloads", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+
+	var response WorkloadsResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if len(response.Workloads) != 3 {
+		t.Fatalf("Expected 3 workloads, got %d", len(response.Workloads))
+	}
+
+	// Sorted by namespace, then workload
+	api, web, worker := response.Workloads[0], response.Workloads[1], response.Workloads[2]
+
+	if api.Namespace != "production" || api.Workload != "api" {
+		t.Errorf("Expected production/api first, got %s/%s", api.Namespace, api.Workload)
+	}
+	if api.TotalVulnerabilities != 4 || api.SeverityBreakdown["MEDIUM"] != 4 {
+		t.Errorf("Expected api to have 4 MEDIUM vulnerabilities, got %v", api.SeverityBreakdown)
+	}
+
+	if web.Workload != "web" || web.WorkloadType != "Deployment" {
+		t.Errorf("Expected production/web Deployment second, got %s %s", web.Workload, web.WorkloadType)
+	}
+	if web.ImageCount != 2 {
+		t.Errorf("Expected web to aggregate 2 images, got %d", web.ImageCount)
+	}
+	if web.TotalVulnerabilities != 7 {
+		t.Errorf("Expected web to have 7 vulnerabilities, got %d", web.TotalVulnerabilities)
+	}
+	expectedWeb := map[string]int{"CRITICAL": 1, "HIGH": 3, "LOW": 3}
+	for severity, count := range expectedWeb {
+		if web.SeverityBreakdown[severity] != count {
+			t.Errorf("Expected web %s=%d, got %d", severity, count, web.SeverityBreakdown[severity])
+		}
+	}
+	if len(web.TopCVEs) != 2 {
+		t.Fatalf("Expected 2 top CVEs for web, got %d", len(web.TopCVEs))
+	}
+	if web.TopCVEs[0].Name != "CVE-2024-0002" || web.TopCVEs[0].ImageCount != 2 {
+		t.Errorf("Expected CVE-2024-0002 in 2 images to rank first, got %+v", web.TopCVEs[0])
+	}
+
+	if worker.Namespace != "staging" || worker.Workload != "worker" {
+		t.Errorf("Expected staging/worker last, got %s/%s", worker.Namespace, worker.Workload)
+	}
+	if worker.SeverityBreakdown["CRITICAL"] != 2 {
+		t.Errorf("Expected worker CRITICAL=2, got %d", worker.SeverityBreakdown["CRITICAL"])
+	}
+}
+
+func TestRollupsCountByRepository(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Three tags of one repository share CVE-2024-0001; one tag also has an
+	// accepted CVE, and another repository has the same CVE
+	newImage := func(repository, tag string, findings ...types.VulnerabilityFinding) *types.ImageVulnerabilityData {
+		uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + repository + ":" + tag
+		counts := make(map[string]int)
+		for _, finding := range findings {
+			if !finding.Accepted {
+				counts[finding.Severity]++
+			}
+		}
+		return &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Repository:      repository,
+				Tag:             tag,
+				Vulnerabilities: counts,
+				Findings:        findings,
+			},
+			ImageInfo: types.ImageInfo{URI: uri, Namespace: "production", Workload: "web", WorkloadType: "Deployment"},
+		}
+	}
+	shared := types.VulnerabilityFinding{Name: "CVE-2024-0001", Severity: "CRITICAL"}
+	data := map[string]*types.ImageVulnerabilityData{
+		"web:v1":     newImage("web", "v1", shared),
+		"web:v2":     newImage("web", "v2", shared, types.VulnerabilityFinding{Name: "CVE-2024-0002", Severity: "HIGH"}),
+		"web:v3":     newImage("web", "v3", shared, types.VulnerabilityFinding{Name: "CVE-2024-0003", Severity: "LOW", Accepted: true}),
+		"sidecar:v1": newImage("sidecar", "v1", shared),
+	}
+	collector := &MockVulnerabilityCollector{data: data, lastUpdated: time.Now()}
+
+	tests := []struct {
+		name              string
+		query             string
+		expectedStatus    int
+		expectedTotal     int
+		expectedBreakdown map[string]int
+	}{
+		{
+			name:              "per image by default",
+			query:             "",
+			expectedStatus:    http.StatusOK,
+			expectedTotal:     5,
+			expectedBreakdown: map[string]int{"CRITICAL": 4, "HIGH": 1},
+		},
+		{
+			name:              "unique CVEs per repository",
+			query:             "?count_by=repository",
+			expectedStatus:    http.StatusOK,
+			expectedTotal:     3,
+			expectedBreakdown: map[string]int{"CRITICAL": 2, "HIGH": 1},
+		},
+		{
+			name:           "invalid mode",
+			query:          "?count_by=tag",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Namespace rollup
+			rr := httptest.NewRecorder()
+			NewNamespacesHandler(collector, Options{}, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities/namespaces"+tt.query, nil))
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Namespaces: expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+			var namespaces NamespacesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &namespaces); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			namespace := namespaces.Namespaces[0]
+			if namespace.TotalVulnerabilities != tt.expectedTotal || !reflect.DeepEqual(namespace.SeverityBreakdown, tt.expectedBreakdown) {
+				t.Errorf("Namespaces: expected %d %v, got %d %v", tt.expectedTotal, tt.expectedBreakdown, namespace.TotalVulnerabilities, namespace.SeverityBreakdown)
+			}
+			if namespace.ImageCount != 4 {
+				t.Errorf("Namespaces: expected 4 images, got %d", namespace.ImageCount)
+			}
+
+			// Workload rollup
+			rr = httptest.NewRecorder()
+			NewWorkloadsHandler(collector, Options{}, logger).ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities/workloads"+tt.query, nil))
+			var workloads WorkloadsResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &workloads); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			workload := workloads.Workloads[0]
+			if workload.TotalVulnerabilities != tt.expectedTotal || !reflect.DeepEqual(workload.SeverityBreakdown, tt.expectedBreakdown) {
+				t.Errorf("Workloads: expected %d %v, got %d %v", tt.expectedTotal, tt.expectedBreakdown, workload.TotalVulnerabilities, workload.SeverityBreakdown)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// ABOUTME: HTTP handler for the compact /summary endpoint used by uptime checks.
+// ABOUTME: Reports image and critical counts, last collection time and readiness.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// SummaryResponse is a small status object for external monitoring
+type SummaryResponse struct {
+	TotalImages    int     `json:"total_images"`
+	TotalCriticals int     `json:"total_criticals"`
+	LastCollection *string `json:"last_collection"` // Null before the first collection
+	Ready          bool    `json:"ready"`
+}
+
+type SummaryHandler struct {
+	collector VulnerabilityDataProvider
+	options   ReadinessOptions
+	logger    *logrus.Logger
+}
+
+// NewSummaryHandler creates a summary handler; readiness follows the same
+// options as /ready
+func NewSummaryHandler(collector VulnerabilityDataProvider, options ReadinessOptions, logger *logrus.Logger) *SummaryHandler {
+	return &SummaryHandler{
+		collector: collector,
+		options:   options,
+		logger:    logger,
+	}
+}
+
+func (h *SummaryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	vulnerabilityData, lastCollectionTime := h.collector.GetVulnerabilityData()
+
+	response := SummaryResponse{TotalImages: len(vulnerabilityData)}
+	for _, vulnData := range vulnerabilityData {
+		if vulnData.ImageVulnerability == nil {
+			continue
+		}
+		// Counts already exclude accepted CVEs
+		response.TotalCriticals += vulnData.Vulnerabilities["CRITICAL"]
+	}
+
+	if !lastCollectionTime.IsZero() {
+		lastCollection := lastCollectionTime.UTC().Format(time.RFC3339)
+		response.LastCollection = &lastCollection
+	}
+
+	failed, total := countFailedScans(vulnerabilityData)
+	failedFraction := float64(0)
+	if total > 0 {
+		failedFraction = float64(failed) / float64(total)
+	}
+	response.Ready = !h.options.notReady(failedFraction, h.options.countBlockingFindings(vulnerabilityData))
+
+	// Always 200 so uptime checks can read the body; readiness is in the payload
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.WithError(err).Error("Failed to encode summary response")
+	}
+}
+
+// CreateSummaryHandler creates a standard HTTP handler
+func CreateSummaryHandler(dataProvider VulnerabilityDataProvider, options ReadinessOptions, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewSummaryHandler(dataProvider, options, logger)
+	return handler.ServeHTTP
+}
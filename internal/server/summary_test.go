@@ -0,0 +1,106 @@
+// ABOUTME: Unit tests for the compact /summary endpoint.
+// ABOUTME: Tests image and critical totals, collection time and readiness.
+
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jfeddern/VulnRelay/internal/types"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSummaryHandler(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Two completed scans with criticals and one failed scan
+	data := scanStatusTestData("COMPLETE", "COMPLETE", "FAILED")
+	data["123456789012.dkr.ecr.us-east-1.amazonaws.com/app-0:v1"].Vulnerabilities = map[string]int{"CRITICAL": 2, "HIGH": 5}
+	data["123456789012.dkr.ecr.us-east-1.amazonaws.com/app-1:v1"].Vulnerabilities = map[string]int{"CRITICAL": 1, "LOW": 3}
+	lastCollection := "2025-01-15T10:35:00Z"
+
+	// Two completed scans with only lower severity findings
+	lowData := scanStatusTestData("COMPLETE", "COMPLETE")
+	lowData["123456789012.dkr.ecr.us-east-1.amazonaws.com/app-0:v1"].Vulnerabilities = map[string]int{"MEDIUM": 4, "LOW": 2}
+	lowData["123456789012.dkr.ecr.us-east-1.amazonaws.com/app-1:v1"].Vulnerabilities = map[string]int{"LOW": 3}
+
+	tests := []struct {
+		name      string
+		collector *MockVulnerabilityCollector
+		options   ReadinessOptions
+		expected  SummaryResponse
+	}{
+		{
+			name:      "sample data",
+			collector: &MockVulnerabilityCollector{data: data, lastUpdated: time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+			expected:  SummaryResponse{TotalImages: 3, TotalCriticals: 3, LastCollection: &lastCollection, Ready: true},
+		},
+		{
+			name:      "not ready above the scan error threshold",
+			collector: &MockVulnerabilityCollector{data: data, lastUpdated: time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+			options:   ReadinessOptions{FailOnScanErrors: true, ScanErrorThreshold: 0.2},
+			expected:  SummaryResponse{TotalImages: 3, TotalCriticals: 3, LastCollection: &lastCollection, Ready: false},
+		},
+		{
+			name:      "low findings below the severity threshold",
+			collector: &MockVulnerabilityCollector{data: lowData, lastUpdated: time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+			options:   ReadinessOptions{SeverityThreshold: "HIGH"},
+			expected:  SummaryResponse{TotalImages: 2, LastCollection: &lastCollection, Ready: true},
+		},
+		{
+			name:      "not ready with findings at the severity threshold",
+			collector: &MockVulnerabilityCollector{data: lowData, lastUpdated: time.Date(2025, 1, 15, 10, 35, 0, 0, time.UTC)},
+			options:   ReadinessOptions{SeverityThreshold: "MEDIUM"},
+			expected:  SummaryResponse{TotalImages: 2, LastCollection: &lastCollection, Ready: false},
+		},
+		{
+			name:      "before the first collection",
+			collector: &MockVulnerabilityCollector{data: map[string]*types.ImageVulnerabilityData{}},
+			expected:  SummaryResponse{Ready: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewSummaryHandler(tt.collector, tt.options, logger)
+
+			req := httptest.NewRequest("GET", "/summary", nil)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("Expected Content-Type application/json, got %s", contentType)
+			}
+
+			var response SummaryResponse
+			if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+
+			if response.TotalImages != tt.expected.TotalImages {
+				t.Errorf("Expected total_images %d, got %d", tt.expected.TotalImages, response.TotalImages)
+			}
+			if response.TotalCriticals != tt.expected.TotalCriticals {
+				t.Errorf("Expected total_criticals %d, got %d", tt.expected.TotalCriticals, response.TotalCriticals)
+			}
+			if response.Ready != tt.expected.Ready {
+				t.Errorf("Expected ready %v, got %v", tt.expected.Ready, response.Ready)
+			}
+			switch {
+			case tt.expected.LastCollection == nil && response.LastCollection != nil:
+				t.Errorf("Expected null last_collection, got %q", *response.LastCollection)
+			case tt.expected.LastCollection != nil && (response.LastCollection == nil || *response.LastCollection != *tt.expected.LastCollection):
+				t.Errorf("Expected last_collection %q, got %v", *tt.expected.LastCollection, response.LastCollection)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jfeddern/VulnRelay/internal/severity"
 	"github.com/jfeddern/VulnRelay/internal/types"
 
 	"github.com/sirupsen/logrus"
@@ -20,22 +21,57 @@ type VulnerabilityDataProvider interface {
 	GetVulnerabilityData() (map[string]*types.ImageVulnerabilityData, time.Time)
 }
 
+// SnapshotProvider is optionally implemented by data providers that retain
+// earlier collections; age 1 is the previous collection.
+// GetSnapshotAndCurrent also returns the current data and collection time,
+// consistent with the snapshot.
+type SnapshotProvider interface {
+	GetSnapshot(age int) (map[string]*types.ImageVulnerabilityData, time.Time, bool)
+	GetSnapshotAndCurrent(age int) (map[string]*types.ImageVulnerabilityData, time.Time, map[string]*types.ImageVulnerabilityData, time.Time, bool)
+}
+
+// Options configures the vulnerability JSON endpoints
+type Options struct {
+	MaxFindings int // Global cap on findings returned across all images (0 = unlimited)
+
+	// SeverityOrder lists the known severities, most severe first. It ranks
+	// top CVEs and limits the severity filter (empty = severity.DefaultOrder).
+	SeverityOrder []string
+
+	// PrettyPrint indents JSON responses unless a request asks otherwise with
+	// the pretty query parameter
+	PrettyPrint bool
+}
+
 type VulnerabilitiesHandler struct {
-	collector VulnerabilityDataProvider
-	logger    *logrus.Logger
+	collector  VulnerabilityDataProvider
+	options    Options
+	severities severity.Order
+	logger     *logrus.Logger
 }
 
+// VulnerabilitiesSchemaVersion is reported as schema_version in /vulnerabilities
+// responses. It is bumped whenever a field is renamed, removed or changes
+// meaning; adding optional fields keeps the version.
+const VulnerabilitiesSchemaVersion = 1
+
+// VulnerabilitiesResponse is the /vulnerabilities body. Fields are encoded in
+// declaration order, images are sorted by URI and map keys are sorted by
+// encoding/json, so equal data always produces the same output.
 type VulnerabilitiesResponse struct {
-	Images      []types.ImageVulnerabilityData `json:"images"`
-	Summary     VulnerabilitySummary           `json:"summary"`
-	LastUpdated string                         `json:"last_updated"`
+	SchemaVersion int                            `json:"schema_version"`
+	Images        []types.ImageVulnerabilityData `json:"images"`
+	Summary       VulnerabilitySummary           `json:"summary"`
+	LastUpdated   string                         `json:"last_updated"`
 }
 
 type VulnerabilitySummary struct {
 	TotalImages          int            `json:"total_images"`
 	TotalVulnerabilities int            `json:"total_vulnerabilities"`
 	SeverityBreakdown    map[string]int `json:"severity_breakdown"`
+	SuppressedCount      int            `json:"suppressed_count"` // Findings excluded by the accepted CVEs list
 	TopCVEs              []CVESummary   `json:"top_cves"`
+	Truncated            bool           `json:"truncated"` // True when findings were cut by the global cap
 }
 
 type CVESummary struct {
@@ -45,36 +81,57 @@ type CVESummary struct {
 	Description string `json:"description"`
 }
 
-func NewVulnerabilitiesHandler(collector VulnerabilityDataProvider, logger *logrus.Logger) *VulnerabilitiesHandler {
+func NewVulnerabilitiesHandler(collector VulnerabilityDataProvider, options Options, logger *logrus.Logger) *VulnerabilitiesHandler {
 	return &VulnerabilitiesHandler{
-		collector: collector,
-		logger:    logger,
+		collector:  collector,
+		options:    options,
+		severities: severity.NewOrder(options.SeverityOrder),
+		logger:     logger,
 	}
 }
 
 func (v *VulnerabilitiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	logger := v.logger.WithField("endpoint", "/vulnerabilities")
 
-	// Get current vulnerability data
-	vulnerabilityData, lastCollectionTime := v.collector.GetVulnerabilityData()
-
 	// Check for query parameters for filtering
+	snapshotParam := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("snapshot")))
 	imageFilter := strings.TrimSpace(r.URL.Query().Get("image"))
 	severityFilter := strings.ToUpper(strings.TrimSpace(r.URL.Query().Get("severity")))
 	limitParam := strings.TrimSpace(r.URL.Query().Get("limit"))
+	format := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("format")))
 
-	// Validate severity filter
-	if severityFilter != "" {
-		validSeverities := map[string]bool{
-			"CRITICAL": true,
-			"HIGH":     true,
-			"MEDIUM":   true,
-			"LOW":      true,
-		}
-		if !validSeverities[severityFilter] {
-			http.Error(w, "Invalid severity filter. Must be one of: CRITICAL, HIGH, MEDIUM, LOW", http.StatusBadRequest)
+	// Validate output format
+	if format != "" && format != "json" && format != "ndjson" {
+		http.Error(w, "Invalid format. Must be one of: json, ndjson", http.StatusBadRequest)
+		return
+	}
+
+	// Select the current data or a retained earlier collection
+	var vulnerabilityData map[string]*types.ImageVulnerabilityData
+	var lastCollectionTime time.Time
+	switch snapshotParam {
+	case "", "current":
+		vulnerabilityData, lastCollectionTime = v.collector.GetVulnerabilityData()
+	case "previous":
+		snapshots, ok := v.collector.(SnapshotProvider)
+		if !ok {
+			http.Error(w, "Snapshots are not available", http.StatusNotFound)
 			return
 		}
+		vulnerabilityData, lastCollectionTime, ok = snapshots.GetSnapshot(1)
+		if !ok {
+			http.Error(w, "No previous snapshot available", http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "Invalid snapshot. Must be one of: current, previous", http.StatusBadRequest)
+		return
+	}
+
+	// Validate severity filter
+	if severityFilter != "" && !v.severities.Known(severityFilter) {
+		http.Error(w, "Invalid severity filter. Must be one of: "+strings.Join(v.severities.Severities(), ", "), http.StatusBadRequest)
+		return
 	}
 
 	// Validate and parse limit parameter
@@ -99,16 +156,19 @@ func (v *VulnerabilitiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 	}
 
 	logger.WithFields(logrus.Fields{
+		"snapshot":        snapshotParam,
 		"image_filter":    imageFilter,
 		"severity_filter": severityFilter,
 		"limit":           limit,
+		"format":          format,
 		"total_images":    len(vulnerabilityData),
 	}).Debug("Processing vulnerabilities request")
 
 	// Filter and prepare response data
 	var filteredImages []types.ImageVulnerabilityData
-	severityBreakdown := make(map[string]int)
+	severityBreakdown := v.severities.Breakdown()
 	totalVulns := 0
+	suppressed := 0
 	cveMap := make(map[string]*CVESummary)
 
 	for _, vulnData := range vulnerabilityData {
@@ -136,9 +196,13 @@ func (v *VulnerabilitiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 
 		// Create filtered image data
 		if len(filteredFindings) > 0 || (imageFilter == "" && severityFilter == "") {
-			filteredImage := *vulnData // Copy the struct
-			filteredImage.Findings = filteredFindings
-			filteredImages = append(filteredImages, filteredImage)
+			// Copy the vulnerability data too, since it's shared with the collector
+			vulnCopy := *vulnData.ImageVulnerability
+			vulnCopy.Findings = filteredFindings
+			filteredImages = append(filteredImages, types.ImageVulnerabilityData{
+				ImageVulnerability: &vulnCopy,
+				ImageInfo:          vulnData.ImageInfo,
+			})
 		}
 
 		// Update statistics (use original data for accurate totals)
@@ -148,80 +212,184 @@ func (v *VulnerabilitiesHandler) ServeHTTP(w http.ResponseWriter, r *http.Reques
 		}
 
 		// Track CVE occurrences
-		for _, finding := range vulnData.Findings {
-			if finding.Name != "" {
-				if cve, exists := cveMap[finding.Name]; exists {
-					cve.ImageCount++
-				} else {
-					cveMap[finding.Name] = &CVESummary{
-						Name:        finding.Name,
-						Severity:    finding.Severity,
-						ImageCount:  1,
-						Description: finding.Description,
-					}
-				}
-			}
-		}
+		trackCVEs(cveMap, vulnData.Findings)
+		suppressed += countAccepted(vulnData.Findings)
 	}
 
-	// Get top CVEs (sort by frequency)
-	var topCVEs []CVESummary
-	for _, cve := range cveMap {
-		topCVEs = append(topCVEs, *cve)
-	}
-	sort.Slice(topCVEs, func(i, j int) bool {
-		if topCVEs[i].ImageCount != topCVEs[j].ImageCount {
-			return topCVEs[i].ImageCount > topCVEs[j].ImageCount
-		}
-		// Secondary sort by severity priority
-		severityPriority := map[string]int{"CRITICAL": 4, "HIGH": 3, "MEDIUM": 2, "LOW": 1}
-		return severityPriority[topCVEs[i].Severity] > severityPriority[topCVEs[j].Severity]
+	// Order images by URI so responses are stable across requests
+	sort.Slice(filteredImages, func(i, j int) bool {
+		return filteredImages[i].ImageURI < filteredImages[j].ImageURI
 	})
 
-	// Limit top CVEs to 10
-	if len(topCVEs) > 10 {
-		topCVEs = topCVEs[:10]
+	// Apply the global findings cap
+	truncated := false
+	if v.options.MaxFindings > 0 {
+		filteredImages, truncated = capFindings(filteredImages, v.options.MaxFindings)
 	}
 
+	// Stream one image per line for NDJSON consumers
+	if format == "ndjson" {
+		v.serveNDJSON(w, filteredImages, truncated, logger)
+		return
+	}
+
+	// Get top CVEs (sort by frequency), limited to 10
+	topCVEs := rankCVEs(cveMap, 10, v.severities)
+
 	response := VulnerabilitiesResponse{
-		Images: filteredImages,
+		SchemaVersion: VulnerabilitiesSchemaVersion,
+		Images:        filteredImages,
 		Summary: VulnerabilitySummary{
 			TotalImages:          len(vulnerabilityData),
 			TotalVulnerabilities: totalVulns,
 			SeverityBreakdown:    severityBreakdown,
+			SuppressedCount:      suppressed,
 			TopCVEs:              topCVEs,
+			Truncated:            truncated,
 		},
 		LastUpdated: lastCollectionTime.Format("2006-01-02T15:04:05Z"),
 	}
 
+	if err := writeJSON(w, r, response, v.options.PrettyPrint); err != nil {
+		logger.WithError(err).Error("Failed to encode JSON response")
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	logger.WithFields(logrus.Fields{
+		"filtered_images": len(filteredImages),
+		"total_vulns":     totalVulns,
+		"top_cves":        len(topCVEs),
+		"truncated":       truncated,
+	}).Info("Served vulnerabilities response")
+}
+
+// capFindings limits the total number of findings across images ordered by
+// URI, so truncation is stable; images past the cap keep no findings.
+func capFindings(images []types.ImageVulnerabilityData, maxFindings int) ([]types.ImageVulnerabilityData, bool) {
+	remaining := maxFindings
+	truncated := false
+	for i := range images {
+		if len(images[i].Findings) > remaining {
+			images[i].Findings = images[i].Findings[:remaining]
+			truncated = true
+		}
+		remaining -= len(images[i].Findings)
+	}
+
+	return images, truncated
+}
+
+// trackCVEs counts CVE occurrences from findings into cveMap, skipping accepted CVEs
+func trackCVEs(cveMap map[string]*CVESummary, findings []types.VulnerabilityFinding) {
+	for _, finding := range findings {
+		if finding.Name == "" || finding.Accepted {
+			continue
+		}
+		if cve, exists := cveMap[finding.Name]; exists {
+			cve.ImageCount++
+		} else {
+			cveMap[finding.Name] = &CVESummary{
+				Name:        finding.Name,
+				Severity:    finding.Severity,
+				ImageCount:  1,
+				Description: finding.Description,
+			}
+		}
+	}
+}
+
+// countAccepted returns how many findings are on the accepted CVEs list
+func countAccepted(findings []types.VulnerabilityFinding) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Accepted {
+			count++
+		}
+	}
+	return count
+}
+
+// rankCVEs returns the most frequent CVEs, breaking ties by severity
+func rankCVEs(cveMap map[string]*CVESummary, limit int, severities severity.Order) []CVESummary {
+	var topCVEs []CVESummary
+	for _, cve := range cveMap {
+		topCVEs = append(topCVEs, *cve)
+	}
+	sort.Slice(topCVEs, func(i, j int) bool {
+		if topCVEs[i].ImageCount != topCVEs[j].ImageCount {
+			return topCVEs[i].ImageCount > topCVEs[j].ImageCount
+		}
+		// Secondary sort by severity priority, then by name for a stable order
+		if severities.Position(topCVEs[i].Severity) != severities.Position(topCVEs[j].Severity) {
+			return severities.MoreSevere(topCVEs[i].Severity, topCVEs[j].Severity)
+		}
+		return topCVEs[i].Name < topCVEs[j].Name
+	})
+
+	if limit > 0 && len(topCVEs) > limit {
+		topCVEs = topCVEs[:limit]
+	}
+	return topCVEs
+}
+
+// writeJSON encodes a JSON response, pretty-printed if the request asks for it
+func writeJSON(w http.ResponseWriter, r *http.Request, response interface{}, pretty bool) error {
 	w.Header().Set("Content-Type", "application/json")
 
-	// Pretty print if requested
-	if r.URL.Query().Get("pretty") != "" {
-		encoder := json.NewEncoder(w)
+	encoder := json.NewEncoder(w)
+	if prettyPrint(r, pretty) {
 		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(response); err != nil {
-			logger.WithError(err).Error("Failed to encode JSON response")
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+	return encoder.Encode(response)
+}
+
+// prettyPrint reports whether to indent a response. The pretty query
+// parameter overrides the configured default: false values such as
+// pretty=false or pretty=0 disable indenting, any other value enables it. An
+// empty value, as in ?pretty or ?pretty=, leaves the default.
+func prettyPrint(r *http.Request, byDefault bool) bool {
+	value := r.URL.Query().Get("pretty")
+	if value == "" {
+		return byDefault
+	}
+	if pretty, err := strconv.ParseBool(value); err == nil {
+		return pretty
+	}
+	return true
+}
+
+// serveNDJSON writes each image as a JSON object on its own line, flushing as it
+// goes so large datasets are streamed rather than buffered in full. Lines have
+// no summary, so a truncated stream is flagged by the X-Findings-Truncated header.
+func (v *VulnerabilitiesHandler) serveNDJSON(w http.ResponseWriter, images []types.ImageVulnerabilityData, truncated bool, logger *logrus.Entry) {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	if truncated {
+		w.Header().Set("X-Findings-Truncated", "true")
+	}
+
+	flusher, canFlush := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+
+	for _, image := range images {
+		// Encode terminates each object with a newline
+		if err := encoder.Encode(image); err != nil {
+			logger.WithError(err).Error("Failed to encode NDJSON line")
 			return
 		}
-	} else {
-		if err := json.NewEncoder(w).Encode(response); err != nil {
-			logger.WithError(err).Error("Failed to encode JSON response")
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
-			return
+		if canFlush {
+			flusher.Flush()
 		}
 	}
 
 	logger.WithFields(logrus.Fields{
-		"filtered_images": len(filteredImages),
-		"total_vulns":     totalVulns,
-		"top_cves":        len(topCVEs),
-	}).Info("Served vulnerabilities response")
+		"images":    len(images),
+		"truncated": truncated,
+	}).Info("Served vulnerabilities NDJSON stream")
 }
 
 // CreateVulnerabilitiesHandler creates a standard HTTP handler
-func CreateVulnerabilitiesHandler(dataProvider VulnerabilityDataProvider, logger *logrus.Logger) http.HandlerFunc {
-	handler := NewVulnerabilitiesHandler(dataProvider, logger)
+func CreateVulnerabilitiesHandler(dataProvider VulnerabilityDataProvider, options Options, logger *logrus.Logger) http.HandlerFunc {
+	handler := NewVulnerabilitiesHandler(dataProvider, options, logger)
 	return handler.ServeHTTP
 }
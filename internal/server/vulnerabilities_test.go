@@ -4,10 +4,15 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -80,7 +85,7 @@ func TestVulnerabilitiesHandler(t *testing.T) {
 		lastUpdated: time.Now(),
 	}
 
-	handler := NewVulnerabilitiesHandler(mockCollector, logger)
+	handler := NewVulnerabilitiesHandler(mockCollector, Options{}, logger)
 
 	tests := []struct {
 		name         string
@@ -173,6 +178,222 @@ func TestVulnerabilitiesHandler(t *testing.T) {
 	}
 }
 
+func TestVulnerabilitiesHandlerNDJSON(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockData := map[string]*types.ImageVulnerabilityData{
+		"app-one:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-one:v1",
+				Vulnerabilities: map[string]int{"HIGH": 1},
+				ScanStatus:      "COMPLETE",
+				Findings: []types.VulnerabilityFinding{
+					{Name: "CVE-2024-1111", Severity: "HIGH"},
+				},
+			},
+			ImageInfo: types.ImageInfo{
+				URI:       "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-one:v1",
+				Namespace: "production",
+			},
+		},
+		"app-two:v2": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-two:v2",
+				Vulnerabilities: map[string]int{"LOW": 2},
+				ScanStatus:      "COMPLETE",
+				Findings: []types.VulnerabilityFinding{
+					{Name: "CVE-2024-2222", Severity: "LOW"},
+					{Name: "CVE-2024-3333", Severity: "LOW"},
+				},
+			},
+			ImageInfo: types.ImageInfo{
+				URI:       "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-two:v2",
+				Namespace: "staging",
+			},
+		},
+	}
+
+	handler := NewVulnerabilitiesHandler(&MockVulnerabilityCollector{data: mockData, lastUpdated: time.Now()}, Options{}, logger)
+
+	t.Run("streams one image per line", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/vulnerabilities?format=ndjson", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+			t.Errorf("Expected Content-Type application/x-ndjson, got %s", ct)
+		}
+
+		seen := make(map[string]int)
+		scanner := bufio.NewScanner(rr.Body)
+		for scanner.Scan() {
+			var image types.ImageVulnerabilityData
+			if err := json.Unmarshal(scanner.Bytes(), &image); err != nil {
+				t.Fatalf("Failed to decode NDJSON line %q: %v", scanner.Text(), err)
+			}
+			seen[image.ImageURI] = len(image.Findings)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("Failed to read NDJSON stream: %v", err)
+		}
+
+		if len(seen) != 2 {
+			t.Fatalf("Expected 2 NDJSON lines, got %d", len(seen))
+		}
+		if seen["123456789012.dkr.ecr.us-east-1.amazonaws.com/app-two:v2"] != 2 {
+			t.Errorf("Expected app-two to carry 2 findings, got %d", seen["123456789012.dkr.ecr.us-east-1.amazonaws.com/app-two:v2"])
+		}
+	})
+
+	t.Run("filters apply to stream", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/vulnerabilities?format=ndjson&severity=HIGH", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		lines := 0
+		scanner := bufio.NewScanner(rr.Body)
+		for scanner.Scan() {
+			lines++
+		}
+		if lines != 1 {
+			t.Errorf("Expected 1 NDJSON line with HIGH filter, got %d", lines)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/vulnerabilities?format=xml", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+func TestVulnerabilitiesHandlerMaxFindings(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	newFindings := func(prefix string, count int) []types.VulnerabilityFinding {
+		var findings []types.VulnerabilityFinding
+		for i := 0; i < count; i++ {
+			findings = append(findings, types.VulnerabilityFinding{
+				Name:     fmt.Sprintf("CVE-2024-%s%d", prefix, i),
+				Severity: "MEDIUM",
+			})
+		}
+		return findings
+	}
+
+	mockData := map[string]*types.ImageVulnerabilityData{
+		"app-a:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-a:v1",
+				Vulnerabilities: map[string]int{"MEDIUM": 4},
+				Findings:        newFindings("A", 4),
+			},
+		},
+		"app-b:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-b:v1",
+				Vulnerabilities: map[string]int{"MEDIUM": 4},
+				Findings:        newFindings("B", 4),
+			},
+		},
+		"app-c:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app-c:v1",
+				Vulnerabilities: map[string]int{"MEDIUM": 4},
+				Findings:        newFindings("C", 4),
+			},
+		},
+	}
+	collector := &MockVulnerabilityCollector{data: mockData, lastUpdated: time.Now()}
+
+	tests := []struct {
+		name              string
+		maxFindings       int
+		expectedTotal     int
+		expectedTruncated bool
+	}{
+		{
+			name:              "cap below total truncates",
+			maxFindings:       6,
+			expectedTotal:     6,
+			expectedTruncated: true,
+		},
+		{
+			name:              "cap equal to total does not truncate",
+			maxFindings:       12,
+			expectedTotal:     12,
+			expectedTruncated: false,
+		},
+		{
+			name:              "no cap",
+			maxFindings:       0,
+			expectedTotal:     12,
+			expectedTruncated: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewVulnerabilitiesHandler(collector, Options{MaxFindings: tt.maxFindings}, logger)
+
+			req := httptest.NewRequest("GET", "/vulnerabilities", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var response VulnerabilitiesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			total := 0
+			for _, image := range response.Images {
+				total += len(image.Findings)
+			}
+
+			if total != tt.expectedTotal {
+				t.Errorf("Expected %d findings returned, got %d", tt.expectedTotal, total)
+			}
+			if response.Summary.Truncated != tt.expectedTruncated {
+				t.Errorf("Expected truncated=%v, got %v", tt.expectedTruncated, response.Summary.Truncated)
+			}
+
+			// Summary totals always reflect the full dataset
+			if response.Summary.TotalVulnerabilities != 12 {
+				t.Errorf("Expected 12 total vulnerabilities in summary, got %d", response.Summary.TotalVulnerabilities)
+			}
+
+			// Streamed output is capped the same way
+			rr = httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities?format=ndjson", nil))
+
+			streamed := 0
+			scanner := bufio.NewScanner(rr.Body)
+			for scanner.Scan() {
+				var image types.ImageVulnerabilityData
+				if err := json.Unmarshal(scanner.Bytes(), &image); err != nil {
+					t.Fatalf("Failed to unmarshal NDJSON line: %v", err)
+				}
+				streamed += len(image.Findings)
+			}
+			if streamed != tt.expectedTotal {
+				t.Errorf("Expected %d findings streamed, got %d", tt.expectedTotal, streamed)
+			}
+			if header := rr.Header().Get("X-Findings-Truncated"); (header == "true") != tt.expectedTruncated {
+				t.Errorf("Expected truncated=%v, got X-Findings-Truncated %q", tt.expectedTruncated, header)
+			}
+		})
+	}
+}
+
 // Mock implementation for testing
 type MockVulnerabilityCollector struct {
 	data        map[string]*types.ImageVulnerabilityData
@@ -186,3 +407,390 @@ func (m *MockVulnerabilityCollector) GetVulnerabilityData() (map[string]*types.I
 func (m *MockVulnerabilityCollector) Start(ctx context.Context) {
 	// Mock implementation - does nothing
 }
+
+// testImage returns the data of an image run by a Deployment
+func testImage(uri, namespace, workload string, findings ...types.VulnerabilityFinding) *types.ImageVulnerabilityData {
+	return &types.ImageVulnerabilityData{
+		ImageVulnerability: &types.ImageVulnerability{ImageURI: uri, Findings: findings},
+		ImageInfo:          types.ImageInfo{URI: uri, Namespace: namespace, Workload: workload, WorkloadType: "Deployment"},
+	}
+}
+
+// highFindings returns a HIGH finding for each CVE
+func highFindings(cves ...string) []types.VulnerabilityFinding {
+	findings := make([]types.VulnerabilityFinding, 0, len(cves))
+	for _, cve := range cves {
+		findings = append(findings, types.VulnerabilityFinding{Name: cve, Severity: "HIGH"})
+	}
+	return findings
+}
+
+func TestTrackCVEsSkipsAccepted(t *testing.T) {
+	cveMap := make(map[string]*CVESummary)
+	trackCVEs(cveMap, []types.VulnerabilityFinding{
+		{Name: "CVE-2024-0001", Severity: "HIGH"},
+		{Name: "CVE-2024-0002", Severity: "CRITICAL", Accepted: true},
+	})
+
+	if _, exists := cveMap["CVE-2024-0002"]; exists {
+		t.Error("Expected accepted CVE to be left out of the top CVEs")
+	}
+	if cve, exists := cveMap["CVE-2024-0001"]; !exists || cve.ImageCount != 1 {
+		t.Errorf("Expected CVE-2024-0001 counted once, got %+v", cve)
+	}
+}
+
+func TestVulnerabilitiesHandlerSuppressedCount(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockData := map[string]*types.ImageVulnerabilityData{
+		"app:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1",
+				Vulnerabilities: map[string]int{"HIGH": 1, "LOW": 0},
+				TotalCount:      1,
+				Findings: []types.VulnerabilityFinding{
+					{Name: "CVE-2024-0001", Severity: "HIGH"},
+					{Name: "CVE-2024-0002", Severity: "LOW", Accepted: true},
+				},
+			},
+		},
+	}
+	collector := &MockVulnerabilityCollector{data: mockData, lastUpdated: time.Now()}
+	handler := NewVulnerabilitiesHandler(collector, Options{}, logger)
+
+	req := httptest.NewRequest("GET", "/vulnerabilities", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	var response VulnerabilitiesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	if response.Summary.SuppressedCount != 1 {
+		t.Errorf("Expected 1 suppressed finding, got %d", response.Summary.SuppressedCount)
+	}
+	if response.Summary.TotalVulnerabilities != 1 {
+		t.Errorf("Expected 1 active vulnerability, got %d", response.Summary.TotalVulnerabilities)
+	}
+	if len(response.Images) != 1 || len(response.Images[0].Findings) != 2 {
+		t.Errorf("Expected accepted finding to still be listed, got %+v", response.Images)
+	}
+}
+
+func TestVulnerabilitiesHandlerSchemaVersion(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Several images sharing CVEs of equal frequency and severity, so map
+	// iteration order would show in an unstable encoding
+	mockData := make(map[string]*types.ImageVulnerabilityData)
+	for _, name := range []string{"web", "api", "batch", "worker"} {
+		uri := "123456789012.dkr.ecr.us-east-1.amazonaws.com/" + name + ":v1"
+		mockData[uri] = &types.ImageVulnerabilityData{
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        uri,
+				Vulnerabilities: map[string]int{"HIGH": 2, "LOW": 1},
+				TotalCount:      3,
+				Findings: []types.VulnerabilityFinding{
+					{Name: "CVE-2024-0001", Severity: "HIGH"},
+					{Name: "CVE-2024-0002", Severity: "HIGH"},
+					{Name: "CVE-2024-0003", Severity: "LOW"},
+				},
+			},
+			ImageInfo: types.ImageInfo{URI: uri},
+		}
+	}
+	collector := &MockVulnerabilityCollector{data: mockData, lastUpdated: time.Now()}
+	handler := NewVulnerabilitiesHandler(collector, Options{}, logger)
+
+	serve := func() []byte {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities", nil))
+		return rr.Body.Bytes()
+	}
+
+	body := serve()
+	var response map[string]any
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	// The version is pinned so that a schema change has to update it here too
+	if version, ok := response["schema_version"]; !ok || version != float64(1) {
+		t.Errorf("Expected schema_version 1, got %v", version)
+	}
+	if prefix := `{"schema_version":1,`; !strings.HasPrefix(string(body), prefix) {
+		t.Errorf("Expected the response to start with %s, got %.40s", prefix, body)
+	}
+
+	for range 10 {
+		if again := serve(); !bytes.Equal(again, body) {
+			t.Fatalf("Expected identical responses for unchanged data:\n%s\n%s", body, again)
+		}
+	}
+}
+
+// snapshotCollector adds retained snapshots to the mock collector
+type snapshotCollector struct {
+	MockVulnerabilityCollector
+	snapshots []map[string]*types.ImageVulnerabilityData // Most recent first
+	taken     []time.Time
+}
+
+func (s *snapshotCollector) GetSnapshot(age int) (map[string]*types.ImageVulnerabilityData, time.Time, bool) {
+	if age < 1 || age > len(s.snapshots) {
+		return nil, time.Time{}, false
+	}
+	return s.snapshots[age-1], s.taken[age-1], true
+}
+
+func (s *snapshotCollector) GetSnapshotAndCurrent(age int) (map[string]*types.ImageVulnerabilityData, time.Time, map[string]*types.ImageVulnerabilityData, time.Time, bool) {
+	snapshot, taken, ok := s.GetSnapshot(age)
+	if !ok {
+		return nil, time.Time{}, nil, time.Time{}, false
+	}
+	current, lastUpdated := s.GetVulnerabilityData()
+	return snapshot, taken, current, lastUpdated, true
+}
+
+func TestVulnerabilitiesHandlerSnapshot(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	imageData := func(uri string) map[string]*types.ImageVulnerabilityData {
+		return map[string]*types.ImageVulnerabilityData{
+			uri: {
+				ImageVulnerability: &types.ImageVulnerability{
+					ImageURI:        uri,
+					Vulnerabilities: map[string]int{"HIGH": 1},
+					TotalCount:      1,
+					Findings:        []types.VulnerabilityFinding{{Name: "CVE-2024-0001", Severity: "HIGH"}},
+				},
+				ImageInfo: types.ImageInfo{URI: uri},
+			},
+		}
+	}
+	currentURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v2"
+	previousURI := "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1"
+	previousTime := time.Date(2025, 1, 15, 10, 30, 0, 0, time.UTC)
+
+	withSnapshot := &snapshotCollector{
+		MockVulnerabilityCollector: MockVulnerabilityCollector{data: imageData(currentURI), lastUpdated: previousTime.Add(5 * time.Minute)},
+		snapshots:                  []map[string]*types.ImageVulnerabilityData{imageData(previousURI)},
+		taken:                      []time.Time{previousTime},
+	}
+	withoutSnapshot := &snapshotCollector{
+		MockVulnerabilityCollector: MockVulnerabilityCollector{data: imageData(currentURI), lastUpdated: previousTime},
+	}
+	withoutSupport := &MockVulnerabilityCollector{data: imageData(currentURI), lastUpdated: previousTime}
+
+	tests := []struct {
+		name           string
+		collector      VulnerabilityDataProvider
+		query          string
+		expectedStatus int
+		expectedImage  string
+		expectedTime   string
+	}{
+		{"current by default", withSnapshot, "", http.StatusOK, currentURI, "2025-01-15T10:35:00Z"},
+		{"explicit current", withSnapshot, "?snapshot=current", http.StatusOK, currentURI, "2025-01-15T10:35:00Z"},
+		{"previous snapshot", withSnapshot, "?snapshot=previous", http.StatusOK, previousURI, "2025-01-15T10:30:00Z"},
+		{"no previous snapshot yet", withoutSnapshot, "?snapshot=previous", http.StatusNotFound, "", ""},
+		{"provider without snapshots", withoutSupport, "?snapshot=previous", http.StatusNotFound, "", ""},
+		{"invalid snapshot", withSnapshot, "?snapshot=yesterday", http.StatusBadRequest, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewVulnerabilitiesHandler(tt.collector, Options{}, logger)
+
+			req := httptest.NewRequest("GET", "/vulnerabilities"+tt.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response VulnerabilitiesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(response.Images) != 1 || response.Images[0].ImageURI != tt.expectedImage {
+				t.Errorf("Expected only image %s, got %+v", tt.expectedImage, response.Images)
+			}
+			if response.LastUpdated != tt.expectedTime {
+				t.Errorf("Expected last_updated %s, got %s", tt.expectedTime, response.LastUpdated)
+			}
+		})
+	}
+}
+
+func TestVulnerabilitiesHandlerSeverityOrder(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	// Every CVE affects one image, so top CVEs are ranked by severity alone
+	mockData := map[string]*types.ImageVulnerabilityData{
+		"app:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1",
+				Vulnerabilities: map[string]int{"LOW": 1, "NEGLIGIBLE": 1, "UNDEFINED": 1, "CRITICAL": 1},
+				TotalCount:      4,
+				Findings: []types.VulnerabilityFinding{
+					{Name: "CVE-2024-0001", Severity: "UNDEFINED"},
+					{Name: "CVE-2024-0002", Severity: "NEGLIGIBLE"},
+					{Name: "CVE-2024-0003", Severity: "LOW"},
+					{Name: "CVE-2024-0004", Severity: "CRITICAL"},
+				},
+			},
+		},
+	}
+	collector := &MockVulnerabilityCollector{data: mockData, lastUpdated: time.Now()}
+	options := Options{SeverityOrder: []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"}}
+
+	handler := NewVulnerabilitiesHandler(collector, options, logger)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities", nil))
+
+	var response VulnerabilitiesResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	expected := []string{"CRITICAL", "LOW", "NEGLIGIBLE", "UNDEFINED"}
+	if len(response.Summary.TopCVEs) != len(expected) {
+		t.Fatalf("Expected %d top CVEs, got %+v", len(expected), response.Summary.TopCVEs)
+	}
+	for i, severity := range expected {
+		if response.Summary.TopCVEs[i].Severity != severity {
+			t.Errorf("Expected top CVE %d to be %s, got %+v", i, severity, response.Summary.TopCVEs[i])
+		}
+	}
+
+	// The severity filter accepts exactly the configured severities
+	tests := []struct {
+		name           string
+		options        Options
+		expectedStatus int
+	}{
+		{"extended ordering", options, http.StatusOK},
+		{"default ordering", Options{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewVulnerabilitiesHandler(collector, tt.options, logger)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities?severity=negligible", nil))
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestVulnerabilitiesHandlerSeverityBreakdownKeys(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	mockData := map[string]*types.ImageVulnerabilityData{
+		"app:v1": {
+			ImageVulnerability: &types.ImageVulnerability{
+				ImageURI:        "123456789012.dkr.ecr.us-east-1.amazonaws.com/app:v1",
+				Vulnerabilities: map[string]int{"HIGH": 2, "UNTRIAGED": 1},
+				TotalCount:      3,
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		data     map[string]*types.ImageVulnerabilityData
+		options  Options
+		expected map[string]int
+	}{
+		{
+			name:     "no data",
+			data:     map[string]*types.ImageVulnerabilityData{},
+			expected: map[string]int{"CRITICAL": 0, "HIGH": 0, "MEDIUM": 0, "LOW": 0},
+		},
+		{
+			name:     "zero-filled with extras",
+			data:     mockData,
+			expected: map[string]int{"CRITICAL": 0, "HIGH": 2, "MEDIUM": 0, "LOW": 0, "UNTRIAGED": 1},
+		},
+		{
+			name:     "extended ordering",
+			data:     mockData,
+			options:  Options{SeverityOrder: []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"}},
+			expected: map[string]int{"CRITICAL": 0, "HIGH": 2, "MEDIUM": 0, "LOW": 0, "NEGLIGIBLE": 0, "UNTRIAGED": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collector := &MockVulnerabilityCollector{data: tt.data, lastUpdated: time.Now()}
+			handler := NewVulnerabilitiesHandler(collector, tt.options, logger)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/vulnerabilities", nil))
+
+			var response VulnerabilitiesResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if !reflect.DeepEqual(response.Summary.SeverityBreakdown, tt.expected) {
+				t.Errorf("Expected severity breakdown %v, got %v", tt.expected, response.Summary.SeverityBreakdown)
+			}
+		})
+	}
+}
+
+func TestVulnerabilitiesHandlerPrettyPrint(t *testing.T) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.ErrorLevel)
+
+	collector := &MockVulnerabilityCollector{data: cveTestData(), lastUpdated: time.Now()}
+
+	tests := []struct {
+		name            string
+		prettyByDefault bool
+		query           string
+		expectPretty    bool
+	}{
+		{"compact by default", false, "", false},
+		{"pretty by default", true, "", true},
+		{"query enables pretty", false, "?pretty=1", true},
+		{"query without value keeps compact default", false, "?pretty", false},
+		{"empty query keeps compact default", false, "?pretty=", false},
+		{"empty query keeps pretty default", true, "?pretty=", true},
+		{"query disables pretty", true, "?pretty=false", false},
+		{"query disables pretty with zero", true, "?pretty=0", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := Options{PrettyPrint: tt.prettyByDefault}
+			for path, handler := range map[string]http.Handler{
+				"/vulnerabilities": NewVulnerabilitiesHandler(collector, options, logger),
+				"/cves":            NewCVEsHandler(collector, options, logger),
+			} {
+				rr := httptest.NewRecorder()
+				handler.ServeHTTP(rr, httptest.NewRequest("GET", path+tt.query, nil))
+
+				if rr.Code != http.StatusOK {
+					t.Fatalf("%s: expected status 200, got %d", path, rr.Code)
+				}
+				if !json.Valid(rr.Body.Bytes()) {
+					t.Fatalf("%s: expected valid JSON, got %q", path, rr.Body.String())
+				}
+				if pretty := strings.Contains(rr.Body.String(), "\n  "); pretty != tt.expectPretty {
+					t.Errorf("%s: expected pretty output %v, got %q", path, tt.expectPretty, rr.Body.String())
+				}
+			}
+		})
+	}
+}
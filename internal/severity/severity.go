@@ -0,0 +1,71 @@
+// ABOUTME: Configurable severity ordering shared by the API, readiness, notifications and cache.
+// ABOUTME: Ranks severities for sorting and validates severities against the configured list.
+
+package severity
+
+import "strings"
+
+// DefaultOrder lists the severities known by default, most severe first
+var DefaultOrder = []string{"CRITICAL", "HIGH", "MEDIUM", "LOW"}
+
+// Order ranks severities from most to least severe. Scanners with
+// additional levels, such as NEGLIGIBLE, are supported by listing them.
+type Order struct {
+	severities []string
+	rank       map[string]int
+}
+
+// NewOrder creates an ordering from severities listed most severe first,
+// matched case-insensitively; an empty list uses DefaultOrder
+func NewOrder(severities []string) Order {
+	if len(severities) == 0 {
+		severities = DefaultOrder
+	}
+
+	order := Order{rank: make(map[string]int, len(severities))}
+	for _, severity := range severities {
+		severity = strings.ToUpper(strings.TrimSpace(severity))
+		if _, exists := order.rank[severity]; severity == "" || exists {
+			continue
+		}
+		order.rank[severity] = len(order.severities)
+		order.severities = append(order.severities, severity)
+	}
+	return order
+}
+
+// Severities returns the known severities, most severe first
+func (o Order) Severities() []string {
+	return o.severities
+}
+
+// Known reports whether a severity is part of the ordering
+func (o Order) Known(severity string) bool {
+	_, ok := o.rank[severity]
+	return ok
+}
+
+// MoreSevere reports whether severity a ranks above b; severities outside
+// the ordering rank below all known ones
+func (o Order) MoreSevere(a, b string) bool {
+	return o.Position(a) < o.Position(b)
+}
+
+// Position returns a severity's index in the ordering, or the number of
+// known severities for unknown ones
+func (o Order) Position(severity string) int {
+	if rank, ok := o.rank[severity]; ok {
+		return rank
+	}
+	return len(o.severities)
+}
+
+// Breakdown returns severity counts with every known severity zero-filled, so
+// the keys are stable whether or not a severity was found
+func (o Order) Breakdown() map[string]int {
+	counts := make(map[string]int, len(o.severities))
+	for _, severity := range o.severities {
+		counts[severity] = 0
+	}
+	return counts
+}
@@ -0,0 +1,60 @@
+// ABOUTME: Unit tests for the configurable severity ordering.
+// ABOUTME: Tests parsing, the default ordering and ranking of known and unknown severities.
+
+package severity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{"default", nil, DefaultOrder},
+		{"extended", []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"}, []string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"}},
+		{"normalized", []string{" critical", "High ", "", "HIGH", "low"}, []string{"CRITICAL", "HIGH", "LOW"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := NewOrder(tt.input)
+			if !reflect.DeepEqual(order.severities, tt.expected) {
+				t.Errorf("Expected severities %v, got %v", tt.expected, order.severities)
+			}
+		})
+	}
+}
+
+func TestOrderRanking(t *testing.T) {
+	order := NewOrder([]string{"CRITICAL", "HIGH", "MEDIUM", "LOW", "NEGLIGIBLE"})
+
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"CRITICAL", "HIGH", true},
+		{"HIGH", "CRITICAL", false},
+		{"LOW", "NEGLIGIBLE", true},
+		{"NEGLIGIBLE", "UNDEFINED", true},
+		{"UNDEFINED", "NEGLIGIBLE", false},
+		{"UNDEFINED", "INFORMATIONAL", false},
+		{"HIGH", "HIGH", false},
+	}
+
+	for _, tt := range tests {
+		if got := order.MoreSevere(tt.a, tt.b); got != tt.expected {
+			t.Errorf("moreSevere(%q, %q) = %v, expected %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+
+	if !order.Known("NEGLIGIBLE") || order.Known("UNDEFINED") {
+		t.Error("Expected NEGLIGIBLE to be known and UNDEFINED to be unknown")
+	}
+	if NewOrder(nil).Known("NEGLIGIBLE") {
+		t.Error("Expected NEGLIGIBLE to be unknown with the default ordering")
+	}
+}
@@ -3,12 +3,19 @@
 
 package types
 
+import "time"
+
 // ImageInfo represents a discovered container image with its Kubernetes context
 type ImageInfo struct {
 	URI          string
 	Namespace    string
 	Workload     string
 	WorkloadType string // "Deployment", "StatefulSet", etc.
+	Release      string `json:"release,omitempty"` // Helm release managing the workload, if any
+
+	// WorkloadCount is the number of distinct workloads using the image,
+	// counted when the engine merges duplicate discoveries
+	WorkloadCount int `json:"workload_count,omitempty"`
 }
 
 // VulnerabilityFinding represents a single vulnerability finding
@@ -25,6 +32,12 @@ type VulnerabilityFinding struct {
 	FixAvailable     string  `json:"fix_available"`     // YES, NO, PARTIAL, or unknown
 	Score            float64 `json:"score"`             // CVSS or provider-specific score
 	Type             string  `json:"type"`              // Vulnerability type
+
+	// FirstSeen is when the engine first observed this CVE on the image
+	FirstSeen *time.Time `json:"first_seen,omitempty"`
+
+	// Accepted marks a CVE on the risk acceptance list; it is excluded from counts
+	Accepted bool `json:"accepted"`
 }
 
 // ImageVulnerability represents vulnerability information for a container image
@@ -36,11 +49,36 @@ type ImageVulnerability struct {
 	TotalCount      int                    `json:"total_count"`
 	ScanStatus      string                 `json:"scan_status"`
 	LastScanTime    *string                `json:"last_scan_time"`
-	Findings        []VulnerabilityFinding `json:"findings"` // Detailed findings
+	Findings        []VulnerabilityFinding `json:"findings"`           // Detailed findings
+	Platform        string                 `json:"platform,omitempty"` // os/arch[/variant] scanned for multi-arch images
+	Digest          string                 `json:"digest,omitempty"`   // Manifest digest scanned, when digest resolution is enabled
+
+	// FindingsDropped marks results whose findings were shed to bound memory;
+	// the severity counts are still complete
+	FindingsDropped bool `json:"findings_dropped,omitempty"`
+}
+
+// RegistryCollection is the outcome of one registry's part of a collection
+type RegistryCollection struct {
+	Registry  string // Registry host, e.g. 123456789012.dkr.ecr.us-east-1.amazonaws.com
+	Collected int    // Images whose vulnerability data was fetched
+	Failed    int    // Images whose fetch failed, or every image when filtering failed
+	Success   bool   // Whether any image was collected, or none failed
+}
+
+// CallerIdentity is the cloud identity a vulnerability source authenticates as
+type CallerIdentity struct {
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+	UserID  string `json:"user_id"`
 }
 
 // ImageVulnerabilityData combines vulnerability data with discovery metadata
 type ImageVulnerabilityData struct {
 	*ImageVulnerability
 	ImageInfo
+
+	// Stale marks data kept from a previous collection because the image's
+	// fetch failed in the latest one
+	Stale bool `json:"stale,omitempty"`
 }